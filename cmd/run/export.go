@@ -0,0 +1,129 @@
+package main
+
+import (
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "dump subscriptions to CSV or JSON",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "format", Value: "csv", Usage: "csv or json"},
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "output file (default stdout)"},
+			&cli.StringFlag{Name: "user", Usage: "export only this user's subscriptions (UUID)"},
+			&cli.StringFlag{Name: "status", Usage: "with --user: only active, paused, cancelled or expired subscriptions"},
+			tenantFlag(),
+		},
+		Action: export,
+	}
+}
+
+func export(c *cli.Context) error {
+	format := c.String("format")
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("--format must be csv or json, got %q", format)
+	}
+	if !lifecycle.ValidFilter(c.String("status")) {
+		return fmt.Errorf("--status must be active, paused, cancelled or expired, got %q", c.String("status"))
+	}
+
+	conf, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	serv, rep, err := openService(c, conf)
+	if err != nil {
+		return err
+	}
+	defer rep.CloseConnection()
+
+	var subscriptions []models.Subscription
+	if user := c.String("user"); user != "" {
+		userID, errParse := tools.ParseUUID(user)
+		if errParse != nil {
+			return fmt.Errorf("--user: invalid UUID %q", user)
+		}
+		subscriptions, err = serv.ListSubscriptions(c.Context, userID, models.ListFilter{Status: c.String("status")})
+	} else if c.String("status") != "" {
+		return fmt.Errorf("--status requires --user")
+	} else {
+		subscriptions, err = serv.ListAllSubscriptions(c.Context)
+	}
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if path := c.String("output"); path != "" {
+		file, errCreate := os.Create(path)
+		if errCreate != nil {
+			return errCreate
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(subscriptions)
+	}
+	return writeCSV(out, subscriptions)
+}
+
+func writeCSV(out io.Writer, subscriptions []models.Subscription) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"user_id", "service_name", "category", "tags", "metadata", "price", "start_date", "end_date", "trial_end", "status", "created_at", "updated_at"}); err != nil {
+		return err
+	}
+	for _, sub := range subscriptions {
+		endDate := ""
+		if sub.EndDate != nil {
+			endDate = sub.EndDate.Format("01-2006")
+		}
+		trialEnd := ""
+		if sub.TrialEnd != nil {
+			trialEnd = sub.TrialEnd.Format("01-2006")
+		}
+		meta := ""
+		if len(sub.Metadata) > 0 {
+			encoded, err := json.Marshal(sub.Metadata)
+			if err != nil {
+				return err
+			}
+			meta = string(encoded)
+		}
+		record := []string{
+			sub.UserID.String(),
+			sub.ServiceName,
+			sub.Category,
+			strings.Join(sub.Tags, ";"),
+			meta,
+			strconv.Itoa(sub.Price),
+			sub.StartDate.Format("01-2006"),
+			endDate,
+			trialEnd,
+			sub.Status,
+			sub.CreatedAt.Format(time.RFC3339),
+			sub.UpdatedAt.Format(time.RFC3339),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -2,45 +2,71 @@ package main
 
 import (
 	_ "agrigation_api/docs"
-	"agrigation_api/internal/app"
 	"agrigation_api/internal/database/repository"
 	"agrigation_api/internal/service"
-	"agrigation_api/migrations"
+	"agrigation_api/internal/tenant"
 	"agrigation_api/pkg/config"
 	logger2 "agrigation_api/pkg/logger"
-	"agrigation_api/pkg/logger/logger"
 	"agrigation_api/pkg/tools"
-	"context"
 	"fmt"
+	"log"
 	"os"
-	"os/signal"
 	"runtime"
-	"syscall"
-	"time"
+
+	"github.com/urfave/cli/v2"
 )
 
 /*
 TODO: Тесты; В CalculateTotal посмотреть пересечения и тд, проваерить там
 */
 /*
-Требуемые переменные окружения (.env файл для Docker-compose):
+Команды (по умолчанию - serve):
+
+	serve                             запуск http сервера (миграции применяются при старте)
+	migrate up|down|to|status         управление схемой БД
+	seed                              генерация тестовых подписок
+	export --format csv|json          выгрузка подписок
+	report total --from --to          подсчет расходов за период
+
+	seed, export и report работают с организацией --tenant (по умолчанию - организация default)
+
+Конфигурация (pkg/config):
+
+	YAML-файл: флаг --config или переменная окружения CONFIG_PATH (пример - config.example.yaml).
+	Переменные окружения переопределяют значения из файла (.env файл для Docker-compose):
 
 	Runtime:
-	tools.GetEnvAsInt("NUM_CPU", runtime.NumCPU())
+	NUM_CPU
 
 	Сам сервер:
-	tools.GetEnvAsInt("SERVER_PORT", 11682)
-	tools.GetEnv("SERVER_IP", "127.0.0.1")
+	SERVER_PORT, SERVER_IP, SHUTDOWN_TIMEOUT, CORS_ALLOWED_ORIGINS, RATE_LIMIT_RPS, RATE_LIMIT_BURST
+
+	Хранилище (postgres, memory, sqlite):
+	STORAGE, SQLITE_PATH
 
-	Postgres
-	tools.GetEnv("PG_USER", "postgres")
-	tools.GetEnv("PG_PASSWORD", "postgres")
-	tools.GetEnv("PG_HOST", "localhost")
-	tools.GetEnvAsInt("PG_PORT", 5432)
-	tools.GetEnv("PG_DATABASE", "aggregation")
+	Postgres:
+	PG_USER, PG_PASSWORD, PG_HOST, PG_PORT, PG_DATABASE, PG_CONNECT_MAX_WAIT,
+	PG_MAX_CONNS, PG_MIN_CONNS, PG_SSLMODE, PG_SSLROOTCERT, PG_APPLICATION_NAME, PG_QUERY_TIMEOUT, PG_STATEMENT_TIMEOUT,
+	PG_REPLICAS, PG_REPLICA_CHECK_INTERVAL, PG_ROW_LEVEL_SECURITY
+
+	Кеш (none, memory, redis):
+	CACHE_BACKEND, CACHE_TTL, REDIS_ADDRESS, REDIS_PASSWORD, REDIS_DB
 
 	logger:
-	tools.GetEnv("LOGGER", "INFO")
+	LOGGER
+
+	Авторизация и фичи:
+	AUTH_ENABLED, AUTH_HEADER, API_KEYS, TENANT_API_KEYS (key=organization_id,...), SWAGGER_ENABLED, STRICT_USERS,
+	REQUIRE_IF_MATCH
+
+	Корзина удаленных подписок:
+	TRASH_RETENTION (0 - не очищать), TRASH_PURGE_INTERVAL
+
+	Ответы на запросы с Idempotency-Key:
+	IDEMPOTENCY_TTL, IDEMPOTENCY_PURGE_INTERVAL
+
+	Горячая перезагрузка (SIGHUP или изменение файла): CONFIG_WATCH_INTERVAL.
+	Без перезапуска применяются logger, server.cors, server.rate_limit и auth.
 */
 
 // @title Subscription Management API
@@ -49,60 +75,63 @@ TODO: Тесты; В CalculateTotal посмотреть пересечения
 // @BasePath /api/v1
 // @schemes http
 func main() {
-	// Ограничение ресурсов
-	runtime.GOMAXPROCS(tools.GetEnvAsInt("NUM_CPU", runtime.NumCPU()))
-
-	// Logger
-	logs := logger2.NewMyLogger(tools.GetEnv("LOGGER", "INFO"))
-
-	// Migrate
-	if errMigrate := migrations.CheckAndCreateTables(); errMigrate != nil {
-		logs.Error("Error to init tables: "+errMigrate.Error(), logger.GetPlace())
-		return
+	application := &cli.App{
+		Name:  "subscriptions",
+		Usage: "Subscription Management API and ops tools",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "path to YAML config file",
+				EnvVars: []string{config.EnvConfigPath},
+			},
+		},
+		Commands: []*cli.Command{
+			serveCommand(),
+			migrateCommand(),
+			seedCommand(),
+			exportCommand(),
+			reportCommand(),
+		},
+		Action: serve,
 	}
-	logs.Info("Init Database successful", logger.GetPlace())
 
-	// Инициализация Postgres
-	rep, errRep := repository.InitRepository()
-	if errRep != nil {
-		logs.Error(fmt.Sprintf("Ошибка инициализации PostgreSQL: %v", errRep), logger.GetPlace())
-		return
+	if err := application.Run(os.Args); err != nil {
+		log.Fatal(err)
 	}
-	logs.Info("Успешное подключение к PostgreSQL", logger.GetPlace())
+}
 
-	// Создаем сервис
-	subService := service.NewSubscriptionService(rep)
+// loadConfig - конфиг из файла --config и переменных окружения
+func loadConfig(c *cli.Context) (*config.Config, error) {
+	conf, err := config.ReadConfig(c.String("config"))
+	if err != nil {
+		return nil, err
+	}
+	runtime.GOMAXPROCS(conf.Runtime.NumCPU)
+	return conf, nil
+}
 
-	// Инициализация конфига
-	conf, err := config.ReadConfig()
+// openService - репозиторий и сервис для ops-команд. Контекст команды переключается на организацию --tenant.
+// Репозиторий нужно закрыть после использования
+func openService(c *cli.Context, conf *config.Config) (service.Subscriptions, repository.Repository, error) {
+	organization, err := tools.ParseUUID(c.String("tenant"))
 	if err != nil {
-		logs.Error(fmt.Sprintf("Reading config file error: %v", err), logger.GetPlace())
-		return
+		return nil, nil, fmt.Errorf("--tenant: invalid UUID %q", c.String("tenant"))
 	}
-	logs.Info("Успешная инициализация конфига", logger.GetPlace())
-
-	// Инициализация сервера
-	application := app.NewApp(conf, logs, subService)
-	go func() {
-		if errStart := application.Start(); errStart != nil {
-			logs.Error(fmt.Sprintf("Server Start error: %v", errStart), logger.GetPlace())
-			return
-		}
-	}()
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-
-	sig := <-quit
-	logs.Info(fmt.Sprintf("Received signal: %v", sig), logger.GetPlace())
-
-	ctx, clos := context.WithTimeout(context.Background(), 30*time.Second)
-	defer clos()
-	if errShut := application.ShutDown(ctx); errShut != nil {
-		logs.Error("Error graceful shutdown. Heavy stopping...", logger.GetPlace())
-		os.Exit(1)
-		return
+	c.Context = tenant.WithID(c.Context, organization)
+
+	rep, err := repository.InitRepository(c.Context, conf, newLogger(conf))
+	if err != nil {
+		return nil, nil, err
 	}
-	rep.CloseConnection()
-	logs.Info("Shutdown successful", logger.GetPlace())
+	return service.NewSubscriptionService(rep).WithStrictUsers(conf.Features.StrictUsers), rep, nil
+}
+
+// tenantFlag - организация, с данными которой работает ops-команда
+func tenantFlag() cli.Flag {
+	return &cli.StringFlag{Name: "tenant", Value: tenant.Default.String(), Usage: "organization ID (UUID)", EnvVars: []string{"TENANT_ID"}}
+}
+
+// newLogger - логгер с уровнем из конфига
+func newLogger(conf *config.Config) logger2.MyLogger {
+	return logger2.NewMyLogger(conf.Logger.Level)
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"agrigation_api/internal/database/postgres"
+	"agrigation_api/internal/database/sqlite"
+	"agrigation_api/migrations"
+	"agrigation_api/pkg/config"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "manage database schema",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "up",
+				Usage: "apply all pending migrations",
+				Action: func(c *cli.Context) error {
+					return withMigrator(c, func(m *migrations.Migrator) ([]migrations.Migration, error) {
+						return m.Up(c.Context)
+					})
+				},
+			},
+			{
+				Name:  "down",
+				Usage: "roll back the last applied migrations",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "steps", Value: 1, Usage: "number of migrations to roll back"},
+				},
+				Action: func(c *cli.Context) error {
+					return withMigrator(c, func(m *migrations.Migrator) ([]migrations.Migration, error) {
+						return m.Down(c.Context, c.Int("steps"))
+					})
+				},
+			},
+			{
+				Name:      "to",
+				Usage:     "migrate up or down to the given version (0 rolls back everything)",
+				ArgsUsage: "<version>",
+				Action: func(c *cli.Context) error {
+					version, err := strconv.ParseInt(c.Args().First(), 10, 64)
+					if err != nil || version < 0 {
+						return fmt.Errorf("version must be a non-negative number, got %q", c.Args().First())
+					}
+					return withMigrator(c, func(m *migrations.Migrator) ([]migrations.Migration, error) {
+						return m.To(c.Context, version)
+					})
+				},
+			},
+			{
+				Name:   "status",
+				Usage:  "show applied and pending migrations",
+				Action: migrateStatus,
+			},
+		},
+	}
+}
+
+// openMigrator - мигратор для хранилища из конфига на отдельном соединении, close закрывает его
+func openMigrator(c *cli.Context) (migrator *migrations.Migrator, close func(), err error) {
+	conf, err := loadConfig(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch conf.Storage.Backend {
+	case config.StoragePostgres:
+	case config.StorageSQLite:
+		db, err := sqlite.Open(c.Context, conf.Storage.SQLite)
+		if err != nil {
+			return nil, nil, err
+		}
+		migrator, err = migrations.NewSQLiteMigrator(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return migrator, func() { db.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("migrations are not supported for storage backend %q", conf.Storage.Backend)
+	}
+
+	pool, err := postgres.InitPGPool(c.Context, conf.Database, func(attempt int, wait time.Duration, err error) {
+		fmt.Fprintf(os.Stderr, "waiting for database (attempt %d, retry in %s): %v\n", attempt, wait.Round(time.Millisecond), err)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err = migrations.NewMigrator(pool)
+	if err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+	return migrator, pool.Close, nil
+}
+
+// withMigrator - выполняет fn и печатает затронутые миграции
+func withMigrator(c *cli.Context, fn func(m *migrations.Migrator) ([]migrations.Migration, error)) error {
+	migrator, closePool, err := openMigrator(c)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+
+	done, err := fn(migrator)
+	for _, migration := range done {
+		fmt.Printf("%04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Println("nothing to do")
+	}
+	return nil
+}
+
+func migrateStatus(c *cli.Context) error {
+	migrator, closePool, err := openMigrator(c)
+	if err != nil {
+		return err
+	}
+	defer closePool()
+
+	statuses, err := migrator.Status(c.Context)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Missing {
+			state = "applied (missing in binary)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
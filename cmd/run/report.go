@@ -0,0 +1,184 @@
+package main
+
+import (
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/service"
+	"agrigation_api/internal/users"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+)
+
+func reportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "report",
+		Usage: "reports over subscriptions",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "total",
+				Usage:  "total cost of subscriptions for a period",
+				Flags:  reportFlags(),
+				Action: reportTotal,
+			},
+			{
+				Name:   "categories",
+				Usage:  "cost of subscriptions for a period by category",
+				Flags:  reportFlags(),
+				Action: reportCategories,
+			},
+		},
+	}
+}
+
+// reportFlags - период и фильтры отчета
+func reportFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "from", Usage: "start month (MM-YYYY)", Required: true},
+		&cli.StringFlag{Name: "to", Usage: "end month (MM-YYYY)", Required: true},
+		&cli.StringFlag{Name: "user", Usage: "filter by user ID (UUID)"},
+		&cli.StringFlag{Name: "service", Usage: "filter by service name"},
+		&cli.StringSliceFlag{Name: "tag", Usage: "filter by tag, repeatable: a subscription must have every tag"},
+		tenantFlag(),
+	}
+}
+
+func reportTotal(c *cli.Context) error {
+	req, err := reportRequest(c)
+	if err != nil {
+		return err
+	}
+
+	conf, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	serv, rep, err := openService(c, conf)
+	if err != nil {
+		return err
+	}
+	defer rep.CloseConnection()
+
+	if err := reportCurrency(c, serv, &req); err != nil {
+		return err
+	}
+	total, err := serv.CalculateTotal(c.Context, req)
+	if err != nil {
+		return err
+	}
+	others, err := serv.OtherCurrencyTotals(c.Context, req)
+	if err != nil {
+		return err
+	}
+
+	printFilters(c, req)
+	fmt.Printf("Total:   %d %s\n", total, req.Currency)
+	printOtherCurrencies(others)
+	return nil
+}
+
+func reportCategories(c *cli.Context) error {
+	req, err := reportRequest(c)
+	if err != nil {
+		return err
+	}
+
+	conf, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	serv, rep, err := openService(c, conf)
+	if err != nil {
+		return err
+	}
+	defer rep.CloseConnection()
+
+	if err := reportCurrency(c, serv, &req); err != nil {
+		return err
+	}
+	categories, err := serv.TotalByCategory(c.Context, req)
+	if err != nil {
+		return err
+	}
+	others, err := serv.OtherCurrencyTotals(c.Context, req)
+	if err != nil {
+		return err
+	}
+
+	printFilters(c, req)
+	total := 0
+	for _, category := range categories {
+		name := category.Category
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Printf("  %-20s %d %s\n", name, category.Total, req.Currency)
+		total += category.Total
+	}
+	fmt.Printf("Total:   %d %s\n", total, req.Currency)
+	printOtherCurrencies(others)
+	return nil
+}
+
+// reportCurrency - валюта отчета: валюта пользователя --user, без него или для незаведенного - catalog.DefaultCurrency
+func reportCurrency(c *cli.Context, serv service.Subscriptions, req *models.CalculateTotalRequest) error {
+	var user *models.User
+	if req.UserID != uuid.Nil {
+		var err error
+		if user, err = serv.GetUser(c.Context, req.UserID); err != nil {
+			return err
+		}
+	}
+	req.Currency = users.Currency(user)
+	return nil
+}
+
+// printOtherCurrencies - суммы подписок в других валютах, в Total они не входят
+func printOtherCurrencies(others []models.CurrencyTotal) {
+	for _, other := range others {
+		fmt.Printf("Other:   %d %s (not included)\n", other.Total, other.Currency)
+	}
+}
+
+// reportRequest - период и фильтры из флагов
+func reportRequest(c *cli.Context) (models.CalculateTotalRequest, error) {
+	startMonth, err := tools.ParseMonthYear(c.String("from"))
+	if err != nil {
+		return models.CalculateTotalRequest{}, fmt.Errorf("--from: expected MM-YYYY, got %q", c.String("from"))
+	}
+	endMonth, err := tools.ParseMonthYear(c.String("to"))
+	if err != nil {
+		return models.CalculateTotalRequest{}, fmt.Errorf("--to: expected MM-YYYY, got %q", c.String("to"))
+	}
+
+	req := models.CalculateTotalRequest{
+		StartMonth:  startMonth,
+		EndMonth:    endMonth,
+		ServiceName: c.String("service"),
+		Tags:        catalog.Tags(c.StringSlice("tag")),
+	}
+	if user := c.String("user"); user != "" {
+		req.UserID, err = tools.ParseUUID(user)
+		if err != nil {
+			return models.CalculateTotalRequest{}, fmt.Errorf("--user: invalid UUID %q", user)
+		}
+	}
+	return req, nil
+}
+
+// printFilters - шапка отчета: период и указанные фильтры
+func printFilters(c *cli.Context, req models.CalculateTotalRequest) {
+	fmt.Printf("Period:  %s .. %s\n", c.String("from"), c.String("to"))
+	if req.UserID != uuid.Nil {
+		fmt.Printf("User:    %s\n", req.UserID)
+	}
+	if req.ServiceName != "" {
+		fmt.Printf("Service: %s\n", req.ServiceName)
+	}
+	if len(req.Tags) > 0 {
+		fmt.Printf("Tags:    %s\n", strings.Join(req.Tags, ", "))
+	}
+}
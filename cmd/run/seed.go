@@ -0,0 +1,112 @@
+package main
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/pkg/models"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+)
+
+// seedCatalog - реальные сервисы и диапазон месячной цены в рублях
+var seedCatalog = []struct {
+	name     string
+	minPrice int
+	maxPrice int
+}{
+	{"Yandex Plus", 299, 449},
+	{"Kinopoisk", 269, 399},
+	{"Netflix", 599, 1199},
+	{"Spotify", 169, 299},
+	{"Apple Music", 169, 269},
+	{"YouTube Premium", 199, 299},
+	{"VK Music", 149, 249},
+	{"Okko", 199, 399},
+	{"Ivi", 199, 399},
+	{"Telegram Premium", 299, 299},
+	{"ChatGPT Plus", 1800, 2200},
+	{"GitHub Copilot", 900, 1000},
+	{"iCloud+", 149, 599},
+	{"Google One", 139, 699},
+	{"World Class", 4500, 9000},
+}
+
+func seedCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "seed",
+		Usage: "generate realistic fake subscriptions",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "users", Value: 10, Usage: "number of users"},
+			&cli.IntFlag{Name: "per-user", Value: 4, Usage: "max subscriptions per user"},
+			&cli.Uint64Flag{Name: "seed", Usage: "random seed (0 - random)"},
+			tenantFlag(),
+		},
+		Action: seed,
+	}
+}
+
+func seed(c *cli.Context) error {
+	users, perUser := c.Int("users"), c.Int("per-user")
+	if users <= 0 || perUser <= 0 {
+		return errors.New("--users and --per-user must be positive")
+	}
+	perUser = min(perUser, len(seedCatalog))
+
+	randomSeed := c.Uint64("seed")
+	if randomSeed == 0 {
+		randomSeed = uint64(time.Now().UnixNano())
+	}
+	rnd := rand.New(rand.NewPCG(randomSeed, randomSeed>>1))
+
+	conf, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+	serv, rep, err := openService(c, conf)
+	if err != nil {
+		return err
+	}
+	defer rep.CloseConnection()
+
+	now := time.Now()
+	currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	created := 0
+	for i := 0; i < users; i++ {
+		userID := uuid.New()
+		// профиль нужен для строгого режима (features.strict_users)
+		user := models.UserRequest{ID: userID, DisplayName: fmt.Sprintf("User %d", i+1)}
+		if _, err := serv.CreateUser(c.Context, user); err != nil {
+			return fmt.Errorf("create user %s: %w", userID, err)
+		}
+		for _, idx := range rnd.Perm(len(seedCatalog))[:1+rnd.IntN(perUser)] {
+			item := seedCatalog[idx]
+			start := currentMonth.AddDate(0, -rnd.IntN(24), 0)
+			req := models.CreateOrUpdateRequest{
+				ServiceName: item.name,
+				Price:       item.minPrice + rnd.IntN(item.maxPrice-item.minPrice+1),
+				UserID:      userID,
+				StartDate:   start.Format("01-2006"),
+			}
+			// примерно треть подписок уже закончилась или закончится
+			if rnd.IntN(3) == 0 {
+				req.EndDate = start.AddDate(0, 1+rnd.IntN(18), 0).Format("01-2006")
+			}
+
+			_, err := serv.CreateSubscription(c.Context, req)
+			if errors.Is(err, dberrors.SubscriptionAlreadyExist) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("create subscription %s for %s: %w", req.ServiceName, userID, err)
+			}
+			created++
+		}
+	}
+
+	fmt.Printf("Created %d subscriptions for %d users (seed %d)\n", created, users, randomSeed)
+	return nil
+}
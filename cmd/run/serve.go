@@ -0,0 +1,208 @@
+package main
+
+import (
+	"agrigation_api/internal/app"
+	"agrigation_api/internal/cache"
+	"agrigation_api/internal/database/repository"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/config"
+	logger2 "agrigation_api/pkg/logger"
+	"agrigation_api/pkg/logger/logger"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "serve",
+		Usage:  "wait for database, apply migrations and start http server",
+		Action: serve,
+	}
+}
+
+// serve - запуск http сервера
+func serve(c *cli.Context) error {
+	// Инициализация конфига
+	conf, err := loadConfig(c)
+	if err != nil {
+		return fmt.Errorf("reading config error: %w", err)
+	}
+
+	// Logger
+	logs := newLogger(conf)
+	logs.Info("Успешная инициализация конфига", logger.GetPlace())
+
+	// Кеш подсчетов и списков, nil - кеш выключен
+	cacheStore, err := cache.NewStore(conf.Cache)
+	if err != nil {
+		return fmt.Errorf("cache init error: %w", err)
+	}
+	if cacheStore != nil {
+		defer cacheStore.Close()
+	}
+
+	// Инициализация сервера. Слушаем порт сразу, чтобы /health и /ready отвечали, пока ждем БД
+	store := config.NewStore(c.String("config"), conf)
+	application := app.NewApp(store, logs)
+	go func() {
+		if errStart := application.Start(); errStart != nil {
+			logs.Error(fmt.Sprintf("Server Start error: %v", errStart), logger.GetPlace())
+			return
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+
+	// Инициализация хранилища: для Postgres - подключение и миграции (с повторными попытками, пока БД не поднимется)
+	application.SetNotReady("waiting for database")
+	dbCtx, cancelDB := context.WithCancel(context.Background())
+	defer cancelDB()
+	type dbResult struct {
+		rep repository.Repository
+		err error
+	}
+	dbReady := make(chan dbResult, 1)
+	go func() {
+		rep, errRep := repository.InitRepository(dbCtx, conf, logs)
+		dbReady <- dbResult{rep: rep, err: errRep}
+	}()
+
+	// Горячая перезагрузка конфига: SIGHUP или изменение файла
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	fileChanged := make(chan struct{}, 1)
+	stopWatch := make(chan struct{})
+	defer close(stopWatch)
+	go store.Watch(conf.Reload.WatchInterval, fileChanged, stopWatch)
+
+	var rep repository.Repository
+	var sig os.Signal
+	for sig == nil {
+		select {
+		case sig = <-quit:
+		case result := <-dbReady:
+			if result.err != nil {
+				logs.Error(fmt.Sprintf("Ошибка инициализации хранилища %s: %v", conf.Storage.Backend, result.err), logger.GetPlace())
+				shutdown(application, conf, logs)
+				return result.err
+			}
+			rep = result.rep
+			logs.Info(fmt.Sprintf("Успешное подключение к хранилищу %s", conf.Storage.Backend), logger.GetPlace())
+
+			// Создаем сервис, при включенном кеше - с кешем поверх
+			var subscriptions service.Subscriptions = service.NewSubscriptionService(rep).
+				WithStrictUsers(conf.Features.StrictUsers).
+				WithIdempotencyTTL(conf.Idempotency.TTL)
+			if cacheStore != nil {
+				subscriptions = service.NewCachedSubscriptions(subscriptions, cacheStore, conf.Cache.TTL, logs)
+				logs.Info(fmt.Sprintf("Cache enabled: %s, ttl %s", conf.Cache.Backend, conf.Cache.TTL), logger.GetPlace())
+			}
+			application.Activate(subscriptions)
+
+			// Очистка корзины до остановки сервиса
+			if conf.Trash.Retention > 0 {
+				go purgeTrash(dbCtx, subscriptions, conf.Trash, logs)
+			}
+			go purgeIdempotencyKeys(dbCtx, subscriptions, conf.Idempotency, logs)
+		case <-reload:
+			reloadConfig(store, logs, "SIGHUP")
+		case <-fileChanged:
+			reloadConfig(store, logs, "config file changed")
+		}
+	}
+	logs.Info(fmt.Sprintf("Received signal: %v", sig), logger.GetPlace())
+
+	cancelDB()
+	shutdown(application, conf, logs)
+	if rep != nil {
+		rep.CloseConnection()
+	}
+	logs.Info("Shutdown successful", logger.GetPlace())
+	return nil
+}
+
+// purgeTrash - раз в conf.PurgeInterval окончательно удаляет подписки, пролежавшие в корзине дольше conf.Retention.
+// Останавливается с отменой ctx
+func purgeTrash(ctx context.Context, subscriptions service.Subscriptions, conf config.TrashConfig, logs logger2.MyLogger) {
+	ticker := time.NewTicker(conf.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := subscriptions.PurgeDeletedSubscriptions(ctx, time.Now().Add(-conf.Retention))
+		if err != nil && ctx.Err() == nil {
+			logs.Error(fmt.Sprintf("Trash purge error: %v", err), logger.GetPlace())
+		}
+		if purged > 0 {
+			logs.Info(fmt.Sprintf("Trash purged: %d subscriptions deleted more than %s ago", purged, conf.Retention), logger.GetPlace())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeIdempotencyKeys - раз в conf.PurgeInterval удаляет истекшие ключи Idempotency-Key с сохраненными ответами.
+// Останавливается с отменой ctx
+func purgeIdempotencyKeys(ctx context.Context, subscriptions service.Subscriptions, conf config.IdempotencyConfig, logs logger2.MyLogger) {
+	ticker := time.NewTicker(conf.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := subscriptions.PurgeIdempotencyKeys(ctx)
+		if err != nil && ctx.Err() == nil {
+			logs.Error(fmt.Sprintf("Idempotency keys purge error: %v", err), logger.GetPlace())
+		}
+		if purged > 0 {
+			logs.Info(fmt.Sprintf("Idempotency keys purged: %d expired", purged), logger.GetPlace())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// shutdown - graceful shutdown http сервера, по таймауту - принудительная остановка
+func shutdown(application *app.App, conf *config.Config, logs logger2.MyLogger) {
+	ctx, clos := context.WithTimeout(context.Background(), conf.Server.ShutdownTimeout)
+	defer clos()
+	if errShut := application.ShutDown(ctx); errShut != nil {
+		logs.Error("Error graceful shutdown. Heavy stopping...", logger.GetPlace())
+		os.Exit(1)
+	}
+}
+
+// reloadConfig - перечитывает конфиг и применяет безопасное подмножество настроек.
+// При невалидном конфиге продолжает работать со старым
+func reloadConfig(store *config.Store, logs logger2.MyLogger, reason string) {
+	logs.Info(fmt.Sprintf("Reloading config (%s)", reason), logger.GetPlace())
+
+	result, err := store.Reload()
+	if err != nil {
+		logs.Error(fmt.Sprintf("Config reload rejected, keeping previous config: %v", err), logger.GetPlace())
+		return
+	}
+
+	logs.SetLevel(store.Get().Logger.Level)
+	if len(result.Applied) == 0 {
+		logs.Info("Config reloaded: no changes", logger.GetPlace())
+	}
+	for _, change := range result.Applied {
+		logs.Info("Config reloaded: "+change, logger.GetPlace())
+	}
+	for _, section := range result.Ignored {
+		logs.Warning(fmt.Sprintf("Config section %q changed but requires restart to apply", section), logger.GetPlace())
+	}
+}
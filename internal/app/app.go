@@ -15,16 +15,26 @@ type App struct {
 	fileServer *server.Server
 }
 
-func NewApp(config *config.Config, logger logger2.MyLogger, service service.Subscriptions) *App {
-	fileServer := server.NewServer(config, logger, service)
+func NewApp(store *config.Store, logger logger2.MyLogger) *App {
+	fileServer := server.NewServer(store, logger)
 	return &App{
 		fileServer: fileServer,
 	}
 }
 
+// SetNotReady - причина, по которой API еще не готово (отдается в /ready)
+func (app *App) SetNotReady(status string) {
+	app.fileServer.Readiness.SetNotReady(status)
+}
+
+// Activate - подключает сервис к роутам API, после этого сервер готов
+func (app *App) Activate(service service.Subscriptions) {
+	app.fileServer.Activate(service)
+}
+
 func (app *App) Start() error {
-	app.fileServer.Logger.Info(fmt.Sprintf("Server listening on port %d", app.fileServer.Port), logger.GetPlace())
-	err := http.ListenAndServe(fmt.Sprintf(":%d", app.fileServer.Port), app.fileServer.Router)
+	app.fileServer.Logger.Info(fmt.Sprintf("Server listening on %s", app.fileServer.Address), logger.GetPlace())
+	err := http.ListenAndServe(app.fileServer.Address, app.fileServer.Router)
 	return err
 }
 
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// auditOperations - операции журнала для фильтра operation
+var auditOperations = []string{
+	models.AuditCreate, models.AuditUpdate, models.AuditDelete, models.AuditRestore, models.AuditStatusChange,
+}
+
+// SubscriptionHistory - GET /subscriptions/{id}/history
+// SubscriptionHistory godoc
+// @Summary Subscription change history
+// @Description Audit log entries of one subscription, newest first: who changed it, when, in which request, and the subscription before and after the change
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Param limit query int false "Maximum number of entries (1-1000), 100 by default"
+// @Success 200 {object} models.AuditResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/subscriptions/{id}/history [get]
+func (h *Handler) SubscriptionHistory(w http.ResponseWriter, r *http.Request) {
+	// роут /subscriptions/{id}/{resource}: /{id}/history пересекается с /user/{id}
+	if r.PathValue("resource") != "history" {
+		problem.Write(w, r, http.StatusNotFound, problem.NotFound, "Not found")
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid subscription id",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "id", "Invalid subscription ID")
+		return
+	}
+	limit, ok := h.auditLimit(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeAudit(w, r, models.AuditFilter{SubscriptionID: id, Limit: limit})
+}
+
+// ListAudit - GET /admin/audit
+// ListAudit godoc
+// @Summary Query the audit log
+// @Description Audit log entries of the organization, newest first. Requires an operator API key (not bound to an organization)
+// @Tags admin
+// @Produce json
+// @Param subscription_id query string false "Subscription ID (UUID)"
+// @Param user_id query string false "Subscription owner (UUID)"
+// @Param actor query string false "Who made the change: X-Actor value or api-key:<fingerprint>"
+// @Param operation query string false "Operation" Enums(create, update, delete, restore, status_change)
+// @Param from query string false "Entries at or after this time (RFC 3339)" example(2026-01-01T00:00:00Z)
+// @Param to query string false "Entries before this time (RFC 3339)"
+// @Param limit query int false "Maximum number of entries (1-1000), 100 by default"
+// @Success 200 {object} models.AuditResponse
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/admin/audit [get]
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	if !h.operator(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.AuditFilter{Actor: query.Get("actor"), Operation: query.Get("operation")}
+	for name, target := range map[string]*uuid.UUID{"subscription_id": &filter.SubscriptionID, "user_id": &filter.UserID} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: audit query with invalid %s",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, name), logger.GetPlace())
+			problem.Invalid(w, r, name, "Invalid "+name)
+			return
+		}
+		*target = id
+	}
+	if filter.Operation != "" && !slices.Contains(auditOperations, filter.Operation) {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: audit query with invalid operation",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "operation", "operation must be one of: create, update, delete, restore, status_change")
+		return
+	}
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		moment, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: audit query with invalid %s",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, name), logger.GetPlace())
+			problem.Invalid(w, r, name, name+" must be a time in RFC 3339 format")
+			return
+		}
+		*target = moment
+	}
+	var ok bool
+	if filter.Limit, ok = h.auditLimit(w, r); !ok {
+		return
+	}
+
+	h.writeAudit(w, r, filter)
+}
+
+// auditLimit - параметр limit запроса журнала. При ошибке ответ уже записан
+func (h *Handler) auditLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultAuditLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxAuditLimit {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: audit query with invalid limit",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "limit", fmt.Sprintf("limit must be an integer from 1 to %d", maxAuditLimit))
+		return 0, false
+	}
+	return limit, true
+}
+
+// writeAudit - ответ с записями журнала по фильтру
+func (h *Handler) writeAudit(w http.ResponseWriter, r *http.Request, filter models.AuditFilter) {
+	entries, err := h.serv.ListAudit(r.Context(), filter)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	tools.WriteJSON(w, http.StatusOK, models.AuditResponse{Entries: entries})
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: audit entries listed successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
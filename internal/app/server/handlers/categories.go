@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"net/http"
+)
+
+// CategoryTotalsHandler - GET /subscriptions/total/categories
+// CategoryTotalsHandler godoc
+// @Summary Spending by category for a period
+// @Description Totals per subscription category with the same period overlap rules and filters as the total. Subscriptions without a category are reported under an empty category. Categories are ordered by total, largest first.
+// @Description As in the total, only subscriptions in the response currency are counted, totals in other currencies are returned in other_currencies
+// @Tags analytics
+// @Produce json
+// @Param start_month query string false "Start month (MM-YYYY or YYYY-MM), required unless user_id is a registered user" example(01-2024)
+// @Param end_month query string false "End month (MM-YYYY or YYYY-MM), required unless user_id is a registered user" example(12-2024)
+// @Param user_id query string false "User ID for filtering (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
+// @Param service_name query string false "Service name for filtering" example(Netflix)
+// @Param tag query []string false "Tag filter, repeatable: a subscription must have every tag" collectionFormat(multi)
+// @Success 200 {object} models.CategoryTotalsResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/subscriptions/total/categories [get]
+func (h *Handler) CategoryTotalsHandler(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := h.totalRequest(w, r)
+	if !ok {
+		return
+	}
+
+	categories, err := h.serv.TotalByCategory(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if categories == nil {
+		categories = []models.CategoryTotal{}
+	}
+	// Подписки в других валютах в отчет не входят
+	others, err := h.serv.OtherCurrencyTotals(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	response := models.CategoryTotalsResponse{
+		Success:         true,
+		Currency:        req.Currency,
+		OtherCurrencies: others,
+		Categories:      categories,
+		Period: models.PeriodInfo{
+			StartMonth: req.StartMonth,
+			EndMonth:   req.EndMonth,
+		},
+		Filters: filterInfo(req),
+	}
+	for _, category := range categories {
+		response.Total += category.Total
+	}
+
+	tools.WriteJSON(w, http.StatusOK, response)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: category totals successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/problem"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// serviceErrors - ответы на ошибки сервиса и репозитория. Ошибка, которой здесь нет, - 500 без подробностей
+var serviceErrors = []struct {
+	err    error
+	status int
+	code   string
+	detail string
+}{
+	{dberrors.SubscriptionNotFound, http.StatusNotFound, problem.SubscriptionNotFound, "Subscription not found"},
+	{dberrors.SubscriptionAlreadyExist, http.StatusConflict, problem.SubscriptionExists, "User already has a subscription to this service"},
+	{dberrors.SubscriptionVersionMismatch, http.StatusPreconditionFailed, problem.SubscriptionModified,
+		"Subscription has been modified, get it again and retry"},
+	{dberrors.SubscriptionStatusError, http.StatusConflict, problem.StatusTransition, "Subscription can not be moved to this status"},
+	{dberrors.SubscriptionDateError, http.StatusBadRequest, problem.InvalidPeriod,
+		"Month is outside of the subscription period, overlaps a previous pause or the period ends before it starts"},
+	{service.ErrPriceRequired, http.StatusBadRequest, problem.PriceRequired, "Price is required: service has no default price"},
+	{service.ErrUnknownUser, http.StatusBadRequest, problem.UnknownUser, "User is not registered"},
+
+	{dberrors.ServiceNotFound, http.StatusNotFound, problem.ServiceNotFound, "Service not found"},
+	{dberrors.ServiceAlreadyExist, http.StatusConflict, problem.ServiceExists, "Service name or alias is already taken"},
+
+	{dberrors.OrganizationNotFound, http.StatusNotFound, problem.OrganizationNotFound, "Organization not found"},
+	{dberrors.OrganizationAlreadyExist, http.StatusConflict, problem.OrganizationExists, "Organization name is already taken"},
+	{dberrors.OrganizationNotEmpty, http.StatusConflict, problem.OrganizationNotEmpty, "Organization has subscriptions, services or users"},
+	{service.ErrDefaultOrganization, http.StatusConflict, problem.DefaultOrganization, "Default organization can not be deleted"},
+	{dberrors.MemberNotFound, http.StatusNotFound, problem.MemberNotFound, "Member not found"},
+
+	{dberrors.UserNotFound, http.StatusNotFound, problem.UserNotFound, "User not found"},
+	{dberrors.UserAlreadyExist, http.StatusConflict, problem.UserExists, "User ID or email is already taken"},
+}
+
+// writeServiceError - ответ на ошибку сервиса по serviceErrors. Известная ошибка пишется в лог предупреждением,
+// остальные - ошибкой. Текст err в ответ не попадает
+func (h *Handler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	for _, known := range serviceErrors {
+		if errors.Is(err, known.err) {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: %v",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
+			problem.Write(w, r, known.status, known.code, known.detail)
+			return
+		}
+	}
+
+	h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: %v",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
+	writeInternalError(w, r)
+}
+
+// invalid - 400 validation_failed по сообщению проверки. Проверки (catalog, users, metadata, lifecycle) начинают
+// сообщение с имени поля, оно попадает в errors
+func invalid(w http.ResponseWriter, r *http.Request, message string) {
+	problem.Invalid(w, r, fieldOf(message), message)
+}
+
+// fieldOf - поле, с которого начинается сообщение проверки
+func fieldOf(message string) string {
+	field, _, _ := strings.Cut(message, " ")
+	return field
+}
+
+// writeInternalError - 500 без подробностей: причина есть только в логе
+func writeInternalError(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, http.StatusInternalServerError, problem.Internal, "Internal server error")
+}
+
+// writeMethodNotAllowed - 405
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	problem.Write(w, r, http.StatusMethodNotAllowed, problem.MethodNotAllowed, "Method not allowed")
+}
+
+// Routes - роутер, который на запросы без роута отвечает problem+json: 404 или 405 с заголовком Allow,
+// как их определил сам роутер
+func Routes(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		unmatched := &unmatchedResponse{header: http.Header{}}
+		handler.ServeHTTP(unmatched, r)
+		if unmatched.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", unmatched.header.Get("Allow"))
+			writeMethodNotAllowed(w, r)
+			return
+		}
+		problem.Write(w, r, http.StatusNotFound, problem.NotFound, "Not found")
+	})
+}
+
+// unmatchedResponse - статус и заголовки ответа роутера на запрос без роута, тело отбрасывается
+type unmatchedResponse struct {
+	header http.Header
+	status int
+}
+
+func (u *unmatchedResponse) Header() http.Header {
+	return u.header
+}
+
+func (u *unmatchedResponse) WriteHeader(status int) {
+	if u.status == 0 {
+		u.status = status
+	}
+}
+
+func (u *unmatchedResponse) Write(data []byte) (int, error) {
+	u.WriteHeader(http.StatusOK)
+	return len(data), nil
+}
+
+// decodeJSON - тело запроса в v. При ошибке ответ malformed_request уже записан: поле неверного типа
+// попадает в errors, текст ошибки декодера с именами типов Go в ответ не попадает
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid json: %v",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
+
+	p := models.Problem{Status: http.StatusBadRequest, Code: problem.MalformedRequest, Detail: "Request body must be a valid JSON object"}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		message := typeErr.Field + " must be " + jsonType(typeErr.Type)
+		p.Detail, p.Errors = message, []models.FieldError{{Field: typeErr.Field, Message: message}}
+	}
+	problem.WriteProblem(w, r, p)
+	return false
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// jsonType - тип JSON, в который декодируется значение типа t
+func jsonType(t reflect.Type) string {
+	// uuid.UUID и другие типы с UnmarshalText приходят строкой
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return "a string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	default:
+		return "an object"
+	}
+}
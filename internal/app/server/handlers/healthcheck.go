@@ -1,14 +1,60 @@
 package handlers
 
 import (
+	logger2 "agrigation_api/pkg/logger"
 	"agrigation_api/pkg/logger/logger"
 	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 )
 
+// Readiness - готовность принимать запросы к API (например, пока ждем БД - не готов)
+type Readiness struct {
+	mu     sync.RWMutex
+	ready  bool
+	status string
+}
+
+// NewReadiness - конструктор, изначально сервис не готов
+func NewReadiness(status string) *Readiness {
+	return &Readiness{status: status}
+}
+
+// SetNotReady - сервис не готов, status - причина ("waiting for database", "shutting down")
+func (r *Readiness) SetNotReady(status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ready, r.status = false, status
+}
+
+// SetReady - сервис готов принимать запросы
+func (r *Readiness) SetReady() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ready, r.status = true, "ready"
+}
+
+// Status - готов ли сервис и текущий статус
+func (r *Readiness) Status() (bool, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ready, r.status
+}
+
+// SystemHandler - служебные роуты, которые работают еще до подключения к БД
+type SystemHandler struct {
+	readiness *Readiness
+	logs      logger2.MyLogger
+}
+
+func NewSystemHandler(readiness *Readiness, logs logger2.MyLogger) *SystemHandler {
+	return &SystemHandler{readiness: readiness, logs: logs}
+}
+
 // HealthCheck godoc
 // @Summary Health check
 // @Description Check if API is running
@@ -16,7 +62,7 @@ import (
 // @Produce json
 // @Success 200 {object} models.HealthResponse
 // @Router /health [get]
-func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+func (h *SystemHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := models.HealthResponse{
 		Status:    "ok",
 		Timestamp: time.Now(),
@@ -29,3 +75,24 @@ func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		h.logs.Error(fmt.Sprintf("Json-Encode Error: %v", errEncode), logger.GetPlace())
 	}
 }
+
+// ReadinessCheck godoc
+// @Summary Readiness check
+// @Description Check if API is ready to serve requests (database connected, migrations applied)
+// @Tags system
+// @Produce json
+// @Success 200 {object} models.ReadinessResponse
+// @Failure 503 {object} models.ReadinessResponse
+// @Router /ready [get]
+func (h *SystemHandler) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	ready, status := h.readiness.Status()
+	code := http.StatusOK
+	if !ready {
+		code = http.StatusServiceUnavailable
+	}
+	tools.WriteJSON(w, code, models.ReadinessResponse{
+		Ready:     ready,
+		Status:    status,
+		Timestamp: time.Now(),
+	})
+}
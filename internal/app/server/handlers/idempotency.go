@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+const (
+	// HeaderIdempotencyKey - ключ, с которым повтор изменяющего запроса получает ответ первого выполнения
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed - ответ повторен из сохраненного, запрос не выполнялся
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKey - предел длины ключа, как колонки idempotency_key
+const maxIdempotencyKey = 200
+
+// replayedHeaders - заголовки ответа, которые сохраняются и повторяются вместе с телом
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency - изменяющие запросы (POST, PUT, DELETE) с заголовком Idempotency-Key выполняются один раз:
+// ответ сохраняется вместе с хешем запроса, повтор с тем же ключом и телом получает его без выполнения.
+// Ключ с другим запросом - 422, повтор, пока первый запрос выполняется, - 409. Ответ 5xx не сохраняется,
+// такой запрос можно повторить с тем же ключом
+func (h *Handler) Idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderIdempotencyKey)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey || strings.IndexFunc(key, unicode.IsControl) >= 0 {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: request with invalid idempotency key",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Invalid(w, r, HeaderIdempotencyKey, fmt.Sprintf("%s must be at most %d characters without control characters",
+				HeaderIdempotencyKey, maxIdempotencyKey))
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: request body read error: %v",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
+			problem.Write(w, r, http.StatusBadRequest, problem.MalformedRequest, "Request body can not be read")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+
+		stored, err := h.serv.ReserveIdempotencyKey(r.Context(), key, hash)
+		switch {
+		case err != nil:
+			h.writeServiceError(w, r, err)
+			return
+		case stored == nil:
+			h.execute(w, r, next, key, hash)
+			return
+		case stored.RequestHash != hash:
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: idempotency key reused with another request",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Write(w, r, http.StatusUnprocessableEntity, problem.IdempotencyKeyReused,
+				HeaderIdempotencyKey+" has already been used with a different request")
+			return
+		case !stored.Completed:
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: idempotency key is in use",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Write(w, r, http.StatusConflict, problem.IdempotencyKeyInUse,
+				"A request with this "+HeaderIdempotencyKey+" is still in progress")
+			return
+		}
+
+		for name, value := range stored.Header {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set(HeaderIdempotentReplayed, "true")
+		w.WriteHeader(stored.Status)
+		w.Write(stored.Body)
+		h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: idempotent response replayed",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+	})
+}
+
+// execute - выполнить запрос с занятым ключом и сохранить ответ. Ключ освобождается при ответе 5xx и панике.
+// Ответ сохраняется и после отмены запроса клиентом, иначе ключ остался бы занятым до истечения
+func (h *Handler) execute(w http.ResponseWriter, r *http.Request, next http.Handler, key, hash string) {
+	ctx := context.WithoutCancel(r.Context())
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		if p := recover(); p != nil {
+			h.release(ctx, r, key)
+			panic(p)
+		}
+	}()
+	next.ServeHTTP(recorder, r)
+
+	if recorder.status >= http.StatusInternalServerError {
+		h.release(ctx, r, key)
+		return
+	}
+	record := models.IdempotencyRecord{Key: key, RequestHash: hash, Status: recorder.status, Body: recorder.body.Bytes(),
+		Header: make(map[string]string)}
+	for _, name := range replayedHeaders {
+		if value := w.Header().Get(name); value != "" {
+			record.Header[name] = value
+		}
+	}
+	if err := h.serv.CompleteIdempotencyKey(ctx, record); err != nil {
+		h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: idempotency key complete error: %v",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
+	}
+}
+
+// release - освободить ключ, ошибка только пишется в лог: ключ освободится по истечении срока
+func (h *Handler) release(ctx context.Context, r *http.Request, key string) {
+	if err := h.serv.ReleaseIdempotencyKey(ctx, key); err != nil {
+		h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: idempotency key release error: %v",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
+	}
+}
+
+// requestHash - SHA-256 метода, пути с параметрами и тела запроса
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.RequestURI())
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder - пишет ответ клиенту и запоминает статус и тело
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = status, true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
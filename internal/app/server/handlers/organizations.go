@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxOrganizationNameLength - organizations.name VARCHAR(100)
+const maxOrganizationNameLength = 100
+
+// ListOrganizations - GET /organizations
+// ListOrganizations godoc
+// @Summary List organizations
+// @Description Organizations ordered by name. Requires an operator API key (not bound to an organization)
+// @Tags organizations
+// @Produce json
+// @Success 200 {object} models.OrganizationsResponse
+// @Failure 403 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/organizations [get]
+func (h *Handler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	if !h.operator(w, r) {
+		return
+	}
+
+	organizations, err := h.serv.ListOrganizations(r.Context())
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if organizations == nil {
+		organizations = []models.Organization{}
+	}
+
+	tools.WriteJSON(w, http.StatusOK, models.OrganizationsResponse{Organizations: organizations})
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: organizations listed successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// GetOrganization - GET /organizations/{id}
+// GetOrganization godoc
+// @Summary Get an organization
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID (UUID)"
+// @Success 200 {object} models.Organization
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/organizations/{id} [get]
+func (h *Handler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+
+	organization, err := h.serv.GetOrganization(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if organization == nil {
+		h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: organization not found",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Write(w, r, http.StatusNotFound, problem.OrganizationNotFound, "Organization not found")
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, organization)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: organization found successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// CreateOrganization - POST /organizations
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Requires an operator API key. Names are unique
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param organization body models.OrganizationRequest true "Organization"
+// @Success 201 {object} models.Organization
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/organizations [post]
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	if !h.operator(w, r) {
+		return
+	}
+
+	var req models.OrganizationRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if msg := validOrganization(req); msg != "" {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid organization: %s",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, msg), logger.GetPlace())
+		invalid(w, r, msg)
+		return
+	}
+
+	organization, err := h.serv.CreateOrganization(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusCreated, organization)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: organization create successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// DeleteOrganization - DELETE /organizations/{id}
+// DeleteOrganization godoc
+// @Summary Delete an organization
+// @Description Requires an operator API key. Only an organization without subscriptions and services can be deleted, the default organization can not
+// @Tags organizations
+// @Param id path string true "Organization ID (UUID)"
+// @Success 204 "Organization deleted successfully"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/organizations/{id} [delete]
+func (h *Handler) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	if !h.operator(w, r) {
+		return
+	}
+	id, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.serv.DeleteOrganization(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: organization delete successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// ListMembers - GET /organizations/{id}/members
+// ListMembers godoc
+// @Summary List organization members
+// @Description Owners and members of subscriptions join the organization automatically
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID (UUID)"
+// @Success 200 {object} models.OrganizationMembersResponse
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/organizations/{id}/members [get]
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+	if !h.organizationExists(w, r, id) {
+		return
+	}
+
+	members, err := h.serv.ListMembers(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if members == nil {
+		members = []models.OrganizationMember{}
+	}
+
+	tools.WriteJSON(w, http.StatusOK, models.OrganizationMembersResponse{OrganizationID: id, Members: members})
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: members listed successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// AddMember - PUT /organizations/{id}/members/{user_id}
+// AddMember godoc
+// @Summary Add a user to an organization
+// @Description Adding an existing member returns the existing membership
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID (UUID)"
+// @Param user_id path string true "User ID (UUID)"
+// @Success 200 {object} models.OrganizationMember
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/organizations/{id}/members/{user_id} [put]
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	member, err := h.serv.AddMember(r.Context(), id, userID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, member)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: member add successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// RemoveMember - DELETE /organizations/{id}/members/{user_id}
+// RemoveMember godoc
+// @Summary Remove a user from an organization
+// @Description Subscriptions of the user stay in the organization
+// @Tags organizations
+// @Param id path string true "Organization ID (UUID)"
+// @Param user_id path string true "User ID (UUID)"
+// @Success 204 "Member removed successfully"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/organizations/{id}/members/{user_id} [delete]
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.serv.RemoveMember(r.Context(), id, userID)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: member remove successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// OrganizationTotal - GET /organizations/{id}/total
+// OrganizationTotal godoc
+// @Summary Calculate organization spending
+// @Description Spending of all subscriptions of the organization for the period, and the share of every member by the same rules as /subscriptions/total
+// @Description in RUB. Subscriptions to catalog services in other currencies are not summed, their totals are returned in other_currencies
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID (UUID)"
+// @Param start_month query string true "Start month (MM-YYYY or YYYY-MM)" example(01-2024)
+// @Param end_month query string true "End month (MM-YYYY or YYYY-MM)" example(12-2024)
+// @Success 200 {object} models.OrganizationTotalResponse
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/organizations/{id}/total [get]
+func (h *Handler) OrganizationTotal(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.organizationID(w, r)
+	if !ok {
+		return
+	}
+	req, _, ok := h.totalRequest(w, r)
+	if !ok {
+		return
+	}
+
+	total, err := h.serv.OrganizationTotal(r.Context(), id, req.StartMonth, req.EndMonth)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, models.OrganizationTotalResponse{
+		Success:         true,
+		OrganizationID:  id,
+		Total:           total.Total,
+		Currency:        total.Currency,
+		OtherCurrencies: total.OtherCurrencies,
+		Users:           total.Users,
+		Period: models.PeriodInfo{
+			StartMonth: req.StartMonth,
+			EndMonth:   req.EndMonth,
+		},
+	})
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: organization total successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// operator - запрос с ключом, не привязанным к организации. Иначе ответ 403 уже записан
+func (h *Handler) operator(w http.ResponseWriter, r *http.Request) bool {
+	if tenant.Bound(r.Context()) {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: organization management with a tenant api key",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Write(w, r, http.StatusForbidden, problem.Forbidden, "API key is bound to an organization")
+		return false
+	}
+	return true
+}
+
+// organizationID - id организации из пути. Ключ, привязанный к организации, открывает только ее.
+// При ошибке ответ уже записан
+func (h *Handler) organizationID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid organization id",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "id", "Invalid organization ID")
+		return uuid.Nil, false
+	}
+	if tenant.Bound(r.Context()) && id != tenant.ID(r.Context()) {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: tenant api key used for another organization",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Write(w, r, http.StatusForbidden, problem.Forbidden, "API key is bound to another organization")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// organizationExists - организация есть, иначе ответ уже записан
+func (h *Handler) organizationExists(w http.ResponseWriter, r *http.Request, id uuid.UUID) bool {
+	organization, err := h.serv.GetOrganization(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return false
+	}
+	if organization == nil {
+		problem.Write(w, r, http.StatusNotFound, problem.OrganizationNotFound, "Organization not found")
+		return false
+	}
+	return true
+}
+
+// memberID - id пользователя из пути, при ошибке ответ уже записан
+func (h *Handler) memberID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(r.PathValue("user_id"))
+	if err != nil {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid userID",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "user_id", "Invalid user_id")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// validOrganization - текст ошибки для клиента, пустая строка - запрос корректен
+func validOrganization(req models.OrganizationRequest) string {
+	switch {
+	case req.Name == "":
+		return "name is required"
+	case utf8.RuneCountInString(req.Name) > maxOrganizationNameLength:
+		return "name must be at most 100 characters"
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/problem"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ListServices - GET /services
+// ListServices godoc
+// @Summary List the service catalog
+// @Description Catalog entries ordered by name. Subscription service names are resolved against names and aliases case-insensitively
+// @Tags services
+// @Produce json
+// @Success 200 {object} models.ServicesResponse
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/services [get]
+func (h *Handler) ListServices(w http.ResponseWriter, r *http.Request) {
+	services, err := h.serv.ListServices(r.Context())
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if services == nil {
+		services = []models.Service{}
+	}
+
+	tools.WriteJSON(w, http.StatusOK, models.ServicesResponse{Services: services})
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: services listed successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// GetService - GET /services/{id}
+// GetService godoc
+// @Summary Get a catalog entry
+// @Tags services
+// @Produce json
+// @Param id path string true "Service ID (UUID)"
+// @Success 200 {object} models.Service
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/services/{id} [get]
+func (h *Handler) GetService(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.serviceID(w, r)
+	if !ok {
+		return
+	}
+
+	service, err := h.serv.GetService(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if service == nil {
+		h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: service not found",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Write(w, r, http.StatusNotFound, problem.ServiceNotFound, "Service not found")
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, service)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: service found successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// CreateService - POST /services
+// CreateService godoc
+// @Summary Create a catalog entry
+// @Description Name and aliases must not match (case-insensitively) a name or alias of another service
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param service body models.ServiceRequest true "Catalog entry"
+// @Success 201 {object} models.Service
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/services [post]
+func (h *Handler) CreateService(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.serviceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	service, err := h.serv.CreateService(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusCreated, service)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: service create successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// UpdateService - PUT /services/{id}
+// UpdateService godoc
+// @Summary Replace a catalog entry
+// @Description Renaming a service renames its subscriptions
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path string true "Service ID (UUID)"
+// @Param service body models.ServiceRequest true "Catalog entry"
+// @Success 200 {object} models.Service
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/services/{id} [put]
+func (h *Handler) UpdateService(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.serviceID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.serviceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	service, err := h.serv.UpdateService(r.Context(), id, req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, service)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: service update successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// DeleteService - DELETE /services/{id}
+// DeleteService godoc
+// @Summary Delete a catalog entry
+// @Description Subscriptions of the service keep their name and lose the catalog reference
+// @Tags services
+// @Param id path string true "Service ID (UUID)"
+// @Success 204 "Service deleted successfully"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/services/{id} [delete]
+func (h *Handler) DeleteService(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.serviceID(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.serv.DeleteService(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: service delete successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// serviceID - id записи каталога из пути, при ошибке ответ уже записан
+func (h *Handler) serviceID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid service id",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "id", "Invalid service ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// serviceRequest - нормализованное и проверенное тело запроса каталога, при ошибке ответ уже записан
+func (h *Handler) serviceRequest(w http.ResponseWriter, r *http.Request) (models.ServiceRequest, bool) {
+	var req models.ServiceRequest
+	if !h.decodeJSON(w, r, &req) {
+		return req, false
+	}
+
+	req = catalog.Normalize(req)
+	if msg := catalog.Validate(req); msg != "" {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid service: %s",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, msg), logger.GetPlace())
+		invalid(w, r, msg)
+		return req, false
+	}
+	return req, true
+}
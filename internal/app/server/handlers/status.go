@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"net/http"
+)
+
+// PauseSubscription - POST /subscriptions/pause
+// PauseSubscription godoc
+// @Summary Pause a subscription
+// @Description Pause an active subscription from the given month (current month by default). Paused months are excluded from totals
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param subscription body models.StatusChangeRequest true "Subscription and month"
+// @Param If-Match header string false "ETag of the subscription version to change"
+// @Success 200 {object} models.Subscription
+// @Header 200 {string} ETag "New subscription version"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/subscriptions/pause [post]
+func (h *Handler) PauseSubscription(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, models.StatusPaused)
+}
+
+// ResumeSubscription - POST /subscriptions/resume
+// ResumeSubscription godoc
+// @Summary Resume a subscription
+// @Description Resume a paused subscription from the given month (current month by default)
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param subscription body models.StatusChangeRequest true "Subscription and month"
+// @Param If-Match header string false "ETag of the subscription version to change"
+// @Success 200 {object} models.Subscription
+// @Header 200 {string} ETag "New subscription version"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/subscriptions/resume [post]
+func (h *Handler) ResumeSubscription(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, models.StatusActive)
+}
+
+// CancelSubscription - POST /subscriptions/cancel
+// CancelSubscription godoc
+// @Summary Cancel a subscription
+// @Description Cancel an active or paused subscription. end_date is set to the given month (current month by default)
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param subscription body models.StatusChangeRequest true "Subscription and month"
+// @Param If-Match header string false "ETag of the subscription version to change"
+// @Success 200 {object} models.Subscription
+// @Header 200 {string} ETag "New subscription version"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/subscriptions/cancel [post]
+func (h *Handler) CancelSubscription(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, models.StatusCancelled)
+}
+
+// changeStatus - общий обработчик переходов статуса
+func (h *Handler) changeStatus(w http.ResponseWriter, r *http.Request, status string) {
+	var req models.StatusChangeRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	// Валидация
+	if req.ServiceName == "" {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid service-name",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "service_name", "service_name is required")
+		return
+	}
+	if req.Month != "" {
+		if _, err := tools.ParseMonthYear(req.Month); err != nil {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid month",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Invalid(w, r, "month", "month must be MM-YYYY")
+			return
+		}
+	}
+	req.Status = status
+
+	subscription, err := h.serv.ChangeStatus(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeSubscription(w, http.StatusOK, subscription)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: subscription status changed to %s",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, status), logger.GetPlace())
+}
@@ -1,38 +1,44 @@
 package handlers
 
 import (
-	"agrigation_api/internal/database/postgres"
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/etag"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/internal/metadata"
+	"agrigation_api/internal/problem"
+	"agrigation_api/internal/users"
 	"agrigation_api/pkg/logger/logger"
 	"agrigation_api/pkg/models"
 	"agrigation_api/pkg/tools"
-	"database/sql"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	"net/http"
+	"time"
 )
 
 // GetSubscription - GET конкретной подписки: GET /subscriptions?user_id=xxx&service=yyy
 // GetSubscription godoc
 // @Summary Get a specific subscription
-// @Description Get subscription by user ID and service name
+// @Description Get subscription by user ID and service name. The ETag header identifies the subscription version:
+// @Description send it in If-Match to update or delete only this version, or in If-None-Match to get 304 while it is unchanged
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param user_id query string true "User ID (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
 // @Param service_name query string true "Service name" example(Netflix)
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Success 200 {object} models.Subscription
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Subscription version"
+// @Success 304 "Subscription has not changed"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /api/v1/subscriptions [get]
 func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 
@@ -40,10 +46,16 @@ func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
 	userIDStr := query.Get("user_id")
 	serviceName := query.Get("service_name")
 
-	if userIDStr == "" || serviceName == "" {
+	var required []models.FieldError
+	for name, value := range map[string]string{"user_id": userIDStr, "service_name": serviceName} {
+		if value == "" {
+			required = append(required, models.FieldError{Field: name, Message: name + " is required"})
+		}
+	}
+	if len(required) > 0 {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request without needed params",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "user_id and service parameters are required")
+		problem.InvalidFields(w, r, required)
 		return
 	}
 
@@ -51,31 +63,32 @@ func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid userID",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "Invalid user ID")
+		problem.Invalid(w, r, "user_id", "Invalid user_id")
 		return
 	}
 	subscription, err := h.serv.GetSubscription(r.Context(), userID, serviceName)
-	if errors.Is(err, sql.ErrNoRows) {
-		h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: %v",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, "subscription does not exists"), logger.GetPlace())
-		tools.WriteError(w, http.StatusNotFound, "subscription does not exists")
-		return
-	}
 	if err != nil {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: %v",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
-		tools.WriteError(w, http.StatusInternalServerError, err.Error())
+		h.writeServiceError(w, r, err)
 		return
 	}
 
 	if subscription == nil {
 		h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: subscription not found",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusNotFound, "Subscription not found")
+		problem.Write(w, r, http.StatusNotFound, problem.SubscriptionNotFound, "Subscription not found")
+		return
+	}
+
+	tag := etag.Of(subscription)
+	if header := r.Header.Get("If-None-Match"); header != "" && etag.MatchWeak(header, tag) {
+		w.Header().Set("ETag", tag)
+		w.WriteHeader(http.StatusNotModified)
+		h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: subscription not modified",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
 		return
 	}
 
-	tools.WriteJSON(w, http.StatusOK, subscription)
+	writeSubscription(w, http.StatusOK, subscription)
 	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: subscription found successfully",
 		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
 }
@@ -84,96 +97,78 @@ func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
 // Создает новую подписку
 // Create	Subscription godoc
 // @Summary Create a subscription
-// @Description Create new subscription
+// @Description Create new subscription. With features.strict_users the owner and members must be registered users, otherwise 400
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body models.CreateOrUpdateRequest true "Subscription data"
+// @Param Idempotency-Key header string false "Client key: a retry with the same key and body replays the original response"
 // @Success 200 {object} models.Subscription
 // @Success 201 {object} models.Subscription
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 422 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /api/v1/subscriptions [post]
 func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 
 	var req models.CreateOrUpdateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid json",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
-	// Валидация
-	if req.ServiceName == "" {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid service-name",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "service_name is required")
-		return
-	}
-	if req.Price <= 0 {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid price",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "price must be positive")
-		return
-	}
-	if req.StartDate == "" {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid start-date",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "start_date is required")
+	if fields := subscriptionErrors(req); len(fields) > 0 {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid fields: %v",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, fields), logger.GetPlace())
+		problem.InvalidFields(w, r, fields)
 		return
 	}
 
 	subscription, err := h.serv.CreateSubscription(r.Context(), req)
-	if errors.Is(err, postgres.SubscriptionAlreadyExist) {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: %v",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "Subscription already exists")
-		return
-	}
 	if err != nil {
-		h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: %v",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
-		tools.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		h.writeServiceError(w, r, err)
 		return
 	}
 
-	tools.WriteJSON(w, http.StatusCreated, subscription)
+	writeSubscription(w, http.StatusCreated, subscription)
 	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: subscription create successfully",
 		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
 }
 
 // DeleteSubscription godoc
 // @Summary Delete a subscription
-// @Description Delete subscription by user ID and service name
+// @Description Move subscription to the trash by user ID and service name. It can be restored with POST /api/v1/subscriptions/{id}/restore
+// @Description until the trash is purged. With If-Match the subscription is deleted only if its ETag matches, otherwise 412.
+// @Description With features.require_if_match a request without If-Match gets 428
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body models.DeleteRequest true "Subscription identification"
+// @Param If-Match header string false "ETag of the subscription version to delete"
 // @Success 204 "Subscription deleted successfully"
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /api/v1/subscriptions [delete]
 func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "DELETE" {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 
 	var req models.DeleteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid json",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "Invalid JSON")
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
@@ -181,21 +176,13 @@ func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 	if req.ServiceName == "" {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses request with invalid service-name",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "service_name is required")
+		problem.Invalid(w, r, "service_name", "service_name is required")
 		return
 	}
 
 	err := h.serv.DeleteSubscription(r.Context(), req.UserID, req.ServiceName)
-	if errors.Is(err, errors.New("subscription not found")) {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: subscription not found",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusNotFound, "Subscription not found")
-		return
-	}
 	if err != nil {
-		h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: delete subscription error: %v",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
-		tools.WriteError(w, http.StatusInternalServerError, err.Error())
+		h.writeServiceError(w, r, err)
 		return
 	}
 
@@ -208,21 +195,26 @@ func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
 
 // ListUserSubscriptions godoc
 // @Summary List all subscriptions for a user
-// @Description Get all subscriptions for a specific user
+// @Description Get all subscriptions for a specific user, including shared subscriptions the user is a member of. Filter by metadata with metadata.<key>=<value>,
+// @Description e.g. metadata.cost_center=eng: the key must hold exactly this string. other_currency_services names the subscriptions
+// @Description whose service is priced in another currency than the user's
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
+// @Param status query string false "Status filter" Enums(active, paused, cancelled, expired)
+// @Param tag query []string false "Tag filter, repeatable: a subscription must have every tag" collectionFormat(multi)
+// @Param metadata.cost_center query string false "Metadata filter, any metadata.<key> is accepted" example(eng)
 // @Success 200 {array} models.Subscription
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /api/v1/subscriptions/user/{id} [get]
 func (h *Handler) ListUserSubscriptions(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 
@@ -232,22 +224,52 @@ func (h *Handler) ListUserSubscriptions(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid userID",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "Invalid user ID")
+		problem.Invalid(w, r, "id", "Invalid user ID")
 		return
 	}
 
-	subscriptions, err := h.serv.ListSubscriptions(r.Context(), userID)
-	if err != nil {
-		h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: list subscriptions error",
+	filter := models.ListFilter{Status: r.URL.Query().Get("status"), Tags: catalog.Tags(r.URL.Query()["tag"])}
+	if !lifecycle.ValidFilter(filter.Status) {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid status",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "status", "status must be active, paused, cancelled or expired")
+		return
+	}
+	if msg := catalog.ValidateLabels("", filter.Tags); msg != "" {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid tags",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		invalid(w, r, msg)
+		return
+	}
+	metadataFilter, msg := metadata.ParseFilter(r.URL.Query())
+	if msg != "" {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid metadata filter",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		invalid(w, r, msg)
+		return
+	}
+	filter.Metadata = metadataFilter
+
+	subscriptions, err := h.serv.ListSubscriptions(r.Context(), userID, filter)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	currency := users.Currency(h.userPreferences(r, userID))
+	otherCurrency, err := h.otherCurrencyServices(r, subscriptions, currency)
+	if err != nil {
+		h.writeServiceError(w, r, err)
 		return
 	}
 
 	response := map[string]interface{}{
 		"user_id":       userID,
 		"subscriptions": subscriptions,
-		"currency":      "RUB",
+		"currency":      currency,
+	}
+	if len(otherCurrency) > 0 {
+		response["other_currency_services"] = otherCurrency
 	}
 	tools.WriteJSON(w, http.StatusOK, response)
 	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: User list subscription found successfully",
@@ -257,47 +279,74 @@ func (h *Handler) ListUserSubscriptions(w http.ResponseWriter, r *http.Request)
 // CalculateTotalHandler - GET /subscriptions/total
 // CalculateTotalHandler godoc
 // @Summary Calculate total cost for a period
-// @Description Calculate total cost of subscriptions for a given period with optional filters
+// @Description Calculate total cost of subscriptions for a given period with optional filters.
+// @Description With user_id, shared subscriptions count only the user's share. For a registered user the currency is the user's currency,
+// @Description and without start_month and end_month the period is the current month in the user's timezone.
+// @Description A subscription is priced in the currency of its catalog service, RUB if the service is not in the catalog.
+// @Description Only subscriptions in the response currency are summed, totals in other currencies are returned in other_currencies
 // @Tags analytics
 // @Accept json
 // @Produce json
-// @Param start_month query string true "Start month (MM-YYYY or YYYY-MM)" example(01-2024)
-// @Param end_month query string true "End month (MM-YYYY or YYYY-MM)" example(12-2024)
+// @Param start_month query string false "Start month (MM-YYYY or YYYY-MM), required unless user_id is a registered user" example(01-2024)
+// @Param end_month query string false "End month (MM-YYYY or YYYY-MM), required unless user_id is a registered user" example(12-2024)
 // @Param user_id query string false "User ID for filtering (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
 // @Param service_name query string false "Service name for filtering" example(Netflix)
+// @Param tag query []string false "Tag filter, repeatable: a subscription must have every tag" collectionFormat(multi)
 // @Success 200 {object} models.CalculateTotalResponse
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /api/v1/subscriptions/total [get]
 func (h *Handler) CalculateTotalHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 
-	query := r.URL.Query()
-	startMonth, errStartMonth := tools.ParseMonthYear(query.Get("start_month"))
-	if errStartMonth != nil {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid start_month",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "start_month is required")
+	req, _, ok := h.totalRequest(w, r)
+	if !ok {
 		return
 	}
-	endMonth, errEnd := tools.ParseMonthYear(query.Get("end_month"))
-	if errEnd != nil {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid end_month",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "end_month is required")
+
+	// Подсчет суммы
+	total, err := h.serv.CalculateTotal(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	// Подписки в других валютах в сумму не входят
+	others, err := h.serv.OtherCurrencyTotals(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
 		return
 	}
 
-	// Парсим параметры из query string
+	// Формируем ответ
+	response := models.CalculateTotalResponse{
+		Success:         true,
+		Total:           total,
+		Currency:        req.Currency,
+		OtherCurrencies: others,
+		Period: models.PeriodInfo{
+			StartMonth: req.StartMonth,
+			EndMonth:   req.EndMonth,
+		},
+		Filters: filterInfo(req),
+	}
+
+	tools.WriteJSON(w, http.StatusOK, response)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: calculate total successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// totalRequest - период и фильтры подсчета из query string и профиль пользователя из user_id (nil - не заведен).
+// Без start_month и end_month у заведенного пользователя период - его текущий месяц. При ошибке ответ уже записан
+func (h *Handler) totalRequest(w http.ResponseWriter, r *http.Request) (models.CalculateTotalRequest, *models.User, bool) {
+	query := r.URL.Query()
 	req := models.CalculateTotalRequest{
-		StartMonth:  startMonth,
-		EndMonth:    endMonth,
 		ServiceName: query.Get("service_name"),
+		Tags:        catalog.Tags(query["tag"]),
 	}
 
 	// user_id из query параметра
@@ -306,112 +355,192 @@ func (h *Handler) CalculateTotalHandler(w http.ResponseWriter, r *http.Request)
 		if err != nil {
 			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid userID",
 				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-			tools.WriteError(w, http.StatusBadRequest, "Invalid user_id")
-			return
+			problem.Invalid(w, r, "user_id", "Invalid user_id")
+			return models.CalculateTotalRequest{}, nil, false
 		}
 		req.UserID = userID
 	}
+	user := h.userPreferences(r, req.UserID)
 
-	// Подсчет суммы
-	total, err := h.serv.CalculateTotal(r.Context(), req)
+	if user != nil && query.Get("start_month") == "" && query.Get("end_month") == "" {
+		req.StartMonth = users.CurrentMonth(user, time.Now())
+		req.EndMonth = req.StartMonth
+	} else {
+		startMonth, errStartMonth := tools.ParseMonthYear(query.Get("start_month"))
+		if errStartMonth != nil {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid start_month",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Invalid(w, r, "start_month", "start_month is required")
+			return models.CalculateTotalRequest{}, nil, false
+		}
+		endMonth, errEnd := tools.ParseMonthYear(query.Get("end_month"))
+		if errEnd != nil {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid end_month",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Invalid(w, r, "end_month", "end_month is required")
+			return models.CalculateTotalRequest{}, nil, false
+		}
+		req.StartMonth, req.EndMonth = startMonth, endMonth
+	}
+
+	if msg := catalog.ValidateLabels("", req.Tags); msg != "" {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid tags",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		invalid(w, r, msg)
+		return models.CalculateTotalRequest{}, nil, false
+	}
+	req.Currency = users.Currency(user)
+	return req, user, true
+}
+
+// otherCurrencyServices - сервисы подписок, цена которых не в валюте currency. Валюта подписки - валюта сервиса
+// каталога, сервиса нет в каталоге - catalog.DefaultCurrency
+func (h *Handler) otherCurrencyServices(r *http.Request, subscriptions []models.Subscription, currency string) ([]string, error) {
+	services, err := h.serv.ListServices(r.Context())
 	if err != nil {
-		h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: calculate total error: %v",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "calculate_total error")
-		return
+		return nil, err
+	}
+	currencies := make(map[string]string, len(services))
+	for _, service := range services {
+		currencies[service.Name] = service.Currency
 	}
 
-	// Формируем ответ
-	response := models.CalculateTotalResponse{
-		Success:  true,
-		Total:    total,
-		Currency: "RUB",
-		Period: models.PeriodInfo{
-			StartMonth: req.StartMonth,
-			EndMonth:   req.EndMonth,
-		},
+	var names []string
+	for _, sub := range subscriptions {
+		subCurrency, ok := currencies[sub.ServiceName]
+		if !ok {
+			subCurrency = catalog.DefaultCurrency
+		}
+		if subCurrency != currency {
+			names = append(names, sub.ServiceName)
+		}
 	}
+	return names, nil
+}
 
-	// Добавляем фильтры, если они были указаны
+// filterInfo - фильтры подсчета, которые были указаны
+func filterInfo(req models.CalculateTotalRequest) models.FilterInfo {
+	var filters models.FilterInfo
 	if req.UserID != uuid.Nil {
 		userIDStr := req.UserID.String()
-		response.Filters.UserID = &userIDStr
+		filters.UserID = &userIDStr
 	}
-
 	if req.ServiceName != "" {
-		response.Filters.ServiceName = &req.ServiceName
+		filters.ServiceName = &req.ServiceName
 	}
-
-	tools.WriteJSON(w, http.StatusOK, response)
-	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: calculate total successfully",
-		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+	filters.Tags = req.Tags
+	return filters
 }
 
 // UpdateSubscription - Update: PUT /subscriptions
 // Обновляет существующую подписку
 // UpdateSubscription godoc
 // @Summary Update a subscription
-// @Description Create new subscription
+// @Description Update an existing subscription. With If-Match the subscription is updated only if its ETag matches, otherwise 412.
+// @Description With features.require_if_match a request without If-Match gets 428
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body models.CreateOrUpdateRequest true "Subscription data"
+// @Param If-Match header string false "ETag of the subscription version to update"
 // @Success 200 {object} models.Subscription
 // @Success 201 {object} models.Subscription
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Header 201 {string} ETag "New subscription version"
+// @Failure 400 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 428 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /api/v1/subscriptions [put]
 func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "PUT" {
 		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user uses not allowed method",
 			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		writeMethodNotAllowed(w, r)
 		return
 	}
 
 	var req models.CreateOrUpdateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid json",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
-	// Валидация
-	if req.ServiceName == "" {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid service-name",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "service_name is required")
-		return
-	}
-	if req.Price <= 0 {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid price",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "price must be positive")
-		return
-	}
-	if req.StartDate == "" {
-		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid start-date",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
-		tools.WriteError(w, http.StatusBadRequest, "start_date is required")
+	if fields := subscriptionErrors(req); len(fields) > 0 {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid fields: %v",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, fields), logger.GetPlace())
+		problem.InvalidFields(w, r, fields)
 		return
 	}
 
 	subscription, err := h.serv.UpdateSubscription(r.Context(), req)
-	if errors.Is(err, pgx.ErrNoRows) {
-		h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: update subscription error: %v",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
-		tools.WriteError(w, http.StatusNotFound, "Subscription not found")
-		return
-	}
 	if err != nil {
-		h.logs.Error(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: update subscription error: %v",
-			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
-		tools.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		h.writeServiceError(w, r, err)
 		return
 	}
 
-	tools.WriteJSON(w, http.StatusCreated, subscription)
+	writeSubscription(w, http.StatusCreated, subscription)
 	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: subscription update successfully",
 		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
 }
+
+// writeSubscription - подписка в ответе с ее ETag
+func writeSubscription(w http.ResponseWriter, status int, sub *models.Subscription) {
+	w.Header().Set("ETag", etag.Of(sub))
+	tools.WriteJSON(w, status, sub)
+}
+
+// subscriptionErrors - ошибки всех полей запроса создания или обновления подписки
+func subscriptionErrors(req models.CreateOrUpdateRequest) []models.FieldError {
+	var fields []models.FieldError
+	add := func(field, message string) {
+		fields = append(fields, models.FieldError{Field: field, Message: message})
+	}
+
+	if req.ServiceName == "" {
+		add("service_name", "service_name is required")
+	}
+	// price 0 - цена сервиса каталога по умолчанию
+	if req.Price < 0 {
+		add("price", "price must not be negative")
+	}
+	start, errStart := tools.ParseMonthYear(req.StartDate)
+	switch {
+	case req.StartDate == "":
+		add("start_date", "start_date is required")
+	case errStart != nil:
+		add("start_date", "start_date must be MM-YYYY")
+	}
+	if req.EndDate != "" {
+		end, err := tools.ParseMonthYear(req.EndDate)
+		switch {
+		case err != nil:
+			add("end_date", "end_date must be MM-YYYY")
+		case errStart == nil && end.Before(start):
+			add("end_date", "end_date must not be before start_date")
+		}
+	}
+	if !validTrialEnd(req) {
+		add("trial_end", "trial_end must be a month not before start_date")
+	}
+	if !validPromo(req) {
+		add("promo", "promo must have from <= to and exactly one of price (>= 0) or percent (1-100)")
+	}
+	if msg := catalog.ValidateLabels(req.Category, nil); msg != "" {
+		add("category", msg)
+	}
+	if msg := catalog.ValidateLabels("", catalog.Tags(req.Tags)); msg != "" {
+		add("tags", msg)
+	}
+	if msg := metadata.Validate(req.Metadata); msg != "" {
+		add("metadata", msg)
+	}
+	if msg := lifecycle.ValidateSharing(req.UserID, req.Sharing); msg != "" {
+		add(fieldOf(msg), msg)
+	}
+	return fields
+}
+
+// validPromo - промо не задано или это корректный промо-период (lifecycle.ValidPromo)
+func validPromo(req models.CreateOrUpdateRequest) bool {
+	promo, err := lifecycle.ParsePromo(req.Promo)
+	return err == nil && (promo == nil || lifecycle.ValidPromo(*promo))
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RestoreSubscription - POST /subscriptions/{id}/restore
+// RestoreSubscription godoc
+// @Summary Restore a deleted subscription
+// @Description Bring a subscription back from the trash by its id. Fails with 409 if the user already has a subscription to the same service
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "Subscription ID (UUID)"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/subscriptions/{id}/restore [post]
+func (h *Handler) RestoreSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid subscription id",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "id", "Invalid subscription ID")
+		return
+	}
+
+	sub, err := h.serv.RestoreSubscription(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	writeSubscription(w, http.StatusOK, sub)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: subscription restored successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// ListTrash - GET /admin/trash
+// ListTrash godoc
+// @Summary List deleted subscriptions
+// @Description Subscriptions of the organization in the trash, most recently deleted first. They are purged after the configured retention.
+// @Description Requires an operator API key (not bound to an organization)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.TrashResponse
+// @Failure 403 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/admin/trash [get]
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	if !h.operator(w, r) {
+		return
+	}
+
+	subscriptions, err := h.serv.ListDeletedSubscriptions(r.Context())
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []models.Subscription{}
+	}
+
+	tools.WriteJSON(w, http.StatusOK, models.TrashResponse{Subscriptions: subscriptions})
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: trash listed successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
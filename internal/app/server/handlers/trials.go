@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrialDays = 7
+	maxTrialDays     = 366
+)
+
+// EndingTrials - GET /subscriptions/trials
+// EndingTrials godoc
+// @Summary List trials ending soon
+// @Description Subscriptions whose free trial ends and billing starts within the given number of days. Cancelled subscriptions and subscriptions ending together with the trial are not listed
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param days query int false "Days ahead (0-366), 7 by default" example(7)
+// @Param user_id query string false "User ID for filtering (UUID)" example(60601fee-2bf1-4721-ae6f-7636e79a0cba)
+// @Success 200 {object} models.TrialsResponse
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/subscriptions/trials [get]
+func (h *Handler) EndingTrials(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := models.TrialsRequest{Days: defaultTrialDays}
+
+	if daysStr := query.Get("days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 0 || days > maxTrialDays {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid days",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Invalid(w, r, "days", fmt.Sprintf("days must be an integer from 0 to %d", maxTrialDays))
+			return
+		}
+		req.Days = days
+	}
+
+	if userIDStr := query.Get("user_id"); userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid userID",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Invalid(w, r, "user_id", "Invalid user_id")
+			return
+		}
+		req.UserID = userID
+	}
+
+	subscriptions, err := h.serv.EndingTrials(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, models.TrialsResponse{Days: req.Days, Subscriptions: subscriptions})
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: ending trials found successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// validTrialEnd - trial_end не задан или это месяц "MM-YYYY" не раньше start_date
+func validTrialEnd(req models.CreateOrUpdateRequest) bool {
+	if req.TrialEnd == "" {
+		return true
+	}
+	trialEnd, err := tools.ParseMonthYear(req.TrialEnd)
+	if err != nil {
+		return false
+	}
+	start, err := tools.ParseMonthYear(req.StartDate)
+	return err != nil || !trialEnd.Before(start)
+}
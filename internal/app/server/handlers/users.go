@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/internal/users"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ListUsers - GET /users
+// ListUsers godoc
+// @Summary List users
+// @Description Users of the organization ordered by display name
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.UsersResponse
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/users [get]
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	list, err := h.serv.ListUsers(r.Context())
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if list == nil {
+		list = []models.User{}
+	}
+
+	tools.WriteJSON(w, http.StatusOK, models.UsersResponse{Users: list})
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: users listed successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// GetUser - GET /users/{id}
+// GetUser godoc
+// @Summary Get a user
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/users/{id} [get]
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.serv.GetUser(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if user == nil {
+		h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user not found",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Write(w, r, http.StatusNotFound, problem.UserNotFound, "User not found")
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, user)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user found successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// CreateUser - POST /users
+// CreateUser godoc
+// @Summary Create a user
+// @Description id is optional: pass the user_id already used in subscriptions to register an existing user.
+// @Description Email is unique within the organization
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body models.UserRequest true "User"
+// @Success 201 {object} models.User
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/users [post]
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.userRequest(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.serv.CreateUser(r.Context(), req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusCreated, user)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user create successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// UpdateUser - PUT /users/{id}
+// UpdateUser godoc
+// @Summary Replace a user profile
+// @Description id in the body is ignored
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param user body models.UserRequest true "User"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/users/{id} [put]
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.userRequest(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.serv.UpdateUser(r.Context(), id, req)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	tools.WriteJSON(w, http.StatusOK, user)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user update successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// DeleteUser - DELETE /users/{id}
+// DeleteUser godoc
+// @Summary Delete a user
+// @Description subscriptions=archive (default) cancels the user's active and paused subscriptions from the current month,
+// @Description subscriptions=delete deletes them. Shares in other users' shared subscriptions are kept
+// @Tags users
+// @Param id path string true "User ID (UUID)"
+// @Param subscriptions query string false "What to do with the user's subscriptions" Enums(archive, delete)
+// @Success 204 "User deleted successfully"
+// @Failure 400 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /api/v1/users/{id} [delete]
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	mode := r.URL.Query().Get("subscriptions")
+	if mode != "" && mode != "archive" && mode != "delete" {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid subscriptions mode",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "subscriptions", "subscriptions must be archive or delete")
+		return
+	}
+
+	_, err := h.serv.DeleteUser(r.Context(), id, mode == "delete")
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	h.logs.Info(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user delete successfully",
+		r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+}
+
+// userID - id пользователя из пути, при ошибке ответ уже записан
+func (h *Handler) userID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid user id",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+		problem.Invalid(w, r, "id", "Invalid user ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// userRequest - нормализованное и проверенное тело запроса пользователя, при ошибке ответ уже записан
+func (h *Handler) userRequest(w http.ResponseWriter, r *http.Request) (models.UserRequest, bool) {
+	var req models.UserRequest
+	if !h.decodeJSON(w, r, &req) {
+		return req, false
+	}
+
+	req = users.Normalize(req)
+	if msg := users.Validate(req); msg != "" {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: user request with invalid user: %s",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, msg), logger.GetPlace())
+		invalid(w, r, msg)
+		return req, false
+	}
+	return req, true
+}
+
+// userPreferences - профиль пользователя подсчета для валюты и текущего месяца, nil - фильтра по пользователю нет
+// или пользователь не заведен. Ошибка чтения пишется в лог: подсчет идет с настройками по умолчанию
+func (h *Handler) userPreferences(r *http.Request, userID uuid.UUID) *models.User {
+	if userID == uuid.Nil {
+		return nil
+	}
+	user, err := h.serv.GetUser(r.Context(), userID)
+	if err != nil {
+		h.logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: get user preferences error: %v",
+			r.RemoteAddr, r.URL, r.Method, logger.TimeFormat, err), logger.GetPlace())
+	}
+	return user
+}
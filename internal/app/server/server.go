@@ -4,6 +4,7 @@ import (
 	"agrigation_api/internal/app/server/handlers"
 	"agrigation_api/internal/database/repository"
 	"agrigation_api/internal/middleware"
+	"agrigation_api/internal/problem"
 	"agrigation_api/internal/service"
 	"agrigation_api/pkg/config"
 	logger2 "agrigation_api/pkg/logger"
@@ -11,22 +12,79 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 	"net/http"
 	"sync"
+	"sync/atomic"
 )
 
 type Server struct {
-	Port        int
+	Address     string
 	Logger      logger2.MyLogger
 	Router      http.Handler
 	Postgres    *repository.Repository
+	Readiness   *handlers.Readiness
+	api         atomic.Pointer[http.ServeMux] // роуты API, появляются после Activate
 	exitChan    chan struct{}
 	connections *sync.WaitGroup
 }
 
-func NewServer(config *config.Config, logs logger2.MyLogger, service service.Subscriptions) *Server {
-	port := config.Port
+// NewServer - сервер со служебными роутами. Роуты API начинают работать после Activate,
+// до этого на них отвечаем 503, а /ready показывает причину
+func NewServer(store *config.Store, logs logger2.MyLogger) *Server {
+	config := store.Get()
+	address := config.Server.Address()
+	readiness := handlers.NewReadiness("starting")
+
+	s := &Server{
+		Address:     address,
+		Logger:      logs,
+		Readiness:   readiness,
+		exitChan:    make(chan struct{}),
+		connections: &sync.WaitGroup{},
+	}
 
 	router := http.NewServeMux()
-	serverHandlers := handlers.NewHandler(service, logs)
+	systemHandlers := handlers.NewSystemHandler(readiness, logs)
+
+	// health check (liveness) и readiness
+	router.HandleFunc("GET /health", systemHandlers.HealthCheck)
+	router.HandleFunc("GET /ready", systemHandlers.ReadinessCheck)
+
+	// API
+	router.HandleFunc("/api/", s.serveAPI)
+
+	// Swagger
+	if config.Features.Swagger {
+		router.Handle("GET /swagger/", httpSwagger.WrapHandler)
+		// Редирект с корня на Swagger UI
+		router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				problem.Write(w, r, http.StatusMethodNotAllowed, problem.MethodNotAllowed, "Method not allowed")
+				return
+			}
+			http.Redirect(w, r, "/swagger/index.html", http.StatusFound)
+		})
+	}
+
+	// Middleware
+	cacheStatusMiddleware := middleware.CacheStatusMiddleware(handlers.Routes(router))
+	readYourWritesMiddleware := middleware.ReadYourWritesMiddleware(cacheStatusMiddleware)
+	preconditionMiddleware := middleware.PreconditionMiddleware(store, logs, readYourWritesMiddleware)
+	tenantMiddleware := middleware.TenantMiddleware(logs, preconditionMiddleware)
+	authMiddleware := middleware.AuthMiddleware(store, logs, tenantMiddleware)
+	rateLimitMiddleware := middleware.RateLimitMiddleware(store, logs, authMiddleware)
+	corsMiddleware := middleware.CORSMiddleware(store, rateLimitMiddleware)
+	shutdownMiddleware := middleware.ShutdownMiddleware(s.exitChan, corsMiddleware)
+	requestMiddleware := middleware.RequestMiddleware(shutdownMiddleware)
+	loggerRouter := middleware.LoggerMiddleware(logs, requestMiddleware)
+	PanicsRouter := middleware.PanicMiddleware(logs, loggerRouter)
+
+	s.Router = PanicsRouter
+	return s
+}
+
+// Activate - подключает роуты API к сервису и помечает сервер готовым
+func (s *Server) Activate(service service.Subscriptions) {
+	router := http.NewServeMux()
+	serverHandlers := handlers.NewHandler(service, s.Logger)
 
 	// Crud-операции
 	router.HandleFunc("GET /api/v1/subscriptions/", serverHandlers.GetSubscription)
@@ -34,35 +92,68 @@ func NewServer(config *config.Config, logs logger2.MyLogger, service service.Sub
 	router.HandleFunc("PUT /api/v1/subscriptions/", serverHandlers.UpdateSubscription)
 	router.HandleFunc("GET /api/v1/subscriptions/user/{id}", serverHandlers.ListUserSubscriptions)
 	router.HandleFunc("DELETE /api/v1/subscriptions/", serverHandlers.DeleteSubscription)
+	// {$}: пути переходов статуса не пересекаются с /{id}/restore
+	router.HandleFunc("POST /api/v1/subscriptions/pause/{$}", serverHandlers.PauseSubscription)
+	router.HandleFunc("POST /api/v1/subscriptions/resume/{$}", serverHandlers.ResumeSubscription)
+	router.HandleFunc("POST /api/v1/subscriptions/cancel/{$}", serverHandlers.CancelSubscription)
+	router.HandleFunc("GET /api/v1/subscriptions/trials/{$}", serverHandlers.EndingTrials)
 
-	router.HandleFunc("GET /api/v1/subscriptions/total/", serverHandlers.CalculateTotalHandler)
+	// Корзина
+	router.HandleFunc("POST /api/v1/subscriptions/{id}/restore", serverHandlers.RestoreSubscription)
+	router.HandleFunc("GET /api/v1/admin/trash", serverHandlers.ListTrash)
 
-	// health check
-	router.HandleFunc("GET /health", serverHandlers.HealthCheck)
+	// Журнал аудита. /{id}/history пересекается с /user/{id}, поэтому роут - /{id}/{resource},
+	// а GET-пути выше и ниже заканчиваются {$}
+	router.HandleFunc("GET /api/v1/subscriptions/{id}/{resource}", serverHandlers.SubscriptionHistory)
+	router.HandleFunc("GET /api/v1/admin/audit", serverHandlers.ListAudit)
 
-	// Swagger
-	router.Handle("GET /swagger/", httpSwagger.WrapHandler)
-	// Редирект с корня на Swagger UI
-	router.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/swagger/index.html", http.StatusFound)
-	})
+	router.HandleFunc("GET /api/v1/subscriptions/total/{$}", serverHandlers.CalculateTotalHandler)
+	router.HandleFunc("GET /api/v1/subscriptions/total/categories/{$}", serverHandlers.CategoryTotalsHandler)
 
-	exitChan := make(chan struct{})
-	// Middleware
-	shutdownMiddleware := middleware.ShutdownMiddleware(exitChan, router)
-	loggerRouter := middleware.LoggerMiddleware(logs, shutdownMiddleware)
-	PanicsRouter := middleware.PanicMiddleware(logs, loggerRouter)
+	// Каталог сервисов
+	router.HandleFunc("GET /api/v1/services/", serverHandlers.ListServices)
+	router.HandleFunc("POST /api/v1/services/", serverHandlers.CreateService)
+	router.HandleFunc("GET /api/v1/services/{id}", serverHandlers.GetService)
+	router.HandleFunc("PUT /api/v1/services/{id}", serverHandlers.UpdateService)
+	router.HandleFunc("DELETE /api/v1/services/{id}", serverHandlers.DeleteService)
 
-	return &Server{
-		Port:        port,
-		Logger:      logs,
-		Router:      PanicsRouter,
-		exitChan:    exitChan,
-		connections: &sync.WaitGroup{},
+	// Пользователи
+	router.HandleFunc("GET /api/v1/users/", serverHandlers.ListUsers)
+	router.HandleFunc("POST /api/v1/users/", serverHandlers.CreateUser)
+	router.HandleFunc("GET /api/v1/users/{id}", serverHandlers.GetUser)
+	router.HandleFunc("PUT /api/v1/users/{id}", serverHandlers.UpdateUser)
+	router.HandleFunc("DELETE /api/v1/users/{id}", serverHandlers.DeleteUser)
+
+	// Организации
+	router.HandleFunc("GET /api/v1/organizations/", serverHandlers.ListOrganizations)
+	router.HandleFunc("POST /api/v1/organizations/", serverHandlers.CreateOrganization)
+	router.HandleFunc("GET /api/v1/organizations/{id}", serverHandlers.GetOrganization)
+	router.HandleFunc("DELETE /api/v1/organizations/{id}", serverHandlers.DeleteOrganization)
+	router.HandleFunc("GET /api/v1/organizations/{id}/members", serverHandlers.ListMembers)
+	router.HandleFunc("PUT /api/v1/organizations/{id}/members/{user_id}", serverHandlers.AddMember)
+	router.HandleFunc("DELETE /api/v1/organizations/{id}/members/{user_id}", serverHandlers.RemoveMember)
+	router.HandleFunc("GET /api/v1/organizations/{id}/total", serverHandlers.OrganizationTotal)
+
+	// Idempotency-Key обрабатывается до роутов: повтор запроса получает сохраненный ответ без выполнения
+	api := http.NewServeMux()
+	api.Handle("/", serverHandlers.Idempotency(handlers.Routes(router)))
+	s.api.Store(api)
+	s.Readiness.SetReady()
+}
+
+// serveAPI - отдает запрос роутам API, пока их нет - 503 с текущим статусом
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	api := s.api.Load()
+	if api == nil {
+		_, status := s.Readiness.Status()
+		problem.Write(w, r, http.StatusServiceUnavailable, problem.ServiceUnavailable, "Service is not ready: "+status)
+		return
 	}
+	api.ServeHTTP(w, r)
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.Readiness.SetNotReady("shutting down")
 	close(s.exitChan)
 
 	finished := make(chan struct{})
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"agrigation_api/pkg/config"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Store - хранилище кеша. Инвалидация через поколения: ключ записи содержит номер поколения тега,
+// Incr делает все записи тега недостижимыми, а старые записи удаляются по TTL или вытеснением
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Generation - текущее поколение тега, 0 - тег еще не инвалидировался
+	Generation(ctx context.Context, tag string) (int64, error)
+	// Incr - новое поколение тега
+	Incr(ctx context.Context, tag string) (int64, error)
+	Close() error
+}
+
+// NewStore - хранилище по конфигу, nil - кеш выключен
+func NewStore(conf config.CacheConfig) (Store, error) {
+	switch conf.Backend {
+	case config.CacheNone:
+		return nil, nil
+	case config.CacheMemory:
+		return NewLRU(conf.MaxEntries), nil
+	case config.CacheRedis:
+		return NewRedis(conf.Redis), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", conf.Backend)
+	}
+}
+
+// Status - результат обращения к кешу, отдается клиенту в заголовке X-Cache
+type Status string
+
+const (
+	Hit    Status = "HIT"
+	Miss   Status = "MISS"
+	Bypass Status = "BYPASS" // запрос прочитан мимо кеша (X-Read-Your-Writes)
+)
+
+// Recorder - статус кеша в рамках одного http-запроса
+type Recorder struct {
+	mu     sync.Mutex
+	status Status
+}
+
+type recorderKey struct{}
+
+// WithRecorder - контекст, в который сервис записывает статус кеша
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	recorder := &Recorder{}
+	return context.WithValue(ctx, recorderKey{}, recorder), recorder
+}
+
+// Record - записывает статус, если в контексте есть Recorder. Промах важнее попадания
+func Record(ctx context.Context, status Status) {
+	recorder, ok := ctx.Value(recorderKey{}).(*Recorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if recorder.status == "" || status == Miss {
+		recorder.status = status
+	}
+}
+
+// Status - статус кеша, пустая строка - к кешу не обращались
+func (r *Recorder) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU - кеш в памяти процесса с вытеснением давно не использованных записей и TTL
+type LRU struct {
+	mu          sync.Mutex
+	maxEntries  int
+	items       map[string]*list.Element
+	order       *list.List // в начале - недавно использованные
+	generations map[string]int64
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRU - кеш не больше maxEntries записей
+func NewLRU(maxEntries int) *LRU {
+	return &LRU{
+		maxEntries:  maxEntries,
+		items:       make(map[string]*list.Element),
+		order:       list.New(),
+		generations: make(map[string]int64),
+	}
+}
+
+func (c *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (c *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Generation - поколения не вытесняются: иначе тег вернулся бы к старому номеру и старые записи ожили бы
+func (c *LRU) Generation(_ context.Context, tag string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[tag], nil
+}
+
+func (c *LRU) Incr(_ context.Context, tag string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[tag]++
+	return c.generations[tag], nil
+}
+
+// Len - количество записей (вместе с истекшими, которые еще не вытеснены)
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) Close() error {
+	return nil
+}
+
+func (c *LRU) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*lruEntry).key)
+}
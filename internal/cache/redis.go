@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"agrigation_api/pkg/config"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// generationPrefix - ключи поколений тегов в Redis
+const generationPrefix = "subscriptions:generation:"
+
+// Redis - кеш в Redis, общий для всех реплик сервиса
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis - клиент подключается лениво, недоступный Redis не мешает старту сервиса
+func NewRedis(conf config.RedisConfig) *Redis {
+	return &Redis{client: redis.NewClient(&redis.Options{
+		Addr:     conf.Address,
+		Password: conf.Password,
+		DB:       conf.DB,
+	})}
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *Redis) Generation(ctx context.Context, tag string) (int64, error) {
+	generation, err := c.client.Get(ctx, generationPrefix+tag).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return generation, err
+}
+
+func (c *Redis) Incr(ctx context.Context, tag string) (int64, error) {
+	return c.client.Incr(ctx, generationPrefix+tag).Result()
+}
+
+func (c *Redis) Close() error {
+	return c.client.Close()
+}
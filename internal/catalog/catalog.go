@@ -0,0 +1,125 @@
+package catalog
+
+import (
+	"agrigation_api/pkg/models"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Правила каталога сервисов, общие для всех хранилищ:
+//
+//	имя сервиса в подписке ищется по ключу Key среди имен и алиасов каталога,
+//	найденный сервис заменяет имя на каноническое, ненайденное имя сохраняется как есть (без пробелов по краям);
+//	категория подписки по умолчанию - категория сервиса, теги подписки хранятся ключами Key
+
+// DefaultCurrency - валюта сервиса, если не указана
+const DefaultCurrency = "RUB"
+
+// Ограничения схемы: services.name VARCHAR(100), category VARCHAR(50)
+const (
+	maxNameLength     = 100
+	maxCategoryLength = 50
+)
+
+// Ограничения тегов подписки: subscription_tags.tag VARCHAR(50)
+const (
+	maxTags      = 20
+	maxTagLength = 50
+)
+
+// Key - ключ поиска: без пробелов по краям и без учета регистра
+func Key(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Normalize - запрос в том виде, в котором он хранится: имя без пробелов по краям, валюта в верхнем регистре
+// (по умолчанию DefaultCurrency), алиасы - уникальные ключи Key по возрастанию без ключа самого имени
+func Normalize(req models.ServiceRequest) models.ServiceRequest {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Category = strings.TrimSpace(req.Category)
+	req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+	if req.Currency == "" {
+		req.Currency = DefaultCurrency
+	}
+
+	name := Key(req.Name)
+	aliases := make([]string, 0, len(req.Aliases))
+	for _, alias := range req.Aliases {
+		if key := Key(alias); key != "" && key != name {
+			aliases = append(aliases, key)
+		}
+	}
+	slices.Sort(aliases)
+	req.Aliases = slices.Compact(aliases)
+	return req
+}
+
+// Keys - все ключи поиска сервиса: имя и алиасы. Запрос - после Normalize
+func Keys(req models.ServiceRequest) []string {
+	return append([]string{Key(req.Name)}, req.Aliases...)
+}
+
+// Validate - текст ошибки для клиента, пустая строка - запрос корректен. Запрос - после Normalize
+func Validate(req models.ServiceRequest) string {
+	switch {
+	case req.Name == "":
+		return "name is required"
+	case utf8.RuneCountInString(req.Name) > maxNameLength:
+		return "name must be at most 100 characters"
+	case utf8.RuneCountInString(req.Category) > maxCategoryLength:
+		return "category must be at most 50 characters"
+	case req.DefaultPrice != nil && *req.DefaultPrice <= 0:
+		return "default_price must be positive"
+	case !ValidCurrency(req.Currency):
+		return "currency must be a 3-letter ISO 4217 code"
+	}
+	for _, alias := range req.Aliases {
+		if utf8.RuneCountInString(alias) > maxNameLength {
+			return "aliases must be at most 100 characters"
+		}
+	}
+	return ""
+}
+
+// Tags - теги в том виде, в котором они хранятся: уникальные ключи Key по возрастанию, nil - тегов нет
+func Tags(tags []string) []string {
+	var keys []string
+	for _, tag := range tags {
+		if key := Key(tag); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// ValidateLabels - текст ошибки для клиента про категорию и теги подписки, пустая строка - корректны.
+// Теги - после Tags
+func ValidateLabels(category string, tags []string) string {
+	if utf8.RuneCountInString(strings.TrimSpace(category)) > maxCategoryLength {
+		return "category must be at most 50 characters"
+	}
+	if len(tags) > maxTags {
+		return "tags must have at most 20 items"
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return "tags must be at most 50 characters"
+		}
+	}
+	return ""
+}
+
+// ValidCurrency - три латинские буквы
+func ValidCurrency(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,1771 @@
+// Package conformance - общий набор тестов для реализаций repository.Repository.
+// Каждое хранилище (postgres, sqlite, memory) должно проходить его без исключений
+package conformance
+
+import (
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/database/repository"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Factory - новое пустое хранилище для одного подтеста. Закрытие - через t.Cleanup
+type Factory func(t *testing.T) repository.Repository
+
+// Run - запускает весь набор, каждый тест на отдельном хранилище
+func Run(t *testing.T, newRepository Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, rep repository.Repository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"NotFound", testNotFound},
+		{"Duplicate", testDuplicate},
+		{"EndDateRoundTrip", testEndDateRoundTrip},
+		{"ListOrder", testListOrder},
+		{"TotalPeriodOverlap", testTotalPeriodOverlap},
+		{"TotalFilters", testTotalFilters},
+		{"TotalDateError", testTotalDateError},
+		{"StatusTransitions", testStatusTransitions},
+		{"StatusSurvivesUpdateAndDelete", testStatusSurvivesUpdateAndDelete},
+		{"TotalExcludesPausedMonths", testTotalExcludesPausedMonths},
+		{"ListByStatus", testListByStatus},
+		{"TrialRoundTrip", testTrialRoundTrip},
+		{"TotalExcludesTrialMonths", testTotalExcludesTrialMonths},
+		{"EndingTrials", testEndingTrials},
+		{"PromoRoundTrip", testPromoRoundTrip},
+		{"TotalAppliesPromoPrice", testTotalAppliesPromoPrice},
+		{"ServiceCatalog", testServiceCatalog},
+		{"ServiceAliasConflict", testServiceAliasConflict},
+		{"ServiceLinksSubscriptions", testServiceLinksSubscriptions},
+		{"TagsRoundTrip", testTagsRoundTrip},
+		{"TotalByCategory", testTotalByCategory},
+		{"TotalByCurrency", testTotalByCurrency},
+		{"MetadataRoundTrip", testMetadataRoundTrip},
+		{"SharedSubscriptions", testSharedSubscriptions},
+		{"Organizations", testOrganizations},
+		{"TenantIsolation", testTenantIsolation},
+		{"Users", testUsers},
+		{"Trash", testTrash},
+		{"Audit", testAudit},
+		{"AuditWithChange", testAuditWithChange},
+		{"Version", testVersion},
+		{"Idempotency", testIdempotency},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fn(t, newRepository(t))
+		})
+	}
+}
+
+func testCreateAndGet(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Yandex Plus", Price: 400, StartDate: "07-2025",
+	})
+	if created.UserID != userID || created.ServiceName != "Yandex Plus" || created.Price != 400 ||
+		!created.StartDate.Equal(month("07-2025")) || created.EndDate != nil {
+		t.Error("created subscription is wrong", created)
+	}
+	if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Error("timestamps must be set", created)
+	}
+
+	got, err := rep.GetSubscription(ctx, userID, "Yandex Plus")
+	if err != nil || got == nil {
+		t.Fatal("subscription not found", err)
+	}
+	if got.Price != 400 || !got.StartDate.Equal(created.StartDate) || got.EndDate != nil {
+		t.Error("stored subscription is wrong", got)
+	}
+
+	// имя сервиса чувствительно к регистру, как и ограничение unique_user_service
+	if other, err := rep.GetSubscription(ctx, userID, "yandex plus"); other != nil || err != nil {
+		t.Error("service name must be case-sensitive", other, err)
+	}
+}
+
+func testUpdate(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025",
+	})
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 650, StartDate: "03-2025", EndDate: "12-2025",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Price != 650 || !updated.StartDate.Equal(month("03-2025")) || updated.EndDate == nil ||
+		!updated.EndDate.Equal(month("12-2025")) {
+		t.Error("updated subscription is wrong", updated)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Error("created_at must not change on update", created.CreatedAt, updated.CreatedAt)
+	}
+	if !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Error("updated_at must change on update", created.UpdatedAt, updated.UpdatedAt)
+	}
+
+	got, err := rep.GetSubscription(ctx, userID, "Netflix")
+	if err != nil || got == nil || got.Price != 650 {
+		t.Error("update is not stored", got, err)
+	}
+}
+
+func testDelete(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 300, StartDate: "01-2025"})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "IVI", Price: 200, StartDate: "01-2025"})
+
+	if err := rep.DeleteSubscription(ctx, userID, "Okko", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rep.GetSubscription(ctx, userID, "Okko"); got != nil || err != nil {
+		t.Error("deleted subscription is still stored", got, err)
+	}
+	if got, err := rep.GetSubscription(ctx, userID, "IVI"); got == nil || err != nil {
+		t.Error("other subscription must stay", got, err)
+	}
+
+	// после удаления имя снова свободно
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 350, StartDate: "02-2025"})
+}
+
+func testNotFound(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	if got, err := rep.GetSubscription(ctx, userID, "Netflix"); got != nil || err != nil {
+		t.Error("GetSubscription: expected nil subscription without error", got, err)
+	}
+	_, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 100, StartDate: "01-2025",
+	}, nil)
+	if !errors.Is(err, dberrors.SubscriptionNotFound) {
+		t.Error("UpdateSubscription: expected SubscriptionNotFound, got", err)
+	}
+	if err := rep.DeleteSubscription(ctx, userID, "Netflix", 0, nil); !errors.Is(err, dberrors.SubscriptionNotFound) {
+		t.Error("DeleteSubscription: expected SubscriptionNotFound, got", err)
+	}
+	if list, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{}); len(list) != 0 || err != nil {
+		t.Error("ListUserSubscriptions: expected empty list", list, err)
+	}
+}
+
+func testDuplicate(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	req := models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Spotify", Price: 300, StartDate: "01-2025"}
+
+	mustCreate(t, rep, req)
+	req.Price = 350
+	if _, err := rep.CreateSubscription(ctx, req, nil); !errors.Is(err, dberrors.SubscriptionAlreadyExist) {
+		t.Error("expected SubscriptionAlreadyExist, got", err)
+	}
+	if got, _ := rep.GetSubscription(ctx, userID, "Spotify"); got == nil || got.Price != 300 {
+		t.Error("duplicate must not overwrite subscription", got)
+	}
+
+	// та же подписка у другого пользователя - не дубликат
+	req.UserID = uuid.New()
+	mustCreate(t, rep, req)
+}
+
+func testEndDateRoundTrip(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Kinopoisk", Price: 400, StartDate: "02-2025", EndDate: "11-2025",
+	})
+	if created.EndDate == nil || !created.EndDate.Equal(month("11-2025")) {
+		t.Error("created end_date is wrong", created.EndDate)
+	}
+
+	got, err := rep.GetSubscription(ctx, userID, "Kinopoisk")
+	if err != nil || got == nil || got.EndDate == nil || !got.EndDate.Equal(month("11-2025")) {
+		t.Fatal("stored end_date is wrong", got, err)
+	}
+
+	// обновление без end_date делает подписку бессрочной
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Kinopoisk", Price: 400, StartDate: "02-2025",
+	}, nil)
+	if err != nil || updated.EndDate != nil {
+		t.Error("end_date must be cleared", updated, err)
+	}
+
+	list, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{})
+	if err != nil || len(list) != 1 || list[0].EndDate != nil {
+		t.Error("listed end_date is wrong", list, err)
+	}
+
+	all, err := rep.ListAllSubscriptions(ctx)
+	if err != nil || len(all) != 1 || all[0].EndDate != nil {
+		t.Error("exported end_date is wrong", all, err)
+	}
+}
+
+func testListOrder(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	first := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	second := uuid.MustParse("00000000-0000-0000-0000-0000000000aa")
+
+	for _, req := range []models.CreateOrUpdateRequest{
+		{UserID: second, ServiceName: "Netflix", Price: 700, StartDate: "01-2025"},
+		{UserID: first, ServiceName: "Spotify", Price: 300, StartDate: "03-2025"},
+		{UserID: first, ServiceName: "Kinopoisk", Price: 400, StartDate: "06-2025"},
+		{UserID: first, ServiceName: "Apple Music", Price: 200, StartDate: "01-2024"},
+	} {
+		mustCreate(t, rep, req)
+	}
+
+	list, err := rep.ListUserSubscriptions(ctx, first, models.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := serviceNames(list); len(names) != 3 || names[0] != "Apple Music" || names[1] != "Kinopoisk" || names[2] != "Spotify" {
+		t.Error("user list must be ordered by service_name", names)
+	}
+
+	all, err := rep.ListAllSubscriptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := serviceNames(all); len(names) != 4 || names[0] != "Apple Music" || names[3] != "Netflix" {
+		t.Error("all subscriptions must be ordered by user_id, service_name", names)
+	}
+}
+
+func testTotalPeriodOverlap(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Spotify", Price: 300, StartDate: "03-2025", EndDate: "05-2025"})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 400, StartDate: "06-2025"})
+
+	cases := []struct {
+		name     string
+		from, to string
+		expected int
+	}{
+		{"before start", "01-2025", "02-2025", 0},
+		{"start month is inclusive", "01-2025", "03-2025", 300},
+		{"end month is inclusive", "05-2025", "05-2025", 300},
+		{"open-ended starts inclusive", "06-2025", "06-2025", 400},
+		{"period covers both", "01-2025", "12-2025", 700},
+		{"period inside subscription", "04-2025", "04-2025", 300},
+		{"open-ended in the future", "01-2030", "12-2030", 400},
+	}
+	for _, c := range cases {
+		total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{
+			UserID: userID, StartMonth: month(c.from), EndMonth: month(c.to),
+		})
+		if err != nil || total != c.expected {
+			t.Errorf("%s: expected %d, got %d (%v)", c.name, c.expected, total, err)
+		}
+	}
+}
+
+func testTotalFilters(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	first, second := uuid.New(), uuid.New()
+
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: first, ServiceName: "Netflix", Price: 500, StartDate: "01-2025"})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: first, ServiceName: "Spotify", Price: 300, StartDate: "01-2025"})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: second, ServiceName: "Netflix", Price: 700, StartDate: "01-2025"})
+
+	period := models.CalculateTotalRequest{StartMonth: month("01-2025"), EndMonth: month("12-2025")}
+	cases := []struct {
+		name     string
+		userID   uuid.UUID
+		service  string
+		expected int
+	}{
+		{"no filters", uuid.Nil, "", 1500},
+		{"user", first, "", 800},
+		{"service", uuid.Nil, "Netflix", 1200},
+		{"user and service", second, "Netflix", 700},
+		{"unknown service", first, "Okko", 0},
+	}
+	for _, c := range cases {
+		req := period
+		req.UserID, req.ServiceName = c.userID, c.service
+		total, err := rep.CalculateTotal(ctx, req)
+		if err != nil || total != c.expected {
+			t.Errorf("%s: expected %d, got %d (%v)", c.name, c.expected, total, err)
+		}
+	}
+}
+
+func testTotalDateError(t *testing.T, rep repository.Repository) {
+	_, err := rep.CalculateTotal(context.Background(), models.CalculateTotalRequest{
+		StartMonth: month("12-2025"), EndMonth: month("01-2025"),
+	})
+	if !errors.Is(err, dberrors.SubscriptionDateError) {
+		t.Error("expected SubscriptionDateError, got", err)
+	}
+}
+
+func testStatusTransitions(t *testing.T, rep repository.Repository) {
+	userID := uuid.New()
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Gym", Price: 2000, StartDate: "01-2025"})
+	if created.Status != models.StatusActive || created.StatusChangedAt.IsZero() {
+		t.Fatal("new subscription must be active", created)
+	}
+
+	change := func(status, from string) (*models.Subscription, error) {
+		return rep.ChangeStatus(context.Background(), models.StatusChangeRequest{
+			UserID: userID, ServiceName: "Gym", Month: from, Status: status,
+		}, nil)
+	}
+
+	if _, err := change(models.StatusActive, "02-2025"); !errors.Is(err, dberrors.SubscriptionStatusError) {
+		t.Error("resume of active subscription: expected SubscriptionStatusError, got", err)
+	}
+	if _, err := change(models.StatusPaused, "12-2024"); !errors.Is(err, dberrors.SubscriptionDateError) {
+		t.Error("pause before start_date: expected SubscriptionDateError, got", err)
+	}
+
+	paused, err := change(models.StatusPaused, "03-2025")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paused.Status != models.StatusPaused || len(paused.Pauses) != 1 || !paused.Pauses[0].From.Equal(month("03-2025")) ||
+		paused.Pauses[0].To != nil || paused.Pauses[0].PausedAt.IsZero() {
+		t.Error("paused subscription is wrong", paused)
+	}
+	if _, err := change(models.StatusPaused, "04-2025"); !errors.Is(err, dberrors.SubscriptionStatusError) {
+		t.Error("second pause: expected SubscriptionStatusError, got", err)
+	}
+	if _, err := change(models.StatusActive, "02-2025"); !errors.Is(err, dberrors.SubscriptionDateError) {
+		t.Error("resume before pause: expected SubscriptionDateError, got", err)
+	}
+
+	resumed, err := change(models.StatusActive, "06-2025")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Status != models.StatusActive || len(resumed.Pauses) != 1 || resumed.Pauses[0].To == nil ||
+		!resumed.Pauses[0].To.Equal(month("06-2025")) || resumed.Pauses[0].ResumedAt == nil {
+		t.Error("resumed subscription is wrong", resumed)
+	}
+	if _, err := change(models.StatusPaused, "05-2025"); !errors.Is(err, dberrors.SubscriptionDateError) {
+		t.Error("pause overlapping previous one: expected SubscriptionDateError, got", err)
+	}
+
+	cancelled, err := change(models.StatusCancelled, "09-2025")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != models.StatusCancelled || cancelled.CancelledAt == nil ||
+		cancelled.EndDate == nil || !cancelled.EndDate.Equal(month("09-2025")) {
+		t.Error("cancelled subscription is wrong", cancelled)
+	}
+	if _, err := change(models.StatusActive, "09-2025"); !errors.Is(err, dberrors.SubscriptionStatusError) {
+		t.Error("resume of cancelled subscription: expected SubscriptionStatusError, got", err)
+	}
+
+	got, err := rep.GetSubscription(context.Background(), userID, "Gym")
+	if err != nil || got == nil {
+		t.Fatal("subscription not found", err)
+	}
+	if got.Status != models.StatusCancelled || len(got.Pauses) != 1 || got.EndDate == nil || !got.EndDate.Equal(month("09-2025")) {
+		t.Error("stored subscription is wrong", got)
+	}
+
+	_, err = rep.ChangeStatus(context.Background(), models.StatusChangeRequest{UserID: userID, ServiceName: "Pool", Status: models.StatusPaused}, nil)
+	if !errors.Is(err, dberrors.SubscriptionNotFound) {
+		t.Error("missing subscription: expected SubscriptionNotFound, got", err)
+	}
+}
+
+func testStatusSurvivesUpdateAndDelete(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	req := models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 400, StartDate: "01-2025"}
+	mustCreate(t, rep, req)
+	if _, err := rep.ChangeStatus(ctx, models.StatusChangeRequest{
+		UserID: userID, ServiceName: "Okko", Month: "02-2025", Status: models.StatusPaused,
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Price = 450
+	updated, err := rep.UpdateSubscription(ctx, req, nil)
+	if err != nil || updated.Status != models.StatusPaused || len(updated.Pauses) != 1 {
+		t.Error("update must keep status and pauses", updated, err)
+	}
+
+	// паузы удаляются вместе с подпиской
+	if err := rep.DeleteSubscription(ctx, userID, "Okko", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	recreated := mustCreate(t, rep, req)
+	if recreated.Status != models.StatusActive || len(recreated.Pauses) != 0 {
+		t.Error("recreated subscription must be active without pauses", recreated)
+	}
+	if got, _ := rep.GetSubscription(ctx, userID, "Okko"); got == nil || len(got.Pauses) != 0 {
+		t.Error("stored recreated subscription must not have pauses", got)
+	}
+}
+
+func testTotalExcludesPausedMonths(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025"})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Spotify", Price: 300, StartDate: "01-2025"})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 200, StartDate: "01-2025"})
+	for _, change := range []models.StatusChangeRequest{
+		{UserID: userID, ServiceName: "Netflix", Month: "03-2025", Status: models.StatusPaused},
+		{UserID: userID, ServiceName: "Netflix", Month: "06-2025", Status: models.StatusActive},
+		{UserID: userID, ServiceName: "Spotify", Month: "04-2025", Status: models.StatusPaused},
+	} {
+		if _, err := rep.ChangeStatus(ctx, change, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name     string
+		from, to string
+		expected int
+	}{
+		{"before pauses", "01-2025", "02-2025", 1000},
+		{"inside closed pause", "04-2025", "05-2025", 200},
+		{"resume month is billed", "05-2025", "06-2025", 700},
+		{"open pause", "07-2025", "12-2025", 700},
+		{"pause start month is not billed", "03-2025", "03-2025", 500},
+		{"whole year", "01-2025", "12-2025", 1000},
+	}
+	for _, c := range cases {
+		total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{
+			UserID: userID, StartMonth: month(c.from), EndMonth: month(c.to),
+		})
+		if err != nil || total != c.expected {
+			t.Errorf("%s: expected %d, got %d (%v)", c.name, c.expected, total, err)
+		}
+	}
+
+	total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{
+		ServiceName: "Netflix", StartMonth: month("04-2025"), EndMonth: month("05-2025"),
+	})
+	if err != nil || total != 0 {
+		t.Error("service filter: paused Netflix must not be counted", total, err)
+	}
+}
+
+func testListByStatus(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	for _, req := range []models.CreateOrUpdateRequest{
+		{UserID: userID, ServiceName: "Active", Price: 100, StartDate: "01-2025"},
+		{UserID: userID, ServiceName: "Paused", Price: 100, StartDate: "01-2025"},
+		{UserID: userID, ServiceName: "Cancelled", Price: 100, StartDate: "01-2020"},
+		{UserID: userID, ServiceName: "Expired", Price: 100, StartDate: "01-2020", EndDate: "06-2020"},
+	} {
+		mustCreate(t, rep, req)
+	}
+	for _, change := range []models.StatusChangeRequest{
+		{UserID: userID, ServiceName: "Paused", Month: "02-2025", Status: models.StatusPaused},
+		// отмена в прошлом: end_date прошел, но статус остается cancelled
+		{UserID: userID, ServiceName: "Cancelled", Month: "03-2020", Status: models.StatusCancelled},
+	} {
+		if _, err := rep.ChangeStatus(ctx, change, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, status := range []string{models.StatusActive, models.StatusPaused, models.StatusCancelled, models.StatusExpired} {
+		list, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{Status: status})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].Status != status {
+			t.Errorf("status %s: unexpected list %v", status, serviceNames(list))
+		}
+	}
+
+	all, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{})
+	if err != nil || len(all) != 4 {
+		t.Error("list without filter must contain all subscriptions", serviceNames(all), err)
+	}
+	if got, _ := rep.GetSubscription(ctx, userID, "Expired"); got == nil || got.Status != models.StatusExpired {
+		t.Error("subscription with past end_date must be expired", got)
+	}
+	_, err = rep.ChangeStatus(ctx, models.StatusChangeRequest{UserID: userID, ServiceName: "Expired", Month: "05-2020", Status: models.StatusPaused}, nil)
+	if !errors.Is(err, dberrors.SubscriptionStatusError) {
+		t.Error("pause of expired subscription: expected SubscriptionStatusError, got", err)
+	}
+}
+
+func testTrialRoundTrip(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	current := lifecycle.CurrentMonth(time.Now())
+
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Trial", Price: 300,
+		StartDate: monthString(current.AddDate(0, -1, 0)), TrialEnd: monthString(current.AddDate(0, 1, 0)),
+	})
+	if created.TrialEnd == nil || !created.TrialEnd.Equal(current.AddDate(0, 1, 0)) || !created.InTrial {
+		t.Error("created subscription must be in trial", created)
+	}
+
+	list, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{})
+	if err != nil || len(list) != 1 || list[0].TrialEnd == nil || !list[0].InTrial {
+		t.Error("listed subscription must be in trial", list, err)
+	}
+
+	// trial_end, как и end_date, перезаписывается: без него пробного периода больше нет
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Trial", Price: 300, StartDate: monthString(current.AddDate(0, -1, 0)),
+	}, nil)
+	if err != nil || updated.TrialEnd != nil || updated.InTrial {
+		t.Error("update without trial_end must clear the trial", updated, err)
+	}
+
+	// пробный период, который закончился до начала подписки, отклоняется схемой
+	_, err = rep.CreateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Broken", Price: 300, StartDate: "05-2025", TrialEnd: "04-2025",
+	}, nil)
+	if err == nil {
+		t.Error("trial_end before start_date must be rejected")
+	}
+}
+
+func testTotalExcludesTrialMonths(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Trial", Price: 100, StartDate: "01-2025", TrialEnd: "03-2025",
+	})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Plain", Price: 50, StartDate: "01-2025",
+	})
+
+	cases := []struct {
+		from, to string
+		want     int
+	}{
+		{"01-2025", "03-2025", 50},  // весь период - пробный
+		{"02-2025", "02-2025", 50},  // месяц внутри пробного периода
+		{"03-2025", "04-2025", 150}, // апрель уже оплачивается
+		{"04-2025", "06-2025", 150}, // пробный период закончился до начала периода
+		{"01-2025", "12-2025", 150},
+	}
+	for _, c := range cases {
+		total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{
+			UserID: userID, StartMonth: month(c.from), EndMonth: month(c.to),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != c.want {
+			t.Errorf("total %s..%s = %d, want %d", c.from, c.to, total, c.want)
+		}
+	}
+}
+
+func testEndingTrials(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID, otherID := uuid.New(), uuid.New()
+	current := lifecycle.CurrentMonth(time.Now())
+	start := monthString(current.AddDate(0, -3, 0))
+
+	for _, req := range []models.CreateOrUpdateRequest{
+		// оплата начнется со следующего месяца - не позже чем через 31 день
+		{UserID: userID, ServiceName: "Soon", Price: 100, StartDate: start, TrialEnd: monthString(current)},
+		{UserID: otherID, ServiceName: "Soon", Price: 100, StartDate: start, TrialEnd: monthString(current)},
+		{UserID: userID, ServiceName: "Later", Price: 100, StartDate: start, TrialEnd: monthString(current.AddDate(0, 2, 0))},
+		{UserID: userID, ServiceName: "Past", Price: 100, StartDate: start, TrialEnd: monthString(current.AddDate(0, -1, 0))},
+		{UserID: userID, ServiceName: "Cancelled", Price: 100, StartDate: start, TrialEnd: monthString(current)},
+		// подписка заканчивается вместе с пробным периодом - оплаты не будет
+		{UserID: userID, ServiceName: "NoCharge", Price: 100, StartDate: start, EndDate: monthString(current), TrialEnd: monthString(current)},
+		{UserID: userID, ServiceName: "NoTrial", Price: 100, StartDate: start},
+	} {
+		mustCreate(t, rep, req)
+	}
+	if _, err := rep.ChangeStatus(ctx, models.StatusChangeRequest{
+		UserID: userID, ServiceName: "Cancelled", Status: models.StatusCancelled,
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	trials, err := rep.EndingTrials(ctx, models.TrialsRequest{UserID: userID, Days: 31})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := serviceNames(trials); len(names) != 1 || names[0] != "Soon" || trials[0].UserID != userID {
+		t.Error("user filter: unexpected trials", names)
+	}
+
+	trials, err = rep.EndingTrials(ctx, models.TrialsRequest{Days: 31})
+	if err != nil || len(trials) != 2 || trials[0].ServiceName != "Soon" || trials[1].ServiceName != "Soon" {
+		t.Error("all users: unexpected trials", serviceNames(trials), err)
+	}
+
+	// через 0 дней оплата еще не начнется ни у кого
+	trials, err = rep.EndingTrials(ctx, models.TrialsRequest{Days: 0})
+	if err != nil || trials != nil {
+		t.Error("days=0: expected no trials", serviceNames(trials), err)
+	}
+}
+
+func testPromoRoundTrip(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	current := lifecycle.CurrentMonth(time.Now())
+	percent := 25
+
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Promo", Price: 400, StartDate: monthString(current.AddDate(0, -1, 0)),
+		Promo: &models.PromoRequest{From: monthString(current), To: monthString(current.AddDate(0, 2, 0)), Percent: &percent},
+	})
+	if created.Price != 400 || created.EffectivePrice != 300 || created.Promo == nil ||
+		!created.Promo.From.Equal(current) || created.Promo.Percent == nil || *created.Promo.Percent != 25 || created.Promo.Price != nil {
+		t.Error("created subscription must keep list price and show promo price", created)
+	}
+
+	got, err := rep.GetSubscription(ctx, userID, "Promo")
+	if err != nil || got == nil || got.Promo == nil || !got.Promo.To.Equal(current.AddDate(0, 2, 0)) || got.EffectivePrice != 300 {
+		t.Error("stored promo is wrong", got, err)
+	}
+
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Promo", Price: 500, StartDate: monthString(current.AddDate(0, -1, 0)),
+	}, nil)
+	if err != nil || updated.Promo != nil || updated.EffectivePrice != 500 {
+		t.Error("update without promo must clear it", updated, err)
+	}
+
+	price := 99
+	for name, promo := range map[string]*models.PromoRequest{
+		"from after to":     {From: "03-2025", To: "01-2025", Price: &price},
+		"price and percent": {From: "01-2025", To: "03-2025", Price: &price, Percent: &percent},
+		"no price":          {From: "01-2025", To: "03-2025"},
+	} {
+		_, err := rep.CreateSubscription(ctx, models.CreateOrUpdateRequest{
+			UserID: userID, ServiceName: "Broken " + name, Price: 300, StartDate: "01-2025", Promo: promo,
+		}, nil)
+		if err == nil {
+			t.Errorf("%s: promo must be rejected", name)
+		}
+	}
+}
+
+func testTotalAppliesPromoPrice(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	promoPrice, percent := 99, 25
+
+	for _, req := range []models.CreateOrUpdateRequest{
+		// первые 3 месяца по 99
+		{UserID: userID, ServiceName: "Fixed", Price: 300, StartDate: "01-2025",
+			Promo: &models.PromoRequest{From: "01-2025", To: "03-2025", Price: &promoPrice}},
+		// май со скидкой 25%
+		{UserID: userID, ServiceName: "Percent", Price: 200, StartDate: "01-2025",
+			Promo: &models.PromoRequest{From: "05-2025", To: "05-2025", Percent: &percent}},
+		{UserID: userID, ServiceName: "Plain", Price: 50, StartDate: "01-2025"},
+	} {
+		mustCreate(t, rep, req)
+	}
+
+	// подписка учитывается по цене первого оплачиваемого месяца периода
+	cases := []struct {
+		from, to string
+		want     int
+	}{
+		{"01-2025", "03-2025", 99 + 200 + 50},
+		{"02-2025", "12-2025", 99 + 200 + 50},
+		{"04-2025", "06-2025", 300 + 200 + 50},
+		{"05-2025", "05-2025", 300 + 150 + 50},
+		{"07-2025", "08-2025", 300 + 200 + 50}, // оба промо-периода закончились
+	}
+	for _, c := range cases {
+		total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{
+			UserID: userID, StartMonth: month(c.from), EndMonth: month(c.to),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != c.want {
+			t.Errorf("total %s..%s = %d, want %d", c.from, c.to, total, c.want)
+		}
+	}
+
+	// пробный период сдвигает первый оплачиваемый месяц внутрь промо-периода
+	mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "TrialPromo", Price: 300, StartDate: "01-2025", TrialEnd: "01-2025",
+		Promo: &models.PromoRequest{From: "02-2025", To: "02-2025", Price: &promoPrice},
+	})
+	total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{
+		ServiceName: "TrialPromo", StartMonth: month("01-2025"), EndMonth: month("06-2025"),
+	})
+	if err != nil || total != 99 {
+		t.Error("trial then promo: expected 99, got", total, err)
+	}
+}
+
+func testServiceCatalog(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	price := 599
+
+	created, err := rep.CreateService(ctx, models.ServiceRequest{
+		Name: " Netflix ", Aliases: []string{"NFLX", "netflix", "Нетфликс"}, Category: "video", DefaultPrice: &price, Currency: "usd",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != "Netflix" || created.Currency != "USD" || created.Category != "video" ||
+		created.DefaultPrice == nil || *created.DefaultPrice != 599 || !slices.Equal(created.Aliases, []string{"nflx", "нетфликс"}) {
+		t.Error("created service is wrong", created)
+	}
+
+	got, err := rep.GetService(ctx, created.ID)
+	if err != nil || got == nil || got.Name != "Netflix" || !slices.Equal(got.Aliases, created.Aliases) || got.DefaultPrice == nil {
+		t.Error("stored service is wrong", got, err)
+	}
+	for _, name := range []string{"netflix", "  NETFLIX", "Nflx", "нетфликс"} {
+		if resolved, err := rep.ResolveService(ctx, name); err != nil || resolved == nil || resolved.ID != created.ID {
+			t.Errorf("%q must resolve to Netflix: %v %v", name, resolved, err)
+		}
+	}
+	if resolved, err := rep.ResolveService(ctx, "Spotify"); resolved != nil || err != nil {
+		t.Error("unknown name must not resolve", resolved, err)
+	}
+
+	updated, err := rep.UpdateService(ctx, created.ID, models.ServiceRequest{Name: "Netflix", Aliases: []string{"nf"}})
+	if err != nil || updated.DefaultPrice != nil || updated.Currency != "RUB" || !slices.Equal(updated.Aliases, []string{"nf"}) {
+		t.Fatal("updated service is wrong", updated, err)
+	}
+	if resolved, _ := rep.ResolveService(ctx, "nflx"); resolved != nil {
+		t.Error("removed alias must not resolve", resolved)
+	}
+
+	services, err := rep.ListServices(ctx)
+	if err != nil || len(services) != 1 || services[0].ID != created.ID {
+		t.Error("catalog listing is wrong", services, err)
+	}
+
+	if err := rep.DeleteService(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rep.GetService(ctx, created.ID); got != nil || err != nil {
+		t.Error("deleted service must not be found", got, err)
+	}
+	if resolved, _ := rep.ResolveService(ctx, "nf"); resolved != nil {
+		t.Error("aliases of a deleted service must not resolve", resolved)
+	}
+	if err := rep.DeleteService(ctx, created.ID); !errors.Is(err, dberrors.ServiceNotFound) {
+		t.Error("expected ServiceNotFound on delete, got", err)
+	}
+	if _, err := rep.UpdateService(ctx, created.ID, models.ServiceRequest{Name: "Netflix"}); !errors.Is(err, dberrors.ServiceNotFound) {
+		t.Error("expected ServiceNotFound on update, got", err)
+	}
+}
+
+func testServiceAliasConflict(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+
+	music, err := rep.CreateService(ctx, models.ServiceRequest{Name: "Spotify", Aliases: []string{"music"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rep.CreateService(ctx, models.ServiceRequest{Name: "SPOTIFY"}); !errors.Is(err, dberrors.ServiceAlreadyExist) {
+		t.Error("name taken case-insensitively, got", err)
+	}
+	if _, err := rep.CreateService(ctx, models.ServiceRequest{Name: "Apple Music", Aliases: []string{"Music"}}); !errors.Is(err, dberrors.ServiceAlreadyExist) {
+		t.Error("alias taken by another service, got", err)
+	}
+	if _, err := rep.CreateService(ctx, models.ServiceRequest{Name: "Music"}); !errors.Is(err, dberrors.ServiceAlreadyExist) {
+		t.Error("name taken by another service alias, got", err)
+	}
+
+	// неудачная запись не оставляет ключей поиска
+	if resolved, _ := rep.ResolveService(ctx, "apple music"); resolved != nil {
+		t.Error("failed create must not leave aliases", resolved)
+	}
+
+	video, err := rep.CreateService(ctx, models.ServiceRequest{Name: "Kinopoisk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rep.UpdateService(ctx, video.ID, models.ServiceRequest{Name: "Kinopoisk", Aliases: []string{"MUSIC"}}); !errors.Is(err, dberrors.ServiceAlreadyExist) {
+		t.Error("update must not take an alias of another service, got", err)
+	}
+	if resolved, _ := rep.ResolveService(ctx, "music"); resolved == nil || resolved.ID != music.ID {
+		t.Error("failed update must keep aliases", resolved)
+	}
+}
+
+func testServiceLinksSubscriptions(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	unlinked := mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 300, StartDate: "01-2025"})
+	if unlinked.ServiceID != nil {
+		t.Error("subscription without catalog entry must not be linked", unlinked)
+	}
+
+	service, err := rep.CreateService(ctx, models.ServiceRequest{Name: "Okko"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked := mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: uuid.New(), ServiceName: "Okko", Price: 300, StartDate: "01-2025"})
+	if linked.ServiceID == nil || *linked.ServiceID != service.ID {
+		t.Error("subscription must be linked to the catalog entry", linked)
+	}
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 350, StartDate: "01-2025"}, nil)
+	if err != nil || updated.ServiceID == nil || *updated.ServiceID != service.ID {
+		t.Error("update must link the subscription", updated, err)
+	}
+
+	if _, err := rep.UpdateService(ctx, service.ID, models.ServiceRequest{Name: "Okko TV", Aliases: []string{"okko"}}); err != nil {
+		t.Fatal(err)
+	}
+	renamed, err := rep.GetSubscription(ctx, userID, "Okko TV")
+	if err != nil || renamed == nil || renamed.Price != 350 || renamed.ServiceID == nil {
+		t.Error("renaming a service must rename its subscriptions", renamed, err)
+	}
+	if old, _ := rep.GetSubscription(ctx, userID, "Okko"); old != nil {
+		t.Error("old name must be gone", old)
+	}
+
+	if err := rep.DeleteService(ctx, service.ID); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := rep.GetSubscription(ctx, userID, "Okko TV")
+	if err != nil || kept == nil || kept.ServiceID != nil {
+		t.Error("deleting a service must keep subscriptions and drop the link", kept, err)
+	}
+}
+
+func testTagsRoundTrip(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025",
+		Category: " video ", Tags: []string{"Family", " family", "work"},
+	})
+	if created.Category != "video" || !slices.Equal(created.Tags, []string{"family", "work"}) {
+		t.Error("category and tags must be normalized", created)
+	}
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Spotify", Price: 300, StartDate: "01-2025", Tags: []string{"family"}})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 200, StartDate: "01-2025"})
+
+	got, err := rep.GetSubscription(ctx, userID, "Netflix")
+	if err != nil || got == nil || got.Category != "video" || !slices.Equal(got.Tags, []string{"family", "work"}) {
+		t.Error("stored tags are wrong", got, err)
+	}
+
+	cases := []struct {
+		tags     []string
+		expected []string
+	}{
+		{nil, []string{"Netflix", "Okko", "Spotify"}},
+		{[]string{"FAMILY"}, []string{"Netflix", "Spotify"}},
+		{[]string{"family", "work"}, []string{"Netflix"}},
+		{[]string{"kids"}, nil},
+	}
+	for _, c := range cases {
+		list, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{Tags: c.tags})
+		if err != nil || !slices.Equal(serviceNames(list), c.expected) {
+			t.Errorf("tags %v: expected %v, got %v (%v)", c.tags, c.expected, serviceNames(list), err)
+		}
+	}
+
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025", Tags: []string{"kids"},
+	}, nil)
+	if err != nil || updated.Category != "" || !slices.Equal(updated.Tags, []string{"kids"}) {
+		t.Error("update must replace category and tags", updated, err)
+	}
+	if list, _ := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{Tags: []string{"work"}}); len(list) != 0 {
+		t.Error("replaced tags must not match", serviceNames(list))
+	}
+}
+
+func testTotalByCategory(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	price := 100
+
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025", Category: "video", Tags: []string{"family"}})
+	// промо-цена считается по месяцам, а не в SQL
+	mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Kinopoisk", Price: 400, StartDate: "01-2025", Category: "video",
+		Promo: &models.PromoRequest{From: "01-2025", To: "03-2025", Price: &price},
+	})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Spotify", Price: 300, StartDate: "01-2025", Category: "music", Tags: []string{"family"}})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Gym", Price: 2000, StartDate: "01-2025"})
+	// пробный период на весь период - в отчет не попадает
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Course", Price: 900, StartDate: "01-2025", TrialEnd: "12-2025", Category: "education"})
+
+	req := models.CalculateTotalRequest{UserID: userID, StartMonth: month("01-2025"), EndMonth: month("12-2025")}
+	categories, err := rep.TotalByCategory(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.CategoryTotal{{Category: "", Total: 2000}, {Category: "video", Total: 600}, {Category: "music", Total: 300}}
+	if !slices.Equal(categories, expected) {
+		t.Errorf("expected %v, got %v", expected, categories)
+	}
+	if total, err := rep.CalculateTotal(ctx, req); err != nil || total != 2900 {
+		t.Error("total must equal the sum of categories, got", total, err)
+	}
+
+	req.Tags = []string{"Family"}
+	categories, err = rep.TotalByCategory(ctx, req)
+	if expected := []models.CategoryTotal{{Category: "video", Total: 500}, {Category: "music", Total: 300}}; err != nil || !slices.Equal(categories, expected) {
+		t.Errorf("tag filter: expected %v, got %v (%v)", expected, categories, err)
+	}
+	if total, err := rep.CalculateTotal(ctx, req); err != nil || total != 800 {
+		t.Error("total with tag filter is wrong", total, err)
+	}
+
+	if _, err := rep.TotalByCategory(ctx, models.CalculateTotalRequest{StartMonth: month("12-2025"), EndMonth: month("01-2025")}); !errors.Is(err, dberrors.SubscriptionDateError) {
+		t.Error("expected SubscriptionDateError, got", err)
+	}
+}
+
+// testTotalByCurrency - валюта подписки - валюта сервиса каталога, сервиса нет в каталоге - catalog.DefaultCurrency
+func testTotalByCurrency(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	if _, err := rep.CreateService(ctx, models.ServiceRequest{Name: "Spotify", Currency: "USD"}); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Spotify", Price: 10, StartDate: "01-2025", Category: "music"})
+	// пробный период - подписка считается по месяцам, а не в SQL
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025", TrialEnd: "01-2025", Category: "video"})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Gym", Price: 2000, StartDate: "01-2025"})
+
+	cases := []struct {
+		currency string
+		expected int
+	}{
+		{"", 2510},
+		{catalog.DefaultCurrency, 2500},
+		{"USD", 10},
+		{"EUR", 0},
+	}
+	for _, c := range cases {
+		req := models.CalculateTotalRequest{UserID: userID, Currency: c.currency, StartMonth: month("01-2025"), EndMonth: month("12-2025")}
+		if total, err := rep.CalculateTotal(ctx, req); err != nil || total != c.expected {
+			t.Errorf("currency %q: expected %d, got %d (%v)", c.currency, c.expected, total, err)
+		}
+	}
+
+	req := models.CalculateTotalRequest{UserID: userID, Currency: "USD", StartMonth: month("01-2025"), EndMonth: month("12-2025")}
+	categories, err := rep.TotalByCategory(ctx, req)
+	if expected := []models.CategoryTotal{{Category: "music", Total: 10}}; err != nil || !slices.Equal(categories, expected) {
+		t.Errorf("categories in USD: expected %v, got %v (%v)", expected, categories, err)
+	}
+}
+
+func mustCreate(t *testing.T, rep repository.Repository, req models.CreateOrUpdateRequest) *models.Subscription {
+	t.Helper()
+	sub, err := rep.CreateSubscription(context.Background(), req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+// month - "MM-YYYY" -> первое число месяца, как в запросах API
+func month(value string) time.Time {
+	m, err := tools.ParseMonthYear(value)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// monthString - первое число месяца -> "MM-YYYY"
+func monthString(m time.Time) string {
+	return m.Format("01-2006")
+}
+
+func serviceNames(subscriptions []models.Subscription) []string {
+	names := make([]string, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		names = append(names, sub.ServiceName)
+	}
+	return names
+}
+
+func testMetadataRoundTrip(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	meta := map[string]any{
+		"cost_center": "eng",
+		"invoice":     "INV-1",
+		"seats":       5,
+		"account":     map[string]any{"email": "team@example.com", "ids": []any{1, "a"}},
+	}
+	expected := map[string]any{
+		"cost_center": "eng",
+		"invoice":     "INV-1",
+		"seats":       float64(5),
+		"account":     map[string]any{"email": "team@example.com", "ids": []any{float64(1), "a"}},
+	}
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025", Metadata: meta,
+	})
+	if !reflect.DeepEqual(created.Metadata, expected) {
+		t.Errorf("expected metadata %v, got %v", expected, created.Metadata)
+	}
+	created.Metadata["cost_center"] = "sales"
+
+	mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Spotify", Price: 300, StartDate: "01-2025",
+		Metadata: map[string]any{"cost_center": "eng", "seats": "5"},
+	})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 200, StartDate: "01-2025"})
+
+	got, err := rep.GetSubscription(ctx, userID, "Netflix")
+	if err != nil || got == nil || !reflect.DeepEqual(got.Metadata, expected) {
+		t.Error("stored metadata is wrong", got, err)
+	}
+	if okko, _ := rep.GetSubscription(ctx, userID, "Okko"); okko == nil || okko.Metadata != nil {
+		t.Error("subscription without metadata must have nil metadata", okko)
+	}
+
+	cases := []struct {
+		filter   map[string]string
+		expected []string
+	}{
+		{nil, []string{"Netflix", "Okko", "Spotify"}},
+		{map[string]string{"cost_center": "eng"}, []string{"Netflix", "Spotify"}},
+		{map[string]string{"cost_center": "eng", "invoice": "INV-1"}, []string{"Netflix"}},
+		{map[string]string{"cost_center": "ENG"}, nil},
+		// только строковые значения: число 5 не совпадает со строкой "5"
+		{map[string]string{"seats": "5"}, []string{"Spotify"}},
+		{map[string]string{"email": "team@example.com"}, nil},
+	}
+	for _, c := range cases {
+		list, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{Metadata: c.filter})
+		if err != nil || !slices.Equal(serviceNames(list), c.expected) {
+			t.Errorf("metadata %v: expected %v, got %v (%v)", c.filter, c.expected, serviceNames(list), err)
+		}
+	}
+
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025",
+		Metadata: map[string]any{"cost_center": "ops"},
+	}, nil)
+	if err != nil || !reflect.DeepEqual(updated.Metadata, map[string]any{"cost_center": "ops"}) {
+		t.Error("update must replace metadata", updated, err)
+	}
+	list, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{Metadata: map[string]string{"invoice": "INV-1"}})
+	if err != nil || len(list) != 0 {
+		t.Error("replaced metadata must not match", serviceNames(list), err)
+	}
+}
+
+func testSharedSubscriptions(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	owner, first, second := uuid.New(), uuid.New(), uuid.New()
+
+	// 1000 поровну на троих: участникам по 333, владельцу остаток 334
+	family := &models.Sharing{Split: models.SplitEqual, Members: []models.Member{{UserID: first}, {UserID: second}}}
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: owner, ServiceName: "Spotify", Price: 1000, StartDate: "01-2025", Category: "music", Tags: []string{"family"},
+		Sharing: family,
+	})
+	expected := &models.Sharing{Split: models.SplitEqual, Members: []models.Member{{UserID: first}, {UserID: second}}}
+	slices.SortFunc(expected.Members, func(a, b models.Member) int { return strings.Compare(a.UserID.String(), b.UserID.String()) })
+	if !reflect.DeepEqual(created.Sharing, expected) {
+		t.Errorf("expected sharing %v, got %v", expected, created.Sharing)
+	}
+	// 900 по процентам: 30% - 270, 50% - 450, владельцу 180
+	mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: first, ServiceName: "Netflix", Price: 900, StartDate: "01-2025", Category: "video",
+		Sharing: &models.Sharing{Split: models.SplitPercentage, Members: []models.Member{{UserID: owner, Percent: 30}, {UserID: second, Percent: 50}}},
+	})
+	// платит только владелец
+	mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: second, ServiceName: "Gym", Price: 500, StartDate: "01-2025",
+		Sharing: &models.Sharing{Split: models.SplitPayer, Members: []models.Member{{UserID: first}}},
+	})
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: owner, ServiceName: "Okko", Price: 200, StartDate: "01-2025"})
+
+	got, err := rep.GetSubscription(ctx, owner, "Spotify")
+	if err != nil || got == nil || !reflect.DeepEqual(got.Sharing, expected) {
+		t.Error("stored sharing is wrong", got, err)
+	}
+	if okko, _ := rep.GetSubscription(ctx, owner, "Okko"); okko == nil || okko.Sharing != nil {
+		t.Error("own subscription must not be shared", okko)
+	}
+
+	cases := []struct {
+		name     string
+		userID   uuid.UUID
+		expected int
+	}{
+		{"owner", owner, 334 + 270 + 200},
+		{"first member", first, 333 + 180},
+		{"second member", second, 333 + 450 + 500},
+		{"no user filter counts full prices", uuid.Nil, 1000 + 900 + 500 + 200},
+	}
+	for _, c := range cases {
+		total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{UserID: c.userID, StartMonth: month("01-2025"), EndMonth: month("12-2025")})
+		if err != nil || total != c.expected {
+			t.Errorf("%s: expected %d, got %d (%v)", c.name, c.expected, total, err)
+		}
+	}
+
+	categories, err := rep.TotalByCategory(ctx, models.CalculateTotalRequest{UserID: first, StartMonth: month("01-2025"), EndMonth: month("12-2025")})
+	if want := []models.CategoryTotal{{Category: "music", Total: 333}, {Category: "video", Total: 180}}; err != nil || !slices.Equal(categories, want) {
+		t.Errorf("expected %v, got %v (%v)", want, categories, err)
+	}
+
+	list, err := rep.ListUserSubscriptions(ctx, first, models.ListFilter{})
+	if want := []string{"Gym", "Netflix", "Spotify"}; err != nil || !slices.Equal(serviceNames(list), want) {
+		t.Errorf("member list: expected %v, got %v (%v)", want, serviceNames(list), err)
+	}
+	list, err = rep.ListUserSubscriptions(ctx, second, models.ListFilter{Tags: []string{"family"}})
+	if err != nil || !slices.Equal(serviceNames(list), []string{"Spotify"}) {
+		t.Error("filters must apply to shared subscriptions", serviceNames(list), err)
+	}
+
+	// без sharing подписка перестает быть совместной
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{UserID: owner, ServiceName: "Spotify", Price: 1000, StartDate: "01-2025"}, nil)
+	if err != nil || updated.Sharing != nil {
+		t.Error("update must replace sharing", updated, err)
+	}
+	list, err = rep.ListUserSubscriptions(ctx, first, models.ListFilter{})
+	if want := []string{"Gym", "Netflix"}; err != nil || !slices.Equal(serviceNames(list), want) {
+		t.Errorf("after update: expected %v, got %v (%v)", want, serviceNames(list), err)
+	}
+	total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{UserID: owner, StartMonth: month("01-2025"), EndMonth: month("12-2025")})
+	if err != nil || total != 1000+270+200 {
+		t.Error("owner pays the whole unshared subscription, got", total, err)
+	}
+
+	if err := rep.DeleteSubscription(ctx, first, "Netflix", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := rep.ListUserSubscriptions(ctx, second, models.ListFilter{}); !slices.Equal(serviceNames(list), []string{"Gym"}) {
+		t.Error("deleted shared subscription must leave member lists", serviceNames(list))
+	}
+}
+
+func testOrganizations(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+
+	// организация по умолчанию создается миграциями
+	if organization, err := rep.GetOrganization(ctx, tenant.Default); err != nil || organization == nil {
+		t.Fatal("default organization must exist", organization, err)
+	}
+	marketing, err := rep.CreateOrganization(ctx, models.OrganizationRequest{Name: "Marketing"})
+	if err != nil || marketing.Name != "Marketing" || marketing.ID == uuid.Nil || marketing.CreatedAt.IsZero() {
+		t.Fatal("create organization failed", marketing, err)
+	}
+	if _, err := rep.CreateOrganization(ctx, models.OrganizationRequest{Name: "Marketing"}); !errors.Is(err, dberrors.OrganizationAlreadyExist) {
+		t.Error("expected OrganizationAlreadyExist, got", err)
+	}
+	list, err := rep.ListOrganizations(ctx)
+	if err != nil || len(list) != 2 || list[0].Name != "Marketing" || list[1].ID != tenant.Default {
+		t.Error("organizations must be ordered by name", list, err)
+	}
+
+	// владелец и участники подписки становятся участниками ее организации
+	owner, member, manual := uuid.New(), uuid.New(), uuid.New()
+	inMarketing := tenant.WithID(ctx, marketing.ID)
+	_, err = rep.CreateSubscription(inMarketing, models.CreateOrUpdateRequest{
+		UserID: owner, ServiceName: "Figma", Price: 1500, StartDate: "01-2025",
+		Sharing: &models.Sharing{Split: models.SplitEqual, Members: []models.Member{{UserID: member}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, err := rep.AddMember(inMarketing, marketing.ID, manual)
+	if err != nil || added.UserID != manual || added.JoinedAt.IsZero() {
+		t.Fatal("add member failed", added, err)
+	}
+	if again, err := rep.AddMember(inMarketing, marketing.ID, manual); err != nil || !again.JoinedAt.Equal(added.JoinedAt) {
+		t.Error("repeated add must keep the membership", again, err)
+	}
+	members, err := rep.ListMembers(inMarketing, marketing.ID)
+	expected := []uuid.UUID{owner, member, manual}
+	slices.SortFunc(expected, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+	if err != nil || len(members) != 3 || members[0].UserID != expected[0] || members[1].UserID != expected[1] || members[2].UserID != expected[2] {
+		t.Errorf("expected members %v, got %v (%v)", expected, members, err)
+	}
+	if members, _ := rep.ListMembers(ctx, tenant.Default); len(members) != 0 {
+		t.Error("members must not leak into another organization", members)
+	}
+
+	if err := rep.RemoveMember(inMarketing, marketing.ID, manual); err != nil {
+		t.Error(err)
+	}
+	if err := rep.RemoveMember(inMarketing, marketing.ID, manual); !errors.Is(err, dberrors.MemberNotFound) {
+		t.Error("expected MemberNotFound, got", err)
+	}
+	if _, err := rep.AddMember(ctx, uuid.New(), manual); !errors.Is(err, dberrors.OrganizationNotFound) {
+		t.Error("expected OrganizationNotFound, got", err)
+	}
+
+	// организацию с подписками удалить нельзя
+	if err := rep.DeleteOrganization(inMarketing, marketing.ID); !errors.Is(err, dberrors.OrganizationNotEmpty) {
+		t.Error("expected OrganizationNotEmpty, got", err)
+	}
+	if err := rep.DeleteSubscription(inMarketing, owner, "Figma", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.DeleteOrganization(inMarketing, marketing.ID); err != nil {
+		t.Fatal(err)
+	}
+	if organization, err := rep.GetOrganization(ctx, marketing.ID); organization != nil || err != nil {
+		t.Error("organization must be deleted", organization, err)
+	}
+	if err := rep.DeleteOrganization(ctx, marketing.ID); !errors.Is(err, dberrors.OrganizationNotFound) {
+		t.Error("expected OrganizationNotFound, got", err)
+	}
+}
+
+func testTenantIsolation(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	organization, err := rep.CreateOrganization(ctx, models.OrganizationRequest{Name: "Sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sales := tenant.WithID(ctx, organization.ID)
+	userID := uuid.New()
+
+	// один и тот же пользователь, сервис и алиас в двух организациях не конфликтуют
+	for _, c := range []context.Context{ctx, sales} {
+		if _, err := rep.CreateService(c, models.ServiceRequest{Name: "Netflix", Aliases: []string{"nflx"}, Currency: "RUB"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Netflix", Price: 700, StartDate: "01-2025"})
+	created, err := rep.CreateSubscription(sales, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Netflix", Price: 300, StartDate: "01-2025"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.TenantID != organization.ID || created.ServiceID == nil {
+		t.Error("subscription must belong to its organization and its catalog", created)
+	}
+
+	period := models.CalculateTotalRequest{StartMonth: month("01-2025"), EndMonth: month("01-2025")}
+	if total, err := rep.CalculateTotal(ctx, period); err != nil || total != 700 {
+		t.Error("default total must not include other organizations, got", total, err)
+	}
+	if total, err := rep.CalculateTotal(sales, period); err != nil || total != 300 {
+		t.Error("organization total must include only its subscriptions, got", total, err)
+	}
+	if got, _ := rep.GetSubscription(sales, userID, "Netflix"); got == nil || got.Price != 300 || got.TenantID != organization.ID {
+		t.Error("organization must see its own subscription", got)
+	}
+	if all, _ := rep.ListAllSubscriptions(sales); len(all) != 1 || all[0].Price != 300 {
+		t.Error("organization must list only its subscriptions", all)
+	}
+
+	// изменения в одной организации не трогают другую
+	if err := rep.DeleteSubscription(sales, userID, "Netflix", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := rep.GetSubscription(ctx, userID, "Netflix"); got == nil {
+		t.Error("delete must not affect another organization")
+	}
+	services, _ := rep.ListServices(sales)
+	if len(services) != 1 {
+		t.Fatal("organization must see only its catalog", services)
+	}
+	if err := rep.DeleteService(ctx, services[0].ID); !errors.Is(err, dberrors.ServiceNotFound) {
+		t.Error("service of another organization must not be found, got", err)
+	}
+	if found, _ := rep.ResolveService(sales, "NFLX"); found == nil || found.ID != services[0].ID {
+		t.Error("aliases must resolve inside the organization", found)
+	}
+
+	// запись в несуществующую организацию
+	missing := tenant.WithID(ctx, uuid.New())
+	if _, err := rep.CreateSubscription(missing, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Okko", Price: 100, StartDate: "01-2025"}, nil); !errors.Is(err, dberrors.OrganizationNotFound) {
+		t.Error("expected OrganizationNotFound, got", err)
+	}
+	if _, err := rep.CreateService(missing, models.ServiceRequest{Name: "Okko", Currency: "RUB"}); !errors.Is(err, dberrors.OrganizationNotFound) {
+		t.Error("expected OrganizationNotFound for service, got", err)
+	}
+	if list, err := rep.ListAllSubscriptions(missing); err != nil || list != nil {
+		t.Error("unknown organization has no subscriptions", list, err)
+	}
+}
+
+func testUsers(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+
+	// существующий user_id подписок заводится со своим id
+	existing := uuid.New()
+	ivan, err := rep.CreateUser(ctx, models.UserRequest{
+		ID: existing, DisplayName: " Ivan ", Email: " Ivan@Example.com ", Timezone: "Europe/Moscow", Currency: "usd",
+	})
+	if err != nil || ivan.ID != existing || ivan.DisplayName != "Ivan" || ivan.Email != "ivan@example.com" ||
+		ivan.Timezone != "Europe/Moscow" || ivan.Currency != "USD" || ivan.CreatedAt.IsZero() {
+		t.Fatal("create user failed", ivan, err)
+	}
+	anna, err := rep.CreateUser(ctx, models.UserRequest{DisplayName: "Anna"})
+	if err != nil || anna.ID == uuid.Nil || anna.Email != "" || anna.Timezone != "UTC" || anna.Currency != "RUB" {
+		t.Fatal("create user with defaults failed", anna, err)
+	}
+	// пользователи без email не мешают друг другу
+	if _, err := rep.CreateUser(ctx, models.UserRequest{DisplayName: "Boris"}); err != nil {
+		t.Error("users without email must not conflict", err)
+	}
+
+	if _, err := rep.CreateUser(ctx, models.UserRequest{ID: existing, DisplayName: "Copy"}); !errors.Is(err, dberrors.UserAlreadyExist) {
+		t.Error("expected UserAlreadyExist for taken id, got", err)
+	}
+	if _, err := rep.CreateUser(ctx, models.UserRequest{DisplayName: "Copy", Email: "IVAN@example.com"}); !errors.Is(err, dberrors.UserAlreadyExist) {
+		t.Error("expected UserAlreadyExist for taken email, got", err)
+	}
+	if _, err := rep.UpdateUser(ctx, anna.ID, models.UserRequest{DisplayName: "Anna", Email: "ivan@example.com"}); !errors.Is(err, dberrors.UserAlreadyExist) {
+		t.Error("expected UserAlreadyExist on update, got", err)
+	}
+
+	list, err := rep.ListUsers(ctx)
+	if err != nil || len(list) != 3 || list[0].ID != anna.ID || list[1].DisplayName != "Boris" || list[2].ID != existing {
+		t.Error("users must be ordered by display_name", list, err)
+	}
+
+	updated, err := rep.UpdateUser(ctx, existing, models.UserRequest{ID: uuid.New(), DisplayName: "Ivan P.", Currency: "EUR"})
+	if err != nil || updated.ID != existing || updated.DisplayName != "Ivan P." || updated.Email != "" ||
+		updated.Timezone != "UTC" || updated.Currency != "EUR" || !updated.CreatedAt.Equal(ivan.CreatedAt) {
+		t.Error("update must replace the profile and keep id", updated, err)
+	}
+	if got, err := rep.GetUser(ctx, existing); err != nil || got == nil || got.Currency != "EUR" {
+		t.Error("get after update failed", got, err)
+	}
+
+	// пользователи видны только своей организации
+	other, err := rep.CreateOrganization(ctx, models.OrganizationRequest{Name: "Other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inOther := tenant.WithID(ctx, other.ID)
+	if got, err := rep.GetUser(inOther, existing); err != nil || got != nil {
+		t.Error("user must not be visible in another organization", got, err)
+	}
+	if _, err := rep.CreateUser(inOther, models.UserRequest{ID: existing, DisplayName: "Ivan"}); err != nil {
+		t.Error("the same id must be allowed in another organization", err)
+	}
+	if err := rep.DeleteOrganization(inOther, other.ID); !errors.Is(err, dberrors.OrganizationNotEmpty) {
+		t.Error("expected OrganizationNotEmpty for organization with users, got", err)
+	}
+	if _, err := rep.CreateUser(tenant.WithID(ctx, uuid.New()), models.UserRequest{DisplayName: "Lost"}); !errors.Is(err, dberrors.OrganizationNotFound) {
+		t.Error("expected OrganizationNotFound, got", err)
+	}
+
+	if err := rep.DeleteUser(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.DeleteUser(ctx, existing); !errors.Is(err, dberrors.UserNotFound) {
+		t.Error("expected UserNotFound, got", err)
+	}
+	if _, err := rep.UpdateUser(ctx, existing, models.UserRequest{DisplayName: "Ivan"}); !errors.Is(err, dberrors.UserNotFound) {
+		t.Error("expected UserNotFound on update, got", err)
+	}
+	if got, err := rep.GetUser(ctx, existing); err != nil || got != nil {
+		t.Error("deleted user must not be found", got, err)
+	}
+}
+
+func testTrash(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID, member := uuid.New(), uuid.New()
+
+	deleted := mustCreate(t, rep, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025", Tags: []string{"family"},
+		Sharing: &models.Sharing{Split: models.SplitEqual, Members: []models.Member{{UserID: member}}},
+	})
+	if _, err := rep.ChangeStatus(ctx, models.StatusChangeRequest{UserID: userID, ServiceName: "Netflix", Month: "03-2025", Status: models.StatusPaused}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.DeleteSubscription(ctx, userID, "Netflix", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// подписка в корзине не видна в чтениях и подсчетах
+	if list, err := rep.ListUserSubscriptions(ctx, userID, models.ListFilter{}); len(list) != 0 || err != nil {
+		t.Error("deleted subscription must not be listed", list, err)
+	}
+	total, err := rep.CalculateTotal(ctx, models.CalculateTotalRequest{UserID: userID, StartMonth: month("01-2025"), EndMonth: month("01-2025")})
+	if err != nil || total != 0 {
+		t.Error("deleted subscription must not be counted", total, err)
+	}
+	trash, err := rep.ListDeletedSubscriptions(ctx)
+	if err != nil || len(trash) != 1 || trash[0].ID != deleted.ID || trash[0].DeletedAt == nil {
+		t.Fatal("deleted subscription must be in the trash", trash, err)
+	}
+
+	// пока подписка в корзине, имя свободно, но восстановить ее нельзя
+	replacement := mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Netflix", Price: 700, StartDate: "02-2025"})
+	if replacement.ID == deleted.ID {
+		t.Error("new subscription must get a new id")
+	}
+	if _, err := rep.RestoreSubscription(ctx, deleted.ID, nil); !errors.Is(err, dberrors.SubscriptionAlreadyExist) {
+		t.Error("expected SubscriptionAlreadyExist, got", err)
+	}
+	if err := rep.DeleteSubscription(ctx, userID, "Netflix", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := rep.RestoreSubscription(ctx, deleted.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID != deleted.ID || restored.Price != 500 || restored.DeletedAt != nil || restored.Status != models.StatusPaused ||
+		!slices.Equal(restored.Tags, []string{"family"}) || restored.Sharing == nil || len(restored.Sharing.Members) != 1 {
+		t.Error("restored subscription must keep its data", restored)
+	}
+	if got, err := rep.GetSubscription(ctx, userID, "Netflix"); err != nil || got == nil || got.ID != deleted.ID {
+		t.Error("restored subscription must be readable", got, err)
+	}
+	if _, err := rep.RestoreSubscription(ctx, deleted.ID, nil); !errors.Is(err, dberrors.SubscriptionNotFound) {
+		t.Error("expected SubscriptionNotFound for a restored subscription, got", err)
+	}
+	if _, err := rep.RestoreSubscription(ctx, uuid.New(), nil); !errors.Is(err, dberrors.SubscriptionNotFound) {
+		t.Error("expected SubscriptionNotFound, got", err)
+	}
+
+	if purged, err := rep.PurgeDeletedSubscriptions(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Error("recently deleted subscriptions must stay in the trash", purged, err)
+	}
+	if purged, err := rep.PurgeDeletedSubscriptions(ctx, time.Now().Add(time.Minute)); err != nil || purged != 1 {
+		t.Error("expected one purged subscription", purged, err)
+	}
+	if trash, err := rep.ListDeletedSubscriptions(ctx); err != nil || len(trash) != 0 {
+		t.Error("trash must be empty after purge", trash, err)
+	}
+}
+
+func testAudit(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID, subscriptionID, other := uuid.New(), uuid.New(), uuid.New()
+
+	entries := []models.AuditEntry{
+		{SubscriptionID: subscriptionID, UserID: userID, ServiceName: "Netflix", Operation: models.AuditCreate, Actor: "ivan",
+			RequestID: "req-1", After: []byte(`{"price": 500}`)},
+		{SubscriptionID: subscriptionID, UserID: userID, ServiceName: "Netflix", Operation: models.AuditUpdate, Actor: "anna",
+			Before: []byte(`{"price": 500}`), After: []byte(`{"price": 650}`)},
+		{SubscriptionID: other, UserID: uuid.New(), ServiceName: "Okko", Operation: models.AuditDelete, Actor: "ivan",
+			Before: []byte(`{"price": 300}`)},
+	}
+	started := time.Now().Add(-time.Second)
+	for _, entry := range entries {
+		if err := rep.RecordAudit(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := rep.ListAudit(ctx, models.AuditFilter{})
+	if err != nil || len(all) != 3 {
+		t.Fatal("expected 3 entries", all, err)
+	}
+	if all[0].ServiceName != "Okko" || all[2].Operation != models.AuditCreate {
+		t.Error("entries must be sorted newest first", all)
+	}
+	first := all[2]
+	if first.ID == uuid.Nil || first.CreatedAt.Before(started) || first.Actor != "ivan" || first.RequestID != "req-1" ||
+		first.Before != nil || !jsonEqual(first.After, `{"price": 500}`) {
+		t.Error("entry is not stored as recorded", first)
+	}
+	if !jsonEqual(all[1].Before, `{"price": 500}`) || !jsonEqual(all[1].After, `{"price": 650}`) || all[0].After != nil {
+		t.Error("snapshots are not stored as recorded", all)
+	}
+
+	cases := []struct {
+		name     string
+		filter   models.AuditFilter
+		expected int
+	}{
+		{"subscription", models.AuditFilter{SubscriptionID: subscriptionID}, 2},
+		{"user", models.AuditFilter{UserID: userID}, 2},
+		{"actor", models.AuditFilter{Actor: "ivan"}, 2},
+		{"operation", models.AuditFilter{Operation: models.AuditUpdate}, 1},
+		{"subscription and operation", models.AuditFilter{SubscriptionID: subscriptionID, Operation: models.AuditDelete}, 0},
+		{"from", models.AuditFilter{From: started}, 3},
+		{"from in the future", models.AuditFilter{From: time.Now().Add(time.Hour)}, 0},
+		{"to is exclusive", models.AuditFilter{To: first.CreatedAt}, 0},
+		{"limit", models.AuditFilter{Limit: 2}, 2},
+	}
+	for _, c := range cases {
+		list, err := rep.ListAudit(ctx, c.filter)
+		if err != nil || len(list) != c.expected {
+			t.Errorf("%s: expected %d entries, got %d (%v)", c.name, c.expected, len(list), err)
+		}
+	}
+	if list, _ := rep.ListAudit(ctx, models.AuditFilter{Limit: 1}); len(list) != 1 || list[0].ID != all[0].ID {
+		t.Error("limit must keep the newest entries", list)
+	}
+
+	// журнал принадлежит организации
+	organization, err := rep.CreateOrganization(ctx, models.OrganizationRequest{Name: "Audit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inOther := tenant.WithID(ctx, organization.ID)
+	if list, err := rep.ListAudit(inOther, models.AuditFilter{}); err != nil || len(list) != 0 {
+		t.Error("audit log must not be visible in another organization", list, err)
+	}
+	if err := rep.RecordAudit(inOther, entries[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.DeleteOrganization(inOther, organization.ID); err != nil {
+		t.Error("audit log must not block organization delete", err)
+	}
+}
+
+func testAuditWithChange(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+	audit := func(operation string) *models.AuditEntry {
+		return &models.AuditEntry{Operation: operation, Actor: "ivan", RequestID: "req-" + operation}
+	}
+
+	created, err := rep.CreateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025",
+	}, audit(models.AuditCreate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 650, StartDate: "01-2025",
+	}, audit(models.AuditUpdate)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rep.ChangeStatus(ctx, models.StatusChangeRequest{
+		UserID: userID, ServiceName: "Netflix", Month: "03-2025", Status: models.StatusPaused,
+	}, audit(models.AuditStatusChange)); err != nil {
+		t.Fatal(err)
+	}
+	// неудачное изменение не попадает в журнал
+	if err := rep.DeleteSubscription(ctx, userID, "Netflix", 1, audit(models.AuditDelete)); !errors.Is(err, dberrors.SubscriptionVersionMismatch) {
+		t.Fatal("expected SubscriptionVersionMismatch, got", err)
+	}
+	if err := rep.DeleteSubscription(ctx, userID, "Netflix", 0, audit(models.AuditDelete)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rep.RestoreSubscription(ctx, created.ID, audit(models.AuditRestore)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := rep.ListAudit(ctx, models.AuditFilter{SubscriptionID: created.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	operations := []string{models.AuditRestore, models.AuditDelete, models.AuditStatusChange, models.AuditUpdate, models.AuditCreate}
+	if len(entries) != len(operations) {
+		t.Fatal("expected an entry per successful change", entries)
+	}
+	for i, entry := range entries {
+		if entry.Operation != operations[i] || entry.UserID != userID || entry.ServiceName != "Netflix" ||
+			entry.Actor != "ivan" || entry.RequestID != "req-"+operations[i] {
+			t.Errorf("entry %d is wrong: %+v", i, entry)
+		}
+	}
+
+	var before, after models.Subscription
+	snapshots := func(entry models.AuditEntry) {
+		before, after = models.Subscription{}, models.Subscription{}
+		if entry.Before != nil {
+			_ = json.Unmarshal(entry.Before, &before)
+		}
+		if entry.After != nil {
+			_ = json.Unmarshal(entry.After, &after)
+		}
+	}
+	snapshots(entries[4])
+	if entries[4].Before != nil || after.Price != 500 || after.Version != 1 {
+		t.Error("create must have only the created subscription", entries[4])
+	}
+	snapshots(entries[3])
+	if before.Price != 500 || after.Price != 650 || after.Version != 2 {
+		t.Error("update must have the subscription before and after it", entries[3])
+	}
+	snapshots(entries[2])
+	if before.Status != models.StatusActive || len(before.Pauses) != 0 || after.Status != models.StatusPaused || len(after.Pauses) != 1 {
+		t.Error("status change must have the subscription before and after it", entries[2])
+	}
+	snapshots(entries[1])
+	if entries[1].After != nil || before.Status != models.StatusPaused || before.Version != 3 {
+		t.Error("delete must have only the deleted subscription", entries[1])
+	}
+	snapshots(entries[0])
+	if entries[0].Before != nil || after.ID != created.ID || after.Version != 5 {
+		t.Error("restore must have only the restored subscription", entries[0])
+	}
+}
+
+func testVersion(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	created := mustCreate(t, rep, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Netflix", Price: 500, StartDate: "01-2025"})
+	if created.Version != 1 {
+		t.Fatal("new subscription must have version 1", created.Version)
+	}
+
+	updated, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 600, StartDate: "01-2025", Version: 1,
+	}, nil)
+	if err != nil || updated.Version != 2 {
+		t.Fatal("update with current version must succeed and bump it", updated, err)
+	}
+	// устаревшая версия: изменение не выполняется
+	_, err = rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Netflix", Price: 700, StartDate: "01-2025", Version: 1,
+	}, nil)
+	if !errors.Is(err, dberrors.SubscriptionVersionMismatch) {
+		t.Error("update with stale version must fail", err)
+	}
+	if got, _ := rep.GetSubscription(ctx, userID, "Netflix"); got == nil || got.Price != 600 || got.Version != 2 {
+		t.Error("stale update must not change the subscription", got)
+	}
+	_, err = rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Okko", Price: 700, StartDate: "01-2025", Version: 1,
+	}, nil)
+	if !errors.Is(err, dberrors.SubscriptionNotFound) {
+		t.Error("versioned update of a missing subscription must be not found", err)
+	}
+
+	status := models.StatusChangeRequest{UserID: userID, ServiceName: "Netflix", Month: "02-2025", Status: models.StatusPaused, Version: 1}
+	if _, err := rep.ChangeStatus(ctx, status, nil); !errors.Is(err, dberrors.SubscriptionVersionMismatch) {
+		t.Error("status change with stale version must fail", err)
+	}
+	status.Version = 2
+	paused, err := rep.ChangeStatus(ctx, status, nil)
+	if err != nil || paused.Version != 3 {
+		t.Fatal("status change must bump version", paused, err)
+	}
+
+	if err := rep.DeleteSubscription(ctx, userID, "Netflix", 2, nil); !errors.Is(err, dberrors.SubscriptionVersionMismatch) {
+		t.Error("delete with stale version must fail", err)
+	}
+	if err := rep.DeleteSubscription(ctx, userID, "Netflix", 3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.DeleteSubscription(ctx, userID, "Netflix", 4, nil); !errors.Is(err, dberrors.SubscriptionNotFound) {
+		t.Error("versioned delete of a deleted subscription must be not found", err)
+	}
+	restored, err := rep.RestoreSubscription(ctx, created.ID, nil)
+	if err != nil || restored.Version != 5 {
+		t.Fatal("delete and restore must bump version", restored, err)
+	}
+
+	// переименование сервиса каталога меняет подписки, их версия растет
+	service, err := rep.CreateService(ctx, models.ServiceRequest{Name: "Netflix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	linked, err := rep.UpdateSubscription(ctx, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Netflix", Price: 600, StartDate: "01-2025"}, nil)
+	if err != nil || linked.ServiceID == nil {
+		t.Fatal("subscription must be linked to the catalog", linked, err)
+	}
+	if _, err := rep.UpdateService(ctx, service.ID, models.ServiceRequest{Name: "Netflix Premium"}); err != nil {
+		t.Fatal(err)
+	}
+	renamed, err := rep.GetSubscription(ctx, userID, "Netflix Premium")
+	if err != nil || renamed == nil || renamed.Version != linked.Version+1 {
+		t.Error("service rename must bump subscription version", renamed, err)
+	}
+}
+
+func testIdempotency(t *testing.T, rep repository.Repository) {
+	ctx := context.Background()
+	record := models.IdempotencyRecord{Key: "retry-1", RequestHash: "hash-1", ExpiresAt: time.Now().Add(time.Hour)}
+
+	if existing, err := rep.ReserveIdempotencyKey(ctx, record); err != nil || existing != nil {
+		t.Fatal("free key must be reserved", existing, err)
+	}
+	// повтор до завершения видит незавершенную запись
+	existing, err := rep.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{Key: "retry-1", RequestHash: "hash-2", ExpiresAt: record.ExpiresAt})
+	if err != nil || existing == nil || existing.Completed || existing.RequestHash != "hash-1" {
+		t.Fatal("reserved key must be returned as in progress with the original hash", existing, err)
+	}
+
+	completed := record
+	completed.Status, completed.Header, completed.Body = 201, map[string]string{"Content-Type": "application/json"}, []byte(`{"id":1}`)
+	if err := rep.CompleteIdempotencyKey(ctx, completed); err != nil {
+		t.Fatal(err)
+	}
+	existing, err = rep.ReserveIdempotencyKey(ctx, record)
+	if err != nil || existing == nil || !existing.Completed || existing.Status != 201 ||
+		existing.Header["Content-Type"] != "application/json" || string(existing.Body) != `{"id":1}` {
+		t.Fatal("completed key must return the stored response", existing, err)
+	}
+
+	// освобожденный ключ можно занять снова
+	if err := rep.ReleaseIdempotencyKey(ctx, "retry-1"); err != nil {
+		t.Fatal(err)
+	}
+	if existing, err := rep.ReserveIdempotencyKey(ctx, record); err != nil || existing != nil {
+		t.Error("released key must be reserved again", existing, err)
+	}
+
+	// истекшая запись не мешает занять ключ и удаляется очисткой
+	expired := models.IdempotencyRecord{Key: "retry-2", RequestHash: "hash-1", ExpiresAt: time.Now().Add(-time.Minute)}
+	if existing, err := rep.ReserveIdempotencyKey(ctx, expired); err != nil || existing != nil {
+		t.Fatal(existing, err)
+	}
+	renewed := models.IdempotencyRecord{Key: "retry-2", RequestHash: "hash-2", ExpiresAt: time.Now().Add(-time.Second)}
+	if existing, err := rep.ReserveIdempotencyKey(ctx, renewed); err != nil || existing != nil {
+		t.Error("expired key must be reserved again", existing, err)
+	}
+	if purged, err := rep.PurgeIdempotencyKeys(ctx, time.Now()); err != nil || purged != 1 {
+		t.Error("purge must delete only expired keys, got", purged, err)
+	}
+	if existing, err := rep.ReserveIdempotencyKey(ctx, record); err != nil || existing == nil {
+		t.Error("purge must keep live keys", existing, err)
+	}
+
+	// ключи организаций не пересекаются
+	organization, err := rep.CreateOrganization(ctx, models.OrganizationRequest{Name: "Sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing, err := rep.ReserveIdempotencyKey(tenant.WithID(ctx, organization.ID), record); err != nil || existing != nil {
+		t.Error("key of another organization must be free", existing, err)
+	}
+}
+
+// jsonEqual - JSON равен ожидаемому без учета форматирования: PostgreSQL хранит JSONB в своем виде
+func jsonEqual(raw []byte, expected string) bool {
+	var got, want any
+	if json.Unmarshal(raw, &got) != nil || json.Unmarshal([]byte(expected), &want) != nil {
+		return false
+	}
+	return reflect.DeepEqual(got, want)
+}
@@ -0,0 +1,16 @@
+package consistency
+
+import "context"
+
+type primaryKey struct{}
+
+// WithPrimary - контекст, в котором чтения идут в основную БД, а не в реплики ("read your writes")
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// PrimaryRequired - нужно ли читать из основной БД
+func PrimaryRequired(ctx context.Context) bool {
+	required, _ := ctx.Value(primaryKey{}).(bool)
+	return required
+}
@@ -0,0 +1,23 @@
+package dberrors
+
+import "errors"
+
+// Ошибки репозитория, общие для всех хранилищ. Хендлеры и сервис проверяют их через errors.Is,
+// поэтому каждая реализация repository.Repository должна возвращать именно их
+
+var SubscriptionAlreadyExist = errors.New("subscription already exists")
+var SubscriptionNotFound = errors.New("subscription not found")
+var SubscriptionDateError = errors.New("subscription date error")
+var SubscriptionStatusError = errors.New("subscription status transition is not allowed")
+var SubscriptionVersionMismatch = errors.New("subscription has been modified")
+
+var ServiceAlreadyExist = errors.New("service name or alias is already taken")
+var ServiceNotFound = errors.New("service not found")
+
+var OrganizationAlreadyExist = errors.New("organization name is already taken")
+var OrganizationNotFound = errors.New("organization not found")
+var OrganizationNotEmpty = errors.New("organization has subscriptions, services or users")
+var MemberNotFound = errors.New("organization member not found")
+
+var UserAlreadyExist = errors.New("user id or email is already taken")
+var UserNotFound = errors.New("user not found")
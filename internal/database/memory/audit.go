@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecordAudit - добавить запись в журнал аудита организации
+func (r *Repository) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, err := r.writable(ctx)
+	if err != nil {
+		return err
+	}
+	entry.ID, entry.CreatedAt = id, time.Now()
+	entry.Before, entry.After = bytes.Clone(entry.Before), bytes.Clone(entry.After)
+	d.record(&entry)
+	return nil
+}
+
+// newAuditEntry - запись audit об изменении подписки before -> after с id и временем, nil - без записи.
+// Создается до изменения данных, чтобы ошибка не оставила изменение без записи
+func newAuditEntry(audit *models.AuditEntry, before, after *models.Subscription) (*models.AuditEntry, error) {
+	if audit == nil {
+		return nil, nil
+	}
+	entry, err := audit.Capture(before, after)
+	if err != nil {
+		return nil, err
+	}
+	if entry.ID, err = uuid.NewV7(); err != nil {
+		return nil, err
+	}
+	entry.CreatedAt = time.Now()
+	return &entry, nil
+}
+
+// record - добавить запись в журнал, nil - без записи. Вызывается под r.mu
+func (d *data) record(entry *models.AuditEntry) {
+	if entry != nil {
+		d.audit = append(d.audit, *entry)
+	}
+}
+
+// ListAudit - записи журнала по фильтру, сначала новые
+func (r *Repository) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var entries []models.AuditEntry
+	for _, entry := range r.data(ctx).audit {
+		if auditMatches(entry, filter) {
+			entries = append(entries, entry)
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(entries, func(a, b models.AuditEntry) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(b.ID[:], a.ID[:])
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// auditMatches - запись подходит под фильтр
+func auditMatches(entry models.AuditEntry, filter models.AuditFilter) bool {
+	switch {
+	case filter.SubscriptionID != uuid.Nil && entry.SubscriptionID != filter.SubscriptionID:
+		return false
+	case filter.UserID != uuid.Nil && entry.UserID != filter.UserID:
+		return false
+	case filter.Actor != "" && entry.Actor != filter.Actor:
+		return false
+	case filter.Operation != "" && entry.Operation != filter.Operation:
+		return false
+	case !filter.From.IsZero() && entry.CreatedAt.Before(filter.From):
+		return false
+	case !filter.To.IsZero() && !entry.CreatedAt.Before(filter.To):
+		return false
+	}
+	return true
+}
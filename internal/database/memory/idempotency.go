@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"maps"
+	"time"
+)
+
+// ReserveIdempotencyKey - занять ключ незавершенной записью. Действующая запись ключа возвращается без изменений
+func (r *Repository) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, err := r.writable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := d.idempotency[record.Key]; ok && existing.ExpiresAt.After(time.Now()) {
+		return cloneIdempotency(existing), nil
+	}
+	d.idempotency[record.Key] = models.IdempotencyRecord{
+		Key: record.Key, RequestHash: record.RequestHash, ExpiresAt: record.ExpiresAt,
+	}
+	return nil, nil
+}
+
+// CompleteIdempotencyKey - сохранить ответ запроса с ключом
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	stored, ok := d.idempotency[record.Key]
+	if !ok {
+		return nil
+	}
+	stored.Completed, stored.Status = true, record.Status
+	stored.Header, stored.Body = maps.Clone(record.Header), bytes.Clone(record.Body)
+	d.idempotency[record.Key] = stored
+	return nil
+}
+
+// ReleaseIdempotencyKey - удалить запись ключа
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.data(ctx).idempotency, key)
+	return nil
+}
+
+// PurgeIdempotencyKeys - удалить записи организации, истекшие раньше before
+func (r *Repository) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	purged := 0
+	for key, record := range d.idempotency {
+		if record.ExpiresAt.Before(before) {
+			delete(d.idempotency, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// cloneIdempotency - копия записи, не разделяющая заголовки и тело с хранилищем
+func cloneIdempotency(record models.IdempotencyRecord) *models.IdempotencyRecord {
+	record.Header, record.Body = maps.Clone(record.Header), bytes.Clone(record.Body)
+	return &record
+}
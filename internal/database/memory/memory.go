@@ -0,0 +1,859 @@
+package memory
+
+import (
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/internal/metadata"
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Ограничения схемы PostgreSQL: service_name VARCHAR(100), category и tag VARCHAR(50)
+const (
+	maxServiceNameLength = 100
+	maxLabelLength       = 50
+)
+
+// Ошибки ограничений схемы, в PostgreSQL их возвращает сама БД
+var (
+	errPriceCheck         = errors.New(`new row for relation "subscriptions" violates check constraint "subscriptions_price_check"`)
+	errServiceNameTooLong = errors.New("value too long for type character varying(100)")
+	errLabelTooLong       = errors.New("value too long for type character varying(50)")
+	errTrialEndCheck      = errors.New(`new row for relation "subscriptions" violates check constraint "subscriptions_trial_end_check"`)
+	errPromoCheck         = errors.New(`new row for relation "subscriptions" violates check constraint "subscriptions_promo_check"`)
+	errDefaultPriceCheck  = errors.New(`new row for relation "services" violates check constraint "services_default_price_check"`)
+	errSplitCheck         = errors.New(`new row for relation "subscriptions" violates check constraint "subscriptions_split_check"`)
+	errPercentCheck       = errors.New(`new row for relation "subscription_members" violates check constraint "subscription_members_percent_check"`)
+	errMemberDuplicate    = errors.New(`duplicate key value violates unique constraint "subscription_members_pkey"`)
+)
+
+// subscriptionKey - уникальный ключ неудаленной подписки, как индекс unique_user_service
+type subscriptionKey struct {
+	userID      uuid.UUID
+	serviceName string
+}
+
+// Repository - хранилище подписок в памяти процесса с той же семантикой, что и PostgreSQL:
+// уникальность (user_id, service_name), ошибки dberrors, сортировка списков и пересечение периодов.
+// Подписки и каталог хранятся отдельно для каждой организации, как строки с tenant_id
+type Repository struct {
+	mu            sync.RWMutex
+	organizations map[uuid.UUID]models.Organization
+	members       map[uuid.UUID]map[uuid.UUID]time.Time // организация -> пользователь -> joined_at
+	tenants       map[uuid.UUID]*data
+}
+
+// data - подписки, каталог сервисов и пользователи одной организации
+type data struct {
+	subscriptions map[subscriptionKey]models.Subscription
+	trash         map[uuid.UUID]models.Subscription // удаленные подписки по id, как строки с deleted_at
+	services      map[uuid.UUID]models.Service
+	aliases       map[string]uuid.UUID // catalog.Key имени или алиаса -> сервис, как таблица service_aliases
+	users         map[uuid.UUID]models.User
+	audit         []models.AuditEntry // журнал аудита в порядке записи
+	idempotency   map[string]models.IdempotencyRecord
+}
+
+// NewRepository - пустое хранилище с организацией по умолчанию, как после миграций
+func NewRepository() *Repository {
+	now := time.Now()
+	return &Repository{
+		organizations: map[uuid.UUID]models.Organization{
+			tenant.Default: {ID: tenant.Default, Name: "default", CreatedAt: now, UpdatedAt: now},
+		},
+		members: make(map[uuid.UUID]map[uuid.UUID]time.Time),
+		tenants: make(map[uuid.UUID]*data),
+	}
+}
+
+// data - данные организации запроса, у организации без данных - пустые
+func (r *Repository) data(ctx context.Context) *data {
+	if d, ok := r.tenants[tenant.ID(ctx)]; ok {
+		return d
+	}
+	return &data{}
+}
+
+// writable - данные организации запроса для записи, организации нет - dberrors.OrganizationNotFound,
+// как внешний ключ tenant_id
+func (r *Repository) writable(ctx context.Context) (*data, error) {
+	id := tenant.ID(ctx)
+	if _, ok := r.organizations[id]; !ok {
+		return nil, dberrors.OrganizationNotFound
+	}
+	d, ok := r.tenants[id]
+	if !ok {
+		d = &data{
+			subscriptions: make(map[subscriptionKey]models.Subscription),
+			trash:         make(map[uuid.UUID]models.Subscription),
+			services:      make(map[uuid.UUID]models.Service),
+			aliases:       make(map[string]uuid.UUID),
+			users:         make(map[uuid.UUID]models.User),
+			idempotency:   make(map[string]models.IdempotencyRecord),
+		}
+		r.tenants[id] = d
+	}
+	return d, nil
+}
+
+// addMembers - владелец и участники подписки становятся участниками ее организации
+func (r *Repository) addMembers(sub models.Subscription, now time.Time) {
+	members, ok := r.members[sub.TenantID]
+	if !ok {
+		members = make(map[uuid.UUID]time.Time)
+		r.members[sub.TenantID] = members
+	}
+	for _, userID := range lifecycle.Users(&sub) {
+		if _, ok := members[userID]; !ok {
+			members[userID] = now
+		}
+	}
+}
+
+// CreateSubscription - создать подписку
+func (r *Repository) CreateSubscription(ctx context.Context, req models.CreateOrUpdateRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sub, err := newSubscription(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, err := r.writable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := subscriptionKey{userID: req.UserID, serviceName: req.ServiceName}
+	if _, ok := d.subscriptions[key]; ok {
+		return nil, dberrors.SubscriptionAlreadyExist
+	}
+
+	now := time.Now()
+	sub.ID = uuid.New()
+	sub.TenantID = tenant.ID(ctx)
+	sub.ServiceID = d.serviceID(sub.ServiceName)
+	sub.Status = models.StatusActive
+	sub.StatusChangedAt = now
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+	sub.Version = 1
+
+	created := clone(sub)
+	lifecycle.Resolve(created, now)
+	entry, err := newAuditEntry(audit, nil, created)
+	if err != nil {
+		return nil, err
+	}
+	d.subscriptions[key] = sub
+	r.addMembers(sub, now)
+	d.record(entry)
+	return created, nil
+}
+
+// UpdateSubscription - обновить цену и период подписки. Статус и паузы сохраняются, updated_at - время обновления,
+// версия растет на 1
+func (r *Repository) UpdateSubscription(ctx context.Context, req models.CreateOrUpdateRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sub, err := newSubscription(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	key := subscriptionKey{userID: req.UserID, serviceName: req.ServiceName}
+	old, ok := d.subscriptions[key]
+	if !ok {
+		return nil, dberrors.SubscriptionNotFound
+	}
+	if req.Version != 0 && req.Version != old.Version {
+		return nil, dberrors.SubscriptionVersionMismatch
+	}
+
+	sub.ID, sub.TenantID = old.ID, old.TenantID
+	sub.ServiceID = d.serviceID(sub.ServiceName)
+	sub.Status, sub.StatusChangedAt, sub.CancelledAt, sub.Pauses = old.Status, old.StatusChangedAt, old.CancelledAt, old.Pauses
+	now := time.Now()
+	sub.CreatedAt = old.CreatedAt
+	sub.UpdatedAt = now
+	sub.Version = old.Version + 1
+
+	before, updated := clone(old), clone(sub)
+	lifecycle.Resolve(before, now)
+	lifecycle.Resolve(updated, now)
+	entry, err := newAuditEntry(audit, before, updated)
+	if err != nil {
+		return nil, err
+	}
+	d.subscriptions[key] = sub
+	r.addMembers(sub, now)
+	d.record(entry)
+	return updated, nil
+}
+
+// GetSubscription - получение подписки у пользователя, nil - подписки нет
+func (r *Repository) GetSubscription(ctx context.Context, userID uuid.UUID, serviceName string) (*models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.data(ctx).subscriptions[subscriptionKey{userID: userID, serviceName: serviceName}]
+	if !ok {
+		return nil, nil
+	}
+	found := clone(sub)
+	lifecycle.Resolve(found, time.Now())
+	return found, nil
+}
+
+// DeleteSubscription - перенос подписки пользователя в корзину
+func (r *Repository) DeleteSubscription(ctx context.Context, userID uuid.UUID, serviceName string, version int, audit *models.AuditEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	key := subscriptionKey{userID: userID, serviceName: serviceName}
+	sub, ok := d.subscriptions[key]
+	if !ok {
+		return dberrors.SubscriptionNotFound
+	}
+	if version != 0 && version != sub.Version {
+		return dberrors.SubscriptionVersionMismatch
+	}
+	deletedAt := time.Now()
+	before := clone(sub)
+	lifecycle.Resolve(before, deletedAt)
+	entry, err := newAuditEntry(audit, before, nil)
+	if err != nil {
+		return err
+	}
+	sub.DeletedAt = &deletedAt
+	sub.Version++
+	delete(d.subscriptions, key)
+	d.trash[sub.ID] = sub
+	d.record(entry)
+	return nil
+}
+
+// ListUserSubscriptions - подписки пользователя и совместные, где он участник, отсортированные по service_name и user_id
+func (r *Repository) ListUserSubscriptions(ctx context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Subscription, error) {
+	subscriptions, err := r.list(ctx, func(sub models.Subscription) bool {
+		return belongs(sub, userID) && (filter.Status == "" || sub.Status == filter.Status) && hasTags(sub, filter.Tags) &&
+			metadata.Matches(sub.Metadata, filter.Metadata)
+	})
+	slices.SortStableFunc(subscriptions, func(a, b models.Subscription) int {
+		return strings.Compare(a.ServiceName, b.ServiceName)
+	})
+	return subscriptions, err
+}
+
+// ListAllSubscriptions - все подписки, отсортированные по user_id и service_name
+func (r *Repository) ListAllSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	return r.list(ctx, func(models.Subscription) bool { return true })
+}
+
+// CalculateTotal - сумма цен подписок, у которых в [StartMonth, EndMonth] есть оплачиваемый месяц,
+// по цене первого такого месяца
+func (r *Repository) CalculateTotal(ctx context.Context, req models.CalculateTotalRequest) (int, error) {
+	subscriptions, err := r.totalSubscriptions(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	return lifecycle.Total(subscriptions, req.UserID, req.StartMonth, req.EndMonth), nil
+}
+
+// TotalByCategory - CalculateTotal по категориям
+func (r *Repository) TotalByCategory(ctx context.Context, req models.CalculateTotalRequest) ([]models.CategoryTotal, error) {
+	subscriptions, err := r.totalSubscriptions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return lifecycle.CategoryTotals(lifecycle.ByCategory(subscriptions, req.UserID, req.StartMonth, req.EndMonth)), nil
+}
+
+// ChangeStatus - пауза, возобновление или отмена подписки
+func (r *Repository) ChangeStatus(ctx context.Context, req models.StatusChangeRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	month, err := lifecycle.ParseMonth(req.Month, now)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	key := subscriptionKey{userID: req.UserID, serviceName: req.ServiceName}
+	old, ok := d.subscriptions[key]
+	if !ok {
+		return nil, dberrors.SubscriptionNotFound
+	}
+	if req.Version != 0 && req.Version != old.Version {
+		return nil, dberrors.SubscriptionVersionMismatch
+	}
+
+	sub := *clone(old)
+	if err := lifecycle.Apply(&sub, req.Status, month, now); err != nil {
+		return nil, err
+	}
+	sub.Version++
+
+	before, changed := clone(old), clone(sub)
+	lifecycle.Resolve(before, now)
+	lifecycle.Resolve(changed, now)
+	entry, err := newAuditEntry(audit, before, changed)
+	if err != nil {
+		return nil, err
+	}
+	d.subscriptions[key] = sub
+	d.record(entry)
+	return changed, nil
+}
+
+// EndingTrials - подписки, у которых в ближайшие req.Days дней заканчивается пробный период
+// и начинается оплата, в порядке trial_end, user_id, service_name
+func (r *Repository) EndingTrials(ctx context.Context, req models.TrialsRequest) ([]models.Subscription, error) {
+	from, before := lifecycle.TrialWindow(time.Now(), req.Days)
+	subscriptions, err := r.list(ctx, func(sub models.Subscription) bool {
+		return (req.UserID == uuid.Nil || sub.UserID == req.UserID) && endingTrial(sub, from, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(subscriptions, func(a, b models.Subscription) int {
+		return a.TrialEnd.Compare(*b.TrialEnd)
+	})
+	return subscriptions, nil
+}
+
+// ListServices - каталог сервисов, отсортированный по name
+func (r *Repository) ListServices(ctx context.Context) ([]models.Service, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var services []models.Service
+	for _, service := range r.data(ctx).services {
+		services = append(services, *cloneService(service))
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(services, func(a, b models.Service) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return services, nil
+}
+
+// GetService - запись каталога, nil - записи нет
+func (r *Repository) GetService(ctx context.Context, id uuid.UUID) (*models.Service, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	service, ok := r.data(ctx).services[id]
+	if !ok {
+		return nil, nil
+	}
+	return cloneService(service), nil
+}
+
+// CreateService - новая запись каталога
+func (r *Repository) CreateService(ctx context.Context, req models.ServiceRequest) (*models.Service, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	req = catalog.Normalize(req)
+	if err := checkService(req); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, err := r.writable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id := uuid.New()
+	if err := d.setKeys(id, req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	service := newService(id, req)
+	service.CreatedAt, service.UpdatedAt = now, now
+	d.services[id] = service
+
+	// подписки, созданные до появления сервиса в каталоге, остаются без ссылки, как и в PostgreSQL
+	return cloneService(service), nil
+}
+
+// UpdateService - заменить запись каталога, при переименовании меняется service_name подписок сервиса
+func (r *Repository) UpdateService(ctx context.Context, id uuid.UUID, req models.ServiceRequest) (*models.Service, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	req = catalog.Normalize(req)
+	if err := checkService(req); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	old, ok := d.services[id]
+	if !ok {
+		return nil, dberrors.ServiceNotFound
+	}
+
+	// подписки сервиса получают новое имя, совпадение с другой подпиской пользователя - как нарушение unique_user_service
+	linked := func(sub models.Subscription) bool { return sub.ServiceID != nil && *sub.ServiceID == id }
+	renamed := make(map[subscriptionKey]models.Subscription)
+	if req.Name != old.Name {
+		for key, sub := range d.subscriptions {
+			if !linked(sub) {
+				continue
+			}
+			sub.ServiceName = req.Name
+			sub.Version++
+			newKey := subscriptionKey{userID: key.userID, serviceName: req.Name}
+			if other, taken := d.subscriptions[newKey]; taken && !linked(other) {
+				return nil, dberrors.ServiceAlreadyExist
+			}
+			if _, taken := renamed[newKey]; taken {
+				return nil, dberrors.ServiceAlreadyExist
+			}
+			renamed[newKey] = sub
+		}
+	}
+	if err := d.setKeys(id, req); err != nil {
+		return nil, err
+	}
+
+	for key, sub := range d.subscriptions {
+		if linked(sub) && req.Name != old.Name {
+			delete(d.subscriptions, key)
+		}
+	}
+	for key, sub := range renamed {
+		d.subscriptions[key] = sub
+	}
+	// удаленные подписки переименовываются вместе с остальными, уникальность среди них не проверяется
+	for id, sub := range d.trash {
+		if linked(sub) && sub.ServiceName != req.Name {
+			sub.ServiceName = req.Name
+			sub.Version++
+			d.trash[id] = sub
+		}
+	}
+
+	service := newService(id, req)
+	service.CreatedAt, service.UpdatedAt = old.CreatedAt, time.Now()
+	d.services[id] = service
+	return cloneService(service), nil
+}
+
+// DeleteService - удалить запись каталога, подписки сервиса остаются без ссылки
+func (r *Repository) DeleteService(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	if _, ok := d.services[id]; !ok {
+		return dberrors.ServiceNotFound
+	}
+	delete(d.services, id)
+	for key, serviceID := range d.aliases {
+		if serviceID == id {
+			delete(d.aliases, key)
+		}
+	}
+	for key, sub := range d.subscriptions {
+		if sub.ServiceID != nil && *sub.ServiceID == id {
+			sub.ServiceID = nil
+			sub.Version++
+			d.subscriptions[key] = sub
+		}
+	}
+	for trashID, sub := range d.trash {
+		if sub.ServiceID != nil && *sub.ServiceID == id {
+			sub.ServiceID = nil
+			sub.Version++
+			d.trash[trashID] = sub
+		}
+	}
+	return nil
+}
+
+// ResolveService - сервис по имени или алиасу без учета регистра, nil - не найден
+func (r *Repository) ResolveService(ctx context.Context, name string) (*models.Service, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d := r.data(ctx)
+	id, ok := d.aliases[catalog.Key(name)]
+	if !ok {
+		return nil, nil
+	}
+	return cloneService(d.services[id]), nil
+}
+
+// CloseConnection - в памяти закрывать нечего
+func (r *Repository) CloseConnection() {}
+
+// totalSubscriptions - подписки, подходящие под фильтры подсчета
+func (r *Repository) totalSubscriptions(ctx context.Context, req models.CalculateTotalRequest) ([]models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if req.StartMonth.After(req.EndMonth) {
+		return nil, dberrors.SubscriptionDateError
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d := r.data(ctx)
+	var subscriptions []models.Subscription
+	for _, sub := range d.subscriptions {
+		if req.UserID != uuid.Nil && !belongs(sub, req.UserID) {
+			continue
+		}
+		if req.ServiceName != "" && sub.ServiceName != req.ServiceName {
+			continue
+		}
+		if !hasTags(sub, req.Tags) {
+			continue
+		}
+		if req.Currency != "" && d.currency(sub.ServiceName) != req.Currency {
+			continue
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	return subscriptions, nil
+}
+
+// list - подписки, подходящие под filter, в порядке ORDER BY user_id, service_name.
+// Пустой результат - nil, как у PostgreSQL-репозитория
+func (r *Repository) list(ctx context.Context, filter func(models.Subscription) bool) ([]models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var subscriptions []models.Subscription
+	for _, sub := range r.data(ctx).subscriptions {
+		sub := clone(sub)
+		lifecycle.Resolve(sub, time.Now())
+		if filter(*sub) {
+			subscriptions = append(subscriptions, *sub)
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(subscriptions, func(a, b models.Subscription) int {
+		if c := bytes.Compare(a.UserID[:], b.UserID[:]); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ServiceName, b.ServiceName)
+	})
+	return subscriptions, nil
+}
+
+// newSubscription - подписка из запроса с теми же проверками, что делает схема PostgreSQL
+func newSubscription(req models.CreateOrUpdateRequest) (models.Subscription, error) {
+	if req.Price <= 0 {
+		return models.Subscription{}, errPriceCheck
+	}
+	if utf8.RuneCountInString(req.ServiceName) > maxServiceNameLength {
+		return models.Subscription{}, errServiceNameTooLong
+	}
+
+	start, err := tools.ParseMonthYear(req.StartDate)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	sub := models.Subscription{
+		UserID:      req.UserID,
+		ServiceName: req.ServiceName,
+		Category:    strings.TrimSpace(req.Category),
+		Tags:        catalog.Tags(req.Tags),
+		Price:       req.Price,
+		StartDate:   start,
+	}
+	if utf8.RuneCountInString(sub.Category) > maxLabelLength {
+		return models.Subscription{}, errLabelTooLong
+	}
+	for _, tag := range sub.Tags {
+		if utf8.RuneCountInString(tag) > maxLabelLength {
+			return models.Subscription{}, errLabelTooLong
+		}
+	}
+	if sub.Metadata, err = metadata.Normalize(req.Metadata); err != nil {
+		return models.Subscription{}, err
+	}
+	if sub.Sharing, err = newSharing(req.Sharing); err != nil {
+		return models.Subscription{}, err
+	}
+	if req.EndDate != "" {
+		end, err := tools.ParseMonthYear(req.EndDate)
+		if err != nil {
+			return models.Subscription{}, err
+		}
+		sub.EndDate = &end
+	}
+	if req.TrialEnd != "" {
+		trialEnd, err := tools.ParseMonthYear(req.TrialEnd)
+		if err != nil {
+			return models.Subscription{}, err
+		}
+		if trialEnd.Before(start) {
+			return models.Subscription{}, errTrialEndCheck
+		}
+		sub.TrialEnd = &trialEnd
+	}
+
+	promo, err := lifecycle.ParsePromo(req.Promo)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	if promo != nil && !lifecycle.ValidPromo(*promo) {
+		return models.Subscription{}, errPromoCheck
+	}
+	sub.Promo = promo
+	return sub, nil
+}
+
+// serviceID - сервис каталога с именем name, как подзапрос по services.name
+func (d *data) serviceID(name string) *uuid.UUID {
+	for id, service := range d.services {
+		if service.Name == name {
+			return &id
+		}
+	}
+	return nil
+}
+
+// currency - валюта подписки на сервис name: валюта сервиса каталога, сервиса нет - catalog.DefaultCurrency
+func (d *data) currency(name string) string {
+	if id := d.serviceID(name); id != nil {
+		return d.services[*id].Currency
+	}
+	return catalog.DefaultCurrency
+}
+
+// setKeys - заменяет ключи поиска сервиса id, ключ другого сервиса - dberrors.ServiceAlreadyExist, как PRIMARY KEY service_aliases
+func (d *data) setKeys(id uuid.UUID, req models.ServiceRequest) error {
+	keys := catalog.Keys(req)
+	for _, key := range keys {
+		if owner, ok := d.aliases[key]; ok && owner != id {
+			return dberrors.ServiceAlreadyExist
+		}
+	}
+	for _, service := range d.services {
+		if service.ID != id && service.Name == req.Name {
+			return dberrors.ServiceAlreadyExist
+		}
+	}
+
+	for key, owner := range d.aliases {
+		if owner == id {
+			delete(d.aliases, key)
+		}
+	}
+	for _, key := range keys {
+		d.aliases[key] = id
+	}
+	return nil
+}
+
+// checkService - проверки схемы таблицы services
+func checkService(req models.ServiceRequest) error {
+	if utf8.RuneCountInString(req.Name) > maxServiceNameLength {
+		return errServiceNameTooLong
+	}
+	if req.DefaultPrice != nil && *req.DefaultPrice <= 0 {
+		return errDefaultPriceCheck
+	}
+	return nil
+}
+
+// newService - запись каталога из нормализованного запроса
+func newService(id uuid.UUID, req models.ServiceRequest) models.Service {
+	return models.Service{
+		ID:           id,
+		Name:         req.Name,
+		Aliases:      req.Aliases,
+		Category:     req.Category,
+		DefaultPrice: req.DefaultPrice,
+		Currency:     req.Currency,
+	}
+}
+
+// cloneService - копия записи каталога, алиасы не пересекаются с хранилищем
+func cloneService(service models.Service) *models.Service {
+	service.Aliases = slices.Clone(service.Aliases)
+	if service.DefaultPrice != nil {
+		price := *service.DefaultPrice
+		service.DefaultPrice = &price
+	}
+	return &service
+}
+
+// newSharing - участники в порядке user_id с проверками схемы: split, percent и уникальность участника
+func newSharing(req *models.Sharing) (*models.Sharing, error) {
+	if req == nil {
+		return nil, nil
+	}
+	switch req.Split {
+	case models.SplitEqual, models.SplitPercentage, models.SplitPayer:
+	default:
+		return nil, errSplitCheck
+	}
+
+	sharing := &models.Sharing{Split: req.Split, Members: slices.Clone(req.Members)}
+	slices.SortFunc(sharing.Members, func(a, b models.Member) int {
+		return bytes.Compare(a.UserID[:], b.UserID[:])
+	})
+	for i, member := range sharing.Members {
+		if member.Percent < 0 || member.Percent > 100 {
+			return nil, errPercentCheck
+		}
+		if i > 0 && member.UserID == sharing.Members[i-1].UserID {
+			return nil, errMemberDuplicate
+		}
+	}
+	return sharing, nil
+}
+
+// belongs - подписка пользователя или совместная, где он участник
+func belongs(sub models.Subscription, userID uuid.UUID) bool {
+	if sub.UserID == userID {
+		return true
+	}
+	return sub.Sharing != nil && slices.ContainsFunc(sub.Sharing.Members, func(member models.Member) bool {
+		return member.UserID == userID
+	})
+}
+
+// hasTags - у подписки есть все теги tags (сравнение по catalog.Key)
+func hasTags(sub models.Subscription, tags []string) bool {
+	for _, tag := range catalog.Tags(tags) {
+		if !slices.Contains(sub.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// endingTrial - пробный период не отменен заранее, trial_end в [from, before) и после него есть оплачиваемый месяц
+func endingTrial(sub models.Subscription, from, before time.Time) bool {
+	if sub.TrialEnd == nil || sub.Status == models.StatusCancelled {
+		return false
+	}
+	if sub.TrialEnd.Before(from) || !sub.TrialEnd.Before(before) {
+		return false
+	}
+	return sub.EndDate == nil || sub.EndDate.After(*sub.TrialEnd)
+}
+
+// clone - копия подписки, чтобы вызывающий код не менял данные хранилища через указатели и паузы
+func clone(sub models.Subscription) *models.Subscription {
+	if sub.EndDate != nil {
+		end := *sub.EndDate
+		sub.EndDate = &end
+	}
+	if sub.TrialEnd != nil {
+		trialEnd := *sub.TrialEnd
+		sub.TrialEnd = &trialEnd
+	}
+	if sub.ServiceID != nil {
+		serviceID := *sub.ServiceID
+		sub.ServiceID = &serviceID
+	}
+	sub.Tags = slices.Clone(sub.Tags)
+	sub.Metadata = metadata.Clone(sub.Metadata)
+	if sub.Sharing != nil {
+		sharing := *sub.Sharing
+		sharing.Members = slices.Clone(sharing.Members)
+		sub.Sharing = &sharing
+	}
+	if sub.Promo != nil {
+		promo := *sub.Promo
+		sub.Promo = &promo
+	}
+	if sub.CancelledAt != nil {
+		cancelledAt := *sub.CancelledAt
+		sub.CancelledAt = &cancelledAt
+	}
+	if sub.DeletedAt != nil {
+		deletedAt := *sub.DeletedAt
+		sub.DeletedAt = &deletedAt
+	}
+	if sub.Pauses != nil {
+		pauses := make([]models.Pause, len(sub.Pauses))
+		for i, pause := range sub.Pauses {
+			if pause.To != nil {
+				to := *pause.To
+				pause.To = &to
+			}
+			if pause.ResumedAt != nil {
+				resumedAt := *pause.ResumedAt
+				pause.ResumedAt = &resumedAt
+			}
+			pauses[i] = pause
+		}
+		sub.Pauses = pauses
+	}
+	return &sub
+}
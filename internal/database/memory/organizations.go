@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListOrganizations - организации, отсортированные по name
+func (r *Repository) ListOrganizations(ctx context.Context) ([]models.Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var organizations []models.Organization
+	for _, organization := range r.organizations {
+		organizations = append(organizations, organization)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(organizations, func(a, b models.Organization) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return organizations, nil
+}
+
+// GetOrganization - организация, nil - организации нет
+func (r *Repository) GetOrganization(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	organization, ok := r.organizations[id]
+	if !ok {
+		return nil, nil
+	}
+	return &organization, nil
+}
+
+// CreateOrganization - новая организация, занятое имя - dberrors.OrganizationAlreadyExist
+func (r *Repository) CreateOrganization(ctx context.Context, req models.OrganizationRequest) (*models.Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, organization := range r.organizations {
+		if organization.Name == req.Name {
+			return nil, dberrors.OrganizationAlreadyExist
+		}
+	}
+
+	now := time.Now()
+	organization := models.Organization{ID: uuid.New(), Name: req.Name, CreatedAt: now, UpdatedAt: now}
+	r.organizations[organization.ID] = organization
+	return &organization, nil
+}
+
+// DeleteOrganization - удалить организацию и ее участников, организацию с подписками, сервисами или пользователями -
+// dberrors.OrganizationNotEmpty, как ON DELETE RESTRICT
+func (r *Repository) DeleteOrganization(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.organizations[id]; !ok {
+		return dberrors.OrganizationNotFound
+	}
+	if d, ok := r.tenants[id]; ok && (len(d.subscriptions) > 0 || len(d.services) > 0 || len(d.users) > 0) {
+		return dberrors.OrganizationNotEmpty
+	}
+	delete(r.organizations, id)
+	delete(r.members, id)
+	delete(r.tenants, id)
+	return nil
+}
+
+// ListMembers - участники организации, отсортированные по user_id
+func (r *Repository) ListMembers(ctx context.Context, organizationID uuid.UUID) ([]models.OrganizationMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var members []models.OrganizationMember
+	for userID, joinedAt := range r.members[organizationID] {
+		members = append(members, models.OrganizationMember{UserID: userID, JoinedAt: joinedAt})
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(members, func(a, b models.OrganizationMember) int {
+		return bytes.Compare(a.UserID[:], b.UserID[:])
+	})
+	return members, nil
+}
+
+// AddMember - добавить пользователя в организацию, повторное добавление возвращает существующую запись
+func (r *Repository) AddMember(ctx context.Context, organizationID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	organization, ok := r.organizations[organizationID]
+	if !ok {
+		return nil, dberrors.OrganizationNotFound
+	}
+	r.addMembers(models.Subscription{TenantID: organization.ID, UserID: userID}, time.Now())
+	return &models.OrganizationMember{UserID: userID, JoinedAt: r.members[organizationID][userID]}, nil
+}
+
+// RemoveMember - убрать пользователя из организации, его подписки остаются
+func (r *Repository) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.members[organizationID][userID]; !ok {
+		return dberrors.MemberNotFound
+	}
+	delete(r.members[organizationID], userID)
+	return nil
+}
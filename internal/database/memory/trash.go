@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RestoreSubscription - вернуть подписку из корзины. Подписки в корзине нет - dberrors.SubscriptionNotFound,
+// у пользователя уже есть подписка на этот сервис - dberrors.SubscriptionAlreadyExist
+func (r *Repository) RestoreSubscription(ctx context.Context, id uuid.UUID, audit *models.AuditEntry) (*models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	sub, ok := d.trash[id]
+	if !ok {
+		return nil, dberrors.SubscriptionNotFound
+	}
+	key := subscriptionKey{userID: sub.UserID, serviceName: sub.ServiceName}
+	if _, taken := d.subscriptions[key]; taken {
+		return nil, dberrors.SubscriptionAlreadyExist
+	}
+
+	sub.DeletedAt = nil
+	sub.Version++
+
+	restored := clone(sub)
+	lifecycle.Resolve(restored, time.Now())
+	entry, err := newAuditEntry(audit, nil, restored)
+	if err != nil {
+		return nil, err
+	}
+	delete(d.trash, id)
+	d.subscriptions[key] = sub
+	d.record(entry)
+	return restored, nil
+}
+
+// ListDeletedSubscriptions - корзина организации, сначала удаленные последними
+func (r *Repository) ListDeletedSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var subscriptions []models.Subscription
+	for _, sub := range r.data(ctx).trash {
+		sub := clone(sub)
+		lifecycle.Resolve(sub, time.Now())
+		subscriptions = append(subscriptions, *sub)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(subscriptions, func(a, b models.Subscription) int {
+		if c := b.DeletedAt.Compare(*a.DeletedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return subscriptions, nil
+}
+
+// PurgeDeletedSubscriptions - окончательно удалить подписки организации, попавшие в корзину раньше before
+func (r *Repository) PurgeDeletedSubscriptions(ctx context.Context, before time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	purged := 0
+	for id, sub := range d.trash {
+		if sub.DeletedAt.Before(before) {
+			delete(d.trash, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
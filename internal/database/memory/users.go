@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/users"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListUsers - пользователи организации, отсортированные по display_name и id
+func (r *Repository) ListUsers(ctx context.Context) ([]models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var list []models.User
+	for _, user := range r.data(ctx).users {
+		list = append(list, user)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(list, func(a, b models.User) int {
+		if c := strings.Compare(a.DisplayName, b.DisplayName); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return list, nil
+}
+
+// GetUser - пользователь, nil - пользователя нет
+func (r *Repository) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.data(ctx).users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+// CreateUser - новый пользователь с id из запроса или новым id
+func (r *Repository) CreateUser(ctx context.Context, req models.UserRequest) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req = users.Normalize(req)
+	if req.ID == uuid.Nil {
+		req.ID = uuid.New()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, err := r.writable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.users[req.ID]; ok {
+		return nil, dberrors.UserAlreadyExist
+	}
+	if d.emailTaken(req.ID, req.Email) {
+		return nil, dberrors.UserAlreadyExist
+	}
+
+	now := time.Now()
+	user := newUser(req.ID, req, now, now)
+	d.users[user.ID] = user
+	return &user, nil
+}
+
+// UpdateUser - заменить профиль пользователя, id из запроса не используется
+func (r *Repository) UpdateUser(ctx context.Context, id uuid.UUID, req models.UserRequest) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req = users.Normalize(req)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	old, ok := d.users[id]
+	if !ok {
+		return nil, dberrors.UserNotFound
+	}
+	if d.emailTaken(id, req.Email) {
+		return nil, dberrors.UserAlreadyExist
+	}
+
+	user := newUser(id, req, old.CreatedAt, time.Now())
+	d.users[id] = user
+	return &user, nil
+}
+
+// DeleteUser - удалить профиль пользователя
+func (r *Repository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := r.data(ctx)
+	if _, ok := d.users[id]; !ok {
+		return dberrors.UserNotFound
+	}
+	delete(d.users, id)
+	return nil
+}
+
+// emailTaken - email занят другим пользователем организации, как частичный уникальный индекс users_email_key
+func (d *data) emailTaken(id uuid.UUID, email string) bool {
+	if email == "" {
+		return false
+	}
+	for _, user := range d.users {
+		if user.ID != id && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// newUser - пользователь из нормализованного запроса
+func newUser(id uuid.UUID, req models.UserRequest, createdAt, updatedAt time.Time) models.User {
+	return models.User{
+		ID:          id,
+		DisplayName: req.DisplayName,
+		Email:       req.Email,
+		Timezone:    req.Timezone,
+		Currency:    req.Currency,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+	}
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/models"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// auditColumns - колонки журнала аудита в порядке scanAudit
+const auditColumns = `id, subscription_id, user_id, service_name, operation, actor, request_id, before_state, after_state, created_at`
+
+// RecordAudit - добавить запись в журнал аудита организации
+func (r *Repository) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if err := insertAudit(ctx, r.pool, entry); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// recordAudit - запись audit об изменении подписки before -> after в транзакции изменения, nil - без записи
+func recordAudit(ctx context.Context, tx pgx.Tx, audit *models.AuditEntry, before, after *models.Subscription) error {
+	if audit == nil {
+		return nil
+	}
+	entry, err := audit.Capture(before, after)
+	if err != nil {
+		return err
+	}
+	return insertAudit(ctx, tx, entry)
+}
+
+// insertAudit - добавить запись в журнал аудита организации tenant.ID(ctx), id записи - UUIDv7
+func insertAudit(ctx context.Context, q querier, entry models.AuditEntry) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `
+    INSERT INTO audit_log (tenant_id, id, subscription_id, user_id, service_name, operation, actor, request_id, before_state, after_state)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb, $10::jsonb)`,
+		tenant.ID(ctx), id, entry.SubscriptionID, entry.UserID, entry.ServiceName, entry.Operation, entry.Actor, entry.RequestID,
+		jsonArg(entry.Before), jsonArg(entry.After))
+	return err
+}
+
+// ListAudit - записи журнала по фильтру, сначала новые
+func (r *Repository) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	where := `tenant_id = $1`
+	args := []any{tenant.ID(ctx)}
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		where += " AND " + condition + " $" + strconv.Itoa(len(args))
+	}
+	if filter.SubscriptionID != uuid.Nil {
+		add("subscription_id =", filter.SubscriptionID)
+	}
+	if filter.UserID != uuid.Nil {
+		add("user_id =", filter.UserID)
+	}
+	if filter.Actor != "" {
+		add("actor =", filter.Actor)
+	}
+	if filter.Operation != "" {
+		add("operation =", filter.Operation)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >=", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at <", filter.To)
+	}
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE ` + where + ` ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + strconv.Itoa(filter.Limit)
+	}
+
+	var entries []models.AuditEntry
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		entries = nil
+		rows, err := pool.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			entry, err := scanAudit(rows)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, *entry)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return entries, nil
+}
+
+// jsonArg - снимок подписки для колонки JSONB, пустой - NULL
+func jsonArg(state []byte) any {
+	if len(state) == 0 {
+		return nil
+	}
+	return string(state)
+}
+
+func scanAudit(row pgx.Row) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	var before, after []byte
+	err := row.Scan(&entry.ID, &entry.SubscriptionID, &entry.UserID, &entry.ServiceName, &entry.Operation, &entry.Actor,
+		&entry.RequestID, &before, &after, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	entry.Before, entry.After = before, after
+	return &entry, nil
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/config"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RetryFunc - вызывается перед очередной попыткой подключения
+type RetryFunc func(attempt int, wait time.Duration, err error)
+
+// InitPGPool - пул соединений с PostgreSQL. Пока БД недоступна, повторяет попытки с экспоненциальной
+// задержкой и jitter, но не дольше conf.Connect.MaxWait. onRetry может быть nil
+func InitPGPool(ctx context.Context, conf config.DatabaseConfig, onRetry RetryFunc) (*pgxpool.Pool, error) {
+	poolConfig, err := PoolConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// пул не подключается сразу, соединения открываются лениво
+	pool, errPGX := pgxpool.NewWithConfig(ctx, poolConfig)
+	if errPGX != nil {
+		return nil, errPGX
+	}
+
+	if err := waitForDatabase(ctx, pool, conf.Connect, onRetry); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// PoolConfig - настройки pgxpool из конфига: DSN с sslmode/application_name, размеры пула и statement_timeout
+func PoolConfig(conf config.DatabaseConfig) (*pgxpool.Config, error) {
+	params := url.Values{}
+	params.Set("sslmode", conf.SSLMode)
+	if conf.SSLRootCert != "" {
+		params.Set("sslrootcert", conf.SSLRootCert)
+	}
+	if conf.ApplicationName != "" {
+		params.Set("application_name", conf.ApplicationName)
+	}
+
+	connStr := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(conf.User, conf.Password),
+		Host:     net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port)),
+		Path:     conf.Database,
+		RawQuery: params.Encode(),
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(connStr.String())
+	if err != nil {
+		return nil, fmt.Errorf("database config: %w", err)
+	}
+
+	applyPoolSettings(poolConfig, conf)
+	return poolConfig, nil
+}
+
+// applyPoolSettings - размеры пула и statement_timeout из конфига, общие для основной БД и реплик
+func applyPoolSettings(poolConfig *pgxpool.Config, conf config.DatabaseConfig) {
+	if conf.Pool.MaxConns > 0 {
+		poolConfig.MaxConns = int32(conf.Pool.MaxConns)
+	}
+	if conf.Pool.MinConns > 0 {
+		poolConfig.MinConns = int32(conf.Pool.MinConns)
+	}
+	if conf.Pool.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = conf.Pool.MaxConnLifetime
+	}
+	if conf.Pool.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = conf.Pool.MaxConnIdleTime
+	}
+	if conf.Pool.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = conf.Pool.HealthCheckPeriod
+	}
+	if conf.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(conf.StatementTimeout.Milliseconds(), 10)
+	}
+	if _, ok := poolConfig.ConnConfig.RuntimeParams["application_name"]; !ok && conf.ApplicationName != "" {
+		poolConfig.ConnConfig.RuntimeParams["application_name"] = conf.ApplicationName
+	}
+	if conf.RowLevelSecurity {
+		poolConfig.PrepareConn = setTenant
+	}
+}
+
+// setTenant - организация запроса в app.tenant_id перед выдачей соединения из пула: по ней фильтруют политики RLS
+func setTenant(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	if _, err := conn.Exec(ctx, `SELECT set_config('app.tenant_id', $1, false)`, tenant.ID(ctx).String()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func waitForDatabase(ctx context.Context, pool *pgxpool.Pool, conf config.ConnectConfig, onRetry RetryFunc) error {
+	deadline := time.Now().Add(conf.MaxWait)
+	backoff := conf.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := pool.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		wait := jitter(backoff)
+		if ctx.Err() != nil || time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("database is not ready after %d attempts: %w", attempt, err)
+		}
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, conf.MaxBackoff)
+	}
+}
+
+// jitter - случайная задержка из [d/2, d], чтобы реплики не стучались в БД одновременно
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReserveIdempotencyKey - занять ключ незавершенной записью. Истекшая запись ключа занимается заново,
+// действующая возвращается без изменений. ON CONFLICT блокирует строку и при невыполненном WHERE,
+// поэтому до SELECT ее не удалят
+func (r *Repository) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var existing *models.IdempotencyRecord
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+        INSERT INTO idempotency_keys (tenant_id, idempotency_key, request_hash, expires_at) VALUES ($1, $2, $3, $4)
+        ON CONFLICT (tenant_id, idempotency_key) DO UPDATE
+        SET request_hash = EXCLUDED.request_hash, completed = false, response_status = NULL, response_headers = NULL,
+            response_body = NULL, created_at = now(), expires_at = EXCLUDED.expires_at
+        WHERE idempotency_keys.expires_at <= now()`,
+			tenant.ID(ctx), record.Key, record.RequestHash, record.ExpiresAt)
+		if err != nil || result.RowsAffected() == 1 {
+			return err
+		}
+
+		existing, err = scanIdempotency(tx.QueryRow(ctx, `
+        SELECT idempotency_key, request_hash, completed, response_status, response_headers, response_body, expires_at
+        FROM idempotency_keys WHERE tenant_id = $1 AND idempotency_key = $2`, tenant.ID(ctx), record.Key))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return existing, nil
+}
+
+// CompleteIdempotencyKey - сохранить ответ запроса с ключом
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx, `
+    UPDATE idempotency_keys SET completed = true, response_status = $3, response_headers = $4::jsonb, response_body = $5
+    WHERE tenant_id = $1 AND idempotency_key = $2`,
+		tenant.ID(ctx), record.Key, record.Status, string(header), record.Body)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey - удалить запись ключа
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE tenant_id = $1 AND idempotency_key = $2`, tenant.ID(ctx), key)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// PurgeIdempotencyKeys - удалить записи организации, истекшие раньше before
+func (r *Repository) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE tenant_id = $1 AND expires_at < $2`, tenant.ID(ctx), before)
+	if err != nil {
+		return 0, fmt.Errorf("%w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+func scanIdempotency(row pgx.Row) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	var status *int
+	var header []byte
+	err := row.Scan(&record.Key, &record.RequestHash, &record.Completed, &status, &header, &record.Body, &record.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if status != nil {
+		record.Status = *status
+	}
+	if header != nil {
+		if err := json.Unmarshal(header, &record.Header); err != nil {
+			return nil, fmt.Errorf("response_headers: %w", err)
+		}
+	}
+	return &record, nil
+}
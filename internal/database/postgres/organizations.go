@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/pkg/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// organizationColumns - колонки организации в порядке scanOrganization
+const organizationColumns = `id, name, created_at, updated_at`
+
+// ListOrganizations - организации, отсортированные по name
+func (r *Repository) ListOrganizations(ctx context.Context) ([]models.Organization, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var organizations []models.Organization
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		organizations = nil
+		rows, err := pool.Query(ctx, `SELECT `+organizationColumns+` FROM organizations ORDER BY name`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			organization, err := scanOrganization(rows)
+			if err != nil {
+				return err
+			}
+			organizations = append(organizations, *organization)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return organizations, nil
+}
+
+// GetOrganization - организация, nil - организации нет
+func (r *Repository) GetOrganization(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var organization *models.Organization
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		organization, err = scanOrganization(pool.QueryRow(ctx,
+			`SELECT `+organizationColumns+` FROM organizations WHERE id = $1`, id))
+		if errors.Is(err, pgx.ErrNoRows) {
+			organization, err = nil, nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return organization, nil
+}
+
+// CreateOrganization - новая организация, занятое имя - dberrors.OrganizationAlreadyExist
+func (r *Repository) CreateOrganization(ctx context.Context, req models.OrganizationRequest) (*models.Organization, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	organization, err := scanOrganization(r.pool.QueryRow(ctx,
+		`INSERT INTO organizations (name) VALUES ($1) RETURNING `+organizationColumns, req.Name))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, dberrors.OrganizationAlreadyExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return organization, nil
+}
+
+// DeleteOrganization - удалить организацию и ее участников. Организацию с подписками, сервисами или пользователями
+// не дают удалить внешние ключи - dberrors.OrganizationNotEmpty
+func (r *Repository) DeleteOrganization(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var result pgconn.CommandTag
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		// корзину не восстановить без организации, ее подписки удаляются сразу
+		if _, err := tx.Exec(ctx, `DELETE FROM subscriptions WHERE tenant_id = $1 AND deleted_at IS NOT NULL`, id); err != nil {
+			return err
+		}
+		var err error
+		result, err = tx.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, id)
+		return err
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return dberrors.OrganizationNotEmpty
+	}
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return dberrors.OrganizationNotFound
+	}
+
+	return nil
+}
+
+// ListMembers - участники организации, отсортированные по user_id
+func (r *Repository) ListMembers(ctx context.Context, organizationID uuid.UUID) ([]models.OrganizationMember, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var members []models.OrganizationMember
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		members = nil
+		rows, err := pool.Query(ctx, `
+    SELECT user_id, joined_at FROM organization_members WHERE organization_id = $1 ORDER BY user_id`,
+			organizationID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var member models.OrganizationMember
+			if err := rows.Scan(&member.UserID, &member.JoinedAt); err != nil {
+				return err
+			}
+			members = append(members, member)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return members, nil
+}
+
+// AddMember - добавить пользователя в организацию, повторное добавление возвращает существующую запись
+func (r *Repository) AddMember(ctx context.Context, organizationID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var member models.OrganizationMember
+	err := r.pool.QueryRow(ctx, `
+    INSERT INTO organization_members (organization_id, user_id) VALUES ($1, $2)
+    ON CONFLICT (organization_id, user_id) DO UPDATE SET user_id = EXCLUDED.user_id
+    RETURNING user_id, joined_at`,
+		organizationID, userID).Scan(&member.UserID, &member.JoinedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return nil, dberrors.OrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return &member, nil
+}
+
+// RemoveMember - убрать пользователя из организации, его подписки остаются
+func (r *Repository) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.pool.Exec(ctx, `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`,
+		organizationID, userID)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return dberrors.MemberNotFound
+	}
+
+	return nil
+}
+
+// scanOrganization - организация, колонки в порядке organizationColumns
+func scanOrganization(row pgx.Row) (*models.Organization, error) {
+	var organization models.Organization
+	if err := row.Scan(&organization.ID, &organization.Name, &organization.CreatedAt, &organization.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
@@ -1,76 +1,113 @@
 package postgres
 
 import (
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/database/consistency"
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/internal/metadata"
+	"agrigation_api/internal/tenant"
 	"agrigation_api/pkg/models"
 	"agrigation_api/pkg/tools"
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
-type Repository struct {
-	pool *pgxpool.Pool
-}
+// subscriptionColumns - колонки подписки в порядке scanSubscription
+const subscriptionColumns = `id, tenant_id, user_id, service_name, service_id, category, metadata, split, price, start_date, end_date, trial_end,
+    promo_price, promo_percent, promo_from, promo_to, status, status_changed_at, cancelled_at, created_at, updated_at, deleted_at, version`
 
-func InitPostgres() (*Repository, error) {
-	pool, err := InitPGPool()
-	if err != nil {
-		return nil, err
-	}
-	return &Repository{
-		pool: pool,
-	}, nil
+// serviceColumns - колонки записи каталога в порядке scanService
+const serviceColumns = `id, name, category, default_price, currency, created_at, updated_at`
+
+// keyFilter - подписка по уникальному ключу, кроме удаленных в корзину. Параметры - keyArgs
+const keyFilter = `tenant_id = $1 AND user_id = $2 AND service_name = $3 AND deleted_at IS NULL`
 
+// tenantFilter - подписки организации запроса, кроме удаленных в корзину. Параметр $1 - tenant.ID
+const tenantFilter = `tenant_id = $1 AND deleted_at IS NULL`
+
+// querier - пул или транзакция
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
 }
 
-func InitPGPool() (*pgxpool.Pool, error) {
-	pgUser := tools.GetEnv("PG_USER", "postgres")
-	pgPassword := tools.GetEnv("PG_PASSWORD", "postgres")
-	pgHost := tools.GetEnv("PG_HOST", "192.168.3.92")
-	pgPort := tools.GetEnvAsInt("PG_PORT", 5432)
-	pgDatabase := tools.GetEnv("PG_DATABASE", "storage")
+type Repository struct {
+	pool         *pgxpool.Pool
+	replicas     *ReplicaSet
+	queryTimeout time.Duration
+}
 
-	connStr := fmt.Sprintf("postgresql://%s:%s@%s:%d/%s", pgUser, pgPassword, pgHost, pgPort, pgDatabase)
+// NewRepository - репозиторий поверх уже подключенного пула.
+// queryTimeout ограничивает каждый запрос, 0 - без ограничения. replicas может быть nil
+func NewRepository(pool *pgxpool.Pool, replicas *ReplicaSet, queryTimeout time.Duration) *Repository {
+	return &Repository{
+		pool:         pool,
+		replicas:     replicas,
+		queryTimeout: queryTimeout,
+	}
+}
 
-	pool, errPGX := pgxpool.New(context.Background(), connStr)
-	if errPGX != nil {
-		return nil, errPGX
+// withTimeout - контекст запроса с таймаутом репозитория. Медленный запрос отменяется
+// и не держит соединение пула дольше queryTimeout
+func (r *Repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
 
-	if err := pool.Ping(context.Background()); err != nil {
-		return nil, err
+// read - выполняет читающий запрос на реплике, если она доступна и контекст не требует основной БД.
+// При обрыве соединения с репликой запрос повторяется на основной БД
+func (r *Repository) read(ctx context.Context, fn func(pool *pgxpool.Pool) error) error {
+	replica := r.replicas.Next()
+	if replica == nil || consistency.PrimaryRequired(ctx) {
+		return fn(r.pool)
 	}
 
-	return pool, nil
+	err := fn(replica)
+	if isConnectionError(err) && ctx.Err() == nil {
+		r.replicas.markDown(replica, err)
+		return fn(r.pool)
+	}
+	return err
 }
 
 // CreateSubscription - создать подписку
-func (r *Repository) CreateSubscription(ctx context.Context, req models.CreateOrUpdateRequest) (*models.Subscription, error) {
+func (r *Repository) CreateSubscription(ctx context.Context, req models.CreateOrUpdateRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `
     INSERT INTO subscriptions 
-    (user_id, service_name, price, start_date, end_date)
-    VALUES ($1, $2, $3, $4, $5)
-    RETURNING user_id, service_name, price, start_date, end_date, created_at, updated_at`
+    (tenant_id, user_id, service_name, service_id, price, start_date, end_date, trial_end, promo_price, promo_percent, promo_from, promo_to,
+     category, metadata, split)
+    VALUES ($14, $1, $2, (SELECT id FROM services WHERE tenant_id = $14 AND name = $2), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12::jsonb, $13)
+    RETURNING ` + subscriptionColumns
 
-	var sub models.Subscription
-	var endDate sql.NullString
+	end, errEnd := parseOptionalMonth(req.EndDate)
+	if errEnd != nil {
+		return nil, errEnd
+	}
 
-	var endDateVal interface{}
-	if req.EndDate == "" {
-		endDateVal = nil
-	} else {
-		endDateVal = req.EndDate
+	trialEnd, errTrial := parseOptionalMonth(req.TrialEnd)
+	if errTrial != nil {
+		return nil, errTrial
 	}
-	var end *time.Time = nil
-	if endDateVal != nil {
-		*end, _ = tools.ParseMonthYear(req.EndDate)
+
+	promo, errPromo := parsePromo(req)
+	if errPromo != nil {
+		return nil, errPromo
 	}
 
 	start, errSt := tools.ParseMonthYear(req.StartDate)
@@ -78,56 +115,90 @@ func (r *Repository) CreateSubscription(ctx context.Context, req models.CreateOr
 		return nil, errSt
 	}
 
-	err := r.pool.QueryRow(ctx, query,
-		req.UserID,
-		req.ServiceName,
-		req.Price,
-		start,
-		end,
-	).Scan(
-		&sub.UserID,
-		&sub.ServiceName,
-		&sub.Price,
-		&sub.StartDate,
-		&endDate,
-		&sub.CreatedAt,
-		&sub.UpdatedAt,
-	)
+	meta, errMeta := metadata.Encode(req.Metadata)
+	if errMeta != nil {
+		return nil, errMeta
+	}
+
+	tags := catalog.Tags(req.Tags)
+	var sub *models.Subscription
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		sub, err = scanSubscription(tx.QueryRow(ctx, query,
+			req.UserID,
+			req.ServiceName,
+			req.Price,
+			start,
+			end,
+			trialEnd,
+			promo.price,
+			promo.percent,
+			promo.from,
+			promo.to,
+			strings.TrimSpace(req.Category),
+			meta,
+			sharingSplit(req.Sharing),
+			tenant.ID(ctx),
+		))
+		if err != nil {
+			return err
+		}
+		if err := setTags(ctx, tx, req.UserID, req.ServiceName, tags); err != nil {
+			return err
+		}
+		if err := setMembers(ctx, tx, req.UserID, req.ServiceName, req.Sharing); err != nil {
+			return err
+		}
+		if err := withMembers(ctx, tx, []*models.Subscription{sub}, keyFilter, keyArgs(ctx, req.UserID, req.ServiceName)...); err != nil {
+			return err
+		}
+		if err := addMembers(ctx, tx, sub); err != nil {
+			return err
+		}
+		sub.Tags = tags
+		return recordAudit(ctx, tx, audit, nil, sub)
+	})
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
-		if pgErr.Code == "23505" {
-			return nil, SubscriptionAlreadyExist
+		switch pgErr.Code {
+		case "23505":
+			return nil, dberrors.SubscriptionAlreadyExist
+		case "23503":
+			return nil, dberrors.OrganizationNotFound
 		}
 	}
 	if err != nil {
 		return nil, fmt.Errorf("%w", err)
 	}
 
-	if endDate.Valid {
-		*sub.EndDate, _ = time.Parse("01-2006", endDate.String)
-	}
-
-	return &sub, nil
+	return sub, nil
 }
 
-func (r *Repository) UpdateSubscription(ctx context.Context, req models.CreateOrUpdateRequest) (*models.Subscription, error) {
+func (r *Repository) UpdateSubscription(ctx context.Context, req models.CreateOrUpdateRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `
     UPDATE subscriptions 
-    SET price = $3, start_date = $4, end_date = $5 where user_id = $1 and service_name = $2
-    RETURNING user_id, service_name, price, start_date, end_date, created_at, updated_at`
+    SET price = $3, start_date = $4, end_date = $5, trial_end = $6,
+        promo_price = $7, promo_percent = $8, promo_from = $9, promo_to = $10, category = $11, metadata = $12::jsonb, split = $13,
+        service_id = (SELECT id FROM services WHERE tenant_id = $14 AND name = $2), updated_at = NOW(), version = version + 1
+    where tenant_id = $14 and user_id = $1 and service_name = $2 and deleted_at IS NULL and ($15 = 0 OR version = $15)
+    RETURNING ` + subscriptionColumns
 
-	var sub models.Subscription
-	var endDate sql.NullString
+	end, errEnd := parseOptionalMonth(req.EndDate)
+	if errEnd != nil {
+		return nil, errEnd
+	}
 
-	var endDateVal interface{}
-	if req.EndDate == "" {
-		endDateVal = nil
-	} else {
-		endDateVal = req.EndDate
+	trialEnd, errTrial := parseOptionalMonth(req.TrialEnd)
+	if errTrial != nil {
+		return nil, errTrial
 	}
-	var end *time.Time = nil
-	if endDateVal != nil {
-		*end, _ = tools.ParseMonthYear(req.EndDate)
+
+	promo, errPromo := parsePromo(req)
+	if errPromo != nil {
+		return nil, errPromo
 	}
 
 	start, errSt := tools.ParseMonthYear(req.StartDate)
@@ -135,178 +206,1041 @@ func (r *Repository) UpdateSubscription(ctx context.Context, req models.CreateOr
 		return nil, errSt
 	}
 
-	err := r.pool.QueryRow(ctx, query,
-		req.UserID,
-		req.ServiceName,
-		req.Price,
-		start,
-		end,
-	).Scan(
-		&sub.UserID,
-		&sub.ServiceName,
-		&sub.Price,
-		&sub.StartDate,
-		&endDate,
-		&sub.CreatedAt,
-		&sub.UpdatedAt,
-	)
+	meta, errMeta := metadata.Encode(req.Metadata)
+	if errMeta != nil {
+		return nil, errMeta
+	}
+
+	tags := catalog.Tags(req.Tags)
+	var sub *models.Subscription
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var before *models.Subscription
+		var err error
+		if audit != nil {
+			if before, err = lockSubscription(ctx, tx, req.UserID, req.ServiceName); err != nil {
+				return err
+			}
+		}
+		sub, err = scanSubscription(tx.QueryRow(ctx, query,
+			req.UserID,
+			req.ServiceName,
+			req.Price,
+			start,
+			end,
+			trialEnd,
+			promo.price,
+			promo.percent,
+			promo.from,
+			promo.to,
+			strings.TrimSpace(req.Category),
+			meta,
+			sharingSplit(req.Sharing),
+			tenant.ID(ctx),
+			req.Version,
+		))
+		if err != nil {
+			return err
+		}
+		if err := setTags(ctx, tx, req.UserID, req.ServiceName, tags); err != nil {
+			return err
+		}
+		if err := setMembers(ctx, tx, req.UserID, req.ServiceName, req.Sharing); err != nil {
+			return err
+		}
+		if err := withPauses(ctx, tx, []*models.Subscription{sub}, keyFilter, keyArgs(ctx, req.UserID, req.ServiceName)...); err != nil {
+			return err
+		}
+		if err := withMembers(ctx, tx, []*models.Subscription{sub}, keyFilter, keyArgs(ctx, req.UserID, req.ServiceName)...); err != nil {
+			return err
+		}
+		if err := addMembers(ctx, tx, sub); err != nil {
+			return err
+		}
+		sub.Tags = tags
+		return recordAudit(ctx, tx, audit, before, sub)
+	})
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, pgx.ErrNoRows
+		return nil, r.missing(ctx, req.UserID, req.ServiceName, req.Version)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("%w", err)
 	}
 
-	if endDate.Valid {
-		*sub.EndDate, _ = time.Parse("01-2006", endDate.String)
-	}
-
-	return &sub, nil
+	return sub, nil
 }
 
 // GetSubscription - получение подписки у пользователя
 func (r *Repository) GetSubscription(ctx context.Context, userID uuid.UUID, serviceName string) (*models.Subscription, error) {
-	query := `
-    SELECT user_id, service_name, price, start_date, end_date, created_at, updated_at
-    FROM subscriptions 
-    WHERE user_id = $1 AND service_name = $2`
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	var sub models.Subscription
-	var endDate sql.NullString
+	var sub *models.Subscription
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		sub, err = getSubscription(ctx, pool, userID, serviceName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	err := r.pool.QueryRow(ctx, query, userID, serviceName).Scan(
-		&sub.UserID,
-		&sub.ServiceName,
-		&sub.Price,
-		&sub.StartDate,
-		&endDate,
-		&sub.CreatedAt,
-		&sub.UpdatedAt,
-	)
+	return sub, nil
+}
+
+// DeleteSubscription - перенос подписки пользователя в корзину
+func (r *Repository) DeleteSubscription(ctx context.Context, userID uuid.UUID, serviceName string, version int, audit *models.AuditEntry) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
+	query := `UPDATE subscriptions SET deleted_at = NOW(), version = version + 1 WHERE ` + keyFilter + ` AND ($4 = 0 OR version = $4)`
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var before *models.Subscription
+		var err error
+		if audit != nil {
+			if before, err = lockSubscription(ctx, tx, userID, serviceName); err != nil {
+				return err
+			}
+		}
+		result, err := tx.Exec(ctx, query, append(keyArgs(ctx, userID, serviceName), version)...)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return recordAudit(ctx, tx, audit, before, nil)
+	})
 	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
+		return r.missing(ctx, userID, serviceName, version)
 	}
 	if err != nil {
-		return nil, err
-	}
-
-	if endDate.Valid {
-		*sub.EndDate, _ = time.Parse("01-2006", endDate.String)
+		return fmt.Errorf("%w", err)
 	}
 
-	return &sub, nil
+	return nil
 }
 
-// DeleteSubscription - удаление подписки у пользователя
-func (r *Repository) DeleteSubscription(ctx context.Context, userID uuid.UUID, serviceName string) error {
-	query := `DELETE FROM subscriptions WHERE user_id = $1 AND service_name = $2`
-
-	result, err := r.pool.Exec(ctx, query, userID, serviceName)
+// missing - ошибка изменения, не затронувшего ни одной строки: подписка есть, но с другой версией -
+// dberrors.SubscriptionVersionMismatch, иначе dberrors.SubscriptionNotFound
+func (r *Repository) missing(ctx context.Context, userID uuid.UUID, serviceName string, version int) error {
+	if version == 0 {
+		return dberrors.SubscriptionNotFound
+	}
+	var exists bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE `+keyFilter+`)`,
+		keyArgs(ctx, userID, serviceName)...).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("%w", err)
 	}
+	if exists {
+		return dberrors.SubscriptionVersionMismatch
+	}
+	return dberrors.SubscriptionNotFound
+}
+
+// ListUserSubscriptions - получение списка подписок у пользователя, включая совместные, где он участник
+func (r *Repository) ListUserSubscriptions(ctx context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Subscription, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	where := tenantFilter + " AND " + userFilter(2)
+	args := []any{tenant.ID(ctx), userID}
 
-	if rows := result.RowsAffected(); rows == 0 {
-		return SubscriptionNotFound
+	// Фильтр по статусу, expired - как в lifecycle.Status
+	if filter.Status != "" {
+		where += " AND CASE WHEN status <> 'cancelled' AND end_date < $3 THEN 'expired' ELSE status END = $4"
+		args = append(args, lifecycle.CurrentMonth(time.Now()), filter.Status)
 	}
 
-	return nil
-}
+	// Фильтр по тегам
+	tagsWhere, tagsArgs := tagsFilter(filter.Tags, len(args)+1)
+	where += tagsWhere
+	args = append(args, tagsArgs...)
 
-// ListUserSubscriptions - получение списка подписок у пользователя
-func (r *Repository) ListUserSubscriptions(ctx context.Context, userID uuid.UUID) ([]models.Subscription, error) {
-	query := `
-    SELECT user_id, service_name, price, start_date, end_date, created_at, updated_at
-    FROM subscriptions 
-    WHERE user_id = $1
-    ORDER BY service_name`
+	// Фильтр по метаданным, использует GIN-индекс idx_subscriptions_metadata
+	if len(filter.Metadata) > 0 {
+		where += " AND metadata @> $" + strconv.Itoa(len(args)+1) + "::jsonb"
+		args = append(args, filter.Metadata)
+	}
 
-	rows, err := r.pool.Query(ctx, query, userID)
+	var subscriptions []models.Subscription
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		subscriptions, err = list(ctx, pool, where, "service_name, user_id", args...)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("%w", err)
 	}
-	defer rows.Close()
 
-	var subscriptions []models.Subscription
-	for rows.Next() {
-		var sub models.Subscription
-		var endDate sql.NullString
-
-		err := rows.Scan(
-			&sub.UserID,
-			&sub.ServiceName,
-			&sub.Price,
-			&sub.StartDate,
-			&endDate,
-			&sub.CreatedAt,
-			&sub.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("%w", err)
-		}
-		/*
-			if endDate.Valid {
-				sub.EndDate = &endDate.String
-			}*/
-		if endDate.Valid {
-			*sub.EndDate, _ = time.Parse("01-2006", endDate.String)
-		}
+	return subscriptions, nil
+}
+
+// ListAllSubscriptions - все подписки (для выгрузки)
+func (r *Repository) ListAllSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-		subscriptions = append(subscriptions, sub)
+	var subscriptions []models.Subscription
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		subscriptions, err = list(ctx, pool, tenantFilter, "user_id, service_name", tenant.ID(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
 	}
 
 	return subscriptions, nil
 }
 
 func (r *Repository) CalculateTotal(ctx context.Context, req models.CalculateTotalRequest) (int, error) {
+	totals, err := r.categoryTotals(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, categoryTotal := range totals {
+		total += categoryTotal
+	}
+	return total, nil
+}
+
+// TotalByCategory - CalculateTotal по категориям
+func (r *Repository) TotalByCategory(ctx context.Context, req models.CalculateTotalRequest) ([]models.CategoryTotal, error) {
+	totals, err := r.categoryTotals(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return lifecycle.CategoryTotals(totals), nil
+}
+
+// categoryTotals - расходы за [StartMonth, EndMonth] по категориям
+func (r *Repository) categoryTotals(ctx context.Context, req models.CalculateTotalRequest) (map[string]int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	if req.StartMonth.After(req.EndMonth) {
-		return 0, SubscriptionDateError
+		return nil, dberrors.SubscriptionDateError
 	}
 
-	// Строим запрос
-	query := `
-    SELECT COALESCE(SUM(price), 0) 
-    FROM subscriptions 
-    WHERE 1=1`
+	// Строим условие: только подписки организации запроса
+	where := tenantFilter
 
-	args := make([]interface{}, 0)
-	argNum := 1
+	args := []interface{}{tenant.ID(ctx)}
+	argNum := 2
 
-	// Фильтр по пользователю
+	// Фильтр по пользователю: его подписки и совместные, где он участник
 	if req.UserID != uuid.Nil {
-		query += " AND user_id = $" + strconv.Itoa(argNum)
+		where += " AND " + userFilter(argNum)
 		args = append(args, req.UserID)
 		argNum++
 	}
 
 	// Фильтр по сервису
 	if req.ServiceName != "" {
-		query += " AND service_name = $" + strconv.Itoa(argNum)
+		where += " AND service_name = $" + strconv.Itoa(argNum)
 		args = append(args, req.ServiceName)
 		argNum++
 	}
 
+	// Фильтр по тегам
+	tagsWhere, tagsArgs := tagsFilter(req.Tags, argNum)
+	where += tagsWhere
+	args = append(args, tagsArgs...)
+	argNum += len(tagsArgs)
+
+	// Фильтр по валюте
+	if req.Currency != "" {
+		where += " AND " + currencyFilter(argNum)
+		args = append(args, catalog.DefaultCurrency, req.Currency)
+		argNum += 2
+	}
+
 	// Фильтр по периоду
 	// Подписка активна в период, если:
 	// 1. start_date <= end_of_period (подписка началась до конца периода)
 	// 2. end_date IS NULL OR end_date >= start_of_period (подписка активна в начале периода или бессрочная)
-	query += " AND start_date <= $" + strconv.Itoa(argNum)
+	where += " AND start_date <= $" + strconv.Itoa(argNum)
 	argNum++
-	query += " AND (end_date IS NULL OR end_date >= $" + strconv.Itoa(argNum) + ")"
+	where += " AND (end_date IS NULL OR end_date >= $" + strconv.Itoa(argNum) + ")"
 
 	args = append(args, req.EndMonth, req.StartMonth)
 
-	var total int
-	err := r.pool.QueryRow(ctx, query, args...).Scan(&total)
+	// Не совместные подписки без пауз, пробного периода и промо-цены в [StartMonth, EndMonth] считаются в SQL,
+	// остальные - по месяцам и долям в lifecycle.ByCategory
+	endMonth, startMonth := "$"+strconv.Itoa(argNum-1), "$"+strconv.Itoa(argNum)
+	plain := `split IS NULL
+        AND NOT EXISTS (SELECT 1 FROM subscription_pauses p WHERE p.subscription_id = subscriptions.id)
+        AND (trial_end IS NULL OR trial_end < ` + startMonth + `)
+        AND (promo_from IS NULL OR promo_from > ` + endMonth + ` OR promo_to < ` + startMonth + `)`
+	query := `
+    SELECT category, SUM(price)
+    FROM subscriptions
+    WHERE ` + where + ` AND ` + plain + `
+    GROUP BY category`
+
+	var totals map[string]int
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		rows, err := pool.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		totals = make(map[string]int)
+		for rows.Next() {
+			var category string
+			var total int
+			if err := rows.Scan(&category, &total); err != nil {
+				return err
+			}
+			totals[category] += total
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		monthly, err := list(ctx, pool, where+" AND NOT ("+plain+")", "user_id, service_name", args...)
+		if err != nil {
+			return err
+		}
+		for category, total := range lifecycle.ByCategory(monthly, req.UserID, req.StartMonth, req.EndMonth) {
+			totals[category] += total
+		}
+		return nil
+	})
 	if err != nil {
-		fmt.Println(req)
-		return 0, fmt.Errorf("%w", err)
+		return nil, fmt.Errorf("%w", err)
 	}
 
-	return total, nil
+	return totals, nil
+}
+
+// currencyFilter - валюта подписки (валюта сервиса каталога, сервиса нет - параметр n) равна параметру n+1
+func currencyFilter(n int) string {
+	return `COALESCE((SELECT s.currency FROM services s
+        WHERE s.tenant_id = subscriptions.tenant_id AND s.name = subscriptions.service_name), $` + strconv.Itoa(n) + `) = $` + strconv.Itoa(n+1)
+}
+
+// ChangeStatus - пауза, возобновление или отмена подписки. Строка подписки блокируется до конца транзакции
+func (r *Repository) ChangeStatus(ctx context.Context, req models.StatusChangeRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	month, err := lifecycle.ParseMonth(req.Month, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var sub *models.Subscription
+	err = pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var id uuid.UUID
+		var version int
+		err := tx.QueryRow(ctx, `SELECT id, version FROM subscriptions WHERE `+keyFilter+` FOR UPDATE`,
+			keyArgs(ctx, req.UserID, req.ServiceName)...).Scan(&id, &version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dberrors.SubscriptionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if req.Version != 0 && req.Version != version {
+			return dberrors.SubscriptionVersionMismatch
+		}
+
+		if sub, err = getSubscription(ctx, tx, req.UserID, req.ServiceName); err != nil {
+			return err
+		}
+		// Apply меняет паузы на месте, у снимка до перехода - своя копия
+		before := *sub
+		before.Pauses = slices.Clone(sub.Pauses)
+		if err := lifecycle.Apply(sub, req.Status, month, now); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+        UPDATE subscriptions SET status = $2, status_changed_at = $3, cancelled_at = $4, end_date = $5, version = version + 1 WHERE id = $1`,
+			id, sub.Status, sub.StatusChangedAt, sub.CancelledAt, sub.EndDate)
+		if err != nil {
+			return err
+		}
+		sub.Version++
+
+		// пауза начинает новую запись, возобновление закрывает открытую
+		switch req.Status {
+		case models.StatusPaused:
+			pause := sub.Pauses[len(sub.Pauses)-1]
+			_, err = tx.Exec(ctx, `
+            INSERT INTO subscription_pauses (subscription_id, paused_from, paused_at) VALUES ($1, $2, $3)`,
+				id, pause.From, pause.PausedAt)
+		case models.StatusActive:
+			pause := sub.Pauses[len(sub.Pauses)-1]
+			_, err = tx.Exec(ctx, `
+            UPDATE subscription_pauses SET resumed_from = $2, resumed_at = $3 WHERE subscription_id = $1 AND resumed_from IS NULL`,
+				id, pause.To, pause.ResumedAt)
+		}
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit, &before, sub)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return sub, nil
+}
+
+// EndingTrials - подписки, у которых в ближайшие req.Days дней заканчивается пробный период
+// и начинается оплата, в порядке trial_end, user_id, service_name
+func (r *Repository) EndingTrials(ctx context.Context, req models.TrialsRequest) ([]models.Subscription, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	from, before := lifecycle.TrialWindow(time.Now(), req.Days)
+
+	// отмененные и закончившиеся вместе с пробным периодом подписки оплачены не будут
+	where := tenantFilter + " AND trial_end >= $2 AND trial_end < $3 AND status <> 'cancelled' AND (end_date IS NULL OR end_date > trial_end)"
+	args := []any{tenant.ID(ctx), from, before}
+
+	// Фильтр по пользователю
+	if req.UserID != uuid.Nil {
+		where += " AND user_id = $4"
+		args = append(args, req.UserID)
+	}
+
+	var subscriptions []models.Subscription
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		subscriptions, err = list(ctx, pool, where, "trial_end, user_id, service_name", args...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// getSubscription - подписка с паузами, nil - подписки нет
+func getSubscription(ctx context.Context, q querier, userID uuid.UUID, serviceName string) (*models.Subscription, error) {
+	query := `
+    SELECT ` + subscriptionColumns + `
+    FROM subscriptions 
+    WHERE ` + keyFilter
+
+	args := keyArgs(ctx, userID, serviceName)
+	sub, err := scanSubscription(q.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := withPauses(ctx, q, []*models.Subscription{sub}, keyFilter, args...); err != nil {
+		return nil, err
+	}
+	if err := withTags(ctx, q, []*models.Subscription{sub}, keyFilter, args...); err != nil {
+		return nil, err
+	}
+	if err := withMembers(ctx, q, []*models.Subscription{sub}, keyFilter, args...); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// lockSubscription - getSubscription с блокировкой строки подписки до конца транзакции tx
+func lockSubscription(ctx context.Context, tx pgx.Tx, userID uuid.UUID, serviceName string) (*models.Subscription, error) {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM subscriptions WHERE `+keyFilter+` FOR UPDATE`, keyArgs(ctx, userID, serviceName)...)
+	if err != nil {
+		return nil, err
+	}
+	return getSubscription(ctx, tx, userID, serviceName)
+}
+
+// list - подписки с паузами по условию where на таблицу subscriptions в порядке orderBy
+func list(ctx context.Context, q querier, where, orderBy string, args ...any) ([]models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE ` + where + ` ORDER BY ` + orderBy
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	refs := make([]*models.Subscription, len(subscriptions))
+	for i := range subscriptions {
+		refs[i] = &subscriptions[i]
+	}
+	if err := withPauses(ctx, q, refs, where, args...); err != nil {
+		return nil, err
+	}
+	if err := withTags(ctx, q, refs, where, args...); err != nil {
+		return nil, err
+	}
+	if err := withMembers(ctx, q, refs, where, args...); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// withTags - загружает теги подписок, выбранных условием where, и раскладывает их по subscriptions
+func withTags(ctx context.Context, q querier, subscriptions []*models.Subscription, where string, args ...any) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	query := `
+    SELECT t.subscription_id, t.tag
+    FROM subscription_tags t
+    WHERE t.subscription_id IN (SELECT id FROM subscriptions WHERE ` + where + `)
+    ORDER BY t.tag`
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[uuid.UUID]*models.Subscription, len(subscriptions))
+	for _, sub := range subscriptions {
+		byID[sub.ID] = sub
+	}
+
+	for rows.Next() {
+		var id uuid.UUID
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		if sub, ok := byID[id]; ok {
+			sub.Tags = append(sub.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+// setTags - заменяет теги подписки userID/serviceName. Теги - после catalog.Tags
+func setTags(ctx context.Context, tx pgx.Tx, userID uuid.UUID, serviceName string, tags []string) error {
+	_, err := tx.Exec(ctx, `
+    DELETE FROM subscription_tags
+    WHERE subscription_id = (SELECT id FROM subscriptions WHERE `+keyFilter+`)`,
+		keyArgs(ctx, userID, serviceName)...)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `
+    INSERT INTO subscription_tags (subscription_id, tag)
+    SELECT s.id, t.tag FROM subscriptions s, unnest($4::text[]) AS t(tag)
+    WHERE s.tenant_id = $1 AND s.user_id = $2 AND s.service_name = $3 AND s.deleted_at IS NULL`,
+		append(keyArgs(ctx, userID, serviceName), tags)...)
+	return err
+}
+
+// withMembers - загружает участников совместных подписок, выбранных условием where, и раскладывает их по subscriptions
+func withMembers(ctx context.Context, q querier, subscriptions []*models.Subscription, where string, args ...any) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	query := `
+    SELECT m.subscription_id, m.user_id, COALESCE(m.percent, 0)
+    FROM subscription_members m
+    WHERE m.subscription_id IN (SELECT id FROM subscriptions WHERE ` + where + `)
+    ORDER BY m.user_id`
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[uuid.UUID]*models.Subscription, len(subscriptions))
+	for _, sub := range subscriptions {
+		byID[sub.ID] = sub
+	}
+
+	for rows.Next() {
+		var id uuid.UUID
+		var member models.Member
+		if err := rows.Scan(&id, &member.UserID, &member.Percent); err != nil {
+			return err
+		}
+		if sub, ok := byID[id]; ok && sub.Sharing != nil {
+			sub.Sharing.Members = append(sub.Sharing.Members, member)
+		}
+	}
+	return rows.Err()
+}
+
+// setMembers - заменяет участников подписки userID/serviceName, nil - подписка не совместная
+func setMembers(ctx context.Context, tx pgx.Tx, userID uuid.UUID, serviceName string, sharing *models.Sharing) error {
+	_, err := tx.Exec(ctx, `
+    DELETE FROM subscription_members
+    WHERE subscription_id = (SELECT id FROM subscriptions WHERE `+keyFilter+`)`,
+		keyArgs(ctx, userID, serviceName)...)
+	if err != nil {
+		return err
+	}
+	if sharing == nil {
+		return nil
+	}
+
+	members := make([]uuid.UUID, len(sharing.Members))
+	percents := make([]*int, len(sharing.Members))
+	for i, member := range sharing.Members {
+		members[i] = member.UserID
+		if member.Percent != 0 {
+			percents[i] = &member.Percent
+		}
+	}
+	_, err = tx.Exec(ctx, `
+    INSERT INTO subscription_members (subscription_id, user_id, percent)
+    SELECT s.id, m.user_id, m.percent FROM subscriptions s, unnest($4::uuid[], $5::int[]) AS m(user_id, percent)
+    WHERE s.tenant_id = $1 AND s.user_id = $2 AND s.service_name = $3 AND s.deleted_at IS NULL`,
+		append(keyArgs(ctx, userID, serviceName), members, percents)...)
+	return err
+}
+
+// addMembers - владелец и участники подписки становятся участниками ее организации
+func addMembers(ctx context.Context, tx pgx.Tx, sub *models.Subscription) error {
+	_, err := tx.Exec(ctx, `
+    INSERT INTO organization_members (organization_id, user_id)
+    SELECT $1, unnest($2::uuid[])
+    ON CONFLICT DO NOTHING`,
+		sub.TenantID, lifecycle.Users(sub))
+	return err
+}
+
+// keyArgs - параметры keyFilter: организация запроса, пользователь и сервис
+func keyArgs(ctx context.Context, userID uuid.UUID, serviceName string) []any {
+	return []any{tenant.ID(ctx), userID, serviceName}
+}
+
+// sharingSplit - колонка split: правило деления, NULL - подписка не совместная
+func sharingSplit(sharing *models.Sharing) *string {
+	if sharing == nil {
+		return nil
+	}
+	return &sharing.Split
+}
+
+// userFilter - подписки пользователя и совместные, где он участник. Параметр $argNum - user_id
+func userFilter(argNum int) string {
+	arg := "$" + strconv.Itoa(argNum)
+	return "(user_id = " + arg + " OR id IN (SELECT subscription_id FROM subscription_members WHERE user_id = " + arg + "))"
+}
+
+// tagsFilter - условие "есть все теги" для WHERE на таблицу subscriptions, параметры нумеруются с argNum
+func tagsFilter(tags []string, argNum int) (string, []any) {
+	var where string
+	var args []any
+	for _, tag := range catalog.Tags(tags) {
+		where += " AND EXISTS (SELECT 1 FROM subscription_tags t WHERE t.subscription_id = subscriptions.id AND t.tag = $" +
+			strconv.Itoa(argNum) + ")"
+		args = append(args, tag)
+		argNum++
+	}
+	return where, args
+}
+
+// withPauses - загружает паузы подписок, выбранных условием where, и раскладывает их по subscriptions
+func withPauses(ctx context.Context, q querier, subscriptions []*models.Subscription, where string, args ...any) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	query := `
+    SELECT p.subscription_id, p.paused_from, p.resumed_from, p.paused_at, p.resumed_at
+    FROM subscription_pauses p
+    WHERE p.subscription_id IN (SELECT id FROM subscriptions WHERE ` + where + `)
+    ORDER BY p.paused_from, p.id`
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[uuid.UUID]*models.Subscription, len(subscriptions))
+	for _, sub := range subscriptions {
+		byID[sub.ID] = sub
+	}
+
+	for rows.Next() {
+		var id uuid.UUID
+		var pause models.Pause
+		if err := rows.Scan(&id, &pause.From, &pause.To, &pause.PausedAt, &pause.ResumedAt); err != nil {
+			return err
+		}
+		if sub, ok := byID[id]; ok {
+			sub.Pauses = append(sub.Pauses, pause)
+		}
+	}
+	return rows.Err()
+}
+
+// scanSubscription - подписка из строки запроса, колонки в порядке subscriptionColumns.
+// Статус и in_trial - на текущий момент (lifecycle.Resolve)
+func scanSubscription(row pgx.Row) (*models.Subscription, error) {
+	var sub models.Subscription
+	var meta []byte
+	var split *string
+	var promo models.Promo
+	var promoFrom, promoTo *time.Time
+	err := row.Scan(
+		&sub.ID,
+		&sub.TenantID,
+		&sub.UserID,
+		&sub.ServiceName,
+		&sub.ServiceID,
+		&sub.Category,
+		&meta,
+		&split,
+		&sub.Price,
+		&sub.StartDate,
+		&sub.EndDate,
+		&sub.TrialEnd,
+		&promo.Price,
+		&promo.Percent,
+		&promoFrom,
+		&promoTo,
+		&sub.Status,
+		&sub.StatusChangedAt,
+		&sub.CancelledAt,
+		&sub.CreatedAt,
+		&sub.UpdatedAt,
+		&sub.DeletedAt,
+		&sub.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if promoFrom != nil && promoTo != nil {
+		promo.From, promo.To = *promoFrom, *promoTo
+		sub.Promo = &promo
+	}
+	if sub.Metadata, err = metadata.Decode(meta); err != nil {
+		return nil, err
+	}
+	if split != nil {
+		sub.Sharing = &models.Sharing{Split: *split}
+	}
+	lifecycle.Resolve(&sub, time.Now())
+
+	return &sub, nil
+}
+
+// parseOptionalMonth - "MM-YYYY" -> *time.Time, пустая строка - NULL (бессрочная подписка, нет пробного периода)
+func parseOptionalMonth(month string) (*time.Time, error) {
+	if month == "" {
+		return nil, nil
+	}
+	parsed, err := tools.ParseMonthYear(month)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// promoColumns - значения колонок promo_*, nil - NULL
+type promoColumns struct {
+	price, percent *int
+	from, to       *time.Time
+}
+
+// parsePromo - промо-период запроса в колонки promo_*
+func parsePromo(req models.CreateOrUpdateRequest) (promoColumns, error) {
+	promo, err := lifecycle.ParsePromo(req.Promo)
+	if err != nil || promo == nil {
+		return promoColumns{}, err
+	}
+	return promoColumns{price: promo.Price, percent: promo.Percent, from: &promo.From, to: &promo.To}, nil
+}
+
+// ListServices - каталог сервисов, отсортированный по name
+func (r *Repository) ListServices(ctx context.Context) ([]models.Service, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var services []models.Service
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		rows, err := pool.Query(ctx, `SELECT `+serviceColumns+` FROM services WHERE tenant_id = $1 ORDER BY name`, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		services = nil
+		for rows.Next() {
+			service, err := scanService(rows)
+			if err != nil {
+				return err
+			}
+			services = append(services, *service)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		refs := make([]*models.Service, len(services))
+		for i := range services {
+			refs[i] = &services[i]
+		}
+		return withAliases(ctx, pool, refs, "tenant_id = $1", tenant.ID(ctx))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return services, nil
+}
+
+// GetService - запись каталога, nil - записи нет
+func (r *Repository) GetService(ctx context.Context, id uuid.UUID) (*models.Service, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var service *models.Service
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		service, err = getService(ctx, pool, "tenant_id = $1 AND id = $2", tenant.ID(ctx), id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return service, nil
+}
+
+// CreateService - новая запись каталога с ключами поиска в одной транзакции
+func (r *Repository) CreateService(ctx context.Context, req models.ServiceRequest) (*models.Service, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	req = catalog.Normalize(req)
+	var service *models.Service
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		service, err = scanService(tx.QueryRow(ctx, `
+    INSERT INTO services (tenant_id, name, category, default_price, currency)
+    VALUES ($5, $1, $2, $3, $4)
+    RETURNING `+serviceColumns,
+			req.Name, req.Category, req.DefaultPrice, req.Currency, tenant.ID(ctx)))
+		if err != nil {
+			return err
+		}
+		return insertKeys(ctx, tx, service.ID, req)
+	})
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	service.Aliases = req.Aliases
+	return service, nil
+}
+
+// UpdateService - заменить запись каталога и ее ключи поиска, при переименовании меняется service_name подписок сервиса
+func (r *Repository) UpdateService(ctx context.Context, id uuid.UUID, req models.ServiceRequest) (*models.Service, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	req = catalog.Normalize(req)
+	var service *models.Service
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		service, err = scanService(tx.QueryRow(ctx, `
+    UPDATE services SET name = $2, category = $3, default_price = $4, currency = $5, updated_at = NOW()
+    WHERE tenant_id = $6 AND id = $1
+    RETURNING `+serviceColumns,
+			id, req.Name, req.Category, req.DefaultPrice, req.Currency, tenant.ID(ctx)))
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM service_aliases WHERE service_id = $1`, id); err != nil {
+			return err
+		}
+		if err := insertKeys(ctx, tx, id, req); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE subscriptions SET service_name = $2, version = version + 1 WHERE tenant_id = $3 AND service_id = $1 AND service_name <> $2`,
+			id, req.Name, tenant.ID(ctx))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, dberrors.ServiceNotFound
+	}
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	service.Aliases = req.Aliases
+	return service, nil
+}
+
+// DeleteService - удалить запись каталога, подписки сервиса остаются без ссылки (ON DELETE SET NULL)
+func (r *Repository) DeleteService(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		// ссылку снимает и ON DELETE SET NULL, явный UPDATE нужен, чтобы выросла версия подписок
+		_, err := tx.Exec(ctx, `UPDATE subscriptions SET service_id = NULL, version = version + 1 WHERE tenant_id = $2 AND service_id = $1`,
+			id, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(ctx, `DELETE FROM services WHERE tenant_id = $2 AND id = $1`, id, tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return dberrors.ServiceNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// ResolveService - сервис по имени или алиасу без учета регистра, nil - не найден
+func (r *Repository) ResolveService(ctx context.Context, name string) (*models.Service, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var service *models.Service
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		service, err = getService(ctx, pool, "tenant_id = $1 AND id = (SELECT service_id FROM service_aliases WHERE tenant_id = $1 AND alias = $2)",
+			tenant.ID(ctx), catalog.Key(name))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return service, nil
 }
 
 func (r *Repository) CloseConnection() {
+	r.replicas.Close()
 	r.pool.Close()
 }
+
+// getService - запись каталога по условию where с алиасами, nil - записи нет
+func getService(ctx context.Context, q querier, where string, args ...any) (*models.Service, error) {
+	service, err := scanService(q.QueryRow(ctx, `SELECT `+serviceColumns+` FROM services WHERE `+where, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := withAliases(ctx, q, []*models.Service{service}, where, args...); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// withAliases - загружает алиасы сервисов, выбранных условием where. Ключ самого имени в алиасы не попадает
+func withAliases(ctx context.Context, q querier, services []*models.Service, where string, args ...any) error {
+	if len(services) == 0 {
+		return nil
+	}
+
+	rows, err := q.Query(ctx, `
+    SELECT service_id, alias FROM service_aliases
+    WHERE service_id IN (SELECT id FROM services WHERE `+where+`)
+    ORDER BY alias`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[uuid.UUID]*models.Service, len(services))
+	for _, service := range services {
+		service.Aliases = []string{}
+		byID[service.ID] = service
+	}
+	for rows.Next() {
+		var serviceID uuid.UUID
+		var alias string
+		if err := rows.Scan(&serviceID, &alias); err != nil {
+			return err
+		}
+		if service, ok := byID[serviceID]; ok && alias != catalog.Key(service.Name) {
+			service.Aliases = append(service.Aliases, alias)
+		}
+	}
+	return rows.Err()
+}
+
+// insertKeys - ключи поиска сервиса: имя и алиасы
+func insertKeys(ctx context.Context, tx pgx.Tx, id uuid.UUID, req models.ServiceRequest) error {
+	for _, key := range catalog.Keys(req) {
+		_, err := tx.Exec(ctx, `INSERT INTO service_aliases (tenant_id, alias, service_id) VALUES ($1, $2, $3)`,
+			tenant.ID(ctx), key, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serviceError - нарушение уникальности имени, алиаса или имени подписки - dberrors.ServiceAlreadyExist,
+// несуществующая организация - dberrors.OrganizationNotFound
+func serviceError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return dberrors.ServiceAlreadyExist
+	}
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return dberrors.OrganizationNotFound
+	}
+	return fmt.Errorf("%w", err)
+}
+
+// scanService - запись каталога, колонки в порядке serviceColumns. Алиасы загружает withAliases
+func scanService(row pgx.Row) (*models.Service, error) {
+	var service models.Service
+	err := row.Scan(
+		&service.ID,
+		&service.Name,
+		&service.Category,
+		&service.DefaultPrice,
+		&service.Currency,
+		&service.CreatedAt,
+		&service.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &service, nil
+}
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"agrigation_api/pkg/config"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReplicaStateFunc - вызывается, когда реплика становится доступной или недоступной
+type ReplicaStateFunc func(replica string, healthy bool, err error)
+
+// ReplicaSet - пулы реплик для чтения с round-robin и периодической проверкой доступности
+type ReplicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	onChange ReplicaStateFunc
+	stop     chan struct{}
+	done     sync.WaitGroup
+}
+
+type replica struct {
+	name    string // host:port, без пароля - для логов
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// NewReplicaSet - пулы для conf.Replicas.DSNs с настройками пула и таймаутов из conf.
+// Доступность проверяется сразу и затем каждые conf.Replicas.CheckInterval. onChange может быть nil
+func NewReplicaSet(ctx context.Context, conf config.DatabaseConfig, onChange ReplicaStateFunc) (*ReplicaSet, error) {
+	set := &ReplicaSet{onChange: onChange, stop: make(chan struct{})}
+	for _, dsn := range conf.Replicas.DSNs {
+		poolConfig, err := pgxpool.ParseConfig(dsn)
+		if err != nil {
+			set.closePools()
+			// ошибка pgx может содержать DSN с паролем
+			return nil, errors.New("database.replicas: invalid dsn")
+		}
+		applyPoolSettings(poolConfig, conf)
+
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			set.closePools()
+			return nil, err
+		}
+		r := &replica{
+			name: net.JoinHostPort(poolConfig.ConnConfig.Host, strconv.Itoa(int(poolConfig.ConnConfig.Port))),
+			pool: pool,
+		}
+		// до первой проверки считаем реплику доступной, чтобы о недоступной сообщил onChange
+		r.healthy.Store(true)
+		set.replicas = append(set.replicas, r)
+	}
+
+	set.check(ctx, conf.Replicas.CheckInterval)
+	set.done.Add(1)
+	go set.watch(conf.Replicas.CheckInterval)
+	return set, nil
+}
+
+// Next - следующая доступная реплика по кругу, nil - доступных реплик нет
+func (s *ReplicaSet) Next() *pgxpool.Pool {
+	if s == nil || len(s.replicas) == 0 {
+		return nil
+	}
+	start := s.next.Add(1)
+	for i := range s.replicas {
+		r := s.replicas[(start+uint64(i))%uint64(len(s.replicas))]
+		if r.healthy.Load() {
+			return r.pool
+		}
+	}
+	return nil
+}
+
+// Healthy - количество доступных реплик
+func (s *ReplicaSet) Healthy() int {
+	if s == nil {
+		return 0
+	}
+	healthy := 0
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// markDown - реплика не ответила на запрос, до следующей проверки запросы к ней не идут
+func (s *ReplicaSet) markDown(pool *pgxpool.Pool, err error) {
+	for _, r := range s.replicas {
+		if r.pool == pool {
+			s.setHealthy(r, false, err)
+		}
+	}
+}
+
+// Close - останавливает проверки и закрывает пулы
+func (s *ReplicaSet) Close() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	s.done.Wait()
+	s.closePools()
+}
+
+func (s *ReplicaSet) closePools() {
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
+}
+
+func (s *ReplicaSet) watch(interval time.Duration) {
+	defer s.done.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.check(context.Background(), interval)
+		}
+	}
+}
+
+// check - ping всех реплик, каждая ограничена timeout
+func (s *ReplicaSet) check(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			err := r.pool.Ping(pingCtx)
+			s.setHealthy(r, err == nil, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *ReplicaSet) setHealthy(r *replica, healthy bool, err error) {
+	if r.healthy.Swap(healthy) != healthy && s.onChange != nil {
+		s.onChange(r.name, healthy, err)
+	}
+}
+
+// isConnectionError - запрос не дошел до сервера или соединение оборвалось.
+// Ошибки самого PostgreSQL и отсутствие строк сюда не относятся
+func isConnectionError(err error) bool {
+	var pgErr *pgconn.PgError
+	return err != nil && !errors.As(err, &pgErr) && !errors.Is(err, pgx.ErrNoRows) &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
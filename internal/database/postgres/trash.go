@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RestoreSubscription - вернуть подписку из корзины. Подписки в корзине нет - dberrors.SubscriptionNotFound,
+// у пользователя уже есть подписка на этот сервис - dberrors.SubscriptionAlreadyExist
+func (r *Repository) RestoreSubscription(ctx context.Context, id uuid.UUID, audit *models.AuditEntry) (*models.Subscription, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	const where = `tenant_id = $1 AND id = $2`
+	args := []any{tenant.ID(ctx), id}
+
+	var sub *models.Subscription
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		sub, err = scanSubscription(tx.QueryRow(ctx, `
+        UPDATE subscriptions SET deleted_at = NULL, version = version + 1
+        WHERE `+where+` AND deleted_at IS NOT NULL
+        RETURNING `+subscriptionColumns, args...))
+		if err != nil {
+			return err
+		}
+		refs := []*models.Subscription{sub}
+		if err := withPauses(ctx, tx, refs, where, args...); err != nil {
+			return err
+		}
+		if err := withTags(ctx, tx, refs, where, args...); err != nil {
+			return err
+		}
+		if err := withMembers(ctx, tx, refs, where, args...); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, audit, nil, sub)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, dberrors.SubscriptionNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, dberrors.SubscriptionAlreadyExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return sub, nil
+}
+
+// ListDeletedSubscriptions - корзина организации, сначала удаленные последними
+func (r *Repository) ListDeletedSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var subscriptions []models.Subscription
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		subscriptions, err = list(ctx, pool, "tenant_id = $1 AND deleted_at IS NOT NULL", "deleted_at DESC, id", tenant.ID(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return subscriptions, nil
+}
+
+// PurgeDeletedSubscriptions - окончательно удалить подписки организации, попавшие в корзину раньше before.
+// Паузы, теги и участники удаляются каскадом
+func (r *Repository) PurgeDeletedSubscriptions(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.pool.Exec(ctx, `DELETE FROM subscriptions WHERE tenant_id = $1 AND deleted_at < $2`, tenant.ID(ctx), before)
+	if err != nil {
+		return 0, fmt.Errorf("%w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/tenant"
+	"agrigation_api/internal/users"
+	"agrigation_api/pkg/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// userColumns - колонки пользователя в порядке scanUser
+const userColumns = `id, display_name, COALESCE(email, ''), timezone, currency, created_at, updated_at`
+
+// ListUsers - пользователи организации, отсортированные по display_name и id
+func (r *Repository) ListUsers(ctx context.Context) ([]models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var list []models.User
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		list = nil
+		rows, err := pool.Query(ctx, `SELECT `+userColumns+` FROM users WHERE tenant_id = $1 ORDER BY display_name, id`,
+			tenant.ID(ctx))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			user, err := scanUser(rows)
+			if err != nil {
+				return err
+			}
+			list = append(list, *user)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return list, nil
+}
+
+// GetUser - пользователь, nil - пользователя нет
+func (r *Repository) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var user *models.User
+	err := r.read(ctx, func(pool *pgxpool.Pool) error {
+		var err error
+		user, err = scanUser(pool.QueryRow(ctx,
+			`SELECT `+userColumns+` FROM users WHERE tenant_id = $1 AND id = $2`, tenant.ID(ctx), id))
+		if errors.Is(err, pgx.ErrNoRows) {
+			user, err = nil, nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return user, nil
+}
+
+// CreateUser - новый пользователь с id из запроса или новым id
+func (r *Repository) CreateUser(ctx context.Context, req models.UserRequest) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	req = users.Normalize(req)
+	if req.ID == uuid.Nil {
+		req.ID = uuid.New()
+	}
+	user, err := scanUser(r.pool.QueryRow(ctx, `
+    INSERT INTO users (tenant_id, id, display_name, email, timezone, currency)
+    VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)
+    RETURNING `+userColumns,
+		tenant.ID(ctx), req.ID, req.DisplayName, req.Email, req.Timezone, req.Currency))
+	if err != nil {
+		return nil, userError(err)
+	}
+
+	return user, nil
+}
+
+// UpdateUser - заменить профиль пользователя, id из запроса не используется
+func (r *Repository) UpdateUser(ctx context.Context, id uuid.UUID, req models.UserRequest) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	req = users.Normalize(req)
+	user, err := scanUser(r.pool.QueryRow(ctx, `
+    UPDATE users SET display_name = $3, email = NULLIF($4, ''), timezone = $5, currency = $6, updated_at = NOW()
+    WHERE tenant_id = $1 AND id = $2
+    RETURNING `+userColumns,
+		tenant.ID(ctx), id, req.DisplayName, req.Email, req.Timezone, req.Currency))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, dberrors.UserNotFound
+	}
+	if err != nil {
+		return nil, userError(err)
+	}
+
+	return user, nil
+}
+
+// DeleteUser - удалить профиль пользователя
+func (r *Repository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.pool.Exec(ctx, `DELETE FROM users WHERE tenant_id = $1 AND id = $2`, tenant.ID(ctx), id)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return dberrors.UserNotFound
+	}
+
+	return nil
+}
+
+// userError - занятый id или email - dberrors.UserAlreadyExist, нет организации - dberrors.OrganizationNotFound
+func userError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return dberrors.UserAlreadyExist
+	}
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return dberrors.OrganizationNotFound
+	}
+	return fmt.Errorf("%w", err)
+}
+
+// scanUser - пользователь, колонки в порядке userColumns
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.DisplayName, &user.Email, &user.Timezone, &user.Currency, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
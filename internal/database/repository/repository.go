@@ -1,22 +1,176 @@
 package repository
 
 import (
+	"agrigation_api/internal/database/memory"
 	repository "agrigation_api/internal/database/postgres"
+	"agrigation_api/internal/database/sqlite"
+	"agrigation_api/migrations"
+	"agrigation_api/pkg/config"
+	logger2 "agrigation_api/pkg/logger"
+	"agrigation_api/pkg/logger/logger"
 	"agrigation_api/pkg/models"
 	"context"
+	"fmt"
 	"github.com/google/uuid"
+	"time"
 )
 
 type Repository interface {
-	CreateSubscription(context.Context, models.CreateOrUpdateRequest) (*models.Subscription, error)
-	UpdateSubscription(context.Context, models.CreateOrUpdateRequest) (*models.Subscription, error)
+	// Изменения подписки принимают audit - запись журнала аудита, которая сохраняется в той же транзакции,
+	// что и изменение (AuditEntry.Capture с подпиской до и после него). nil - без записи в журнал
+	CreateSubscription(ctx context.Context, req models.CreateOrUpdateRequest, audit *models.AuditEntry) (*models.Subscription, error)
+	UpdateSubscription(ctx context.Context, req models.CreateOrUpdateRequest, audit *models.AuditEntry) (*models.Subscription, error)
 	GetSubscription(context.Context, uuid.UUID, string) (*models.Subscription, error)
-	DeleteSubscription(context.Context, uuid.UUID, string) error
-	ListUserSubscriptions(context.Context, uuid.UUID) ([]models.Subscription, error)
+	// DeleteSubscription - перенос в корзину. version - ожидаемая версия подписки, 0 - без проверки,
+	// при несовпадении - dberrors.SubscriptionVersionMismatch. Так же проверяют UpdateSubscription и ChangeStatus
+	DeleteSubscription(ctx context.Context, userID uuid.UUID, serviceName string, version int, audit *models.AuditEntry) error
+	ListUserSubscriptions(context.Context, uuid.UUID, models.ListFilter) ([]models.Subscription, error)
+	ListAllSubscriptions(context.Context) ([]models.Subscription, error)
 	CalculateTotal(context.Context, models.CalculateTotalRequest) (int, error)
+	// TotalByCategory - расходы за период по категориям, с теми же фильтрами и правилами, что CalculateTotal
+	TotalByCategory(context.Context, models.CalculateTotalRequest) ([]models.CategoryTotal, error)
+	// ChangeStatus - переход подписки по правилам пакета lifecycle
+	ChangeStatus(ctx context.Context, req models.StatusChangeRequest, audit *models.AuditEntry) (*models.Subscription, error)
+	// EndingTrials - подписки, у которых оплата после пробного периода начнется в ближайшие дни
+	EndingTrials(context.Context, models.TrialsRequest) ([]models.Subscription, error)
+
+	// Корзина. DeleteSubscription переносит подписку в корзину: она не видна в чтении и подсчетах выше.
+	// RestoreSubscription - по id подписки, занятый ключ - dberrors.SubscriptionAlreadyExist
+	RestoreSubscription(ctx context.Context, id uuid.UUID, audit *models.AuditEntry) (*models.Subscription, error)
+	ListDeletedSubscriptions(context.Context) ([]models.Subscription, error)
+	// PurgeDeletedSubscriptions - окончательное удаление подписок, попавших в корзину раньше момента, возвращает их число
+	PurgeDeletedSubscriptions(context.Context, time.Time) (int, error)
+
+	// Журнал аудита организации. Записи остаются после очистки корзины и удаляются вместе с организацией.
+	// RecordAudit задает id (UUIDv7) и created_at записи, так же их задают изменения подписки с audit
+	RecordAudit(context.Context, models.AuditEntry) error
+	// ListAudit - записи по фильтру, сначала новые
+	ListAudit(context.Context, models.AuditFilter) ([]models.AuditEntry, error)
+
+	// Ключи идемпотентности организации. ReserveIdempotencyKey сохраняет незавершенную запись, если у ключа
+	// нет действующей (ExpiresAt в будущем), и возвращает nil. Иначе возвращает действующую запись, не меняя ее
+	ReserveIdempotencyKey(context.Context, models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// CompleteIdempotencyKey - сохранить ответ запроса: Completed, Status, Header и Body записи
+	CompleteIdempotencyKey(context.Context, models.IdempotencyRecord) error
+	// ReleaseIdempotencyKey - удалить запись, запрос с ключом снова выполнится
+	ReleaseIdempotencyKey(context.Context, string) error
+	// PurgeIdempotencyKeys - удалить записи, истекшие раньше момента, возвращает их число
+	PurgeIdempotencyKeys(context.Context, time.Time) (int, error)
+
+	// Каталог сервисов. Запросы нормализуются catalog.Normalize, занятое имя или алиас - dberrors.ServiceAlreadyExist
+	ListServices(context.Context) ([]models.Service, error)
+	GetService(context.Context, uuid.UUID) (*models.Service, error)
+	CreateService(context.Context, models.ServiceRequest) (*models.Service, error)
+	// UpdateService - переименование сервиса меняет service_name подписок, которые на него ссылаются
+	UpdateService(context.Context, uuid.UUID, models.ServiceRequest) (*models.Service, error)
+	// DeleteService - подписки сервиса остаются со своим именем и service_id = NULL
+	DeleteService(context.Context, uuid.UUID) error
+	// ResolveService - сервис по имени или алиасу без учета регистра (catalog.Key), nil - не найден
+	ResolveService(context.Context, string) (*models.Service, error)
+
+	// Организации. Подписки и каталог выше - данные организации tenant.ID(ctx),
+	// запись в несуществующую организацию - dberrors.OrganizationNotFound
+	ListOrganizations(context.Context) ([]models.Organization, error)
+	GetOrganization(context.Context, uuid.UUID) (*models.Organization, error)
+	// CreateOrganization - занятое имя - dberrors.OrganizationAlreadyExist
+	CreateOrganization(context.Context, models.OrganizationRequest) (*models.Organization, error)
+	// DeleteOrganization - организацию с подписками, сервисами или пользователями удалить нельзя - dberrors.OrganizationNotEmpty.
+	// Корзина организации удаляется вместе с ней
+	DeleteOrganization(context.Context, uuid.UUID) error
+	// ListMembers - участники организации. Владельцы и участники подписок добавляются при их записи
+	ListMembers(context.Context, uuid.UUID) ([]models.OrganizationMember, error)
+	AddMember(context.Context, uuid.UUID, uuid.UUID) (*models.OrganizationMember, error)
+	RemoveMember(context.Context, uuid.UUID, uuid.UUID) error
+
+	// Пользователи организации tenant.ID(ctx). Запросы нормализуются users.Normalize,
+	// занятый id или email - dberrors.UserAlreadyExist
+	ListUsers(context.Context) ([]models.User, error)
+	GetUser(context.Context, uuid.UUID) (*models.User, error)
+	CreateUser(context.Context, models.UserRequest) (*models.User, error)
+	UpdateUser(context.Context, uuid.UUID, models.UserRequest) (*models.User, error)
+	// DeleteUser - только профиль, подписки пользователя удаляет или отменяет сервис
+	DeleteUser(context.Context, uuid.UUID) error
 	CloseConnection()
 }
 
-func InitRepository() (Repository, error) {
-	return repository.InitPostgres()
+// InitRepository - репозиторий для хранилища из conf.Storage
+func InitRepository(ctx context.Context, conf *config.Config, logs logger2.MyLogger) (Repository, error) {
+	switch conf.Storage.Backend {
+	case config.StorageMemory:
+		logs.Warning("Using in-memory storage, data will be lost on restart", logger.GetPlace())
+		return memory.NewRepository(), nil
+	case config.StorageSQLite:
+		return initSQLite(ctx, conf.Storage.SQLite, logs)
+	case config.StoragePostgres:
+		return initPostgres(ctx, conf.Database, logs)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", conf.Storage.Backend)
+	}
+}
+
+// initPostgres - подключение к БД (с повторными попытками) и применение миграций на том же пуле
+func initPostgres(ctx context.Context, conf config.DatabaseConfig, logs logger2.MyLogger) (Repository, error) {
+	pool, err := repository.InitPGPool(ctx, conf, func(attempt int, wait time.Duration, err error) {
+		logs.Warning(fmt.Sprintf("Waiting for database (attempt %d, retry in %s): %v", attempt, wait.Round(time.Millisecond), err),
+			logger.GetPlace())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := migrations.NewMigrator(pool)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		logs.Info(fmt.Sprintf("Applied migration %04d_%s", migration.Version, migration.Name), logger.GetPlace())
+	}
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+
+	var replicas *repository.ReplicaSet
+	if len(conf.Replicas.DSNs) > 0 {
+		replicas, err = repository.NewReplicaSet(ctx, conf, func(replica string, healthy bool, err error) {
+			if healthy {
+				logs.Info(fmt.Sprintf("Replica %s is available", replica), logger.GetPlace())
+				return
+			}
+			logs.Warning(fmt.Sprintf("Replica %s is unavailable, reads fall back to primary: %v", replica, err),
+				logger.GetPlace())
+		})
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	return repository.NewRepository(pool, replicas, conf.QueryTimeout), nil
+}
+
+// initSQLite - открытие файла БД и применение миграций SQLite
+func initSQLite(ctx context.Context, conf config.SQLiteConfig, logs logger2.MyLogger) (Repository, error) {
+	db, err := sqlite.Open(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := migrations.NewSQLiteMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		logs.Info(fmt.Sprintf("Applied sqlite migration %04d_%s", migration.Version, migration.Name), logger.GetPlace())
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+
+	return sqlite.NewRepository(db), nil
 }
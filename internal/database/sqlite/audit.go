@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"agrigation_api/pkg/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// auditColumns - колонки журнала аудита в порядке scanAudit
+const auditColumns = `id, subscription_id, user_id, service_name, operation, actor, request_id, before_state, after_state, created_at`
+
+// sortableTimeLayout - время журнала и ключей идемпотентности в UTC постоянной ширины: строки сравниваются и сортируются как время
+const sortableTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// RecordAudit - добавить запись в журнал аудита организации
+func (r *Repository) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	if err := insertAudit(ctx, r.db, entry); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
+// recordAudit - запись audit об изменении подписки before -> after в транзакции изменения, nil - без записи
+func recordAudit(ctx context.Context, tx *sql.Tx, audit *models.AuditEntry, before, after *models.Subscription) error {
+	if audit == nil {
+		return nil
+	}
+	entry, err := audit.Capture(before, after)
+	if err != nil {
+		return err
+	}
+	return insertAudit(ctx, tx, entry)
+}
+
+// insertAudit - добавить запись в журнал аудита организации tenant.ID(ctx), id записи - UUIDv7
+func insertAudit(ctx context.Context, q querier, entry models.AuditEntry) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `
+    INSERT INTO audit_log (tenant_id, id, subscription_id, user_id, service_name, operation, actor, request_id, before_state, after_state, created_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tenantID(ctx), id.String(), entry.SubscriptionID.String(), entry.UserID.String(), entry.ServiceName, entry.Operation,
+		entry.Actor, entry.RequestID, jsonArg(entry.Before), jsonArg(entry.After), time.Now().UTC().Format(sortableTimeLayout))
+	return err
+}
+
+// ListAudit - записи журнала по фильтру, сначала новые
+func (r *Repository) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	where := `tenant_id = ?`
+	args := []any{tenantID(ctx)}
+	if filter.SubscriptionID != uuid.Nil {
+		where += " AND subscription_id = ?"
+		args = append(args, filter.SubscriptionID.String())
+	}
+	if filter.UserID != uuid.Nil {
+		where += " AND user_id = ?"
+		args = append(args, filter.UserID.String())
+	}
+	if filter.Actor != "" {
+		where += " AND actor = ?"
+		args = append(args, filter.Actor)
+	}
+	if filter.Operation != "" {
+		where += " AND operation = ?"
+		args = append(args, filter.Operation)
+	}
+	if !filter.From.IsZero() {
+		where += " AND created_at >= ?"
+		args = append(args, filter.From.UTC().Format(sortableTimeLayout))
+	}
+	if !filter.To.IsZero() {
+		where += " AND created_at < ?"
+		args = append(args, filter.To.UTC().Format(sortableTimeLayout))
+	}
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE ` + where + ` ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + strconv.Itoa(filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		entry, err := scanAudit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return entries, nil
+}
+
+// jsonArg - снимок подписки для колонки с JSON, пустой - NULL
+func jsonArg(state []byte) any {
+	if len(state) == 0 {
+		return nil
+	}
+	return string(state)
+}
+
+func scanAudit(row interface{ Scan(dest ...any) error }) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	var id, subscriptionID, userID, createdAt string
+	var before, after sql.NullString
+	err := row.Scan(&id, &subscriptionID, &userID, &entry.ServiceName, &entry.Operation, &entry.Actor, &entry.RequestID,
+		&before, &after, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("id: %w", err)
+	}
+	if entry.SubscriptionID, err = uuid.Parse(subscriptionID); err != nil {
+		return nil, fmt.Errorf("subscription_id: %w", err)
+	}
+	if entry.UserID, err = uuid.Parse(userID); err != nil {
+		return nil, fmt.Errorf("user_id: %w", err)
+	}
+	if entry.CreatedAt, err = time.Parse(sortableTimeLayout, createdAt); err != nil {
+		return nil, fmt.Errorf("created_at: %w", err)
+	}
+	if before.Valid {
+		entry.Before = []byte(before.String)
+	}
+	if after.Valid {
+		entry.After = []byte(after.String)
+	}
+	return &entry, nil
+}
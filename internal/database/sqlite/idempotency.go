@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"agrigation_api/pkg/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ReserveIdempotencyKey - занять ключ незавершенной записью. Истекшая запись ключа занимается заново,
+// действующая возвращается без изменений
+func (r *Repository) ReserveIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(sortableTimeLayout)
+	result, err := tx.ExecContext(ctx, `
+    INSERT INTO idempotency_keys (tenant_id, idempotency_key, request_hash, created_at, expires_at) VALUES (?, ?, ?, ?, ?)
+    ON CONFLICT (tenant_id, idempotency_key) DO UPDATE
+    SET request_hash = excluded.request_hash, completed = 0, response_status = NULL, response_headers = NULL,
+        response_body = NULL, created_at = excluded.created_at, expires_at = excluded.expires_at
+    WHERE idempotency_keys.expires_at <= excluded.created_at`,
+		tenantID(ctx), record.Key, record.RequestHash, now, record.ExpiresAt.UTC().Format(sortableTimeLayout))
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	var existing *models.IdempotencyRecord
+	if rows == 0 {
+		existing, err = scanIdempotency(tx.QueryRowContext(ctx, `
+        SELECT idempotency_key, request_hash, completed, response_status, response_headers, response_body, expires_at
+        FROM idempotency_keys WHERE tenant_id = ? AND idempotency_key = ?`, tenantID(ctx), record.Key))
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return existing, nil
+}
+
+// CompleteIdempotencyKey - сохранить ответ запроса с ключом
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+    UPDATE idempotency_keys SET completed = 1, response_status = ?, response_headers = ?, response_body = ?
+    WHERE tenant_id = ? AND idempotency_key = ?`,
+		record.Status, string(header), record.Body, tenantID(ctx), record.Key)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey - удалить запись ключа
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE tenant_id = ? AND idempotency_key = ?`, tenantID(ctx), key)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys - удалить записи организации, истекшие раньше before
+func (r *Repository) PurgeIdempotencyKeys(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE tenant_id = ? AND expires_at < ?`,
+		tenantID(ctx), before.UTC().Format(sortableTimeLayout))
+	if err != nil {
+		return 0, fmt.Errorf("%w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%w", err)
+	}
+	return int(rows), nil
+}
+
+func scanIdempotency(row interface{ Scan(dest ...any) error }) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	var status sql.NullInt64
+	var header sql.NullString
+	var expiresAt string
+	err := row.Scan(&record.Key, &record.RequestHash, &record.Completed, &status, &header, &record.Body, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	record.Status = int(status.Int64)
+	if header.Valid {
+		if err := json.Unmarshal([]byte(header.String), &record.Header); err != nil {
+			return nil, fmt.Errorf("response_headers: %w", err)
+		}
+	}
+	if record.ExpiresAt, err = time.Parse(sortableTimeLayout, expiresAt); err != nil {
+		return nil, fmt.Errorf("expires_at: %w", err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,215 @@
+package sqlite
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/pkg/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// organizationColumns - колонки организации в порядке scanOrganization
+const organizationColumns = `id, name, created_at, updated_at`
+
+// ListOrganizations - организации, отсортированные по name
+func (r *Repository) ListOrganizations(ctx context.Context) ([]models.Organization, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+organizationColumns+` FROM organizations ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	var organizations []models.Organization
+	for rows.Next() {
+		organization, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		organizations = append(organizations, *organization)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return organizations, nil
+}
+
+// GetOrganization - организация, nil - организации нет
+func (r *Repository) GetOrganization(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	organization, err := scanOrganization(r.db.QueryRowContext(ctx,
+		`SELECT `+organizationColumns+` FROM organizations WHERE id = ?`, id.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return organization, nil
+}
+
+// CreateOrganization - новая организация, занятое имя - dberrors.OrganizationAlreadyExist
+func (r *Repository) CreateOrganization(ctx context.Context, req models.OrganizationRequest) (*models.Organization, error) {
+	now := time.Now().UTC().Format(timestampLayout)
+	organization, err := scanOrganization(r.db.QueryRowContext(ctx, `
+    INSERT INTO organizations (id, name, created_at, updated_at) VALUES (?, ?, ?, ?)
+    RETURNING `+organizationColumns,
+		uuid.NewString(), req.Name, now, now))
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return nil, dberrors.OrganizationAlreadyExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return organization, nil
+}
+
+// DeleteOrganization - удалить организацию и ее участников. Организацию с подписками, сервисами или пользователями
+// не дают удалить внешние ключи - dberrors.OrganizationNotEmpty
+func (r *Repository) DeleteOrganization(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	// корзину не восстановить без организации, ее подписки удаляются сразу
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE tenant_id = ? AND deleted_at IS NOT NULL`, id.String()); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM organizations WHERE id = ?`, id.String())
+	if foreignKeyError(err) {
+		return dberrors.OrganizationNotEmpty
+	}
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if rows == 0 {
+		return dberrors.OrganizationNotFound
+	}
+	return tx.Commit()
+}
+
+// ListMembers - участники организации, отсортированные по user_id
+func (r *Repository) ListMembers(ctx context.Context, organizationID uuid.UUID) ([]models.OrganizationMember, error) {
+	rows, err := r.db.QueryContext(ctx, `
+    SELECT user_id, joined_at FROM organization_members WHERE organization_id = ? ORDER BY user_id`,
+		organizationID.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	var members []models.OrganizationMember
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		members = append(members, *member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return members, nil
+}
+
+// AddMember - добавить пользователя в организацию, повторное добавление возвращает существующую запись
+func (r *Repository) AddMember(ctx context.Context, organizationID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	member, err := scanMember(r.db.QueryRowContext(ctx, `
+    INSERT INTO organization_members (organization_id, user_id, joined_at) VALUES (?, ?, ?)
+    ON CONFLICT (organization_id, user_id) DO UPDATE SET user_id = excluded.user_id
+    RETURNING user_id, joined_at`,
+		organizationID.String(), userID.String(), time.Now().UTC().Format(timestampLayout)))
+	if foreignKeyError(err) {
+		return nil, dberrors.OrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return member, nil
+}
+
+// RemoveMember - убрать пользователя из организации, его подписки остаются
+func (r *Repository) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM organization_members WHERE organization_id = ? AND user_id = ?`,
+		organizationID.String(), userID.String())
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if rows == 0 {
+		return dberrors.MemberNotFound
+	}
+	return nil
+}
+
+// foreignKeyError - нарушение внешнего ключа. Нарушение ON DELETE RESTRICT SQLite возвращает
+// с кодом SQLITE_CONSTRAINT_TRIGGER, а не SQLITE_CONSTRAINT_FOREIGNKEY
+func foreignKeyError(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return true
+	case sqlite3.SQLITE_CONSTRAINT_TRIGGER:
+		return strings.Contains(sqliteErr.Error(), "FOREIGN KEY")
+	}
+	return false
+}
+
+// scanOrganization - организация, колонки в порядке organizationColumns
+func scanOrganization(row interface{ Scan(dest ...any) error }) (*models.Organization, error) {
+	var organization models.Organization
+	var id, createdAt, updatedAt string
+	if err := row.Scan(&id, &organization.Name, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if organization.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("id: %w", err)
+	}
+	if organization.CreatedAt, err = time.Parse(timestampLayout, createdAt); err != nil {
+		return nil, fmt.Errorf("created_at: %w", err)
+	}
+	if organization.UpdatedAt, err = time.Parse(timestampLayout, updatedAt); err != nil {
+		return nil, fmt.Errorf("updated_at: %w", err)
+	}
+	return &organization, nil
+}
+
+// scanMember - участник организации из колонок user_id, joined_at
+func scanMember(row interface{ Scan(dest ...any) error }) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	var userID, joinedAt string
+	if err := row.Scan(&userID, &joinedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if member.UserID, err = uuid.Parse(userID); err != nil {
+		return nil, fmt.Errorf("user_id: %w", err)
+	}
+	if member.JoinedAt, err = time.Parse(timestampLayout, joinedAt); err != nil {
+		return nil, fmt.Errorf("joined_at: %w", err)
+	}
+	return &member, nil
+}
@@ -0,0 +1,1253 @@
+package sqlite
+
+import (
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/internal/metadata"
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/config"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	dateLayout      = "2006-01-02"     // start_date, end_date, trial_end, месяцы пауз и промо
+	timestampLayout = time.RFC3339Nano // created_at, updated_at, время переходов статуса
+	memoryPath      = ":memory:"
+)
+
+// subscriptionColumns - колонки подписки в порядке scanSubscription
+const subscriptionColumns = `id, tenant_id, user_id, service_name, service_id, category, metadata, split, price, start_date, end_date, trial_end,
+    promo_price, promo_percent, promo_from, promo_to, status, status_changed_at, cancelled_at, created_at, updated_at, deleted_at, version`
+
+// serviceColumns - колонки записи каталога в порядке scanService
+const serviceColumns = `id, name, category, default_price, currency, created_at, updated_at`
+
+// keyFilter - подписка по уникальному ключу, кроме удаленных в корзину. Параметры - keyArgs
+const keyFilter = `tenant_id = ? AND user_id = ? AND service_name = ? AND deleted_at IS NULL`
+
+// tenantFilter - подписки или сервисы организации запроса. Параметр - tenantID
+const tenantFilter = `tenant_id = ?`
+
+// liveFilter - подписки организации запроса, кроме удаленных в корзину. Параметр - tenantID
+const liveFilter = tenantFilter + ` AND deleted_at IS NULL`
+
+// userFilter - подписки пользователя и совместные, где он участник. Параметры - user_id дважды
+const userFilter = `(user_id = ? OR id IN (SELECT subscription_id FROM subscription_members WHERE user_id = ?))`
+
+// statusExpression - статус с учетом истечения, как lifecycle.Status. Параметр - текущий месяц
+const statusExpression = `CASE WHEN status <> 'cancelled' AND end_date < ? THEN 'expired' ELSE status END`
+
+type Repository struct {
+	db *sql.DB
+}
+
+// querier - *sql.DB или *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Open - БД SQLite по пути из конфига: WAL, внешние ключи, ожидание блокировки записи и проверка соединения.
+// Транзакции начинаются с BEGIN IMMEDIATE, чтобы чтение с последующей записью не упиралось в SQLITE_BUSY
+func Open(ctx context.Context, conf config.SQLiteConfig) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", conf.BusyTimeout.Milliseconds()))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+conf.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if conf.Path == memoryPath {
+		// у каждого соединения к :memory: своя БД
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open sqlite %s: %w", conf.Path, err)
+	}
+	return db, nil
+}
+
+// NewRepository - репозиторий поверх открытой БД с примененными миграциями
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateSubscription - создать подписку
+func (r *Repository) CreateSubscription(ctx context.Context, req models.CreateOrUpdateRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	query := `
+    INSERT INTO subscriptions
+    (id, tenant_id, user_id, service_name, service_id, category, metadata, split, price, start_date, end_date, trial_end,
+     promo_price, promo_percent, promo_from, promo_to, status, status_changed_at, created_at, updated_at)
+    VALUES (?, ?, ?, ?, (SELECT id FROM services WHERE tenant_id = ? AND name = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    RETURNING ` + subscriptionColumns
+
+	start, end, trialEnd, err := parseDates(req)
+	if err != nil {
+		return nil, err
+	}
+	promo, err := parsePromo(req)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := metadata.Encode(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Format(timestampLayout)
+	tags := catalog.Tags(req.Tags)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	sub, err := scanSubscription(tx.QueryRowContext(ctx, query,
+		uuid.NewString(),
+		tenantID(ctx),
+		req.UserID.String(),
+		req.ServiceName,
+		tenantID(ctx),
+		req.ServiceName,
+		strings.TrimSpace(req.Category),
+		meta,
+		sharingSplit(req.Sharing),
+		req.Price,
+		start,
+		end,
+		trialEnd,
+		promo.price,
+		promo.percent,
+		promo.from,
+		promo.to,
+		models.StatusActive,
+		now,
+		now,
+		now,
+	))
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return nil, dberrors.SubscriptionAlreadyExist
+	}
+	if foreignKeyError(err) {
+		return nil, dberrors.OrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	if err := setTags(ctx, tx, req.UserID, req.ServiceName, tags); err != nil {
+		return nil, err
+	}
+	if err := setMembers(ctx, tx, req.UserID, req.ServiceName, req.Sharing); err != nil {
+		return nil, err
+	}
+	if err := withMembers(ctx, tx, []*models.Subscription{sub}, keyFilter, keyArgs(ctx, req.UserID, req.ServiceName)...); err != nil {
+		return nil, err
+	}
+	if err := addMembers(ctx, tx, sub); err != nil {
+		return nil, err
+	}
+	sub.Tags = tags
+	if err := recordAudit(ctx, tx, audit, nil, sub); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return sub, nil
+}
+
+func (r *Repository) UpdateSubscription(ctx context.Context, req models.CreateOrUpdateRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	query := `
+    UPDATE subscriptions
+    SET price = ?, start_date = ?, end_date = ?, trial_end = ?, category = ?, metadata = ?, split = ?,
+        promo_price = ?, promo_percent = ?, promo_from = ?, promo_to = ?, updated_at = ?, version = version + 1,
+        service_id = (SELECT id FROM services WHERE services.tenant_id = subscriptions.tenant_id AND services.name = subscriptions.service_name)
+    WHERE ` + keyFilter + ` AND (? = 0 OR version = ?)
+    RETURNING ` + subscriptionColumns
+
+	start, end, trialEnd, err := parseDates(req)
+	if err != nil {
+		return nil, err
+	}
+	promo, err := parsePromo(req)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := metadata.Encode(req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := catalog.Tags(req.Tags)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	var before *models.Subscription
+	if audit != nil {
+		if before, err = getSubscription(ctx, tx, req.UserID, req.ServiceName); err != nil {
+			return nil, err
+		}
+	}
+
+	sub, err := scanSubscription(tx.QueryRowContext(ctx, query,
+		req.Price,
+		start,
+		end,
+		trialEnd,
+		strings.TrimSpace(req.Category),
+		meta,
+		sharingSplit(req.Sharing),
+		promo.price,
+		promo.percent,
+		promo.from,
+		promo.to,
+		time.Now().UTC().Format(timestampLayout),
+		tenantID(ctx),
+		req.UserID.String(),
+		req.ServiceName,
+		req.Version,
+		req.Version,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, missing(ctx, tx, req.UserID, req.ServiceName, req.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	if err := setTags(ctx, tx, req.UserID, req.ServiceName, tags); err != nil {
+		return nil, err
+	}
+	if err := setMembers(ctx, tx, req.UserID, req.ServiceName, req.Sharing); err != nil {
+		return nil, err
+	}
+	if err := withPauses(ctx, tx, []*models.Subscription{sub}, keyFilter, keyArgs(ctx, req.UserID, req.ServiceName)...); err != nil {
+		return nil, err
+	}
+	if err := withMembers(ctx, tx, []*models.Subscription{sub}, keyFilter, keyArgs(ctx, req.UserID, req.ServiceName)...); err != nil {
+		return nil, err
+	}
+	if err := addMembers(ctx, tx, sub); err != nil {
+		return nil, err
+	}
+	sub.Tags = tags
+	if err := recordAudit(ctx, tx, audit, before, sub); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return sub, nil
+}
+
+// GetSubscription - получение подписки у пользователя
+func (r *Repository) GetSubscription(ctx context.Context, userID uuid.UUID, serviceName string) (*models.Subscription, error) {
+	return getSubscription(ctx, r.db, userID, serviceName)
+}
+
+// DeleteSubscription - перенос подписки пользователя в корзину
+func (r *Repository) DeleteSubscription(ctx context.Context, userID uuid.UUID, serviceName string, version int, audit *models.AuditEntry) error {
+	query := `UPDATE subscriptions SET deleted_at = ?, version = version + 1 WHERE ` + keyFilter + ` AND (? = 0 OR version = ?)`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	var before *models.Subscription
+	if audit != nil {
+		if before, err = getSubscription(ctx, tx, userID, serviceName); err != nil {
+			return err
+		}
+	}
+
+	args := append([]any{time.Now().UTC().Format(timestampLayout)}, keyArgs(ctx, userID, serviceName)...)
+	result, err := tx.ExecContext(ctx, query, append(args, version, version)...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if rows == 0 {
+		return missing(ctx, tx, userID, serviceName, version)
+	}
+	if err := recordAudit(ctx, tx, audit, before, nil); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
+// missing - ошибка изменения, не затронувшего ни одной строки: подписка есть, но с другой версией -
+// dberrors.SubscriptionVersionMismatch, иначе dberrors.SubscriptionNotFound
+func missing(ctx context.Context, q querier, userID uuid.UUID, serviceName string, version int) error {
+	if version == 0 {
+		return dberrors.SubscriptionNotFound
+	}
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM subscriptions WHERE `+keyFilter+`)`,
+		keyArgs(ctx, userID, serviceName)...).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if exists {
+		return dberrors.SubscriptionVersionMismatch
+	}
+	return dberrors.SubscriptionNotFound
+}
+
+// ListUserSubscriptions - получение списка подписок у пользователя, включая совместные, где он участник
+func (r *Repository) ListUserSubscriptions(ctx context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Subscription, error) {
+	where := liveFilter + " AND " + userFilter
+	args := []any{tenantID(ctx), userID.String(), userID.String()}
+
+	// Фильтр по статусу
+	if filter.Status != "" {
+		where += " AND " + statusExpression + " = ?"
+		args = append(args, lifecycle.CurrentMonth(time.Now()).Format(dateLayout), filter.Status)
+	}
+
+	// Фильтр по тегам
+	tagsWhere, tagsArgs := tagsFilter(filter.Tags)
+	where += tagsWhere
+	args = append(args, tagsArgs...)
+
+	// Фильтр по метаданным
+	metadataWhere, metadataArgs := metadataFilter(filter.Metadata)
+	where += metadataWhere
+	args = append(args, metadataArgs...)
+
+	return r.list(ctx, where, "service_name, user_id", args...)
+}
+
+// ListAllSubscriptions - все подписки (для выгрузки)
+func (r *Repository) ListAllSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	return r.list(ctx, liveFilter, "user_id, service_name", tenantID(ctx))
+}
+
+func (r *Repository) CalculateTotal(ctx context.Context, req models.CalculateTotalRequest) (int, error) {
+	totals, err := r.categoryTotals(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, categoryTotal := range totals {
+		total += categoryTotal
+	}
+	return total, nil
+}
+
+// TotalByCategory - CalculateTotal по категориям
+func (r *Repository) TotalByCategory(ctx context.Context, req models.CalculateTotalRequest) ([]models.CategoryTotal, error) {
+	totals, err := r.categoryTotals(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return lifecycle.CategoryTotals(totals), nil
+}
+
+// categoryTotals - расходы за [StartMonth, EndMonth] по категориям
+func (r *Repository) categoryTotals(ctx context.Context, req models.CalculateTotalRequest) (map[string]int, error) {
+	if req.StartMonth.After(req.EndMonth) {
+		return nil, dberrors.SubscriptionDateError
+	}
+
+	// даты в формате YYYY-MM-DD сравниваются как строки
+	where := liveFilter + " AND start_date <= ? AND (end_date IS NULL OR end_date >= ?)"
+	args := []any{tenantID(ctx), req.EndMonth.Format(dateLayout), req.StartMonth.Format(dateLayout)}
+
+	// Фильтр по пользователю: его подписки и совместные, где он участник
+	if req.UserID != uuid.Nil {
+		where += " AND " + userFilter
+		args = append(args, req.UserID.String(), req.UserID.String())
+	}
+
+	// Фильтр по сервису
+	if req.ServiceName != "" {
+		where += " AND service_name = ?"
+		args = append(args, req.ServiceName)
+	}
+
+	// Фильтр по тегам
+	tagsWhere, tagsArgs := tagsFilter(req.Tags)
+	where += tagsWhere
+	args = append(args, tagsArgs...)
+
+	// Фильтр по валюте: валюта сервиса каталога, сервиса нет в каталоге - catalog.DefaultCurrency
+	if req.Currency != "" {
+		where += ` AND COALESCE((SELECT s.currency FROM services s
+            WHERE s.tenant_id = subscriptions.tenant_id AND s.name = subscriptions.service_name), ?) = ?`
+		args = append(args, catalog.DefaultCurrency, req.Currency)
+	}
+
+	// Не совместные подписки без пауз, пробного периода и промо-цены в [StartMonth, EndMonth] считаются в SQL,
+	// остальные - по месяцам и долям в lifecycle.ByCategory
+	plain := `split IS NULL
+        AND NOT EXISTS (SELECT 1 FROM subscription_pauses p WHERE p.subscription_id = subscriptions.id)
+        AND (trial_end IS NULL OR trial_end < ?)
+        AND (promo_from IS NULL OR promo_from > ? OR promo_to < ?)`
+	args = append(args, req.StartMonth.Format(dateLayout), req.EndMonth.Format(dateLayout), req.StartMonth.Format(dateLayout))
+
+	query := `SELECT category, SUM(price) FROM subscriptions WHERE ` + where + ` AND ` + plain + ` GROUP BY category`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[string]int)
+	for rows.Next() {
+		var category string
+		var total int
+		if err := rows.Scan(&category, &total); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		totals[category] += total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	monthly, err := r.list(ctx, where+" AND NOT ("+plain+")", "user_id, service_name", args...)
+	if err != nil {
+		return nil, err
+	}
+	for category, total := range lifecycle.ByCategory(monthly, req.UserID, req.StartMonth, req.EndMonth) {
+		totals[category] += total
+	}
+
+	return totals, nil
+}
+
+// ChangeStatus - пауза, возобновление или отмена подписки в одной транзакции
+func (r *Repository) ChangeStatus(ctx context.Context, req models.StatusChangeRequest, audit *models.AuditEntry) (*models.Subscription, error) {
+	now := time.Now().UTC()
+	month, err := lifecycle.ParseMonth(req.Month, now)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	var id string
+	var version int
+	err = tx.QueryRowContext(ctx, `SELECT id, version FROM subscriptions WHERE `+keyFilter,
+		keyArgs(ctx, req.UserID, req.ServiceName)...).Scan(&id, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, dberrors.SubscriptionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	if req.Version != 0 && req.Version != version {
+		return nil, dberrors.SubscriptionVersionMismatch
+	}
+
+	sub, err := getSubscription(ctx, tx, req.UserID, req.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	// Apply меняет паузы на месте, у снимка до перехода - своя копия
+	before := *sub
+	before.Pauses = slices.Clone(sub.Pauses)
+	if err := lifecycle.Apply(sub, req.Status, month, now); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    UPDATE subscriptions SET status = ?, status_changed_at = ?, cancelled_at = ?, end_date = ?, version = version + 1 WHERE id = ?`,
+		sub.Status, formatTimestamp(&sub.StatusChangedAt), formatTimestamp(sub.CancelledAt), formatDate(sub.EndDate), id)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	sub.Version++
+
+	// пауза начинает новую запись, возобновление закрывает открытую
+	switch req.Status {
+	case models.StatusPaused:
+		pause := sub.Pauses[len(sub.Pauses)-1]
+		_, err = tx.ExecContext(ctx, `
+        INSERT INTO subscription_pauses (subscription_id, paused_from, paused_at) VALUES (?, ?, ?)`,
+			id, pause.From.Format(dateLayout), formatTimestamp(&pause.PausedAt))
+	case models.StatusActive:
+		pause := sub.Pauses[len(sub.Pauses)-1]
+		_, err = tx.ExecContext(ctx, `
+        UPDATE subscription_pauses SET resumed_from = ?, resumed_at = ? WHERE subscription_id = ? AND resumed_from IS NULL`,
+			formatDate(pause.To), formatTimestamp(pause.ResumedAt), id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	if err := recordAudit(ctx, tx, audit, &before, sub); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return sub, nil
+}
+
+// EndingTrials - подписки, у которых в ближайшие req.Days дней заканчивается пробный период
+// и начинается оплата, в порядке trial_end, user_id, service_name
+func (r *Repository) EndingTrials(ctx context.Context, req models.TrialsRequest) ([]models.Subscription, error) {
+	from, before := lifecycle.TrialWindow(time.Now(), req.Days)
+
+	// отмененные и закончившиеся вместе с пробным периодом подписки оплачены не будут
+	where := liveFilter + " AND trial_end >= ? AND trial_end < ? AND status <> 'cancelled' AND (end_date IS NULL OR end_date > trial_end)"
+	args := []any{tenantID(ctx), from.Format(dateLayout), before.Format(dateLayout)}
+
+	// Фильтр по пользователю
+	if req.UserID != uuid.Nil {
+		where += " AND user_id = ?"
+		args = append(args, req.UserID.String())
+	}
+
+	return r.list(ctx, where, "trial_end, user_id, service_name", args...)
+}
+
+// ListServices - каталог сервисов, отсортированный по name
+func (r *Repository) ListServices(ctx context.Context) ([]models.Service, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+serviceColumns+` FROM services WHERE `+tenantFilter+` ORDER BY name`, tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	var services []models.Service
+	for rows.Next() {
+		service, err := scanService(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		services = append(services, *service)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	refs := make([]*models.Service, len(services))
+	for i := range services {
+		refs[i] = &services[i]
+	}
+	if err := withAliases(ctx, r.db, refs, tenantFilter, tenantID(ctx)); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// GetService - запись каталога, nil - записи нет
+func (r *Repository) GetService(ctx context.Context, id uuid.UUID) (*models.Service, error) {
+	return getService(ctx, r.db, tenantFilter+" AND id = ?", tenantID(ctx), id.String())
+}
+
+// CreateService - новая запись каталога с ключами поиска в одной транзакции
+func (r *Repository) CreateService(ctx context.Context, req models.ServiceRequest) (*models.Service, error) {
+	req = catalog.Normalize(req)
+	now := time.Now().UTC().Format(timestampLayout)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	service, err := scanService(tx.QueryRowContext(ctx, `
+    INSERT INTO services (id, tenant_id, name, category, default_price, currency, created_at, updated_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    RETURNING `+serviceColumns,
+		uuid.NewString(), tenantID(ctx), req.Name, req.Category, req.DefaultPrice, req.Currency, now, now))
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	if err := insertKeys(ctx, tx, service.ID, req); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	service.Aliases = req.Aliases
+	return service, nil
+}
+
+// UpdateService - заменить запись каталога и ее ключи поиска, при переименовании меняется service_name подписок сервиса
+func (r *Repository) UpdateService(ctx context.Context, id uuid.UUID, req models.ServiceRequest) (*models.Service, error) {
+	req = catalog.Normalize(req)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	service, err := scanService(tx.QueryRowContext(ctx, `
+    UPDATE services SET name = ?, category = ?, default_price = ?, currency = ?, updated_at = ? WHERE tenant_id = ? AND id = ?
+    RETURNING `+serviceColumns,
+		req.Name, req.Category, req.DefaultPrice, req.Currency, time.Now().UTC().Format(timestampLayout), tenantID(ctx), id.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, dberrors.ServiceNotFound
+	}
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM service_aliases WHERE service_id = ?`, id.String()); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	if err := insertKeys(ctx, tx, id, req); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE subscriptions SET service_name = ?, version = version + 1 WHERE tenant_id = ? AND service_id = ? AND service_name <> ?`,
+		req.Name, tenantID(ctx), id.String(), req.Name)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	service.Aliases = req.Aliases
+	return service, nil
+}
+
+// DeleteService - удалить запись каталога, подписки сервиса остаются без ссылки
+func (r *Repository) DeleteService(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	// в схеме SQLite у subscriptions.service_id нет ON DELETE SET NULL
+	_, err = tx.ExecContext(ctx, `UPDATE subscriptions SET service_id = NULL, version = version + 1 WHERE tenant_id = ? AND service_id = ?`,
+		tenantID(ctx), id.String())
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM services WHERE tenant_id = ? AND id = ?`, tenantID(ctx), id.String())
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if rows == 0 {
+		return dberrors.ServiceNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	return nil
+}
+
+// ResolveService - сервис по имени или алиасу без учета регистра, nil - не найден
+func (r *Repository) ResolveService(ctx context.Context, name string) (*models.Service, error) {
+	return getService(ctx, r.db, tenantFilter+" AND id = (SELECT service_id FROM service_aliases WHERE tenant_id = ? AND alias = ?)",
+		tenantID(ctx), tenantID(ctx), catalog.Key(name))
+}
+
+func (r *Repository) CloseConnection() {
+	r.db.Close()
+}
+
+// list - подписки с паузами по условию where на таблицу subscriptions в порядке orderBy
+func (r *Repository) list(ctx context.Context, where, orderBy string, args ...any) ([]models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE ` + where + ` ORDER BY ` + orderBy
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []models.Subscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		subscriptions = append(subscriptions, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	refs := make([]*models.Subscription, len(subscriptions))
+	for i := range subscriptions {
+		refs[i] = &subscriptions[i]
+	}
+	if err := withPauses(ctx, r.db, refs, where, args...); err != nil {
+		return nil, err
+	}
+	if err := withTags(ctx, r.db, refs, where, args...); err != nil {
+		return nil, err
+	}
+	if err := withMembers(ctx, r.db, refs, where, args...); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// getSubscription - подписка с паузами, nil - подписки нет
+func getSubscription(ctx context.Context, q querier, userID uuid.UUID, serviceName string) (*models.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE ` + keyFilter
+
+	args := keyArgs(ctx, userID, serviceName)
+	sub, err := scanSubscription(q.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := withPauses(ctx, q, []*models.Subscription{sub}, keyFilter, args...); err != nil {
+		return nil, err
+	}
+	if err := withTags(ctx, q, []*models.Subscription{sub}, keyFilter, args...); err != nil {
+		return nil, err
+	}
+	if err := withMembers(ctx, q, []*models.Subscription{sub}, keyFilter, args...); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// withTags - загружает теги подписок, выбранных условием where, и раскладывает их по subscriptions
+func withTags(ctx context.Context, q querier, subscriptions []*models.Subscription, where string, args ...any) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	query := `
+    SELECT t.subscription_id, t.tag
+    FROM subscription_tags t
+    WHERE t.subscription_id IN (SELECT id FROM subscriptions WHERE ` + where + `)
+    ORDER BY t.tag`
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*models.Subscription, len(subscriptions))
+	for _, sub := range subscriptions {
+		byID[sub.ID.String()] = sub
+	}
+
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		if sub, ok := byID[id]; ok {
+			sub.Tags = append(sub.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+// setTags - заменяет теги подписки userID/serviceName. Теги - после catalog.Tags
+func setTags(ctx context.Context, tx *sql.Tx, userID uuid.UUID, serviceName string, tags []string) error {
+	_, err := tx.ExecContext(ctx, `
+    DELETE FROM subscription_tags
+    WHERE subscription_id = (SELECT id FROM subscriptions WHERE `+keyFilter+`)`,
+		keyArgs(ctx, userID, serviceName)...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	for _, tag := range tags {
+		_, err := tx.ExecContext(ctx, `
+    INSERT INTO subscription_tags (subscription_id, tag)
+    SELECT id, ? FROM subscriptions WHERE `+keyFilter,
+			append([]any{tag}, keyArgs(ctx, userID, serviceName)...)...)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+	return nil
+}
+
+// tagsFilter - условие "есть все теги" для WHERE на таблицу subscriptions и его параметры
+func tagsFilter(tags []string) (string, []any) {
+	var where string
+	var args []any
+	for _, tag := range catalog.Tags(tags) {
+		where += " AND EXISTS (SELECT 1 FROM subscription_tags t WHERE t.subscription_id = subscriptions.id AND t.tag = ?)"
+		args = append(args, tag)
+	}
+	return where, args
+}
+
+// withMembers - загружает участников совместных подписок, выбранных условием where, и раскладывает их по subscriptions
+func withMembers(ctx context.Context, q querier, subscriptions []*models.Subscription, where string, args ...any) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	query := `
+    SELECT m.subscription_id, m.user_id, m.percent
+    FROM subscription_members m
+    WHERE m.subscription_id IN (SELECT id FROM subscriptions WHERE ` + where + `)
+    ORDER BY m.user_id`
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*models.Subscription, len(subscriptions))
+	for _, sub := range subscriptions {
+		byID[sub.ID.String()] = sub
+	}
+
+	for rows.Next() {
+		var id, memberID string
+		var percent sql.NullInt64
+		if err := rows.Scan(&id, &memberID, &percent); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		member := models.Member{Percent: int(percent.Int64)}
+		if member.UserID, err = uuid.Parse(memberID); err != nil {
+			return fmt.Errorf("member user_id: %w", err)
+		}
+		if sub, ok := byID[id]; ok && sub.Sharing != nil {
+			sub.Sharing.Members = append(sub.Sharing.Members, member)
+		}
+	}
+	return rows.Err()
+}
+
+// setMembers - заменяет участников подписки userID/serviceName, nil - подписка не совместная
+func setMembers(ctx context.Context, tx *sql.Tx, userID uuid.UUID, serviceName string, sharing *models.Sharing) error {
+	_, err := tx.ExecContext(ctx, `
+    DELETE FROM subscription_members
+    WHERE subscription_id = (SELECT id FROM subscriptions WHERE `+keyFilter+`)`,
+		keyArgs(ctx, userID, serviceName)...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if sharing == nil {
+		return nil
+	}
+
+	for _, member := range sharing.Members {
+		var percent any
+		if member.Percent != 0 {
+			percent = member.Percent
+		}
+		_, err := tx.ExecContext(ctx, `
+    INSERT INTO subscription_members (subscription_id, user_id, percent)
+    SELECT id, ?, ? FROM subscriptions WHERE `+keyFilter,
+			append([]any{member.UserID.String(), percent}, keyArgs(ctx, userID, serviceName)...)...)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+	return nil
+}
+
+// addMembers - владелец и участники подписки становятся участниками ее организации
+func addMembers(ctx context.Context, tx *sql.Tx, sub *models.Subscription) error {
+	joinedAt := time.Now().UTC().Format(timestampLayout)
+	for _, userID := range lifecycle.Users(sub) {
+		_, err := tx.ExecContext(ctx, `
+    INSERT INTO organization_members (organization_id, user_id, joined_at) VALUES (?, ?, ?)
+    ON CONFLICT DO NOTHING`,
+			sub.TenantID.String(), userID.String(), joinedAt)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+	return nil
+}
+
+// tenantID - организация запроса, значение tenant_id
+func tenantID(ctx context.Context) string {
+	return tenant.ID(ctx).String()
+}
+
+// keyArgs - параметры keyFilter
+func keyArgs(ctx context.Context, userID uuid.UUID, serviceName string) []any {
+	return []any{tenantID(ctx), userID.String(), serviceName}
+}
+
+// sharingSplit - колонка split: правило деления, NULL - подписка не совместная
+func sharingSplit(sharing *models.Sharing) any {
+	if sharing == nil {
+		return nil
+	}
+	return sharing.Split
+}
+
+// metadataFilter - условие "значение ключа метаданных - строка value" для WHERE на таблицу subscriptions
+// и его параметры. Ключи - после metadata.ValidKey
+func metadataFilter(filter map[string]string) (string, []any) {
+	keys := slices.Sorted(maps.Keys(filter))
+	var where string
+	var args []any
+	for _, key := range keys {
+		path := `$."` + key + `"`
+		where += " AND json_type(metadata, ?) = 'text' AND json_extract(metadata, ?) = ?"
+		args = append(args, path, path, filter[key])
+	}
+	return where, args
+}
+
+// withPauses - загружает паузы подписок, выбранных условием where, и раскладывает их по subscriptions
+func withPauses(ctx context.Context, q querier, subscriptions []*models.Subscription, where string, args ...any) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	query := `
+    SELECT p.subscription_id, p.paused_from, p.resumed_from, p.paused_at, p.resumed_at
+    FROM subscription_pauses p
+    WHERE p.subscription_id IN (SELECT id FROM subscriptions WHERE ` + where + `)
+    ORDER BY p.paused_from, p.id`
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*models.Subscription, len(subscriptions))
+	for _, sub := range subscriptions {
+		byID[sub.ID.String()] = sub
+	}
+
+	for rows.Next() {
+		var id, from, pausedAt string
+		var to, resumedAt sql.NullString
+		if err := rows.Scan(&id, &from, &to, &pausedAt, &resumedAt); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		var pause models.Pause
+		if pause.From, err = time.Parse(dateLayout, from); err != nil {
+			return fmt.Errorf("paused_from: %w", err)
+		}
+		if pause.To, err = parseNullable(dateLayout, to); err != nil {
+			return fmt.Errorf("resumed_from: %w", err)
+		}
+		if pause.PausedAt, err = time.Parse(timestampLayout, pausedAt); err != nil {
+			return fmt.Errorf("paused_at: %w", err)
+		}
+		if pause.ResumedAt, err = parseNullable(timestampLayout, resumedAt); err != nil {
+			return fmt.Errorf("resumed_at: %w", err)
+		}
+
+		if sub, ok := byID[id]; ok {
+			sub.Pauses = append(sub.Pauses, pause)
+		}
+	}
+	return rows.Err()
+}
+
+// scanSubscription - подписка из строки запроса, колонки в порядке subscriptionColumns.
+// Статус и in_trial - на текущий момент (lifecycle.Resolve)
+func scanSubscription(row interface{ Scan(dest ...any) error }) (*models.Subscription, error) {
+	var sub models.Subscription
+	var id, tenantID, userID, meta, start, statusChangedAt, createdAt, updatedAt string
+	var serviceID, split, end, trialEnd, promoFrom, promoTo, cancelledAt, deletedAt sql.NullString
+	var promoPrice, promoPercent sql.NullInt64
+
+	if err := row.Scan(&id, &tenantID, &userID, &sub.ServiceName, &serviceID, &sub.Category, &meta, &split, &sub.Price, &start, &end, &trialEnd,
+		&promoPrice, &promoPercent, &promoFrom, &promoTo, &sub.Status, &statusChangedAt, &cancelledAt, &createdAt, &updatedAt, &deletedAt,
+		&sub.Version); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if sub.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("id: %w", err)
+	}
+	if sub.TenantID, err = uuid.Parse(tenantID); err != nil {
+		return nil, fmt.Errorf("tenant_id: %w", err)
+	}
+	if sub.UserID, err = uuid.Parse(userID); err != nil {
+		return nil, fmt.Errorf("user_id: %w", err)
+	}
+	if serviceID.Valid {
+		id, err := uuid.Parse(serviceID.String)
+		if err != nil {
+			return nil, fmt.Errorf("service_id: %w", err)
+		}
+		sub.ServiceID = &id
+	}
+	if sub.Metadata, err = metadata.Decode([]byte(meta)); err != nil {
+		return nil, err
+	}
+	if split.Valid {
+		sub.Sharing = &models.Sharing{Split: split.String}
+	}
+	if sub.StartDate, err = time.Parse(dateLayout, start); err != nil {
+		return nil, fmt.Errorf("start_date: %w", err)
+	}
+	if sub.EndDate, err = parseNullable(dateLayout, end); err != nil {
+		return nil, fmt.Errorf("end_date: %w", err)
+	}
+	if sub.TrialEnd, err = parseNullable(dateLayout, trialEnd); err != nil {
+		return nil, fmt.Errorf("trial_end: %w", err)
+	}
+	if promoFrom.Valid {
+		sub.Promo = &models.Promo{Price: nullableInt(promoPrice), Percent: nullableInt(promoPercent)}
+		if sub.Promo.From, err = time.Parse(dateLayout, promoFrom.String); err != nil {
+			return nil, fmt.Errorf("promo_from: %w", err)
+		}
+		if sub.Promo.To, err = time.Parse(dateLayout, promoTo.String); err != nil {
+			return nil, fmt.Errorf("promo_to: %w", err)
+		}
+	}
+	if sub.StatusChangedAt, err = time.Parse(timestampLayout, statusChangedAt); err != nil {
+		return nil, fmt.Errorf("status_changed_at: %w", err)
+	}
+	if sub.CancelledAt, err = parseNullable(timestampLayout, cancelledAt); err != nil {
+		return nil, fmt.Errorf("cancelled_at: %w", err)
+	}
+	if sub.CreatedAt, err = time.Parse(timestampLayout, createdAt); err != nil {
+		return nil, fmt.Errorf("created_at: %w", err)
+	}
+	if sub.UpdatedAt, err = time.Parse(timestampLayout, updatedAt); err != nil {
+		return nil, fmt.Errorf("updated_at: %w", err)
+	}
+	if sub.DeletedAt, err = parseNullable(timestampLayout, deletedAt); err != nil {
+		return nil, fmt.Errorf("deleted_at: %w", err)
+	}
+	lifecycle.Resolve(&sub, time.Now())
+
+	return &sub, nil
+}
+
+// parseNullable - NULL -> nil, иначе значение в формате layout
+func parseNullable(layout string, value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(layout, value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// nullableInt - NULL -> nil
+func nullableInt(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
+
+// formatDate - *time.Time -> 'YYYY-MM-DD' или NULL
+func formatDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format(dateLayout)
+}
+
+// formatTimestamp - *time.Time -> RFC 3339 в UTC или NULL
+func formatTimestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(timestampLayout)
+}
+
+// parseDates - "MM-YYYY" -> "YYYY-MM-DD", пустые end_date и trial_end - NULL
+func parseDates(req models.CreateOrUpdateRequest) (string, *string, *string, error) {
+	start, err := tools.ParseMonthYear(req.StartDate)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	end, err := parseOptionalMonth(req.EndDate)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	trialEnd, err := parseOptionalMonth(req.TrialEnd)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return start.Format(dateLayout), end, trialEnd, nil
+}
+
+// parseOptionalMonth - "MM-YYYY" -> "YYYY-MM-DD", пустая строка - NULL
+func parseOptionalMonth(month string) (*string, error) {
+	if month == "" {
+		return nil, nil
+	}
+	parsed, err := tools.ParseMonthYear(month)
+	if err != nil {
+		return nil, err
+	}
+	date := parsed.Format(dateLayout)
+	return &date, nil
+}
+
+// promoColumns - значения колонок promo_*, NULL - промо нет
+type promoColumns struct {
+	price, percent, from, to any
+}
+
+// parsePromo - промо-период запроса в колонки promo_*
+func parsePromo(req models.CreateOrUpdateRequest) (promoColumns, error) {
+	promo, err := lifecycle.ParsePromo(req.Promo)
+	if err != nil || promo == nil {
+		return promoColumns{}, err
+	}
+
+	columns := promoColumns{from: promo.From.Format(dateLayout), to: promo.To.Format(dateLayout)}
+	if promo.Price != nil {
+		columns.price = *promo.Price
+	}
+	if promo.Percent != nil {
+		columns.percent = *promo.Percent
+	}
+	return columns, nil
+}
+
+// getService - запись каталога по условию where с алиасами, nil - записи нет
+func getService(ctx context.Context, q querier, where string, args ...any) (*models.Service, error) {
+	service, err := scanService(q.QueryRowContext(ctx, `SELECT `+serviceColumns+` FROM services WHERE `+where, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	if err := withAliases(ctx, q, []*models.Service{service}, where, args...); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// withAliases - загружает алиасы сервисов, выбранных условием where. Ключ самого имени в алиасы не попадает
+func withAliases(ctx context.Context, q querier, services []*models.Service, where string, args ...any) error {
+	if len(services) == 0 {
+		return nil
+	}
+
+	rows, err := q.QueryContext(ctx, `
+    SELECT service_id, alias FROM service_aliases
+    WHERE service_id IN (SELECT id FROM services WHERE `+where+`)
+    ORDER BY alias`, args...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*models.Service, len(services))
+	for _, service := range services {
+		service.Aliases = []string{}
+		byID[service.ID.String()] = service
+	}
+	for rows.Next() {
+		var serviceID, alias string
+		if err := rows.Scan(&serviceID, &alias); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		if service, ok := byID[serviceID]; ok && alias != catalog.Key(service.Name) {
+			service.Aliases = append(service.Aliases, alias)
+		}
+	}
+	return rows.Err()
+}
+
+// insertKeys - ключи поиска сервиса: имя и алиасы
+func insertKeys(ctx context.Context, tx *sql.Tx, id uuid.UUID, req models.ServiceRequest) error {
+	for _, key := range catalog.Keys(req) {
+		_, err := tx.ExecContext(ctx, `INSERT INTO service_aliases (tenant_id, alias, service_id) VALUES (?, ?, ?)`,
+			tenantID(ctx), key, id.String())
+		if err != nil {
+			return serviceError(err)
+		}
+	}
+	return nil
+}
+
+// serviceError - нарушение уникальности имени, алиаса или имени подписки - dberrors.ServiceAlreadyExist,
+// несуществующая организация - dberrors.OrganizationNotFound
+func serviceError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return dberrors.ServiceAlreadyExist
+	}
+	if foreignKeyError(err) {
+		return dberrors.OrganizationNotFound
+	}
+	return fmt.Errorf("%w", err)
+}
+
+// scanService - запись каталога, колонки в порядке serviceColumns. Алиасы загружает withAliases
+func scanService(row interface{ Scan(dest ...any) error }) (*models.Service, error) {
+	var service models.Service
+	var id, createdAt, updatedAt string
+	var defaultPrice sql.NullInt64
+
+	if err := row.Scan(&id, &service.Name, &service.Category, &defaultPrice, &service.Currency, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if service.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("id: %w", err)
+	}
+	service.DefaultPrice = nullableInt(defaultPrice)
+	if service.CreatedAt, err = time.Parse(timestampLayout, createdAt); err != nil {
+		return nil, fmt.Errorf("created_at: %w", err)
+	}
+	if service.UpdatedAt, err = time.Parse(timestampLayout, updatedAt); err != nil {
+		return nil, fmt.Errorf("updated_at: %w", err)
+	}
+	return &service, nil
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/pkg/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// RestoreSubscription - вернуть подписку из корзины. Подписки в корзине нет - dberrors.SubscriptionNotFound,
+// у пользователя уже есть подписка на этот сервис - dberrors.SubscriptionAlreadyExist
+func (r *Repository) RestoreSubscription(ctx context.Context, id uuid.UUID, audit *models.AuditEntry) (*models.Subscription, error) {
+	const where = tenantFilter + ` AND id = ?`
+	args := []any{tenantID(ctx), id.String()}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer tx.Rollback()
+
+	sub, err := scanSubscription(tx.QueryRowContext(ctx, `
+    UPDATE subscriptions SET deleted_at = NULL, version = version + 1
+    WHERE `+where+` AND deleted_at IS NOT NULL
+    RETURNING `+subscriptionColumns, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, dberrors.SubscriptionNotFound
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return nil, dberrors.SubscriptionAlreadyExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	refs := []*models.Subscription{sub}
+	if err := withPauses(ctx, tx, refs, where, args...); err != nil {
+		return nil, err
+	}
+	if err := withTags(ctx, tx, refs, where, args...); err != nil {
+		return nil, err
+	}
+	if err := withMembers(ctx, tx, refs, where, args...); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, tx, audit, nil, sub); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return sub, nil
+}
+
+// ListDeletedSubscriptions - корзина организации, сначала удаленные последними
+func (r *Repository) ListDeletedSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	return r.list(ctx, tenantFilter+" AND deleted_at IS NOT NULL", "deleted_at DESC, id", tenantID(ctx))
+}
+
+// PurgeDeletedSubscriptions - окончательно удалить подписки организации, попавшие в корзину раньше before.
+// Паузы, теги и участники удаляются каскадом
+func (r *Repository) PurgeDeletedSubscriptions(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM subscriptions WHERE tenant_id = ? AND deleted_at < ?`,
+		tenantID(ctx), before.UTC().Format(timestampLayout))
+	if err != nil {
+		return 0, fmt.Errorf("%w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%w", err)
+	}
+	return int(rows), nil
+}
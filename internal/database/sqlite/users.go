@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/internal/users"
+	"agrigation_api/pkg/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// userColumns - колонки пользователя в порядке scanUser
+const userColumns = `id, display_name, COALESCE(email, ''), timezone, currency, created_at, updated_at`
+
+// ListUsers - пользователи организации, отсортированные по display_name и id
+func (r *Repository) ListUsers(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users WHERE tenant_id = ? ORDER BY display_name, id`,
+		tenantID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer rows.Close()
+
+	var list []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		list = append(list, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return list, nil
+}
+
+// GetUser - пользователь, nil - пользователя нет
+func (r *Repository) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` FROM users WHERE tenant_id = ? AND id = ?`, tenantID(ctx), id.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	return user, nil
+}
+
+// CreateUser - новый пользователь с id из запроса или новым id
+func (r *Repository) CreateUser(ctx context.Context, req models.UserRequest) (*models.User, error) {
+	req = users.Normalize(req)
+	if req.ID == uuid.Nil {
+		req.ID = uuid.New()
+	}
+	now := time.Now().UTC().Format(timestampLayout)
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, `
+    INSERT INTO users (tenant_id, id, display_name, email, timezone, currency, created_at, updated_at)
+    VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)
+    RETURNING `+userColumns,
+		tenantID(ctx), req.ID.String(), req.DisplayName, req.Email, req.Timezone, req.Currency, now, now))
+	if err != nil {
+		return nil, userError(err)
+	}
+	return user, nil
+}
+
+// UpdateUser - заменить профиль пользователя, id из запроса не используется
+func (r *Repository) UpdateUser(ctx context.Context, id uuid.UUID, req models.UserRequest) (*models.User, error) {
+	req = users.Normalize(req)
+
+	user, err := scanUser(r.db.QueryRowContext(ctx, `
+    UPDATE users SET display_name = ?, email = NULLIF(?, ''), timezone = ?, currency = ?, updated_at = ?
+    WHERE tenant_id = ? AND id = ?
+    RETURNING `+userColumns,
+		req.DisplayName, req.Email, req.Timezone, req.Currency, time.Now().UTC().Format(timestampLayout),
+		tenantID(ctx), id.String()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, dberrors.UserNotFound
+	}
+	if err != nil {
+		return nil, userError(err)
+	}
+	return user, nil
+}
+
+// DeleteUser - удалить профиль пользователя
+func (r *Repository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE tenant_id = ? AND id = ?`, tenantID(ctx), id.String())
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	if rows == 0 {
+		return dberrors.UserNotFound
+	}
+	return nil
+}
+
+// userError - занятый id или email - dberrors.UserAlreadyExist, нет организации - dberrors.OrganizationNotFound
+func userError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+		sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY) {
+		return dberrors.UserAlreadyExist
+	}
+	if foreignKeyError(err) {
+		return dberrors.OrganizationNotFound
+	}
+	return fmt.Errorf("%w", err)
+}
+
+// scanUser - пользователь, колонки в порядке userColumns
+func scanUser(row interface{ Scan(dest ...any) error }) (*models.User, error) {
+	var user models.User
+	var id, createdAt, updatedAt string
+	if err := row.Scan(&id, &user.DisplayName, &user.Email, &user.Timezone, &user.Currency, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if user.ID, err = uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("id: %w", err)
+	}
+	if user.CreatedAt, err = time.Parse(timestampLayout, createdAt); err != nil {
+		return nil, fmt.Errorf("created_at: %w", err)
+	}
+	if user.UpdatedAt, err = time.Parse(timestampLayout, updatedAt); err != nil {
+		return nil, fmt.Errorf("updated_at: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,35 @@
+package etag
+
+import (
+	"agrigation_api/pkg/models"
+	"fmt"
+	"strings"
+)
+
+// Of - сильный ETag подписки из id и версии строки: меняется при каждом изменении,
+// а у подписки, пересозданной с тем же ключом, не совпадает с прежней
+func Of(sub *models.Subscription) string {
+	return fmt.Sprintf(`"%s.%d"`, sub.ID, sub.Version)
+}
+
+// Match - сильное сравнение для If-Match: "*" или один из ETag списка через запятую, слабые не совпадают
+func Match(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchWeak - слабое сравнение для If-None-Match: префикс W/ не учитывается
+func MatchWeak(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
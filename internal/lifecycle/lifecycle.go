@@ -0,0 +1,331 @@
+package lifecycle
+
+import (
+	"agrigation_api/internal/database/dberrors"
+	"agrigation_api/pkg/models"
+	"agrigation_api/pkg/tools"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Правила жизненного цикла подписки, общие для всех хранилищ:
+//
+//	active -> paused -> active (пауза с месяца M до месяца N: месяцы [M, N) не оплачиваются)
+//	active | paused -> cancelled (end_date = месяц отмены)
+//	expired - end_date раньше текущего месяца, переходы из него запрещены, как и из cancelled
+//
+// Месяцы пробного периода (с start_date по trial_end включительно) не оплачиваются независимо от статуса.
+// Месяцы промо-периода оплачиваются по промо-цене вместо базовой.
+// Расходы совместной подписки в подсчете по пользователю - его доля (Share), без фильтра по пользователю - вся цена
+
+// maxMembers - участников совместной подписки, не считая владельца
+const maxMembers = 20
+
+// CurrentMonth - первое число месяца now в UTC
+func CurrentMonth(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ParseMonth - месяц перехода "MM-YYYY", пустая строка - текущий месяц
+func ParseMonth(month string, now time.Time) (time.Time, error) {
+	if month == "" {
+		return CurrentMonth(now), nil
+	}
+	return tools.ParseMonthYear(month)
+}
+
+// Status - статус подписки на момент now: хранимый статус или expired, если end_date уже прошел
+func Status(sub models.Subscription, now time.Time) string {
+	if sub.Status != models.StatusCancelled && sub.EndDate != nil && sub.EndDate.Before(CurrentMonth(now)) {
+		return models.StatusExpired
+	}
+	return sub.Status
+}
+
+// InTrial - входит ли месяц now в пробный период подписки
+func InTrial(sub models.Subscription, now time.Time) bool {
+	month := CurrentMonth(now)
+	return sub.TrialEnd != nil && !month.Before(sub.StartDate) && !month.After(*sub.TrialEnd)
+}
+
+// Resolve - заполняет вычисляемые поля подписки на момент now: статус, in_trial и effective_price
+func Resolve(sub *models.Subscription, now time.Time) {
+	sub.Status = Status(*sub, now)
+	sub.InTrial = InTrial(*sub, now)
+	sub.EffectivePrice = PriceAt(*sub, CurrentMonth(now))
+}
+
+// ParsePromo - промо-период из запроса, nil - промо нет
+func ParsePromo(req *models.PromoRequest) (*models.Promo, error) {
+	if req == nil {
+		return nil, nil
+	}
+	from, err := tools.ParseMonthYear(req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := tools.ParseMonthYear(req.To)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Promo{From: from, To: to, Price: req.Price, Percent: req.Percent}, nil
+}
+
+// ValidPromo - те же условия, что subscriptions_promo_check в схеме: from <= to,
+// задано ровно одно из price (>= 0) и percent (1-100)
+func ValidPromo(promo models.Promo) bool {
+	if promo.To.Before(promo.From) || (promo.Price == nil) == (promo.Percent == nil) {
+		return false
+	}
+	if promo.Price != nil {
+		return *promo.Price >= 0
+	}
+	return *promo.Percent >= 1 && *promo.Percent <= 100
+}
+
+// PriceAt - цена месяца month: промо-цена внутри промо-периода, иначе базовая.
+// Скидка в процентах округляется до целого
+func PriceAt(sub models.Subscription, month time.Time) int {
+	promo := sub.Promo
+	if promo == nil || month.Before(promo.From) || month.After(promo.To) {
+		return sub.Price
+	}
+	if promo.Price != nil {
+		return *promo.Price
+	}
+	return (sub.Price*(100-*promo.Percent) + 50) / 100
+}
+
+// TrialWindow - границы trial_end для пробных периодов, после которых оплата начнется в ближайшие days дней:
+// from <= trial_end < before. Оплата начинается с месяца, следующего за trial_end
+func TrialWindow(now time.Time, days int) (from, before time.Time) {
+	return CurrentMonth(now), CurrentMonth(now.AddDate(0, 0, days))
+}
+
+// ValidFilter - можно ли фильтровать список по status
+func ValidFilter(status string) bool {
+	switch status {
+	case "", models.StatusActive, models.StatusPaused, models.StatusCancelled, models.StatusExpired:
+		return true
+	}
+	return false
+}
+
+// Apply - переводит sub в status с месяца month: меняет статус, время перехода, паузы и end_date.
+// Недопустимый переход - dberrors.SubscriptionStatusError, месяц вне периода подписки - dberrors.SubscriptionDateError
+func Apply(sub *models.Subscription, status string, month, now time.Time) error {
+	current := Status(*sub, now)
+	if month.Before(sub.StartDate) || (sub.EndDate != nil && month.After(*sub.EndDate)) {
+		return dberrors.SubscriptionDateError
+	}
+
+	switch {
+	case status == models.StatusPaused && current == models.StatusActive:
+		if last := lastPause(sub); last != nil && (last.To == nil || month.Before(*last.To)) {
+			return dberrors.SubscriptionDateError
+		}
+		sub.Pauses = append(sub.Pauses, models.Pause{From: month, PausedAt: now})
+	case status == models.StatusActive && current == models.StatusPaused:
+		open := lastPause(sub)
+		if open == nil || open.To != nil {
+			return dberrors.SubscriptionStatusError
+		}
+		if month.Before(open.From) {
+			return dberrors.SubscriptionDateError
+		}
+		open.To, open.ResumedAt = &month, &now
+	case status == models.StatusCancelled && (current == models.StatusActive || current == models.StatusPaused):
+		if last := lastPause(sub); last != nil && month.Before(last.From) {
+			return dberrors.SubscriptionDateError
+		}
+		sub.EndDate, sub.CancelledAt = &month, &now
+	default:
+		return dberrors.SubscriptionStatusError
+	}
+
+	sub.Status = status
+	sub.StatusChangedAt = now
+	return nil
+}
+
+// Charge - сколько подписка добавляет к сумме за [from, to]: цена первого оплачиваемого месяца периода, 0 - таких месяцев нет
+func Charge(sub models.Subscription, from, to time.Time) int {
+	month, ok := firstBillable(sub, from, to)
+	if !ok {
+		return 0
+	}
+	return PriceAt(sub, month)
+}
+
+// Total - сумма Charge подписок за [from, to], для userID - сумма его долей. uuid.Nil - без фильтра по пользователю
+func Total(subscriptions []models.Subscription, userID uuid.UUID, from, to time.Time) int {
+	total := 0
+	for _, sub := range subscriptions {
+		total += Share(sub, userID, Charge(sub, from, to))
+	}
+	return total
+}
+
+// ByCategory - Total по категориям
+func ByCategory(subscriptions []models.Subscription, userID uuid.UUID, from, to time.Time) map[string]int {
+	totals := make(map[string]int)
+	for _, sub := range subscriptions {
+		if charge := Share(sub, userID, Charge(sub, from, to)); charge > 0 {
+			totals[sub.Category] += charge
+		}
+	}
+	return totals
+}
+
+// Share - доля пользователя userID в сумме amount подписки sub. uuid.Nil - вся сумма.
+// Остаток от деления нацело достается владельцу, поэтому доли в сумме всегда дают amount
+func Share(sub models.Subscription, userID uuid.UUID, amount int) int {
+	if userID == uuid.Nil {
+		return amount
+	}
+	if sub.Sharing == nil {
+		if userID == sub.UserID {
+			return amount
+		}
+		return 0
+	}
+
+	shares := 0
+	for _, member := range sub.Sharing.Members {
+		share := memberShare(*sub.Sharing, member, amount)
+		if member.UserID == userID {
+			return share
+		}
+		shares += share
+	}
+	if userID == sub.UserID {
+		return amount - shares
+	}
+	return 0
+}
+
+// Users - владелец подписки и участники, если подписка совместная
+func Users(sub *models.Subscription) []uuid.UUID {
+	users := []uuid.UUID{sub.UserID}
+	if sub.Sharing != nil {
+		for _, member := range sub.Sharing.Members {
+			users = append(users, member.UserID)
+		}
+	}
+	return users
+}
+
+// memberShare - доля участника (не владельца) в сумме amount
+func memberShare(sharing models.Sharing, member models.Member, amount int) int {
+	switch sharing.Split {
+	case models.SplitEqual:
+		return amount / (len(sharing.Members) + 1)
+	case models.SplitPercentage:
+		return amount * member.Percent / 100
+	default:
+		return 0
+	}
+}
+
+// ValidateSharing - текст ошибки для клиента про совместную подписку владельца owner, пустая строка - корректна.
+// nil - подписка не совместная
+func ValidateSharing(owner uuid.UUID, sharing *models.Sharing) string {
+	if sharing == nil {
+		return ""
+	}
+	switch sharing.Split {
+	case models.SplitEqual, models.SplitPercentage, models.SplitPayer:
+	default:
+		return "sharing.split must be equal, percentage or payer"
+	}
+	if len(sharing.Members) == 0 || len(sharing.Members) > maxMembers {
+		return "sharing.members must have from 1 to 20 members"
+	}
+
+	seen := make(map[uuid.UUID]bool, len(sharing.Members))
+	percents := 0
+	for _, member := range sharing.Members {
+		switch {
+		case member.UserID == uuid.Nil:
+			return "sharing.members must have user_id"
+		case member.UserID == owner:
+			return "sharing.members must not include the subscription owner"
+		case seen[member.UserID]:
+			return "sharing.members must not repeat"
+		}
+		seen[member.UserID] = true
+
+		if sharing.Split != models.SplitPercentage {
+			if member.Percent != 0 {
+				return "sharing.members percent is allowed only with split percentage"
+			}
+			continue
+		}
+		if member.Percent < 1 || member.Percent > 100 {
+			return "sharing.members percent must be from 1 to 100"
+		}
+		percents += member.Percent
+	}
+	if percents > 100 {
+		return "sharing.members percents must add up to at most 100"
+	}
+	return ""
+}
+
+// CategoryTotals - суммы по категориям по убыванию, при равенстве - по категории. Нулевые суммы не попадают
+func CategoryTotals(totals map[string]int) []models.CategoryTotal {
+	var result []models.CategoryTotal
+	for category, total := range totals {
+		if total > 0 {
+			result = append(result, models.CategoryTotal{Category: category, Total: total})
+		}
+	}
+	slices.SortFunc(result, func(a, b models.CategoryTotal) int {
+		if a.Total != b.Total {
+			return b.Total - a.Total
+		}
+		return strings.Compare(a.Category, b.Category)
+	})
+	return result
+}
+
+// firstBillable - первый оплачиваемый месяц в [from, to]: внутри периода подписки, после пробного периода и не на паузе
+func firstBillable(sub models.Subscription, from, to time.Time) (time.Time, bool) {
+	if sub.StartDate.After(from) {
+		from = sub.StartDate
+	}
+	if sub.TrialEnd != nil && !sub.TrialEnd.Before(from) {
+		from = sub.TrialEnd.AddDate(0, 1, 0)
+	}
+	if sub.EndDate != nil && sub.EndDate.Before(to) {
+		to = *sub.EndDate
+	}
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		if !paused(sub, month) {
+			return month, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// paused - month попадает в одну из пауз
+func paused(sub models.Subscription, month time.Time) bool {
+	for _, pause := range sub.Pauses {
+		if !month.Before(pause.From) && (pause.To == nil || month.Before(*pause.To)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastPause - последняя пауза (паузы хранятся по возрастанию From), nil - пауз не было
+func lastPause(sub *models.Subscription) *models.Pause {
+	if len(sub.Pauses) == 0 {
+		return nil
+	}
+	return &sub.Pauses[len(sub.Pauses)-1]
+}
@@ -0,0 +1,154 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Правила метаданных подписки, общие для всех хранилищ:
+//
+//	метаданные - JSON-объект с произвольными значениями, хранится целиком и заменяется при обновлении;
+//	фильтр списка metadata.<key>=<value> находит подписки, у которых значение ключа - строка value
+
+// FilterPrefix - префикс параметров запроса списка, фильтрующих по метаданным
+const FilterPrefix = "metadata."
+
+// Ограничения метаданных
+const (
+	maxKeys      = 50
+	maxKeyLength = 64
+	maxSize      = 4096 // байт в JSON
+)
+
+// Validate - текст ошибки для клиента, пустая строка - метаданные корректны
+func Validate(metadata map[string]any) string {
+	if len(metadata) > maxKeys {
+		return "metadata must have at most 50 keys"
+	}
+	for key := range metadata {
+		if !ValidKey(key) {
+			return fmt.Sprintf("metadata key %q is invalid: use up to 64 letters, digits, '_' or '-', starting with a letter", key)
+		}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return "metadata must be a JSON object"
+	}
+	if len(encoded) > maxSize {
+		return "metadata must be at most 4096 bytes"
+	}
+	return ""
+}
+
+// ValidKey - ключ из латинских букв, цифр, '_' и '-', начинается с буквы
+func ValidKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '_' || r == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Encode - метаданные в том виде, в котором они хранятся: JSON-объект, без метаданных - "{}"
+func Encode(metadata map[string]any) (string, error) {
+	if len(metadata) == 0 {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("metadata: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// Decode - метаданные из хранимого JSON-объекта, nil - метаданных нет
+func Decode(encoded []byte) (map[string]any, error) {
+	var metadata map[string]any
+	if err := json.Unmarshal(encoded, &metadata); err != nil {
+		return nil, fmt.Errorf("metadata: %w", err)
+	}
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	return metadata, nil
+}
+
+// Normalize - метаданные после сохранения и чтения из хранилища: числа - float64, пустые - nil.
+// Возвращает независимую копию
+func Normalize(metadata map[string]any) (map[string]any, error) {
+	encoded, err := Encode(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return Decode([]byte(encoded))
+}
+
+// Clone - глубокая копия метаданных после Normalize: вложенные объекты и массивы не разделяются
+func Clone(metadata map[string]any) map[string]any {
+	if metadata == nil {
+		return nil
+	}
+	return cloneValue(metadata).(map[string]any)
+}
+
+// cloneValue - копия значения, разобранного encoding/json
+func cloneValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for key, item := range value {
+			copied[key] = cloneValue(item)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(value))
+		for i, item := range value {
+			copied[i] = cloneValue(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// ParseFilter - фильтр metadata.<key>=<value> из параметров запроса, nil - фильтра нет.
+// Второе значение - текст ошибки для клиента
+func ParseFilter(query url.Values) (map[string]string, string) {
+	var filter map[string]string
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, FilterPrefix)
+		if !ok {
+			continue
+		}
+		if !ValidKey(key) {
+			return nil, fmt.Sprintf("metadata filter key %q is invalid", key)
+		}
+		if len(values) != 1 {
+			return nil, fmt.Sprintf("metadata filter %q must be given once", param)
+		}
+		if filter == nil {
+			filter = make(map[string]string)
+		}
+		filter[key] = values[0]
+	}
+	return filter, ""
+}
+
+// Matches - у метаданных есть все пары фильтра со строковыми значениями
+func Matches(metadata map[string]any, filter map[string]string) bool {
+	for key, want := range filter {
+		if value, ok := metadata[key].(string); !ok || value != want {
+			return false
+		}
+	}
+	return true
+}
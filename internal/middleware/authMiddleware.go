@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/internal/request"
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/config"
+	logger2 "agrigation_api/pkg/logger"
+	"agrigation_api/pkg/logger/logger"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// AuthMiddleware - проверка API-ключа для запросов к /api/. Health check и swagger доступны без ключа.
+// Ключ из auth.tenant_keys привязывает запрос к своей организации (tenant.WithBoundKey).
+// Запрос без X-Actor выполняется от имени ключа (keyActor).
+// Настройки берутся из store на каждый запрос, поэтому ключи обновляются при перезагрузке конфига
+func AuthMiddleware(store *config.Store, logs logger2.MyLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conf := store.Get().Auth
+		if !conf.Enabled || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := r.Header.Get(conf.Header)
+		if organization, ok := tenantKey(conf.TenantKeys, key); ok {
+			next.ServeHTTP(w, r.WithContext(tenant.WithBoundKey(withKeyActor(r.Context(), key), organization)))
+			return
+		}
+		if !validAPIKey(conf.APIKeys, key) {
+			logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: request with invalid api key",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Write(w, r, http.StatusUnauthorized, problem.Unauthorized, "Invalid or missing API key")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withKeyActor(r.Context(), key)))
+	})
+}
+
+// withKeyActor - автор запроса без X-Actor - ключ: api-key и первые байты SHA-256 ключа, сам ключ не сохраняется
+func withKeyActor(ctx context.Context, key string) context.Context {
+	if request.Actor(ctx) != anonymous {
+		return ctx
+	}
+	sum := sha256.Sum256([]byte(key))
+	return request.WithActor(ctx, "api-key:"+hex.EncodeToString(sum[:4]))
+}
+
+// tenantKey - организация ключа из tenant_keys, сравнение за постоянное время
+func tenantKey(keys map[string]string, key string) (uuid.UUID, bool) {
+	if key == "" {
+		return uuid.Nil, false
+	}
+	organization, found := uuid.Nil, false
+	for k, id := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			organization, found = uuid.MustParse(id), true
+		}
+	}
+	return organization, found
+}
+
+// validAPIKey - сравнение ключей за постоянное время
+func validAPIKey(keys []string, key string) bool {
+	if key == "" {
+		return false
+	}
+	valid := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
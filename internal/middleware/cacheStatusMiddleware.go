@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"agrigation_api/internal/cache"
+	"net/http"
+)
+
+// HeaderCache - заголовок ответа со статусом кеша: HIT, MISS или BYPASS
+const HeaderCache = "X-Cache"
+
+// CacheStatusMiddleware - передает в контекст запроса cache.Recorder и перед отправкой ответа
+// выставляет X-Cache, если сервис обращался к кешу
+func CacheStatusMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, recorder := cache.WithRecorder(r.Context())
+		next.ServeHTTP(&cacheStatusWriter{ResponseWriter: w, recorder: recorder}, r.WithContext(ctx))
+	})
+}
+
+// cacheStatusWriter - добавляет X-Cache к заголовкам при первой записи ответа
+type cacheStatusWriter struct {
+	http.ResponseWriter
+	recorder    *cache.Recorder
+	wroteHeader bool
+}
+
+func (w *cacheStatusWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status := w.recorder.Status(); status != "" {
+			w.Header().Set(HeaderCache, string(status))
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *cacheStatusWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheStatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
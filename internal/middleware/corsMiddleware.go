@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"agrigation_api/pkg/config"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// CORSMiddleware - CORS-заголовки для разрешенных источников и ответ на preflight-запросы
+func CORSMiddleware(store *config.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		conf := store.Get()
+		allowed := conf.Server.CORS.AllowedOrigins
+		w.Header().Add("Vary", "Origin")
+		if !slices.Contains(allowed, "*") && !slices.Contains(allowed, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers",
+				strings.Join([]string{"Content-Type", "If-Match", "If-None-Match", "Idempotency-Key", HeaderReadYourWrites, HeaderTenant, conf.Auth.Header}, ", "))
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", HeaderCache+", ETag, Idempotent-Replayed")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"agrigation_api/internal/problem"
 	logger2 "agrigation_api/pkg/logger"
 	"agrigation_api/pkg/logger/logger"
 	"net/http"
@@ -11,7 +12,7 @@ func PanicMiddleware(log logger2.MyLogger, next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Error("Panic: "+err.(error).Error(), logger.GetPlace())
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				problem.Write(w, r, http.StatusInternalServerError, problem.Internal, "Internal server error")
 			}
 		}()
 
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/internal/request"
+	"agrigation_api/pkg/config"
+	logger2 "agrigation_api/pkg/logger"
+	"agrigation_api/pkg/logger/logger"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// subscriptionsPath - условные запросы (If-Match) поддерживают только подписки
+const subscriptionsPath = "/api/v1/subscriptions/"
+
+// PreconditionMiddleware - If-Match запроса к подпискам в контексте (request.WithIfMatch): сервис меняет
+// подписку, только если ее ETag совпадает, иначе 412. С features.require_if_match PUT и DELETE
+// без If-Match - 428. Настройка читается при старте, как и остальные features
+func PreconditionMiddleware(store *config.Store, logs logger2.MyLogger, next http.Handler) http.Handler {
+	required := store.Get().Features.RequireIfMatch
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, subscriptionsPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := r.Header.Get("If-Match")
+		if header != "" {
+			next.ServeHTTP(w, r.WithContext(request.WithIfMatch(r.Context(), header)))
+			return
+		}
+		if required && (r.Method == http.MethodPut || r.Method == http.MethodDelete) {
+			logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: request without required If-Match",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Write(w, r, http.StatusPreconditionRequired, problem.PreconditionRequired, "If-Match header is required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
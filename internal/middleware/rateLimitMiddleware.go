@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/pkg/config"
+	logger2 "agrigation_api/pkg/logger"
+	"agrigation_api/pkg/logger/logger"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// bucketIdleTTL - через сколько удаляется корзина клиента без запросов
+const bucketIdleTTL = 10 * time.Minute
+
+// tokenBucket - корзина токенов одного клиента
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter - лимит запросов по IP клиента, лимиты читаются из конфига на каждый запрос
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastClean time.Time
+}
+
+// RateLimitMiddleware - ограничение числа запросов с одного IP (token bucket)
+func RateLimitMiddleware(store *config.Store, logs logger2.MyLogger, next http.Handler) http.Handler {
+	limiter := &rateLimiter{buckets: make(map[string]*tokenBucket), lastClean: time.Now()}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := store.Get().Server.RateLimit
+		if limit.RequestsPerSecond <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, retryAfter := limiter.allow(clientIP(r), limit, time.Now())
+		if !allowed {
+			logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: rate limit exceeded",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			problem.Write(w, r, http.StatusTooManyRequests, problem.RateLimited, "Rate limit exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *rateLimiter) allow(key string, limit config.RateLimitConfig, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastClean) > bucketIdleTTL {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > bucketIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastClean = now
+	}
+
+	burst := float64(limit.Burst)
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, lastSeen: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*limit.RequestsPerSecond)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / limit.RequestsPerSecond * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// clientIP - IP клиента без порта
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
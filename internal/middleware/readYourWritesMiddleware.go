@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"agrigation_api/internal/database/consistency"
+	"net/http"
+	"strconv"
+)
+
+// HeaderReadYourWrites - заголовок, с которым чтения запроса идут в основную БД, а не в реплики.
+// Нужен клиенту, который читает сразу после записи и не может ждать репликации
+const HeaderReadYourWrites = "X-Read-Your-Writes"
+
+// ReadYourWritesMiddleware - помечает контекст запроса с заголовком X-Read-Your-Writes: true
+func ReadYourWritesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if required, err := strconv.ParseBool(r.Header.Get(HeaderReadYourWrites)); err == nil && required {
+			r = r.WithContext(consistency.WithPrimary(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
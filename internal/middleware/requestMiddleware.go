@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"agrigation_api/internal/request"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// HeaderRequestID - id запроса: из заголовка клиента или новый, возвращается в ответе
+	HeaderRequestID = "X-Request-ID"
+	// HeaderActor - пользователь, от имени которого клиент выполняет запрос. Попадает в журнал аудита
+	HeaderActor = "X-Actor"
+)
+
+// maxHeaderValue - предел длины X-Request-ID и X-Actor, как колонок request_id и actor журнала аудита
+const maxHeaderValue = 200
+
+// anonymous - автор запроса без X-Actor при выключенной авторизации
+const anonymous = "anonymous"
+
+// RequestMiddleware - id запроса и его автор в контексте (пакет request). Некорректный X-Request-ID
+// заменяется новым, без X-Actor автором считается anonymous, AuthMiddleware заменяет его API-ключом
+func RequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if !validHeaderValue(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(HeaderRequestID, id)
+
+		actor := strings.TrimSpace(r.Header.Get(HeaderActor))
+		if !validHeaderValue(actor) {
+			actor = anonymous
+		}
+
+		ctx := request.WithActor(request.WithID(r.Context(), id), actor)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validHeaderValue - непустое значение без управляющих символов, не длиннее maxHeaderValue
+func validHeaderValue(value string) bool {
+	if value == "" || len(value) > maxHeaderValue {
+		return false
+	}
+	return strings.IndexFunc(value, unicode.IsControl) < 0
+}
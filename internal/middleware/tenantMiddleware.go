@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"agrigation_api/internal/problem"
+	"agrigation_api/internal/tenant"
+	logger2 "agrigation_api/pkg/logger"
+	"agrigation_api/pkg/logger/logger"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// HeaderTenant - заголовок с id организации, с данными которой работает запрос
+const HeaderTenant = "X-Tenant-ID"
+
+// TenantMiddleware - организация запроса к /api/ из заголовка X-Tenant-ID, без заголовка - tenant.Default.
+// Ключ, привязанный к организации, другую организацию выбрать не может: 403
+func TenantMiddleware(logs logger2.MyLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(HeaderTenant)
+		if header == "" || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := uuid.Parse(header)
+		if err != nil {
+			logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: request with invalid tenant id",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Invalid(w, r, HeaderTenant, "Invalid "+HeaderTenant)
+			return
+		}
+		if tenant.Bound(r.Context()) && id != tenant.ID(r.Context()) {
+			logs.Warning(fmt.Sprintf("Client: %s; EndPoint: %s; Method: %s; Time: %v; Message: tenant api key used for another organization",
+				r.RemoteAddr, r.URL, r.Method, logger.TimeFormat), logger.GetPlace())
+			problem.Write(w, r, http.StatusForbidden, problem.Forbidden, "API key is bound to another organization")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), id)))
+	})
+}
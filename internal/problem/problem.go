@@ -0,0 +1,86 @@
+// Package problem - ошибки API в формате RFC 9457 (application/problem+json)
+package problem
+
+import (
+	"agrigation_api/internal/request"
+	"agrigation_api/pkg/models"
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType - тип ответа с ошибкой
+const ContentType = "application/problem+json"
+
+// typePrefix - type ошибки: URN с ее кодом, не зависит от адреса, на котором развернут сервис
+const typePrefix = "urn:problem-type:"
+
+// Коды ошибок. Они стабильны: клиенты разбирают ответ по code (или type), а не по тексту detail
+const (
+	MalformedRequest     = "malformed_request" // тело запроса - не JSON или поле неверного типа
+	ValidationFailed     = "validation_failed" // некорректные параметры или поля, подробности - в errors
+	Unauthorized         = "unauthorized"
+	Forbidden            = "forbidden"
+	NotFound             = "not_found"
+	MethodNotAllowed     = "method_not_allowed"
+	RateLimited          = "rate_limited"
+	PreconditionRequired = "precondition_required"
+	ServiceUnavailable   = "service_unavailable"
+	Internal             = "internal_error"
+
+	SubscriptionNotFound = "subscription_not_found"
+	SubscriptionExists   = "subscription_already_exists"
+	SubscriptionModified = "subscription_modified" // If-Match не совпал с версией подписки
+	StatusTransition     = "status_transition_not_allowed"
+	InvalidPeriod        = "invalid_period" // месяц вне периода подписки или начало периода позже конца
+	PriceRequired        = "price_required"
+	UnknownUser          = "unknown_user" // features.strict_users: пользователь не заведен
+
+	ServiceNotFound      = "service_not_found"
+	ServiceExists        = "service_already_exists"
+	OrganizationNotFound = "organization_not_found"
+	OrganizationExists   = "organization_already_exists"
+	OrganizationNotEmpty = "organization_not_empty"
+	DefaultOrganization  = "default_organization"
+	MemberNotFound       = "member_not_found"
+	UserNotFound         = "user_not_found"
+	UserExists           = "user_already_exists"
+
+	IdempotencyKeyReused = "idempotency_key_reused" // Idempotency-Key с другим запросом
+	IdempotencyKeyInUse  = "idempotency_key_in_use" // запрос с этим ключом еще выполняется
+)
+
+// Write - ответ с ошибкой status, кодом code и описанием detail для человека
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	WriteProblem(w, r, models.Problem{Status: status, Code: code, Detail: detail})
+}
+
+// Invalid - 400 validation_failed из-за одного поля или параметра. Пустой field - ошибка не относится к одному полю
+func Invalid(w http.ResponseWriter, r *http.Request, field, message string) {
+	p := models.Problem{Status: http.StatusBadRequest, Code: ValidationFailed, Detail: message}
+	if field != "" {
+		p.Errors = []models.FieldError{{Field: field, Message: message}}
+	}
+	WriteProblem(w, r, p)
+}
+
+// InvalidFields - 400 validation_failed со всеми ошибками полей запроса
+func InvalidFields(w http.ResponseWriter, r *http.Request, fields []models.FieldError) {
+	detail := "Request has an invalid field"
+	if len(fields) == 1 {
+		detail = fields[0].Message
+	} else if len(fields) > 1 {
+		detail = "Request has invalid fields"
+	}
+	WriteProblem(w, r, models.Problem{Status: http.StatusBadRequest, Code: ValidationFailed, Detail: detail, Errors: fields})
+}
+
+// WriteProblem - ответ с ошибкой p: type и title выводятся из кода и статуса, request_id - из контекста запроса
+func WriteProblem(w http.ResponseWriter, r *http.Request, p models.Problem) {
+	p.Type = typePrefix + p.Code
+	p.Title = http.StatusText(p.Status)
+	p.RequestID = request.ID(r.Context())
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,42 @@
+package request
+
+import "context"
+
+type idKey struct{}
+
+type actorKey struct{}
+
+// WithID - контекст запроса с его id (X-Request-ID)
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// ID - id запроса, пусто - вызов не из HTTP-запроса
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// WithActor - контекст с тем, кто выполняет запрос: пользователь из X-Actor или API-ключ
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor - кто выполняет запрос, пусто - не задан
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+type ifMatchKey struct{}
+
+// WithIfMatch - контекст с заголовком If-Match: изменение подписки выполняется, только если ее ETag совпадает
+func WithIfMatch(ctx context.Context, header string) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, header)
+}
+
+// IfMatch - заголовок If-Match запроса, false - запрос без условия
+func IfMatch(ctx context.Context) (string, bool) {
+	header, ok := ctx.Value(ifMatchKey{}).(string)
+	return header, ok
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"agrigation_api/internal/cache"
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/database/consistency"
+	"agrigation_api/internal/tenant"
+	logger2 "agrigation_api/pkg/logger"
+	"agrigation_api/pkg/logger/logger"
+	"agrigation_api/pkg/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tagAll - тег подсчета без фильтров, его меняет любая запись
+const tagAll = "all"
+
+// tagCatalog - тег каталога сервисов. Его поколение входит в каждый ключ: переименование сервиса
+// меняет имена подписок и разрешение алиасов
+const tagCatalog = "catalog"
+
+// CachedSubscriptions - read-through кеш подсчета расходов и списков подписок пользователя поверх Subscriptions.
+// Запись (Create, Update, Delete, Restore, ChangeStatus, DeleteUser) инвалидирует теги пользователя, участников совместной подписки и сервиса,
+// запись каталога - весь кеш организации. Теги у каждой организации свои. Ошибки кеша не ломают запросы:
+// они пишутся в лог, а данные читаются из хранилища
+type CachedSubscriptions struct {
+	Subscriptions
+	store cache.Store
+	ttl   time.Duration
+	logs  logger2.MyLogger
+}
+
+// NewCachedSubscriptions - кеш поверх next
+func NewCachedSubscriptions(next Subscriptions, store cache.Store, ttl time.Duration, logs logger2.MyLogger) *CachedSubscriptions {
+	return &CachedSubscriptions{Subscriptions: next, store: store, ttl: ttl, logs: logs}
+}
+
+func (s *CachedSubscriptions) CreateSubscription(ctx context.Context, req models.CreateOrUpdateRequest) (*models.Subscription, error) {
+	sub, err := s.Subscriptions.CreateSubscription(ctx, req)
+	if err == nil {
+		s.invalidate(ctx, sub.UserID, sub.ServiceName, members(sub)...)
+	}
+	return sub, err
+}
+
+func (s *CachedSubscriptions) UpdateSubscription(ctx context.Context, req models.CreateOrUpdateRequest) (*models.Subscription, error) {
+	previous := s.previousMembers(ctx, req.UserID, req.ServiceName)
+	sub, err := s.Subscriptions.UpdateSubscription(ctx, req)
+	if err == nil {
+		s.invalidate(ctx, sub.UserID, sub.ServiceName, append(previous, members(sub)...)...)
+	}
+	return sub, err
+}
+
+func (s *CachedSubscriptions) DeleteSubscription(ctx context.Context, userID uuid.UUID, name string) error {
+	name, err := s.Subscriptions.CanonicalServiceName(ctx, name)
+	if err != nil {
+		return err
+	}
+	previous := s.previousMembers(ctx, userID, name)
+	err = s.Subscriptions.DeleteSubscription(ctx, userID, name)
+	if err == nil {
+		s.invalidate(ctx, userID, name, previous...)
+	}
+	return err
+}
+
+func (s *CachedSubscriptions) RestoreSubscription(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	sub, err := s.Subscriptions.RestoreSubscription(ctx, id)
+	if err == nil {
+		s.invalidate(ctx, sub.UserID, sub.ServiceName, members(sub)...)
+	}
+	return sub, err
+}
+
+func (s *CachedSubscriptions) ChangeStatus(ctx context.Context, req models.StatusChangeRequest) (*models.Subscription, error) {
+	sub, err := s.Subscriptions.ChangeStatus(ctx, req)
+	if err == nil {
+		s.invalidate(ctx, sub.UserID, sub.ServiceName, members(sub)...)
+	}
+	return sub, err
+}
+
+func (s *CachedSubscriptions) DeleteUser(ctx context.Context, id uuid.UUID, cascade bool) ([]models.Subscription, error) {
+	affected, err := s.Subscriptions.DeleteUser(ctx, id, cascade)
+	// подписки, удаленные или отмененные до ошибки, тоже изменились
+	for _, sub := range affected {
+		s.invalidate(ctx, sub.UserID, sub.ServiceName, members(&sub)...)
+	}
+	return affected, err
+}
+
+func (s *CachedSubscriptions) CreateService(ctx context.Context, req models.ServiceRequest) (*models.Service, error) {
+	service, err := s.Subscriptions.CreateService(ctx, req)
+	if err == nil {
+		s.incr(ctx, tagCatalog)
+	}
+	return service, err
+}
+
+func (s *CachedSubscriptions) UpdateService(ctx context.Context, id uuid.UUID, req models.ServiceRequest) (*models.Service, error) {
+	service, err := s.Subscriptions.UpdateService(ctx, id, req)
+	if err == nil {
+		s.incr(ctx, tagCatalog)
+	}
+	return service, err
+}
+
+func (s *CachedSubscriptions) DeleteService(ctx context.Context, id uuid.UUID) error {
+	err := s.Subscriptions.DeleteService(ctx, id)
+	if err == nil {
+		s.incr(ctx, tagCatalog)
+	}
+	return err
+}
+
+func (s *CachedSubscriptions) ListSubscriptions(ctx context.Context, userID uuid.UUID, filter models.ListFilter) ([]models.Subscription, error) {
+	return readThrough(ctx, s, userTag(userID), listKey(filter), func() ([]models.Subscription, error) {
+		return s.Subscriptions.ListSubscriptions(ctx, userID, filter)
+	})
+}
+
+func (s *CachedSubscriptions) CalculateTotal(ctx context.Context, req models.CalculateTotalRequest) (int, error) {
+	req, err := s.canonical(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	return readThrough(ctx, s, totalTag(req), totalKey(req), func() (int, error) {
+		return s.Subscriptions.CalculateTotal(ctx, req)
+	})
+}
+
+func (s *CachedSubscriptions) TotalByCategory(ctx context.Context, req models.CalculateTotalRequest) ([]models.CategoryTotal, error) {
+	req, err := s.canonical(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return readThrough(ctx, s, totalTag(req), "categories:"+totalKey(req), func() ([]models.CategoryTotal, error) {
+		return s.Subscriptions.TotalByCategory(ctx, req)
+	})
+}
+
+// canonical - тег сервиса инвалидируется по каноническому имени, поэтому ключ подсчета строится по нему же
+func (s *CachedSubscriptions) canonical(ctx context.Context, req models.CalculateTotalRequest) (models.CalculateTotalRequest, error) {
+	if req.ServiceName == "" {
+		return req, nil
+	}
+	name, err := s.Subscriptions.CanonicalServiceName(ctx, req.ServiceName)
+	req.ServiceName = name
+	return req, err
+}
+
+// readThrough - значение из кеша или из load с записью в кеш. Ключ включает поколение тега,
+// прочитанное до load, поэтому результат, посчитанный во время инвалидации, в кеш не попадет
+func readThrough[T any](ctx context.Context, s *CachedSubscriptions, tag, key string, load func() (T, error)) (T, error) {
+	if consistency.PrimaryRequired(ctx) {
+		cache.Record(ctx, cache.Bypass)
+		return load()
+	}
+
+	tag = scoped(ctx, tag)
+	generation, err := s.store.Generation(ctx, tag)
+	var catalogGeneration int64
+	if err == nil {
+		catalogGeneration, err = s.store.Generation(ctx, scoped(ctx, tagCatalog))
+	}
+	if err != nil {
+		s.logs.Warning(fmt.Sprintf("Cache generation error: %v", err), logger.GetPlace())
+		cache.Record(ctx, cache.Miss)
+		return load()
+	}
+	fullKey := fmt.Sprintf("subscriptions:%s:%d:%d:%s", tag, generation, catalogGeneration, key)
+
+	data, ok, err := s.store.Get(ctx, fullKey)
+	if err != nil {
+		s.logs.Warning(fmt.Sprintf("Cache get error: %v", err), logger.GetPlace())
+	}
+	if ok {
+		var cached T
+		if err := json.Unmarshal(data, &cached); err == nil {
+			cache.Record(ctx, cache.Hit)
+			return cached, nil
+		}
+	}
+
+	cache.Record(ctx, cache.Miss)
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	if data, err := json.Marshal(value); err == nil {
+		if err := s.store.Set(ctx, fullKey, data, s.ttl); err != nil {
+			s.logs.Warning(fmt.Sprintf("Cache set error: %v", err), logger.GetPlace())
+		}
+	}
+	return value, nil
+}
+
+// invalidate - новые поколения тегов, которые зависят от подписки userID/serviceName с участниками memberIDs
+func (s *CachedSubscriptions) invalidate(ctx context.Context, userID uuid.UUID, serviceName string, memberIDs ...uuid.UUID) {
+	tags := []string{userTag(userID), serviceTag(serviceName), tagAll}
+	for _, memberID := range memberIDs {
+		tags = append(tags, userTag(memberID))
+	}
+	s.incr(ctx, tags...)
+}
+
+// previousMembers - участники подписки до записи: после нее их подсчеты и списки тоже устаревают.
+// Ошибка чтения пишется в лог, тогда кеш участников живет до истечения TTL
+func (s *CachedSubscriptions) previousMembers(ctx context.Context, userID uuid.UUID, serviceName string) []uuid.UUID {
+	sub, err := s.Subscriptions.GetSubscription(consistency.WithPrimary(ctx), userID, serviceName)
+	if err != nil {
+		s.logs.Error(fmt.Sprintf("Cache invalidation error for %s: %v", userTag(userID), err), logger.GetPlace())
+		return nil
+	}
+	return members(sub)
+}
+
+// members - участники совместной подписки, nil - подписки нет или она не совместная
+func members(sub *models.Subscription) []uuid.UUID {
+	if sub == nil || sub.Sharing == nil {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(sub.Sharing.Members))
+	for i, member := range sub.Sharing.Members {
+		ids[i] = member.UserID
+	}
+	return ids
+}
+
+// incr - новые поколения тегов
+func (s *CachedSubscriptions) incr(ctx context.Context, tags ...string) {
+	// запись уже сделана: инвалидируем даже если клиент отменил запрос
+	ctx = context.WithoutCancel(ctx)
+	for _, tag := range tags {
+		if _, err := s.store.Incr(ctx, scoped(ctx, tag)); err != nil {
+			s.logs.Error(fmt.Sprintf("Cache invalidation error for %s: %v", tag, err), logger.GetPlace())
+		}
+	}
+}
+
+// totalTag - тег, от которого зависит подсчет: пользователь, иначе сервис, иначе все подписки.
+// Запись подписки (user, service) меняет теги user, service и all, поэтому любой затронутый подсчет
+// инвалидируется, а подсчеты других пользователей остаются в кеше
+func totalTag(req models.CalculateTotalRequest) string {
+	switch {
+	case req.UserID != uuid.Nil:
+		return userTag(req.UserID)
+	case req.ServiceName != "":
+		return serviceTag(req.ServiceName)
+	default:
+		return tagAll
+	}
+}
+
+// totalKey - нормализованный запрос: месяцы в формате YYYY-MM, пустые фильтры - "*"
+func totalKey(req models.CalculateTotalRequest) string {
+	user, service, currency := "*", "*", "*"
+	if req.UserID != uuid.Nil {
+		user = req.UserID.String()
+	}
+	if req.ServiceName != "" {
+		service = url.QueryEscape(req.ServiceName)
+	}
+	if req.Currency != "" {
+		currency = req.Currency
+	}
+	return fmt.Sprintf("total:%s:%s:%s:%s:%s:%s", user, service, tagsKey(req.Tags), currency,
+		req.StartMonth.Format("2006-01"), req.EndMonth.Format("2006-01"))
+}
+
+// listKey - нормализованный фильтр списка: статус, теги и метаданные
+func listKey(filter models.ListFilter) string {
+	return "list:" + filter.Status + ":" + tagsKey(filter.Tags) + ":" + metadataKey(filter.Metadata)
+}
+
+// metadataKey - фильтр по метаданным в порядке ключей, без фильтра - "*"
+func metadataKey(filter map[string]string) string {
+	if len(filter) == 0 {
+		return "*"
+	}
+	values := url.Values{}
+	for key, value := range filter {
+		values.Set(key, value)
+	}
+	return values.Encode()
+}
+
+// tagsKey - нормализованный фильтр по тегам, без фильтра - "*"
+func tagsKey(tags []string) string {
+	tags = catalog.Tags(tags)
+	if len(tags) == 0 {
+		return "*"
+	}
+	escaped := make([]string, len(tags))
+	for i, tag := range tags {
+		escaped[i] = url.QueryEscape(tag)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// scoped - тег в пространстве организации запроса: одинаковые пользователи и сервисы
+// разных организаций не делят кеш
+func scoped(ctx context.Context, tag string) string {
+	return "tenant:" + tenant.ID(ctx).String() + ":" + tag
+}
+
+func userTag(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+func serviceTag(serviceName string) string {
+	return "service:" + url.QueryEscape(serviceName)
+}
@@ -1,49 +1,570 @@
 package service
 
 import (
+	"agrigation_api/internal/catalog"
+	"agrigation_api/internal/database/consistency"
+	"agrigation_api/internal/database/dberrors"
 	"agrigation_api/internal/database/repository"
+	"agrigation_api/internal/etag"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/internal/request"
+	"agrigation_api/internal/tenant"
 	"agrigation_api/pkg/models"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 )
 
+// ErrPriceRequired - цена подписки не указана, а у сервиса каталога нет цены по умолчанию
+var ErrPriceRequired = errors.New("price is required: service has no default price")
+
+// ErrDefaultOrganization - организацию по умолчанию удалить нельзя: в нее попадают запросы без организации
+var ErrDefaultOrganization = errors.New("default organization can not be deleted")
+
+// ErrUnknownUser - в строгом режиме владелец или участник подписки должен быть заведен через /users
+var ErrUnknownUser = errors.New("user is not registered")
+
 type Subscriptions interface {
 	CreateSubscription(context.Context, models.CreateOrUpdateRequest) (*models.Subscription, error)
 	UpdateSubscription(context.Context, models.CreateOrUpdateRequest) (*models.Subscription, error)
 	GetSubscription(context.Context, uuid.UUID, string) (*models.Subscription, error)
 	DeleteSubscription(context.Context, uuid.UUID, string) error
-	ListSubscriptions(context.Context, uuid.UUID) ([]models.Subscription, error)
+	ListSubscriptions(context.Context, uuid.UUID, models.ListFilter) ([]models.Subscription, error)
+	ListAllSubscriptions(context.Context) ([]models.Subscription, error)
 	CalculateTotal(context.Context, models.CalculateTotalRequest) (int, error)
+	TotalByCategory(context.Context, models.CalculateTotalRequest) ([]models.CategoryTotal, error)
+	OtherCurrencyTotals(context.Context, models.CalculateTotalRequest) ([]models.CurrencyTotal, error)
+	ChangeStatus(context.Context, models.StatusChangeRequest) (*models.Subscription, error)
+	EndingTrials(context.Context, models.TrialsRequest) ([]models.Subscription, error)
+	RestoreSubscription(context.Context, uuid.UUID) (*models.Subscription, error)
+	ListDeletedSubscriptions(context.Context) ([]models.Subscription, error)
+	PurgeDeletedSubscriptions(context.Context, time.Time) (int, error)
+	ListAudit(context.Context, models.AuditFilter) ([]models.AuditEntry, error)
+	ReserveIdempotencyKey(ctx context.Context, key, requestHash string) (*models.IdempotencyRecord, error)
+	CompleteIdempotencyKey(context.Context, models.IdempotencyRecord) error
+	ReleaseIdempotencyKey(context.Context, string) error
+	PurgeIdempotencyKeys(context.Context) (int, error)
+	CanonicalServiceName(context.Context, string) (string, error)
+	ListServices(context.Context) ([]models.Service, error)
+	GetService(context.Context, uuid.UUID) (*models.Service, error)
+	CreateService(context.Context, models.ServiceRequest) (*models.Service, error)
+	UpdateService(context.Context, uuid.UUID, models.ServiceRequest) (*models.Service, error)
+	DeleteService(context.Context, uuid.UUID) error
+	ListOrganizations(context.Context) ([]models.Organization, error)
+	GetOrganization(context.Context, uuid.UUID) (*models.Organization, error)
+	CreateOrganization(context.Context, models.OrganizationRequest) (*models.Organization, error)
+	DeleteOrganization(context.Context, uuid.UUID) error
+	ListMembers(context.Context, uuid.UUID) ([]models.OrganizationMember, error)
+	AddMember(context.Context, uuid.UUID, uuid.UUID) (*models.OrganizationMember, error)
+	RemoveMember(context.Context, uuid.UUID, uuid.UUID) error
+	OrganizationTotal(ctx context.Context, id uuid.UUID, from, to time.Time) (*models.OrganizationTotal, error)
+	ListUsers(context.Context) ([]models.User, error)
+	GetUser(context.Context, uuid.UUID) (*models.User, error)
+	CreateUser(context.Context, models.UserRequest) (*models.User, error)
+	UpdateUser(context.Context, uuid.UUID, models.UserRequest) (*models.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID, cascade bool) ([]models.Subscription, error)
 }
 
+// defaultIdempotencyTTL - сколько хранится ответ запроса с Idempotency-Key, если срок не задан
+const defaultIdempotencyTTL = 24 * time.Hour
+
 type SubscriptionService struct {
-	rep repository.Repository
+	rep            repository.Repository
+	strictUsers    bool
+	idempotencyTTL time.Duration
 }
 
 func NewSubscriptionService(rep repository.Repository) *SubscriptionService {
-	return &SubscriptionService{rep}
+	return &SubscriptionService{rep: rep, idempotencyTTL: defaultIdempotencyTTL}
+}
+
+// WithStrictUsers - строгий режим: запись подписки незаведенного пользователя - ErrUnknownUser
+func (s *SubscriptionService) WithStrictUsers(strict bool) *SubscriptionService {
+	s.strictUsers = strict
+	return s
+}
+
+// WithIdempotencyTTL - сколько хранится ответ запроса с Idempotency-Key
+func (s *SubscriptionService) WithIdempotencyTTL(ttl time.Duration) *SubscriptionService {
+	s.idempotencyTTL = ttl
+	return s
 }
 
 func (s *SubscriptionService) CreateSubscription(ctx context.Context, req models.CreateOrUpdateRequest) (*models.Subscription, error) {
-	return s.rep.CreateSubscription(ctx, req)
+	req, err := s.withService(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkUsers(ctx, req); err != nil {
+		return nil, err
+	}
+	return s.rep.CreateSubscription(ctx, req, auditEntry(ctx, models.AuditCreate))
 }
 
 func (s *SubscriptionService) UpdateSubscription(ctx context.Context, req models.CreateOrUpdateRequest) (*models.Subscription, error) {
-	return s.rep.UpdateSubscription(ctx, req)
+	req, err := s.withService(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkUsers(ctx, req); err != nil {
+		return nil, err
+	}
+	if req.Version, err = s.expectedVersion(ctx, req.UserID, req.ServiceName); err != nil {
+		return nil, err
+	}
+	return s.rep.UpdateSubscription(ctx, req, auditEntry(ctx, models.AuditUpdate))
 }
 
 func (s *SubscriptionService) GetSubscription(ctx context.Context, req uuid.UUID, name string) (*models.Subscription, error) {
+	name, err := s.CanonicalServiceName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
 	return s.rep.GetSubscription(ctx, req, name)
 }
 
 func (s *SubscriptionService) DeleteSubscription(ctx context.Context, req uuid.UUID, name string) error {
-	return s.rep.DeleteSubscription(ctx, req, name)
+	name, err := s.CanonicalServiceName(ctx, name)
+	if err != nil {
+		return err
+	}
+	return s.deleteSubscription(ctx, req, name)
+}
+
+// deleteSubscription - перенос подписки в корзину с записью в журнал аудита
+func (s *SubscriptionService) deleteSubscription(ctx context.Context, userID uuid.UUID, name string) error {
+	version, err := s.expectedVersion(ctx, userID, name)
+	if err != nil {
+		return err
+	}
+	return s.rep.DeleteSubscription(ctx, userID, name, version, auditEntry(ctx, models.AuditDelete))
+}
+
+func (s *SubscriptionService) ListSubscriptions(ctx context.Context, req uuid.UUID, filter models.ListFilter) ([]models.Subscription, error) {
+	return s.rep.ListUserSubscriptions(ctx, req, filter)
 }
 
-func (s *SubscriptionService) ListSubscriptions(ctx context.Context, req uuid.UUID) ([]models.Subscription, error) {
-	return s.rep.ListUserSubscriptions(ctx, req)
+func (s *SubscriptionService) ListAllSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	return s.rep.ListAllSubscriptions(ctx)
 }
 
 func (s *SubscriptionService) CalculateTotal(ctx context.Context, req models.CalculateTotalRequest) (int, error) {
+	req, err := s.withCanonicalService(ctx, req)
+	if err != nil {
+		return 0, err
+	}
 	return s.rep.CalculateTotal(ctx, req)
 }
+
+func (s *SubscriptionService) TotalByCategory(ctx context.Context, req models.CalculateTotalRequest) ([]models.CategoryTotal, error) {
+	req, err := s.withCanonicalService(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.rep.TotalByCategory(ctx, req)
+}
+
+// OtherCurrencyTotals - CalculateTotal по фильтрам req в каждой валюте, кроме req.Currency: валюты сервисов каталога
+// и catalog.DefaultCurrency подписок на сервисы не из каталога. Валюты с нулевой суммой не возвращаются
+func (s *SubscriptionService) OtherCurrencyTotals(ctx context.Context, req models.CalculateTotalRequest) ([]models.CurrencyTotal, error) {
+	services, err := s.rep.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currencies := []string{catalog.DefaultCurrency}
+	for _, service := range services {
+		currencies = append(currencies, service.Currency)
+	}
+	slices.Sort(currencies)
+
+	var totals []models.CurrencyTotal
+	for _, currency := range slices.Compact(currencies) {
+		if currency == req.Currency {
+			continue
+		}
+		other := req
+		other.Currency = currency
+		total, err := s.CalculateTotal(ctx, other)
+		if err != nil {
+			return nil, err
+		}
+		if total != 0 {
+			totals = append(totals, models.CurrencyTotal{Currency: currency, Total: total})
+		}
+	}
+	return totals, nil
+}
+
+func (s *SubscriptionService) ChangeStatus(ctx context.Context, req models.StatusChangeRequest) (*models.Subscription, error) {
+	name, err := s.CanonicalServiceName(ctx, req.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	req.ServiceName = name
+	return s.changeStatus(ctx, req)
+}
+
+// changeStatus - переход подписки с записью в журнал аудита
+func (s *SubscriptionService) changeStatus(ctx context.Context, req models.StatusChangeRequest) (*models.Subscription, error) {
+	version, err := s.expectedVersion(ctx, req.UserID, req.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	req.Version = version
+	return s.rep.ChangeStatus(ctx, req, auditEntry(ctx, models.AuditStatusChange))
+}
+
+func (s *SubscriptionService) EndingTrials(ctx context.Context, req models.TrialsRequest) ([]models.Subscription, error) {
+	return s.rep.EndingTrials(ctx, req)
+}
+
+func (s *SubscriptionService) RestoreSubscription(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	return s.rep.RestoreSubscription(ctx, id, auditEntry(ctx, models.AuditRestore))
+}
+
+func (s *SubscriptionService) ListDeletedSubscriptions(ctx context.Context) ([]models.Subscription, error) {
+	return s.rep.ListDeletedSubscriptions(ctx)
+}
+
+// PurgeDeletedSubscriptions - очистка корзин всех организаций от подписок, удаленных раньше before.
+// Каждая организация очищается в своем контексте, чтобы работали политики RLS. Возвращает число удаленных подписок
+func (s *SubscriptionService) PurgeDeletedSubscriptions(ctx context.Context, before time.Time) (int, error) {
+	organizations, err := s.rep.ListOrganizations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, organization := range organizations {
+		count, err := s.rep.PurgeDeletedSubscriptions(tenant.WithID(ctx, organization.ID), before)
+		purged += count
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+func (s *SubscriptionService) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	return s.rep.ListAudit(ctx, filter)
+}
+
+// ReserveIdempotencyKey - занять ключ на idempotencyTTL. nil - ключ свободен и запрос надо выполнить,
+// иначе - запись ключа: выполняющийся запрос или его ответ
+func (s *SubscriptionService) ReserveIdempotencyKey(ctx context.Context, key, requestHash string) (*models.IdempotencyRecord, error) {
+	return s.rep.ReserveIdempotencyKey(ctx, models.IdempotencyRecord{
+		Key: key, RequestHash: requestHash, ExpiresAt: time.Now().Add(s.idempotencyTTL),
+	})
+}
+
+func (s *SubscriptionService) CompleteIdempotencyKey(ctx context.Context, record models.IdempotencyRecord) error {
+	return s.rep.CompleteIdempotencyKey(ctx, record)
+}
+
+func (s *SubscriptionService) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return s.rep.ReleaseIdempotencyKey(ctx, key)
+}
+
+// PurgeIdempotencyKeys - удаление истекших ключей идемпотентности всех организаций, каждой в своем контексте
+func (s *SubscriptionService) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	organizations, err := s.rep.ListOrganizations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	now := time.Now()
+	for _, organization := range organizations {
+		count, err := s.rep.PurgeIdempotencyKeys(tenant.WithID(ctx, organization.ID), now)
+		purged += count
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// CanonicalServiceName - имя сервиса каталога, найденного по имени или алиасу без учета регистра.
+// Имя, которого нет в каталоге, возвращается без пробелов по краям
+func (s *SubscriptionService) CanonicalServiceName(ctx context.Context, name string) (string, error) {
+	service, err := s.rep.ResolveService(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if service == nil {
+		return strings.TrimSpace(name), nil
+	}
+	return service.Name, nil
+}
+
+func (s *SubscriptionService) ListServices(ctx context.Context) ([]models.Service, error) {
+	return s.rep.ListServices(ctx)
+}
+
+func (s *SubscriptionService) GetService(ctx context.Context, id uuid.UUID) (*models.Service, error) {
+	return s.rep.GetService(ctx, id)
+}
+
+func (s *SubscriptionService) CreateService(ctx context.Context, req models.ServiceRequest) (*models.Service, error) {
+	return s.rep.CreateService(ctx, req)
+}
+
+func (s *SubscriptionService) UpdateService(ctx context.Context, id uuid.UUID, req models.ServiceRequest) (*models.Service, error) {
+	return s.rep.UpdateService(ctx, id, req)
+}
+
+func (s *SubscriptionService) DeleteService(ctx context.Context, id uuid.UUID) error {
+	return s.rep.DeleteService(ctx, id)
+}
+
+func (s *SubscriptionService) ListOrganizations(ctx context.Context) ([]models.Organization, error) {
+	return s.rep.ListOrganizations(ctx)
+}
+
+func (s *SubscriptionService) GetOrganization(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	return s.rep.GetOrganization(ctx, id)
+}
+
+func (s *SubscriptionService) CreateOrganization(ctx context.Context, req models.OrganizationRequest) (*models.Organization, error) {
+	return s.rep.CreateOrganization(ctx, req)
+}
+
+func (s *SubscriptionService) DeleteOrganization(ctx context.Context, id uuid.UUID) error {
+	if id == tenant.Default {
+		return ErrDefaultOrganization
+	}
+	return s.rep.DeleteOrganization(tenant.WithID(ctx, id), id)
+}
+
+// Участники читаются и меняются в контексте их организации, чтобы работали политики RLS
+
+func (s *SubscriptionService) ListMembers(ctx context.Context, id uuid.UUID) ([]models.OrganizationMember, error) {
+	return s.rep.ListMembers(tenant.WithID(ctx, id), id)
+}
+
+func (s *SubscriptionService) AddMember(ctx context.Context, id, userID uuid.UUID) (*models.OrganizationMember, error) {
+	return s.rep.AddMember(tenant.WithID(ctx, id), id, userID)
+}
+
+func (s *SubscriptionService) RemoveMember(ctx context.Context, id, userID uuid.UUID) error {
+	return s.rep.RemoveMember(tenant.WithID(ctx, id), id, userID)
+}
+
+// OrganizationTotal - расходы организации за [from, to] и доли каждого участника по тем же правилам, что CalculateTotal.
+// Считаются подписки в catalog.DefaultCurrency, суммы в других валютах - в OtherCurrencies.
+// Организации нет - dberrors.OrganizationNotFound
+func (s *SubscriptionService) OrganizationTotal(ctx context.Context, id uuid.UUID, from, to time.Time) (*models.OrganizationTotal, error) {
+	if from.After(to) {
+		return nil, dberrors.SubscriptionDateError
+	}
+	organization, err := s.rep.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if organization == nil {
+		return nil, dberrors.OrganizationNotFound
+	}
+
+	ctx = tenant.WithID(ctx, id)
+	subscriptions, err := s.rep.ListAllSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.rep.ListMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	services, err := s.rep.ListServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// валюта подписки - валюта сервиса каталога, сервиса нет в каталоге - catalog.DefaultCurrency
+	serviceCurrencies := make(map[string]string, len(services))
+	for _, service := range services {
+		serviceCurrencies[service.Name] = service.Currency
+	}
+	byCurrency := make(map[string][]models.Subscription)
+	for _, sub := range subscriptions {
+		currency, ok := serviceCurrencies[sub.ServiceName]
+		if !ok {
+			currency = catalog.DefaultCurrency
+		}
+		byCurrency[currency] = append(byCurrency[currency], sub)
+	}
+
+	subscriptions = byCurrency[catalog.DefaultCurrency]
+	total := &models.OrganizationTotal{
+		Total:    lifecycle.Total(subscriptions, uuid.Nil, from, to),
+		Currency: catalog.DefaultCurrency,
+		Users:    make([]models.UserTotal, len(members)),
+	}
+	for i, member := range members {
+		total.Users[i] = models.UserTotal{UserID: member.UserID, Total: lifecycle.Total(subscriptions, member.UserID, from, to)}
+	}
+	for _, currency := range slices.Sorted(maps.Keys(byCurrency)) {
+		if currency == catalog.DefaultCurrency {
+			continue
+		}
+		if other := lifecycle.Total(byCurrency[currency], uuid.Nil, from, to); other != 0 {
+			total.OtherCurrencies = append(total.OtherCurrencies, models.CurrencyTotal{Currency: currency, Total: other})
+		}
+	}
+	return total, nil
+}
+
+func (s *SubscriptionService) ListUsers(ctx context.Context) ([]models.User, error) {
+	return s.rep.ListUsers(ctx)
+}
+
+func (s *SubscriptionService) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return s.rep.GetUser(ctx, id)
+}
+
+func (s *SubscriptionService) CreateUser(ctx context.Context, req models.UserRequest) (*models.User, error) {
+	return s.rep.CreateUser(ctx, req)
+}
+
+func (s *SubscriptionService) UpdateUser(ctx context.Context, id uuid.UUID, req models.UserRequest) (*models.User, error) {
+	return s.rep.UpdateUser(ctx, id, req)
+}
+
+// DeleteUser - удалить пользователя и его подписки (cascade) или отменить их с текущего месяца.
+// Подписки обрабатываются до удаления профиля, поэтому после ошибки удаление можно повторить.
+// Доли пользователя в чужих совместных подписках остаются. Возвращает затронутые подписки
+func (s *SubscriptionService) DeleteUser(ctx context.Context, id uuid.UUID, cascade bool) ([]models.Subscription, error) {
+	user, err := s.rep.GetUser(consistency.WithPrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, dberrors.UserNotFound
+	}
+
+	subscriptions, err := s.rep.ListUserSubscriptions(consistency.WithPrimary(ctx), id, models.ListFilter{})
+	if err != nil {
+		return nil, err
+	}
+	var affected []models.Subscription
+	for _, sub := range subscriptions {
+		if sub.UserID != id {
+			continue
+		}
+		if cascade {
+			if err := s.deleteSubscription(ctx, id, sub.ServiceName); err != nil {
+				return affected, err
+			}
+			affected = append(affected, sub)
+			continue
+		}
+		if sub.Status != models.StatusActive && sub.Status != models.StatusPaused {
+			continue
+		}
+		month := lifecycle.CurrentMonth(time.Now())
+		if sub.StartDate.After(month) {
+			month = sub.StartDate
+		}
+		cancelled, err := s.changeStatus(ctx, models.StatusChangeRequest{
+			UserID: id, ServiceName: sub.ServiceName, Month: month.Format("01-2006"), Status: models.StatusCancelled,
+		})
+		if err != nil {
+			return affected, err
+		}
+		affected = append(affected, *cancelled)
+	}
+
+	return affected, s.rep.DeleteUser(ctx, id)
+}
+
+// auditEntry - запись журнала аудита об операции, которую репозиторий сохранит в транзакции изменения подписки.
+// Автор и id запроса берутся из контекста (пакет request), вне HTTP-запроса автор - system
+func auditEntry(ctx context.Context, operation string) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		Operation: operation,
+		Actor:     request.Actor(ctx),
+		RequestID: request.ID(ctx),
+	}
+	if entry.Actor == "" {
+		entry.Actor = "system"
+	}
+	return entry
+}
+
+// expectedVersion - версия, которую репозиторий проверит вместе с изменением подписки: 0 - запрос без If-Match.
+// If-Match не совпал с ETag текущей подписки или подписки нет - dberrors.SubscriptionVersionMismatch
+func (s *SubscriptionService) expectedVersion(ctx context.Context, userID uuid.UUID, name string) (int, error) {
+	header, ok := request.IfMatch(ctx)
+	if !ok {
+		return 0, nil
+	}
+	current, err := s.rep.GetSubscription(consistency.WithPrimary(ctx), userID, name)
+	if err != nil {
+		return 0, err
+	}
+	if current == nil || !etag.Match(header, etag.Of(current)) {
+		return 0, dberrors.SubscriptionVersionMismatch
+	}
+	return current.Version, nil
+}
+
+// checkUsers - в строгом режиме владелец и участники подписки должны быть заведены
+func (s *SubscriptionService) checkUsers(ctx context.Context, req models.CreateOrUpdateRequest) error {
+	if !s.strictUsers {
+		return nil
+	}
+	ids := []uuid.UUID{req.UserID}
+	if req.Sharing != nil {
+		for _, member := range req.Sharing.Members {
+			ids = append(ids, member.UserID)
+		}
+	}
+	for _, id := range ids {
+		user, err := s.rep.GetUser(consistency.WithPrimary(ctx), id)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("%w: %s", ErrUnknownUser, id)
+		}
+	}
+	return nil
+}
+
+// withCanonicalService - фильтр подсчета по каноническому имени сервиса
+func (s *SubscriptionService) withCanonicalService(ctx context.Context, req models.CalculateTotalRequest) (models.CalculateTotalRequest, error) {
+	if req.ServiceName == "" {
+		return req, nil
+	}
+	name, err := s.CanonicalServiceName(ctx, req.ServiceName)
+	req.ServiceName = name
+	return req, err
+}
+
+// withService - запрос с каноническим именем сервиса. Цена 0 заменяется ценой сервиса по умолчанию,
+// пустая категория - категорией сервиса
+func (s *SubscriptionService) withService(ctx context.Context, req models.CreateOrUpdateRequest) (models.CreateOrUpdateRequest, error) {
+	service, err := s.rep.ResolveService(ctx, req.ServiceName)
+	if err != nil {
+		return req, err
+	}
+
+	req.ServiceName = strings.TrimSpace(req.ServiceName)
+	if service != nil {
+		req.ServiceName = service.Name
+		if strings.TrimSpace(req.Category) == "" {
+			req.Category = service.Category
+		}
+	}
+	if req.Price == 0 {
+		if service == nil || service.DefaultPrice == nil {
+			return req, ErrPriceRequired
+		}
+		req.Price = *service.DefaultPrice
+	}
+	return req, nil
+}
@@ -0,0 +1,44 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Default - организация по умолчанию: ей принадлежат данные, созданные до появления организаций,
+// и запросы, в которых организация не выбрана
+var Default = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+type scopeKey struct{}
+
+// scope - организация запроса и привязан ли к ней API-ключ
+type scope struct {
+	id    uuid.UUID
+	bound bool
+}
+
+// WithID - контекст, в котором репозиторий работает с данными организации id
+func WithID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope{id: id, bound: Bound(ctx)})
+}
+
+// WithBoundKey - контекст запроса с ключом, привязанным к организации id: такой ключ не выбирает
+// организацию и не управляет организациями
+func WithBoundKey(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope{id: id, bound: true})
+}
+
+// ID - организация запроса, без организации - Default
+func ID(ctx context.Context) uuid.UUID {
+	if s, ok := ctx.Value(scopeKey{}).(scope); ok {
+		return s.id
+	}
+	return Default
+}
+
+// Bound - API-ключ запроса привязан к организации
+func Bound(ctx context.Context) bool {
+	s, _ := ctx.Value(scopeKey{}).(scope)
+	return s.bound
+}
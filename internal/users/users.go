@@ -0,0 +1,89 @@
+package users
+
+import (
+	"agrigation_api/internal/catalog"
+	"agrigation_api/pkg/models"
+	"net/mail"
+	"strings"
+	"time"
+	_ "time/tzdata" // часовые пояса пользователей не зависят от tzdata образа
+	"unicode/utf8"
+)
+
+// Правила профиля пользователя, общие для всех хранилищ:
+//
+//	email необязателен, хранится в нижнем регистре и уникален в организации;
+//	timezone - имя часового пояса IANA, по нему определяется текущий месяц пользователя;
+//	currency - валюта, в которой пользователь указывает цены своих подписок и получает подсчеты
+
+// DefaultTimezone - часовой пояс пользователя, если не указан
+const DefaultTimezone = "UTC"
+
+// Ограничения схемы: users.display_name VARCHAR(100), email VARCHAR(254)
+const (
+	maxNameLength  = 100
+	maxEmailLength = 254
+)
+
+// Normalize - запрос в том виде, в котором он хранится: без пробелов по краям, email в нижнем регистре,
+// часовой пояс и валюта по умолчанию
+func Normalize(req models.UserRequest) models.UserRequest {
+	req.DisplayName = strings.TrimSpace(req.DisplayName)
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.Timezone = strings.TrimSpace(req.Timezone)
+	if req.Timezone == "" {
+		req.Timezone = DefaultTimezone
+	}
+	req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+	if req.Currency == "" {
+		req.Currency = catalog.DefaultCurrency
+	}
+	return req
+}
+
+// Validate - текст ошибки для клиента, пустая строка - запрос корректен. Запрос - после Normalize
+func Validate(req models.UserRequest) string {
+	switch {
+	case req.DisplayName == "":
+		return "display_name is required"
+	case utf8.RuneCountInString(req.DisplayName) > maxNameLength:
+		return "display_name must be at most 100 characters"
+	case len(req.Email) > maxEmailLength:
+		return "email must be at most 254 characters"
+	case req.Email != "" && !validEmail(req.Email):
+		return "email is invalid"
+	case !catalog.ValidCurrency(req.Currency):
+		return "currency must be a 3-letter ISO 4217 code"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil || req.Timezone == "Local" {
+		return "timezone must be an IANA time zone name, e.g. Europe/Moscow"
+	}
+	return ""
+}
+
+// Currency - валюта подсчетов пользователя, nil - пользователя нет, валюта по умолчанию
+func Currency(user *models.User) string {
+	if user == nil || user.Currency == "" {
+		return catalog.DefaultCurrency
+	}
+	return user.Currency
+}
+
+// CurrentMonth - первое число текущего месяца пользователя в его часовом поясе (в UTC, как месяцы подписок).
+// nil - пользователя нет, месяц по UTC
+func CurrentMonth(user *models.User, now time.Time) time.Time {
+	location := time.UTC
+	if user != nil {
+		if loaded, err := time.LoadLocation(user.Timezone); err == nil {
+			location = loaded
+		}
+	}
+	now = now.In(location)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// validEmail - один адрес без имени и угловых скобок
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
@@ -1,58 +1,104 @@
 package migrations
 
 import (
-	"agrigation_api/internal/database/postgres"
-	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 )
 
-// CheckAndCreateTables - создание таблиц если их нет
-func CheckAndCreateTables() error {
-	// Проверяем, существует ли таблица subscriptions
-	db, err := postgres.InitPGPool()
+//go:embed sql/*.sql sqlite/*.sql
+var embedded embed.FS
+
+// fileNamePattern - NNNN_name.up.sql / NNNN_name.down.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration - одна версия схемы
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// All - миграции PostgreSQL, встроенные в бинарник
+func All() ([]Migration, error) {
+	return loadEmbedded("sql")
+}
+
+// SQLite - миграции SQLite, встроенные в бинарник. Версии ведутся отдельно от PostgreSQL
+func SQLite() ([]Migration, error) {
+	return loadEmbedded("sqlite")
+}
+
+func loadEmbedded(dir string) ([]Migration, error) {
+	sub, err := fs.Sub(embedded, dir)
 	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	var tableExists bool
-	errSearch := db.QueryRow(context.Background(), `
-        SELECT EXISTS (
-            SELECT FROM information_schema.tables 
-            WHERE table_name = 'subscriptions'
-        )
-    `).Scan(&tableExists)
-
-	if errSearch != nil {
-		return err
+		return nil, err
 	}
+	return Load(sub)
+}
 
-	if !tableExists {
-		_, err := db.Exec(context.Background(), `
-            CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
-            CREATE TABLE subscriptions (
-				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+// Load - читает миграции из корня fsys и сортирует по версии.
+// У каждой версии должны быть оба файла: .up.sql и .down.sql
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
 
-                user_id UUID NOT NULL,
-                service_name VARCHAR(100) NOT NULL,
+	byVersion := make(map[int64]*Migration)
+	seen := make(map[string]bool) // "версия/направление"
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %q: file name must match NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
 
-                price INTEGER NOT NULL CHECK (price > 0),
-                start_date DATE NOT NULL,
-                end_date DATE,
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %q: version must be a positive number", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
 
-                created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-                updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, migration.Name, match[2])
+		}
 
-				CONSTRAINT unique_user_service UNIQUE (user_id, service_name)
-            );
-            
-            CREATE INDEX idx_subscriptions_user ON subscriptions(user_id);
-            CREATE INDEX idx_subscriptions_service ON subscriptions(service_name);
-        `)
+		key := fmt.Sprintf("%d/%s", version, match[3])
+		if seen[key] {
+			return nil, fmt.Errorf("migration %d: duplicate %s file", version, match[3])
+		}
+		seen[key] = true
 
-		if err != nil {
-			return err
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
 		}
+	}
 
+	result := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if !seen[fmt.Sprintf("%d/up", migration.Version)] || !seen[fmt.Sprintf("%d/down", migration.Version)] {
+			return nil, fmt.Errorf("migration %04d_%s: both .up.sql and .down.sql are required", migration.Version, migration.Name)
+		}
+		result = append(result, *migration)
 	}
-	return nil
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
 }
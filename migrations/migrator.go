@@ -0,0 +1,184 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrUnknownVersion - версии нет среди встроенных миграций
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// Status - состояние одной миграции
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Missing   bool // применена в БД, но отсутствует в бинарнике
+}
+
+// Migrator - применяет и откатывает миграции, версии хранятся в таблице schema_migrations
+type Migrator struct {
+	open       func(ctx context.Context, fn func(s session) error) error
+	migrations []Migration
+}
+
+// session - соединение с БД под блокировкой миграций
+type session interface {
+	applied(ctx context.Context) (map[int64]time.Time, error)
+	apply(ctx context.Context, migration Migration) error
+	rollback(ctx context.Context, migration Migration) error
+}
+
+// NewMigrator - конструктор мигратора со встроенными миграциями PostgreSQL
+func NewMigrator(pool *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		open: func(ctx context.Context, fn func(s session) error) error {
+			return withPostgresLock(ctx, pool, fn)
+		},
+		migrations: migrations,
+	}, nil
+}
+
+// Latest - последняя известная версия
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up - применяет все непримененные миграции
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down - откатывает последние steps миграций
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	var done []Migration
+	err := m.open(ctx, func(s session) error {
+		applied, err := s.applied(ctx)
+		if err != nil {
+			return err
+		}
+		versions := sortedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && len(done) < steps; i-- {
+			migration, err := m.find(versions[i])
+			if err != nil {
+				return err
+			}
+			if err := s.rollback(ctx, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// To - приводит схему к версии version: применяет недостающие миграции или откатывает лишние.
+// version = 0 откатывает все
+func (m *Migrator) To(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 {
+		if _, err := m.find(version); err != nil {
+			return nil, err
+		}
+	}
+
+	var done []Migration
+	err := m.open(ctx, func(s session) error {
+		applied, err := s.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		// откат версий выше целевой, от новых к старым
+		versions := sortedVersions(applied)
+		for i := len(versions) - 1; i >= 0 && versions[i] > version; i-- {
+			migration, err := m.find(versions[i])
+			if err != nil {
+				return err
+			}
+			if err := s.rollback(ctx, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+
+		// применение недостающих версий до целевой
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := s.apply(ctx, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status - список миграций с отметкой, какие применены
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+	err := m.open(ctx, func(s session) error {
+		applied, err := s.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+				delete(applied, migration.Version)
+			}
+			result = append(result, status)
+		}
+		for _, version := range sortedVersions(applied) {
+			appliedAt := applied[version]
+			result = append(result, Status{Version: version, Applied: true, AppliedAt: &appliedAt, Missing: true})
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+		return nil
+	})
+	return result, err
+}
+
+func (m *Migrator) find(version int64) (Migration, error) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, nil
+		}
+	}
+	return Migration{}, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+}
+
+func sortedVersions(applied map[int64]time.Time) []int64 {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
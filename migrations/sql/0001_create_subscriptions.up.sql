@@ -1,6 +1,7 @@
-CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+-- IF NOT EXISTS: базы, созданные до появления версионных миграций, уже содержат таблицу
 CREATE TABLE IF NOT EXISTS subscriptions (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
     user_id UUID NOT NULL,
     service_name VARCHAR(100) NOT NULL,
 
@@ -11,9 +12,8 @@ CREATE TABLE IF NOT EXISTS subscriptions (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 
-    PRIMARY KEY (user_id, service_name),
     CONSTRAINT unique_user_service UNIQUE (user_id, service_name)
 );
 
 CREATE INDEX IF NOT EXISTS idx_subscriptions_user ON subscriptions(user_id);
-CREATE INDEX IF NOT EXISTS idx_subscriptions_service ON subscriptions(service_name);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_subscriptions_service ON subscriptions(service_name);
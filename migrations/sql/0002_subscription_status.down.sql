@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS subscription_pauses;
+
+DROP INDEX IF EXISTS idx_subscriptions_user_status;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,28 @@
+-- Статус подписки: active, paused, cancelled. expired не хранится - его определяет end_date
+ALTER TABLE subscriptions
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CONSTRAINT subscriptions_status_check CHECK (status IN ('active', 'paused', 'cancelled')),
+    ADD COLUMN status_changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN cancelled_at TIMESTAMPTZ;
+
+UPDATE subscriptions SET status_changed_at = created_at WHERE created_at IS NOT NULL;
+
+CREATE INDEX idx_subscriptions_user_status ON subscriptions(user_id, status);
+
+-- Паузы: месяцы [paused_from, resumed_from) не оплачиваются, resumed_from IS NULL - пауза еще идет
+CREATE TABLE subscription_pauses (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+
+    paused_from DATE NOT NULL,
+    resumed_from DATE,
+
+    paused_at TIMESTAMPTZ NOT NULL,
+    resumed_at TIMESTAMPTZ,
+
+    CONSTRAINT subscription_pauses_period_check CHECK (resumed_from IS NULL OR resumed_from >= paused_from)
+);
+
+CREATE INDEX idx_subscription_pauses_subscription ON subscription_pauses(subscription_id);
+-- у подписки не больше одной незавершенной паузы
+CREATE UNIQUE INDEX idx_subscription_pauses_open ON subscription_pauses(subscription_id) WHERE resumed_from IS NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS subscriptions_promo_check,
+    DROP COLUMN IF EXISTS promo_to,
+    DROP COLUMN IF EXISTS promo_from,
+    DROP COLUMN IF EXISTS promo_percent,
+    DROP COLUMN IF EXISTS promo_price;
@@ -0,0 +1,13 @@
+-- Промо-цена: с promo_from по promo_to включительно подписка стоит promo_price
+-- или price со скидкой promo_percent. price остается базовой ценой
+ALTER TABLE subscriptions
+    ADD COLUMN promo_price INTEGER,
+    ADD COLUMN promo_percent INTEGER,
+    ADD COLUMN promo_from DATE,
+    ADD COLUMN promo_to DATE,
+    ADD CONSTRAINT subscriptions_promo_check CHECK (
+        (promo_from IS NULL AND promo_to IS NULL AND promo_price IS NULL AND promo_percent IS NULL)
+        OR (promo_from IS NOT NULL AND promo_to IS NOT NULL AND promo_to >= promo_from
+            AND ((promo_price IS NOT NULL AND promo_price >= 0 AND promo_percent IS NULL)
+                OR (promo_percent IS NOT NULL AND promo_percent BETWEEN 1 AND 100 AND promo_price IS NULL)))
+    );
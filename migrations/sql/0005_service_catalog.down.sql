@@ -0,0 +1,7 @@
+-- Слитые имена подписок не восстанавливаются
+DROP INDEX IF EXISTS idx_subscriptions_service_id;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS service_id;
+
+DROP TABLE IF EXISTS service_aliases;
+DROP TABLE IF EXISTS services;
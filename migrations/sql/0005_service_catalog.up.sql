@@ -0,0 +1,50 @@
+-- Каталог сервисов: каноническое имя, категория, цена по умолчанию и валюта
+CREATE TABLE services (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    name VARCHAR(100) NOT NULL CONSTRAINT services_name_key UNIQUE,
+    category VARCHAR(50) NOT NULL DEFAULT '',
+    default_price INTEGER CONSTRAINT services_default_price_check CHECK (default_price > 0),
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Ключи поиска сервиса: lower(btrim(...)) имени и алиасов. Ключ принадлежит одному сервису
+CREATE TABLE service_aliases (
+    alias VARCHAR(100) PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_service_aliases_service ON service_aliases(service_id);
+
+ALTER TABLE subscriptions ADD COLUMN service_id UUID REFERENCES services(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_subscriptions_service_id ON subscriptions(service_id);
+
+-- Слияние свободных имен: написания, совпадающие без учета регистра и пробелов по краям, становятся одним
+-- сервисом, каноническое имя - самое частое написание
+INSERT INTO services (name)
+SELECT DISTINCT ON (lower(btrim(service_name))) btrim(service_name)
+FROM subscriptions
+WHERE btrim(service_name) <> ''
+GROUP BY btrim(service_name)
+ORDER BY lower(btrim(service_name)), count(*) DESC, btrim(service_name);
+
+INSERT INTO service_aliases (alias, service_id)
+SELECT lower(name), id FROM services;
+
+UPDATE subscriptions s SET service_id = a.service_id
+FROM service_aliases a
+WHERE a.alias = lower(btrim(s.service_name));
+
+-- Каноническое имя получает одна подписка пользователя на сервис: если у пользователя уже есть подписка
+-- с каноническим именем или несколько написаний, остальные сохраняют свое имя, но ссылаются на тот же сервис
+UPDATE subscriptions s SET service_name = sv.name
+FROM services sv
+WHERE sv.id = s.service_id AND s.service_name <> sv.name
+    AND NOT EXISTS (
+        SELECT 1 FROM subscriptions o
+        WHERE o.user_id = s.user_id AND o.service_id = s.service_id AND (o.service_name = sv.name OR o.id < s.id)
+    );
@@ -0,0 +1,18 @@
+-- Категория подписки: по умолчанию берется из каталога сервисов
+ALTER TABLE subscriptions ADD COLUMN category VARCHAR(50) NOT NULL DEFAULT '';
+
+UPDATE subscriptions s SET category = c.category
+FROM services c
+WHERE c.id = s.service_id;
+
+CREATE INDEX idx_subscriptions_category ON subscriptions(category);
+
+-- Теги подписки в нижнем регистре, без повторов
+CREATE TABLE subscription_tags (
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    tag VARCHAR(50) NOT NULL,
+
+    PRIMARY KEY (subscription_id, tag)
+);
+
+CREATE INDEX idx_subscription_tags_tag ON subscription_tags(tag);
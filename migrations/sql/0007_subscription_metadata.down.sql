@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_metadata;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,6 @@
+-- Произвольные метаданные подписки: номер счета, центр затрат, почта аккаунта
+ALTER TABLE subscriptions ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}'::jsonb
+    CHECK (jsonb_typeof(metadata) = 'object');
+
+-- Фильтр списка metadata.<key>=<value> - условие metadata @> '{"key": "value"}'
+CREATE INDEX idx_subscriptions_metadata ON subscriptions USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,14 @@
+-- Совместная подписка: правило деления расходов, NULL - подписка не совместная
+ALTER TABLE subscriptions ADD COLUMN split VARCHAR(20)
+    CHECK (split IN ('equal', 'percentage', 'payer'));
+
+-- Участники совместной подписки, кроме владельца (subscriptions.user_id)
+CREATE TABLE subscription_members (
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    percent INTEGER CHECK (percent BETWEEN 1 AND 100), -- доля участника при split = 'percentage'
+
+    PRIMARY KEY (subscription_id, user_id)
+);
+
+CREATE INDEX idx_subscription_members_user_id ON subscription_members(user_id);
@@ -0,0 +1,35 @@
+-- Данные организаций, кроме организации по умолчанию, удаляются: без tenant_id они нарушили бы уникальность
+DROP POLICY IF EXISTS tenant_isolation ON subscription_members;
+ALTER TABLE subscription_members DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscription_tags;
+ALTER TABLE subscription_tags DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscription_pauses;
+ALTER TABLE subscription_pauses DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON organization_members;
+DROP POLICY IF EXISTS tenant_isolation ON service_aliases;
+ALTER TABLE service_aliases DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON services;
+ALTER TABLE services DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;
+
+DELETE FROM subscriptions WHERE tenant_id <> '00000000-0000-0000-0000-000000000001';
+DELETE FROM services WHERE tenant_id <> '00000000-0000-0000-0000-000000000001';
+
+ALTER TABLE service_aliases
+    DROP CONSTRAINT service_aliases_pkey,
+    ADD CONSTRAINT service_aliases_pkey PRIMARY KEY (alias);
+ALTER TABLE service_aliases DROP COLUMN tenant_id;
+
+ALTER TABLE services
+    DROP CONSTRAINT services_name_key,
+    ADD CONSTRAINT services_name_key UNIQUE (name);
+ALTER TABLE services DROP COLUMN tenant_id;
+
+ALTER TABLE subscriptions
+    DROP CONSTRAINT unique_user_service,
+    ADD CONSTRAINT unique_user_service UNIQUE (user_id, service_name);
+ALTER TABLE subscriptions DROP COLUMN tenant_id;
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,82 @@
+-- Организации (tenants): подписки, каталог сервисов и участники принадлежат одной организации
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL CONSTRAINT organizations_name_key UNIQUE,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Организация по умолчанию (tenant.Default): ей достаются существующие данные
+INSERT INTO organizations (id, name) VALUES ('00000000-0000-0000-0000-000000000001', 'default');
+
+-- Пользователи организации: владельцы и участники подписок добавляются при записи подписки
+CREATE TABLE organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+INSERT INTO organization_members (organization_id, user_id)
+SELECT '00000000-0000-0000-0000-000000000001', user_id FROM subscriptions
+UNION
+SELECT '00000000-0000-0000-0000-000000000001', user_id FROM subscription_members;
+
+-- tenant_id без значения по умолчанию: запись без организации - ошибка, а не данные чужой организации.
+-- Организацию с подписками или сервисами удалить нельзя
+ALTER TABLE subscriptions ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    REFERENCES organizations(id) ON DELETE RESTRICT;
+ALTER TABLE subscriptions ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE subscriptions
+    DROP CONSTRAINT unique_user_service,
+    ADD CONSTRAINT unique_user_service UNIQUE (tenant_id, user_id, service_name);
+
+ALTER TABLE services ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    REFERENCES organizations(id) ON DELETE RESTRICT;
+ALTER TABLE services ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE services
+    DROP CONSTRAINT services_name_key,
+    ADD CONSTRAINT services_name_key UNIQUE (tenant_id, name);
+
+ALTER TABLE service_aliases ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001'
+    REFERENCES organizations(id) ON DELETE RESTRICT;
+ALTER TABLE service_aliases ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE service_aliases
+    DROP CONSTRAINT service_aliases_pkey,
+    ADD CONSTRAINT service_aliases_pkey PRIMARY KEY (tenant_id, alias);
+
+-- Row-level security: строки видны, только если app.tenant_id соединения - их организация.
+-- Значение задает репозиторий при database.row_level_security. Владелец таблиц политики обходит,
+-- поэтому защита работает, когда сервис подключается отдельной ролью без прав владельца
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscriptions
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+ALTER TABLE services ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON services
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+ALTER TABLE service_aliases ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON service_aliases
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+ALTER TABLE organization_members ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON organization_members
+    USING (organization_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+-- Паузы, теги и участники подписок видны вместе со своей подпиской
+ALTER TABLE subscription_pauses ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscription_pauses
+    USING (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id));
+
+ALTER TABLE subscription_tags ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscription_tags
+    USING (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id));
+
+ALTER TABLE subscription_members ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON subscription_members
+    USING (EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = subscription_id));
@@ -0,0 +1,22 @@
+-- Пользователи организации: профиль и настройки подсчетов. id совпадает с user_id подписок,
+-- поэтому существующих пользователей заводят с их id. Организацию с пользователями удалить нельзя
+CREATE TABLE users (
+    tenant_id UUID NOT NULL REFERENCES organizations(id) ON DELETE RESTRICT,
+    id UUID NOT NULL,
+
+    display_name VARCHAR(100) NOT NULL,
+    email VARCHAR(254),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    currency CHAR(3) NOT NULL DEFAULT 'RUB',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (tenant_id, id)
+);
+
+CREATE UNIQUE INDEX users_email_key ON users(tenant_id, email) WHERE email IS NOT NULL;
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON users
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
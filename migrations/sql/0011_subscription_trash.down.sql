@@ -0,0 +1,8 @@
+-- Подписки из корзины удаляются: без deleted_at они нарушили бы уникальность
+DELETE FROM subscriptions WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_subscriptions_deleted_at;
+DROP INDEX IF EXISTS unique_user_service;
+ALTER TABLE subscriptions ADD CONSTRAINT unique_user_service UNIQUE (tenant_id, user_id, service_name);
+
+ALTER TABLE subscriptions DROP COLUMN deleted_at;
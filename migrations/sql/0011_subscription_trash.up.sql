@@ -0,0 +1,8 @@
+-- Корзина: удаленная подписка получает deleted_at и не видна в чтении и подсчетах, пока ее не восстановят
+-- или не очистят. Ключ (tenant_id, user_id, service_name) уникален только среди неудаленных подписок
+ALTER TABLE subscriptions ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE subscriptions DROP CONSTRAINT unique_user_service;
+CREATE UNIQUE INDEX unique_user_service ON subscriptions(tenant_id, user_id, service_name) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_subscriptions_deleted_at ON subscriptions(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,25 @@
+-- Журнал аудита: кто, когда и как менял подписки. Внешнего ключа на подписку нет, поэтому история
+-- остается после очистки корзины. Записи удаляются вместе с организацией.
+-- before_state и after_state - подписка до и после изменения в том виде, в каком ее возвращает API
+CREATE TABLE audit_log (
+    tenant_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    id UUID PRIMARY KEY,
+
+    subscription_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    service_name VARCHAR(100) NOT NULL,
+    operation VARCHAR(20) NOT NULL CHECK (operation IN ('create', 'update', 'delete', 'restore', 'status_change')),
+    actor VARCHAR(200) NOT NULL,
+    request_id VARCHAR(200) NOT NULL DEFAULT '',
+    before_state JSONB,
+    after_state JSONB,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_log_subscription ON audit_log(tenant_id, subscription_id, created_at);
+CREATE INDEX idx_audit_log_created_at ON audit_log(tenant_id, created_at);
+
+ALTER TABLE audit_log ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON audit_log
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
@@ -0,0 +1,24 @@
+-- Ключи идемпотентности (заголовок Idempotency-Key): хеш запроса и его ответ, который получит повтор запроса
+-- с тем же ключом. completed = false - запрос еще выполняется. Истекшие записи удаляются фоновой задачей,
+-- до этого ключ можно занять заново. Записи удаляются вместе с организацией
+CREATE TABLE idempotency_keys (
+    tenant_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(200) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+
+    completed BOOLEAN NOT NULL DEFAULT false,
+    response_status INTEGER,
+    response_headers JSONB,
+    response_body BYTEA,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (tenant_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(tenant_id, expires_at);
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON idempotency_keys
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
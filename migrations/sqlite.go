@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// sqliteSession - все миграции одного вызова в одной транзакции BEGIN IMMEDIATE.
+// Транзакция держит блокировку записи, поэтому два процесса не применят миграции одновременно.
+// DDL в SQLite транзакционный: при ошибке откатывается весь вызов
+type sqliteSession struct {
+	conn *sql.Conn
+}
+
+// NewSQLiteMigrator - мигратор со встроенными миграциями SQLite
+func NewSQLiteMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := SQLite()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		open: func(ctx context.Context, fn func(s session) error) error {
+			return withSQLiteLock(ctx, db, fn)
+		},
+		migrations: migrations,
+	}, nil
+}
+
+func withSQLiteLock(ctx context.Context, db *sql.DB, fn func(s session) error) (err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// используем отдельный контекст: исходный может быть уже отменен
+		if err != nil {
+			_, _ = conn.ExecContext(context.Background(), "ROLLBACK")
+			return
+		}
+		if _, errCommit := conn.ExecContext(context.Background(), "COMMIT"); errCommit != nil {
+			err = fmt.Errorf("commit migrations: %w", errCommit)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at TEXT NOT NULL
+        )`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	return fn(&sqliteSession{conn: conn})
+}
+
+func (s *sqliteSession) applied(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := s.conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("schema_migrations %d: %w", version, err)
+		}
+		applied[version] = parsed
+	}
+	return applied, rows.Err()
+}
+
+func (s *sqliteSession) apply(ctx context.Context, migration Migration) error {
+	if _, err := s.conn.ExecContext(ctx, migration.Up); err != nil {
+		return fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+	}
+	_, err := s.conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		migration.Version, migration.Name, time.Now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+func (s *sqliteSession) rollback(ctx context.Context, migration Migration) error {
+	if _, err := s.conn.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("migration %04d_%s down: %w", migration.Version, migration.Name, err)
+	}
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	return err
+}
@@ -0,0 +1,20 @@
+-- Даты хранятся строками: start_date/end_date - 'YYYY-MM-DD' (первое число месяца),
+-- created_at/updated_at - RFC 3339 в UTC. Такие строки сравниваются и сортируются как даты
+CREATE TABLE subscriptions (
+    id TEXT PRIMARY KEY,
+
+    user_id TEXT NOT NULL,
+    service_name TEXT NOT NULL CHECK (length(service_name) <= 100),
+
+    price INTEGER NOT NULL CHECK (price > 0),
+    start_date TEXT NOT NULL,
+    end_date TEXT,
+
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    CONSTRAINT unique_user_service UNIQUE (user_id, service_name)
+);
+
+CREATE INDEX idx_subscriptions_user ON subscriptions(user_id);
+CREATE INDEX idx_subscriptions_service ON subscriptions(service_name);
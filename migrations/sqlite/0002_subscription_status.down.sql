@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS subscription_pauses;
+
+DROP INDEX IF EXISTS idx_subscriptions_user_status;
+
+ALTER TABLE subscriptions DROP COLUMN cancelled_at;
+ALTER TABLE subscriptions DROP COLUMN status_changed_at;
+ALTER TABLE subscriptions DROP COLUMN status;
@@ -0,0 +1,27 @@
+-- Статус подписки: active, paused, cancelled. expired не хранится - его определяет end_date
+ALTER TABLE subscriptions ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'paused', 'cancelled'));
+ALTER TABLE subscriptions ADD COLUMN status_changed_at TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscriptions ADD COLUMN cancelled_at TEXT;
+
+UPDATE subscriptions SET status_changed_at = created_at;
+
+CREATE INDEX idx_subscriptions_user_status ON subscriptions(user_id, status);
+
+-- Паузы: месяцы [paused_from, resumed_from) не оплачиваются, resumed_from IS NULL - пауза еще идет
+CREATE TABLE subscription_pauses (
+    id INTEGER PRIMARY KEY,
+    subscription_id TEXT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+
+    paused_from TEXT NOT NULL,
+    resumed_from TEXT,
+
+    paused_at TEXT NOT NULL,
+    resumed_at TEXT,
+
+    CHECK (resumed_from IS NULL OR resumed_from >= paused_from)
+);
+
+CREATE INDEX idx_subscription_pauses_subscription ON subscription_pauses(subscription_id);
+-- у подписки не больше одной незавершенной паузы
+CREATE UNIQUE INDEX idx_subscription_pauses_open ON subscription_pauses(subscription_id) WHERE resumed_from IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_subscriptions_trial_end;
+
+ALTER TABLE subscriptions DROP COLUMN trial_end;
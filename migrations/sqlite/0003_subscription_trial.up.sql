@@ -0,0 +1,6 @@
+-- Пробный период: месяцы с start_date по trial_end включительно не оплачиваются
+ALTER TABLE subscriptions ADD COLUMN trial_end TEXT
+    CHECK (trial_end IS NULL OR trial_end >= start_date);
+
+-- для поиска заканчивающихся пробных периодов
+CREATE INDEX idx_subscriptions_trial_end ON subscriptions(trial_end) WHERE trial_end IS NOT NULL;
@@ -0,0 +1,5 @@
+-- promo_to первой: ее CHECK ссылается на остальные колонки
+ALTER TABLE subscriptions DROP COLUMN promo_to;
+ALTER TABLE subscriptions DROP COLUMN promo_from;
+ALTER TABLE subscriptions DROP COLUMN promo_percent;
+ALTER TABLE subscriptions DROP COLUMN promo_price;
@@ -0,0 +1,12 @@
+-- Промо-цена: с promo_from по promo_to включительно подписка стоит promo_price
+-- или price со скидкой promo_percent. price остается базовой ценой
+ALTER TABLE subscriptions ADD COLUMN promo_price INTEGER;
+ALTER TABLE subscriptions ADD COLUMN promo_percent INTEGER;
+ALTER TABLE subscriptions ADD COLUMN promo_from TEXT;
+-- проверка всего промо-периода висит на последней колонке: SQLite не добавляет табличные CHECK через ALTER
+ALTER TABLE subscriptions ADD COLUMN promo_to TEXT CHECK (
+    (promo_from IS NULL AND promo_to IS NULL AND promo_price IS NULL AND promo_percent IS NULL)
+    OR (promo_from IS NOT NULL AND promo_to IS NOT NULL AND promo_to >= promo_from
+        AND ((promo_price IS NOT NULL AND promo_price >= 0 AND promo_percent IS NULL)
+            OR (promo_percent IS NOT NULL AND promo_percent BETWEEN 1 AND 100 AND promo_price IS NULL)))
+);
@@ -0,0 +1,58 @@
+-- Каталог сервисов: каноническое имя, категория, цена по умолчанию и валюта
+CREATE TABLE services (
+    id TEXT PRIMARY KEY,
+
+    name TEXT NOT NULL UNIQUE,
+    category TEXT NOT NULL DEFAULT '',
+    default_price INTEGER CHECK (default_price > 0),
+    currency TEXT NOT NULL DEFAULT 'RUB',
+
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+-- Ключи поиска сервиса: lower(trim(...)) имени и алиасов. Ключ принадлежит одному сервису
+CREATE TABLE service_aliases (
+    alias TEXT PRIMARY KEY,
+    service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_service_aliases_service ON service_aliases(service_id);
+
+-- Без REFERENCES: SQLite не удаляет колонки внешних ключей, при удалении сервиса service_id обнуляет репозиторий
+ALTER TABLE subscriptions ADD COLUMN service_id TEXT;
+
+CREATE INDEX idx_subscriptions_service_id ON subscriptions(service_id);
+
+-- Слияние свободных имен: написания, совпадающие без учета регистра и пробелов по краям, становятся одним
+-- сервисом, каноническое имя - самое частое написание. lower() в SQLite меняет регистр только латиницы
+INSERT INTO services (id, name, created_at, updated_at)
+SELECT lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(2)) || '-' ||
+             hex(randomblob(2)) || '-' || hex(randomblob(6))),
+       name, strftime('%Y-%m-%dT%H:%M:%SZ', 'now'), strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+FROM (
+    SELECT trim(service_name) AS name,
+           row_number() OVER (PARTITION BY lower(trim(service_name)) ORDER BY count(*) DESC, trim(service_name)) AS rank
+    FROM subscriptions
+    WHERE trim(service_name) <> ''
+    GROUP BY trim(service_name)
+)
+WHERE rank = 1;
+
+INSERT INTO service_aliases (alias, service_id)
+SELECT lower(name), id FROM services;
+
+UPDATE subscriptions
+SET service_id = (SELECT service_id FROM service_aliases WHERE alias = lower(trim(subscriptions.service_name)));
+
+-- Каноническое имя получает одна подписка пользователя на сервис: если у пользователя уже есть подписка
+-- с каноническим именем или несколько написаний, остальные сохраняют свое имя, но ссылаются на тот же сервис
+UPDATE subscriptions
+SET service_name = (SELECT name FROM services WHERE id = subscriptions.service_id)
+WHERE service_id IS NOT NULL
+    AND service_name <> (SELECT name FROM services WHERE id = subscriptions.service_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM subscriptions o
+        WHERE o.user_id = subscriptions.user_id AND o.service_id = subscriptions.service_id
+            AND (o.service_name = (SELECT name FROM services WHERE id = subscriptions.service_id) OR o.id < subscriptions.id)
+    );
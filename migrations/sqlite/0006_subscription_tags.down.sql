@@ -0,0 +1,5 @@
+DROP TABLE subscription_tags;
+
+DROP INDEX idx_subscriptions_category;
+
+ALTER TABLE subscriptions DROP COLUMN category;
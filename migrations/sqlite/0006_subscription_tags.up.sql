@@ -0,0 +1,16 @@
+-- Категория подписки: по умолчанию берется из каталога сервисов
+ALTER TABLE subscriptions ADD COLUMN category TEXT NOT NULL DEFAULT '' CHECK (length(category) <= 50);
+
+UPDATE subscriptions SET category = COALESCE((SELECT c.category FROM services c WHERE c.id = subscriptions.service_id), '');
+
+CREATE INDEX idx_subscriptions_category ON subscriptions(category);
+
+-- Теги подписки в нижнем регистре, без повторов
+CREATE TABLE subscription_tags (
+    subscription_id TEXT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL CHECK (length(tag) <= 50),
+
+    PRIMARY KEY (subscription_id, tag)
+);
+
+CREATE INDEX idx_subscription_tags_tag ON subscription_tags(tag);
@@ -0,0 +1 @@
+ALTER TABLE subscriptions DROP COLUMN metadata;
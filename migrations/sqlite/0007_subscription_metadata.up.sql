@@ -0,0 +1,3 @@
+-- Произвольные метаданные подписки: номер счета, центр затрат, почта аккаунта
+ALTER TABLE subscriptions ADD COLUMN metadata TEXT NOT NULL DEFAULT '{}'
+    CHECK (json_valid(metadata) AND json_type(metadata) = 'object');
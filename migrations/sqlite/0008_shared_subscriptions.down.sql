@@ -0,0 +1,3 @@
+DROP TABLE subscription_members;
+
+ALTER TABLE subscriptions DROP COLUMN split;
@@ -0,0 +1,134 @@
+-- Данные организаций, кроме организации по умолчанию, удаляются: без tenant_id они нарушили бы уникальность.
+-- Таблицы пересоздаются в прежнем виде так же, как в up
+DELETE FROM subscriptions WHERE tenant_id <> '00000000-0000-0000-0000-000000000001';
+DELETE FROM services WHERE tenant_id <> '00000000-0000-0000-0000-000000000001';
+
+CREATE TABLE subscriptions_old (
+    id TEXT PRIMARY KEY,
+
+    user_id TEXT NOT NULL,
+    service_name TEXT NOT NULL CHECK (length(service_name) <= 100),
+    service_id TEXT,
+    category TEXT NOT NULL DEFAULT '' CHECK (length(category) <= 50),
+    metadata TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(metadata) AND json_type(metadata) = 'object'),
+    split TEXT CHECK (split IN ('equal', 'percentage', 'payer')),
+
+    price INTEGER NOT NULL CHECK (price > 0),
+    start_date TEXT NOT NULL,
+    end_date TEXT,
+    trial_end TEXT CHECK (trial_end IS NULL OR trial_end >= start_date),
+
+    promo_price INTEGER,
+    promo_percent INTEGER,
+    promo_from TEXT,
+    -- CHECK промо-периода на колонке promo_to, как после 0004: откат 0004 удаляет ее вместе с проверкой
+    promo_to TEXT CHECK (
+        (promo_from IS NULL AND promo_to IS NULL AND promo_price IS NULL AND promo_percent IS NULL)
+        OR (promo_from IS NOT NULL AND promo_to IS NOT NULL AND promo_to >= promo_from
+            AND ((promo_price IS NOT NULL AND promo_price >= 0 AND promo_percent IS NULL)
+                OR (promo_percent IS NOT NULL AND promo_percent BETWEEN 1 AND 100 AND promo_price IS NULL)))
+    ),
+
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled')),
+    status_changed_at TEXT NOT NULL DEFAULT '',
+    cancelled_at TEXT,
+
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    CONSTRAINT unique_user_service UNIQUE (user_id, service_name)
+);
+
+INSERT INTO subscriptions_old (id, user_id, service_name, service_id, category, metadata, split,
+    price, start_date, end_date, trial_end, promo_price, promo_percent, promo_from, promo_to,
+    status, status_changed_at, cancelled_at, created_at, updated_at)
+SELECT id, user_id, service_name, service_id, category, metadata, split,
+    price, start_date, end_date, trial_end, promo_price, promo_percent, promo_from, promo_to,
+    status, status_changed_at, cancelled_at, created_at, updated_at
+FROM subscriptions;
+
+CREATE TABLE subscription_pauses_old (
+    id INTEGER PRIMARY KEY,
+    subscription_id TEXT NOT NULL REFERENCES subscriptions_old(id) ON DELETE CASCADE,
+
+    paused_from TEXT NOT NULL,
+    resumed_from TEXT,
+
+    paused_at TEXT NOT NULL,
+    resumed_at TEXT,
+
+    CHECK (resumed_from IS NULL OR resumed_from >= paused_from)
+);
+
+INSERT INTO subscription_pauses_old SELECT id, subscription_id, paused_from, resumed_from, paused_at, resumed_at
+FROM subscription_pauses;
+
+CREATE TABLE subscription_tags_old (
+    subscription_id TEXT NOT NULL REFERENCES subscriptions_old(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL CHECK (length(tag) <= 50),
+
+    PRIMARY KEY (subscription_id, tag)
+);
+
+INSERT INTO subscription_tags_old SELECT subscription_id, tag FROM subscription_tags;
+
+CREATE TABLE subscription_members_old (
+    subscription_id TEXT NOT NULL REFERENCES subscriptions_old(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    percent INTEGER CHECK (percent BETWEEN 1 AND 100), -- доля участника при split = 'percentage'
+
+    PRIMARY KEY (subscription_id, user_id)
+);
+
+INSERT INTO subscription_members_old SELECT subscription_id, user_id, percent FROM subscription_members;
+
+CREATE TABLE services_old (
+    id TEXT PRIMARY KEY,
+
+    name TEXT NOT NULL UNIQUE,
+    category TEXT NOT NULL DEFAULT '',
+    default_price INTEGER CHECK (default_price > 0),
+    currency TEXT NOT NULL DEFAULT 'RUB',
+
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+INSERT INTO services_old (id, name, category, default_price, currency, created_at, updated_at)
+SELECT id, name, category, default_price, currency, created_at, updated_at FROM services;
+
+CREATE TABLE service_aliases_old (
+    alias TEXT PRIMARY KEY,
+    service_id TEXT NOT NULL REFERENCES services_old(id) ON DELETE CASCADE
+);
+
+INSERT INTO service_aliases_old (alias, service_id) SELECT alias, service_id FROM service_aliases;
+
+DROP TABLE subscription_pauses;
+DROP TABLE subscription_tags;
+DROP TABLE subscription_members;
+DROP TABLE subscriptions;
+DROP TABLE service_aliases;
+DROP TABLE services;
+
+ALTER TABLE subscriptions_old RENAME TO subscriptions;
+ALTER TABLE subscription_pauses_old RENAME TO subscription_pauses;
+ALTER TABLE subscription_tags_old RENAME TO subscription_tags;
+ALTER TABLE subscription_members_old RENAME TO subscription_members;
+ALTER TABLE services_old RENAME TO services;
+ALTER TABLE service_aliases_old RENAME TO service_aliases;
+
+CREATE INDEX idx_subscriptions_user ON subscriptions(user_id);
+CREATE INDEX idx_subscriptions_service ON subscriptions(service_name);
+CREATE INDEX idx_subscriptions_user_status ON subscriptions(user_id, status);
+CREATE INDEX idx_subscriptions_trial_end ON subscriptions(trial_end) WHERE trial_end IS NOT NULL;
+CREATE INDEX idx_subscriptions_service_id ON subscriptions(service_id);
+CREATE INDEX idx_subscriptions_category ON subscriptions(category);
+CREATE INDEX idx_subscription_pauses_subscription ON subscription_pauses(subscription_id);
+CREATE UNIQUE INDEX idx_subscription_pauses_open ON subscription_pauses(subscription_id) WHERE resumed_from IS NULL;
+CREATE INDEX idx_subscription_tags_tag ON subscription_tags(tag);
+CREATE INDEX idx_subscription_members_user_id ON subscription_members(user_id);
+CREATE INDEX idx_service_aliases_service ON service_aliases(service_id);
+
+DROP TABLE organization_members;
+DROP TABLE organizations;
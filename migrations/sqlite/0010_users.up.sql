@@ -0,0 +1,18 @@
+-- Пользователи организации: профиль и настройки подсчетов. id совпадает с user_id подписок,
+-- поэтому существующих пользователей заводят с их id. Организацию с пользователями удалить нельзя
+CREATE TABLE users (
+    tenant_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE RESTRICT,
+    id TEXT NOT NULL,
+
+    display_name TEXT NOT NULL CHECK (length(display_name) <= 100),
+    email TEXT CHECK (length(email) <= 254),
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    currency TEXT NOT NULL DEFAULT 'RUB',
+
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    PRIMARY KEY (tenant_id, id)
+);
+
+CREATE UNIQUE INDEX users_email_key ON users(tenant_id, email) WHERE email IS NOT NULL;
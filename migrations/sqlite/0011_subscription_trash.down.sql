@@ -0,0 +1,105 @@
+-- Подписки из корзины удаляются: без deleted_at они нарушили бы уникальность.
+-- Таблицы пересоздаются в прежнем виде так же, как в up
+DELETE FROM subscriptions WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE subscriptions_old (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE RESTRICT,
+
+    user_id TEXT NOT NULL,
+    service_name TEXT NOT NULL CHECK (length(service_name) <= 100),
+    service_id TEXT,
+    category TEXT NOT NULL DEFAULT '' CHECK (length(category) <= 50),
+    metadata TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(metadata) AND json_type(metadata) = 'object'),
+    split TEXT CHECK (split IN ('equal', 'percentage', 'payer')),
+
+    price INTEGER NOT NULL CHECK (price > 0),
+    start_date TEXT NOT NULL,
+    end_date TEXT,
+    trial_end TEXT CHECK (trial_end IS NULL OR trial_end >= start_date),
+
+    promo_price INTEGER,
+    promo_percent INTEGER,
+    promo_from TEXT,
+    -- CHECK промо-периода на колонке promo_to, как после 0004: откат 0004 удаляет ее вместе с проверкой
+    promo_to TEXT CHECK (
+        (promo_from IS NULL AND promo_to IS NULL AND promo_price IS NULL AND promo_percent IS NULL)
+        OR (promo_from IS NOT NULL AND promo_to IS NOT NULL AND promo_to >= promo_from
+            AND ((promo_price IS NOT NULL AND promo_price >= 0 AND promo_percent IS NULL)
+                OR (promo_percent IS NOT NULL AND promo_percent BETWEEN 1 AND 100 AND promo_price IS NULL)))
+    ),
+
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'cancelled')),
+    status_changed_at TEXT NOT NULL DEFAULT '',
+    cancelled_at TEXT,
+
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+
+    CONSTRAINT unique_user_service UNIQUE (tenant_id, user_id, service_name)
+);
+
+INSERT INTO subscriptions_old (id, tenant_id, user_id, service_name, service_id, category, metadata, split,
+    price, start_date, end_date, trial_end, promo_price, promo_percent, promo_from, promo_to,
+    status, status_changed_at, cancelled_at, created_at, updated_at)
+SELECT id, tenant_id, user_id, service_name, service_id, category, metadata, split,
+    price, start_date, end_date, trial_end, promo_price, promo_percent, promo_from, promo_to,
+    status, status_changed_at, cancelled_at, created_at, updated_at
+FROM subscriptions;
+
+CREATE TABLE subscription_pauses_new (
+    id INTEGER PRIMARY KEY,
+    subscription_id TEXT NOT NULL REFERENCES subscriptions_old(id) ON DELETE CASCADE,
+
+    paused_from TEXT NOT NULL,
+    resumed_from TEXT,
+
+    paused_at TEXT NOT NULL,
+    resumed_at TEXT,
+
+    CHECK (resumed_from IS NULL OR resumed_from >= paused_from)
+);
+
+INSERT INTO subscription_pauses_new SELECT id, subscription_id, paused_from, resumed_from, paused_at, resumed_at
+FROM subscription_pauses;
+
+CREATE TABLE subscription_tags_new (
+    subscription_id TEXT NOT NULL REFERENCES subscriptions_old(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL CHECK (length(tag) <= 50),
+
+    PRIMARY KEY (subscription_id, tag)
+);
+
+INSERT INTO subscription_tags_new SELECT subscription_id, tag FROM subscription_tags;
+
+CREATE TABLE subscription_members_new (
+    subscription_id TEXT NOT NULL REFERENCES subscriptions_old(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    percent INTEGER CHECK (percent BETWEEN 1 AND 100), -- доля участника при split = 'percentage'
+
+    PRIMARY KEY (subscription_id, user_id)
+);
+
+INSERT INTO subscription_members_new SELECT subscription_id, user_id, percent FROM subscription_members;
+
+DROP TABLE subscription_pauses;
+DROP TABLE subscription_tags;
+DROP TABLE subscription_members;
+DROP TABLE subscriptions;
+
+ALTER TABLE subscriptions_old RENAME TO subscriptions;
+ALTER TABLE subscription_pauses_new RENAME TO subscription_pauses;
+ALTER TABLE subscription_tags_new RENAME TO subscription_tags;
+ALTER TABLE subscription_members_new RENAME TO subscription_members;
+
+CREATE INDEX idx_subscriptions_user ON subscriptions(user_id);
+CREATE INDEX idx_subscriptions_service ON subscriptions(service_name);
+CREATE INDEX idx_subscriptions_user_status ON subscriptions(user_id, status);
+CREATE INDEX idx_subscriptions_trial_end ON subscriptions(trial_end) WHERE trial_end IS NOT NULL;
+CREATE INDEX idx_subscriptions_service_id ON subscriptions(service_id);
+CREATE INDEX idx_subscriptions_category ON subscriptions(category);
+CREATE INDEX idx_subscription_pauses_subscription ON subscription_pauses(subscription_id);
+-- у подписки не больше одной незавершенной паузы
+CREATE UNIQUE INDEX idx_subscription_pauses_open ON subscription_pauses(subscription_id) WHERE resumed_from IS NULL;
+CREATE INDEX idx_subscription_tags_tag ON subscription_tags(tag);
+CREATE INDEX idx_subscription_members_user_id ON subscription_members(user_id);
@@ -0,0 +1,21 @@
+-- Журнал аудита: кто, когда и как менял подписки. Внешнего ключа на подписку нет, поэтому история
+-- остается после очистки корзины. Записи удаляются вместе с организацией.
+-- before_state и after_state - подписка до и после изменения в том виде, в каком ее возвращает API
+CREATE TABLE audit_log (
+    tenant_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    id TEXT PRIMARY KEY,
+
+    subscription_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    service_name TEXT NOT NULL CHECK (length(service_name) <= 100),
+    operation TEXT NOT NULL CHECK (operation IN ('create', 'update', 'delete', 'restore', 'status_change')),
+    actor TEXT NOT NULL CHECK (length(actor) <= 200),
+    request_id TEXT NOT NULL DEFAULT '' CHECK (length(request_id) <= 200),
+    before_state TEXT,
+    after_state TEXT,
+
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX idx_audit_log_subscription ON audit_log(tenant_id, subscription_id, created_at);
+CREATE INDEX idx_audit_log_created_at ON audit_log(tenant_id, created_at);
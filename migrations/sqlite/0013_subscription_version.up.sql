@@ -0,0 +1,3 @@
+-- Версия строки подписки: растет на 1 при каждом изменении, из нее и id строится ETag.
+-- PUT, DELETE и смена статуса с If-Match меняют подписку, только если версия не изменилась
+ALTER TABLE subscriptions ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,20 @@
+-- Ключи идемпотентности (заголовок Idempotency-Key): хеш запроса и его ответ, который получит повтор запроса
+-- с тем же ключом. completed = 0 - запрос еще выполняется. Истекшие записи удаляются фоновой задачей,
+-- до этого ключ можно занять заново. Записи удаляются вместе с организацией
+CREATE TABLE idempotency_keys (
+    tenant_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    idempotency_key TEXT NOT NULL CHECK (length(idempotency_key) <= 200),
+    request_hash TEXT NOT NULL,
+
+    completed INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    response_headers TEXT,
+    response_body BLOB,
+
+    created_at TEXT NOT NULL,
+    expires_at TEXT NOT NULL,
+
+    PRIMARY KEY (tenant_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(tenant_id, expires_at);
@@ -1,18 +1,559 @@
 package config
 
-import "agrigation_api/pkg/tools"
+import (
+	"agrigation_api/pkg/tools"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"go.yaml.in/yaml/v3"
+)
+
+// EnvConfigPath - переменная окружения с путем до YAML-конфига (аналог флага --config)
+const EnvConfigPath = "CONFIG_PATH"
+
+// Config - единая конфигурация приложения
 type Config struct {
-	Port      int    `yaml:"Port"`
-	IPAddress string `yaml:"IPAddress"`
+	Server      ServerConfig      `yaml:"server"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Logger      LoggerConfig      `yaml:"logger"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Features    FeaturesConfig    `yaml:"features"`
+	Trash       TrashConfig       `yaml:"trash"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Runtime     RuntimeConfig     `yaml:"runtime"`
+	Reload      ReloadConfig      `yaml:"reload"`
+}
+
+// ServerConfig - настройки http сервера
+type ServerConfig struct {
+	Port            int             `yaml:"port"`
+	IPAddress       string          `yaml:"ip_address"`
+	ShutdownTimeout time.Duration   `yaml:"shutdown_timeout"`
+	CORS            CORSConfig      `yaml:"cors"`
+	RateLimit       RateLimitConfig `yaml:"rate_limit"`
+}
+
+// CORSConfig - разрешенные источники для браузерных клиентов ("*" - любой)
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// RateLimitConfig - ограничение запросов с одного IP (0 - без ограничения)
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+}
+
+// Хранилища подписок
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory" // данные в памяти процесса, теряются при перезапуске - для локального запуска и демо
+	StorageSQLite   = "sqlite" // файл SQLite, для небольших установок без PostgreSQL
+)
+
+// StorageConfig - выбор реализации репозитория
+type StorageConfig struct {
+	Backend string       `yaml:"backend"`
+	SQLite  SQLiteConfig `yaml:"sqlite"`
+}
+
+// SQLiteConfig - настройки хранилища SQLite
+type SQLiteConfig struct {
+	Path        string        `yaml:"path"`         // путь до файла БД
+	BusyTimeout time.Duration `yaml:"busy_timeout"` // ожидание блокировки записи другим соединением
+}
+
+// Хранилища кеша
+const (
+	CacheNone   = "none"
+	CacheMemory = "memory" // LRU в памяти процесса, у каждой реплики сервиса свой
+	CacheRedis  = "redis"
+)
+
+// CacheConfig - кеш подсчета расходов и списков подписок пользователя
+type CacheConfig struct {
+	Backend    string        `yaml:"backend"`
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"max_entries"` // размер LRU для backend memory
+	Redis      RedisConfig   `yaml:"redis"`
+}
+
+// RedisConfig - подключение к Redis
+type RedisConfig struct {
+	Address  string `yaml:"address"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// DatabaseConfig - настройки подключения к PostgreSQL
+type DatabaseConfig struct {
+	Host     string        `yaml:"host"`
+	Port     int           `yaml:"port"`
+	User     string        `yaml:"user"`
+	Password string        `yaml:"password"`
+	Database string        `yaml:"database"`
+	Connect  ConnectConfig `yaml:"connect"`
+	Pool     PoolConfig    `yaml:"pool"`
+
+	SSLMode         string `yaml:"sslmode"`     // disable, allow, prefer, require, verify-ca, verify-full
+	SSLRootCert     string `yaml:"sslrootcert"` // путь до CA-сертификата
+	ApplicationName string `yaml:"application_name"`
+
+	QueryTimeout     time.Duration `yaml:"query_timeout"`     // таймаут контекста каждого запроса репозитория, 0 - без таймаута
+	StatementTimeout time.Duration `yaml:"statement_timeout"` // statement_timeout на стороне PostgreSQL, 0 - без таймаута
+
+	// RowLevelSecurity - соединение получает организацию запроса в app.tenant_id, и политики RLS
+	// скрывают строки чужих организаций. Работает для роли, которая не владеет таблицами
+	RowLevelSecurity bool `yaml:"row_level_security"`
+
+	Replicas ReplicasConfig `yaml:"replicas"`
+}
+
+// ReplicasConfig - реплики для чтения. Без DSN все запросы идут в основную БД
+type ReplicasConfig struct {
+	DSNs          []string      `yaml:"dsns"`           // строки подключения, настройки pool и таймаутов берутся из database
+	CheckInterval time.Duration `yaml:"check_interval"` // период проверки доступности реплик
+}
+
+// PoolConfig - настройки пула pgx, нулевые значения - значения pgx по умолчанию
+type PoolConfig struct {
+	MaxConns          int           `yaml:"max_conns"`
+	MinConns          int           `yaml:"min_conns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+}
+
+// ConnectConfig - повторные попытки первого подключения (экспоненциальный backoff с jitter)
+type ConnectConfig struct {
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	MaxWait        time.Duration `yaml:"max_wait"` // сколько всего ждать БД, 0 - одна попытка
 }
 
-func ReadConfig() (*Config, error) {
-	port := tools.GetEnvAsInt("SERVER_PORT", 11682)
-	ip := tools.GetEnv("SERVER_IP", "127.0.0.1")
-	config := &Config{
-		Port:      port,
-		IPAddress: ip,
+// LoggerConfig - настройки логгера
+type LoggerConfig struct {
+	Level string `yaml:"level"` // INFO, WARNING, ERROR
+}
+
+// AuthConfig - авторизация по API-ключам. Ключи api_keys выбирают организацию заголовком X-Tenant-ID,
+// ключи tenant_keys привязаны к своей организации
+type AuthConfig struct {
+	Enabled    bool              `yaml:"enabled"`
+	Header     string            `yaml:"header"`
+	APIKeys    []string          `yaml:"api_keys"`
+	TenantKeys map[string]string `yaml:"tenant_keys"` // API-ключ -> id организации
+}
+
+// FeaturesConfig - включение/выключение отдельных возможностей
+type FeaturesConfig struct {
+	Swagger        bool `yaml:"swagger"`
+	StrictUsers    bool `yaml:"strict_users"`     // подписки только для пользователей, заведенных через /users
+	RequireIfMatch bool `yaml:"require_if_match"` // PUT и DELETE подписки без If-Match - 428
+}
+
+// TrashConfig - корзина удаленных подписок
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention"`      // сколько подписка хранится в корзине, 0 - не очищать
+	PurgeInterval time.Duration `yaml:"purge_interval"` // как часто очищать корзину
+}
+
+// IdempotencyConfig - сохраненные ответы на запросы с Idempotency-Key
+type IdempotencyConfig struct {
+	TTL           time.Duration `yaml:"ttl"`            // сколько хранится ответ, после этого ключ можно использовать снова
+	PurgeInterval time.Duration `yaml:"purge_interval"` // как часто удалять истекшие ключи
+}
+
+// RuntimeConfig - ограничения рантайма
+type RuntimeConfig struct {
+	NumCPU int `yaml:"num_cpu"`
+}
+
+// ReloadConfig - горячая перезагрузка конфига (кроме SIGHUP)
+type ReloadConfig struct {
+	WatchInterval time.Duration `yaml:"watch_interval"` // как часто проверять изменение файла, 0 - не следить
+}
+
+// Default - конфиг по умолчанию, поверх него применяются файл и переменные окружения
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            11682,
+			IPAddress:       "127.0.0.1",
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend: StoragePostgres,
+			SQLite: SQLiteConfig{
+				Path:        "subscriptions.db",
+				BusyTimeout: 5 * time.Second,
+			},
+		},
+		Database: DatabaseConfig{
+			Host:     "localhost",
+			Port:     5432,
+			User:     "postgres",
+			Password: "postgres",
+			Database: "aggregation",
+			Connect: ConnectConfig{
+				InitialBackoff: 500 * time.Millisecond,
+				MaxBackoff:     10 * time.Second,
+				MaxWait:        time.Minute,
+			},
+			SSLMode:         "prefer",
+			ApplicationName: "subscriptions-api",
+			QueryTimeout:    10 * time.Second,
+			Replicas: ReplicasConfig{
+				CheckInterval: 5 * time.Second,
+			},
+		},
+		Cache: CacheConfig{
+			Backend:    CacheNone,
+			TTL:        time.Minute,
+			MaxEntries: 10000,
+			Redis: RedisConfig{
+				Address: "localhost:6379",
+			},
+		},
+		Logger: LoggerConfig{
+			Level: "INFO",
+		},
+		Auth: AuthConfig{
+			Header: "X-API-Key",
+		},
+		Features: FeaturesConfig{
+			Swagger: true,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:           24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Runtime: RuntimeConfig{
+			NumCPU: runtime.NumCPU(),
+		},
+	}
+}
+
+// ReadConfig - читает конфиг: значения по умолчанию, затем YAML-файл (если путь не пустой),
+// затем переменные окружения. Результат валидируется.
+func ReadConfig(path string) (*Config, error) {
+	config := Default()
+
+	if path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 	return config, nil
 }
+
+// loadFile - накладывает значения из YAML-файла
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read config file %q: %w", path, err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %q: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv - переопределяет значения переменными окружения (имена совместимы с .env)
+func (c *Config) applyEnv() error {
+	var errs []error
+
+	errs = append(errs,
+		envInt("SERVER_PORT", &c.Server.Port),
+		envDuration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout),
+		envFloat("RATE_LIMIT_RPS", &c.Server.RateLimit.RequestsPerSecond),
+		envInt("RATE_LIMIT_BURST", &c.Server.RateLimit.Burst),
+		envDuration("CONFIG_WATCH_INTERVAL", &c.Reload.WatchInterval),
+		envInt("PG_PORT", &c.Database.Port),
+		envDuration("PG_CONNECT_MAX_WAIT", &c.Database.Connect.MaxWait),
+		envInt("PG_MAX_CONNS", &c.Database.Pool.MaxConns),
+		envInt("PG_MIN_CONNS", &c.Database.Pool.MinConns),
+		envDuration("PG_QUERY_TIMEOUT", &c.Database.QueryTimeout),
+		envDuration("PG_STATEMENT_TIMEOUT", &c.Database.StatementTimeout),
+		envBool("PG_ROW_LEVEL_SECURITY", &c.Database.RowLevelSecurity),
+		envDuration("PG_REPLICA_CHECK_INTERVAL", &c.Database.Replicas.CheckInterval),
+		envDuration("CACHE_TTL", &c.Cache.TTL),
+		envInt("REDIS_DB", &c.Cache.Redis.DB),
+		envBool("AUTH_ENABLED", &c.Auth.Enabled),
+		envBool("SWAGGER_ENABLED", &c.Features.Swagger),
+		envBool("STRICT_USERS", &c.Features.StrictUsers),
+		envBool("REQUIRE_IF_MATCH", &c.Features.RequireIfMatch),
+		envDuration("TRASH_RETENTION", &c.Trash.Retention),
+		envDuration("TRASH_PURGE_INTERVAL", &c.Trash.PurgeInterval),
+		envDuration("IDEMPOTENCY_TTL", &c.Idempotency.TTL),
+		envDuration("IDEMPOTENCY_PURGE_INTERVAL", &c.Idempotency.PurgeInterval),
+		envInt("NUM_CPU", &c.Runtime.NumCPU),
+	)
+
+	c.Server.IPAddress = tools.GetEnv("SERVER_IP", c.Server.IPAddress)
+	c.Storage.Backend = tools.GetEnv("STORAGE", c.Storage.Backend)
+	c.Storage.SQLite.Path = tools.GetEnv("SQLITE_PATH", c.Storage.SQLite.Path)
+	c.Database.Host = tools.GetEnv("PG_HOST", c.Database.Host)
+	c.Database.User = tools.GetEnv("PG_USER", c.Database.User)
+	c.Database.Password = tools.GetEnv("PG_PASSWORD", c.Database.Password)
+	c.Database.Database = tools.GetEnv("PG_DATABASE", c.Database.Database)
+	c.Database.SSLMode = tools.GetEnv("PG_SSLMODE", c.Database.SSLMode)
+	c.Database.SSLRootCert = tools.GetEnv("PG_SSLROOTCERT", c.Database.SSLRootCert)
+	c.Database.ApplicationName = tools.GetEnv("PG_APPLICATION_NAME", c.Database.ApplicationName)
+	c.Cache.Backend = tools.GetEnv("CACHE_BACKEND", c.Cache.Backend)
+	c.Cache.Redis.Address = tools.GetEnv("REDIS_ADDRESS", c.Cache.Redis.Address)
+	c.Cache.Redis.Password = tools.GetEnv("REDIS_PASSWORD", c.Cache.Redis.Password)
+	c.Logger.Level = tools.GetEnv("LOGGER", c.Logger.Level)
+	c.Auth.Header = tools.GetEnv("AUTH_HEADER", c.Auth.Header)
+	if keys, ok := os.LookupEnv("API_KEYS"); ok {
+		c.Auth.APIKeys = splitList(keys)
+	}
+	if keys, ok := os.LookupEnv("TENANT_API_KEYS"); ok {
+		tenantKeys, err := splitPairs("TENANT_API_KEYS", keys)
+		errs = append(errs, err)
+		c.Auth.TenantKeys = tenantKeys
+	}
+	if replicas, ok := os.LookupEnv("PG_REPLICAS"); ok {
+		c.Database.Replicas.DSNs = splitList(replicas)
+	}
+	if origins, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok {
+		c.Server.CORS.AllowedOrigins = splitList(origins)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate - проверка конфига, возвращает все найденные ошибки сразу
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535,
+		"server.port: must be in range 1..65535, got %d", c.Server.Port)
+	check(c.Server.IPAddress != "", "server.ip_address: must not be empty")
+	check(c.Server.ShutdownTimeout > 0,
+		"server.shutdown_timeout: must be positive, got %s", c.Server.ShutdownTimeout)
+	check(c.Server.RateLimit.RequestsPerSecond >= 0,
+		"server.rate_limit.requests_per_second: must not be negative, got %v", c.Server.RateLimit.RequestsPerSecond)
+	if c.Server.RateLimit.RequestsPerSecond > 0 {
+		check(c.Server.RateLimit.Burst > 0,
+			"server.rate_limit.burst: must be positive when rate limit is enabled, got %d", c.Server.RateLimit.Burst)
+	}
+	for i, origin := range c.Server.CORS.AllowedOrigins {
+		check(origin == "*" || strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+			"server.cors.allowed_origins[%d]: must be \"*\" or start with http:// or https://, got %q", i, origin)
+	}
+
+	switch c.Storage.Backend {
+	case StoragePostgres, StorageMemory:
+	case StorageSQLite:
+		check(c.Storage.SQLite.Path != "", "storage.sqlite.path: must not be empty")
+		check(c.Storage.SQLite.BusyTimeout >= 0,
+			"storage.sqlite.busy_timeout: must not be negative, got %s", c.Storage.SQLite.BusyTimeout)
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend: must be one of %s, %s, %s, got %q",
+			StoragePostgres, StorageMemory, StorageSQLite, c.Storage.Backend))
+	}
+
+	check(c.Database.Host != "", "database.host: must not be empty")
+	check(c.Database.Port > 0 && c.Database.Port <= 65535,
+		"database.port: must be in range 1..65535, got %d", c.Database.Port)
+	check(c.Database.User != "", "database.user: must not be empty")
+	check(c.Database.Database != "", "database.database: must not be empty")
+	check(c.Database.Connect.MaxWait >= 0,
+		"database.connect.max_wait: must not be negative, got %s", c.Database.Connect.MaxWait)
+	check(c.Database.Pool.MaxConns >= 0, "database.pool.max_conns: must not be negative, got %d", c.Database.Pool.MaxConns)
+	check(c.Database.Pool.MinConns >= 0, "database.pool.min_conns: must not be negative, got %d", c.Database.Pool.MinConns)
+	if c.Database.Pool.MaxConns > 0 {
+		check(c.Database.Pool.MinConns <= c.Database.Pool.MaxConns,
+			"database.pool.min_conns: must not exceed max_conns (%d), got %d", c.Database.Pool.MaxConns, c.Database.Pool.MinConns)
+	}
+	check(c.Database.Pool.MaxConnLifetime >= 0 && c.Database.Pool.MaxConnIdleTime >= 0 && c.Database.Pool.HealthCheckPeriod >= 0,
+		"database.pool: durations must not be negative")
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("database.sslmode: must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q",
+			c.Database.SSLMode))
+	}
+	if c.Database.SSLRootCert != "" {
+		_, errStat := os.Stat(c.Database.SSLRootCert)
+		check(errStat == nil, "database.sslrootcert: %v", errStat)
+	}
+	check(c.Database.QueryTimeout >= 0, "database.query_timeout: must not be negative, got %s", c.Database.QueryTimeout)
+	check(c.Database.StatementTimeout >= 0,
+		"database.statement_timeout: must not be negative, got %s", c.Database.StatementTimeout)
+	if len(c.Database.Replicas.DSNs) > 0 {
+		check(!slices.Contains(c.Database.Replicas.DSNs, ""), "database.replicas.dsns: must not contain empty values")
+		check(c.Database.Replicas.CheckInterval > 0,
+			"database.replicas.check_interval: must be positive, got %s", c.Database.Replicas.CheckInterval)
+	}
+	if c.Database.Connect.MaxWait > 0 {
+		check(c.Database.Connect.InitialBackoff > 0,
+			"database.connect.initial_backoff: must be positive, got %s", c.Database.Connect.InitialBackoff)
+		check(c.Database.Connect.MaxBackoff >= c.Database.Connect.InitialBackoff,
+			"database.connect.max_backoff: must not be less than initial_backoff, got %s", c.Database.Connect.MaxBackoff)
+	}
+
+	switch c.Cache.Backend {
+	case CacheNone:
+	case CacheMemory, CacheRedis:
+		check(c.Cache.TTL > 0, "cache.ttl: must be positive, got %s", c.Cache.TTL)
+		if c.Cache.Backend == CacheMemory {
+			check(c.Cache.MaxEntries > 0, "cache.max_entries: must be positive, got %d", c.Cache.MaxEntries)
+		} else {
+			check(c.Cache.Redis.Address != "", "cache.redis.address: must not be empty")
+			check(c.Cache.Redis.DB >= 0, "cache.redis.db: must not be negative, got %d", c.Cache.Redis.DB)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("cache.backend: must be one of %s, %s, %s, got %q",
+			CacheNone, CacheMemory, CacheRedis, c.Cache.Backend))
+	}
+
+	check(c.Trash.Retention >= 0, "trash.retention: must not be negative, got %s", c.Trash.Retention)
+	if c.Trash.Retention > 0 {
+		check(c.Trash.PurgeInterval > 0, "trash.purge_interval: must be positive, got %s", c.Trash.PurgeInterval)
+	}
+	check(c.Idempotency.TTL > 0, "idempotency.ttl: must be positive, got %s", c.Idempotency.TTL)
+	check(c.Idempotency.PurgeInterval > 0, "idempotency.purge_interval: must be positive, got %s", c.Idempotency.PurgeInterval)
+
+	switch c.Logger.Level {
+	case "INFO", "WARNING", "ERROR":
+	default:
+		errs = append(errs, fmt.Errorf("logger.level: must be one of INFO, WARNING, ERROR, got %q", c.Logger.Level))
+	}
+
+	if c.Auth.Enabled {
+		check(c.Auth.Header != "", "auth.header: must not be empty when auth is enabled")
+		check(len(c.Auth.APIKeys)+len(c.Auth.TenantKeys) > 0,
+			"auth.api_keys: at least one key is required when auth is enabled")
+	}
+	for i, key := range c.Auth.APIKeys {
+		check(strings.TrimSpace(key) != "", "auth.api_keys[%d]: must not be empty", i)
+	}
+	for key, organization := range c.Auth.TenantKeys {
+		check(strings.TrimSpace(key) != "", "auth.tenant_keys: keys must not be empty")
+		check(!slices.Contains(c.Auth.APIKeys, key), "auth.tenant_keys: key is also listed in api_keys")
+		_, errParse := uuid.Parse(organization)
+		check(errParse == nil, "auth.tenant_keys: organization must be a UUID, got %q", organization)
+	}
+
+	check(c.Runtime.NumCPU > 0, "runtime.num_cpu: must be positive, got %d", c.Runtime.NumCPU)
+	check(c.Reload.WatchInterval >= 0, "reload.watch_interval: must not be negative, got %s", c.Reload.WatchInterval)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Address - адрес, который слушает http сервер
+func (s ServerConfig) Address() string {
+	return net.JoinHostPort(s.IPAddress, strconv.Itoa(s.Port))
+}
+
+func envInt(key string, dst *int) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("env %s: expected integer, got %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func envBool(key string, dst *bool) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("env %s: expected boolean, got %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func envFloat(key string, dst *float64) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("env %s: expected number, got %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+func envDuration(key string, dst *time.Duration) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("env %s: expected duration (e.g. 30s), got %q", key, value)
+	}
+	*dst = parsed
+	return nil
+}
+
+// splitPairs - "key=value,key=value" из переменной окружения name
+func splitPairs(name, value string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, item := range splitList(value) {
+		key, val, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("env %s: expected key=value pairs, got %q", name, item)
+		}
+		result[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return result, nil
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store - потокобезопасное хранилище текущего конфига. Middleware читают конфиг на каждый запрос,
+// поэтому после Reload новые значения применяются без перезапуска и без разрыва соединений.
+type Store struct {
+	path    string
+	current atomic.Pointer[Config]
+	mu      sync.Mutex // сериализует перезагрузки
+}
+
+// NewStore - конструктор хранилища, path - путь до YAML-файла (может быть пустым)
+func NewStore(path string, conf *Config) *Store {
+	store := &Store{path: path}
+	store.current.Store(conf)
+	return store
+}
+
+// Get - текущий конфиг. Возвращаемое значение нельзя изменять
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Path - путь до файла конфига
+func (s *Store) Path() string {
+	return s.path
+}
+
+// ReloadResult - что изменилось после перезагрузки
+type ReloadResult struct {
+	Applied []string // примененные изменения
+	Ignored []string // изменения, которые требуют перезапуска
+}
+
+// Reload - перечитывает конфиг. Применяется только безопасное подмножество настроек (уровень логов,
+// rate limit, CORS, API-ключи), остальные изменения игнорируются до перезапуска.
+// Если новый конфиг невалиден, остается старый.
+func (s *Store) Reload() (ReloadResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := ReadConfig(s.path)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	old := s.Get()
+	applied := *old
+	applied.Logger = next.Logger
+	applied.Server.CORS = next.Server.CORS
+	applied.Server.RateLimit = next.Server.RateLimit
+	applied.Auth = next.Auth
+
+	result := ReloadResult{
+		Applied: reloadableChanges(old, next),
+		Ignored: restartRequiredChanges(old, next),
+	}
+	s.current.Store(&applied)
+	return result, nil
+}
+
+func reloadableChanges(old, next *Config) []string {
+	var changes []string
+	if old.Logger.Level != next.Logger.Level {
+		changes = append(changes, fmt.Sprintf("logger.level: %s -> %s", old.Logger.Level, next.Logger.Level))
+	}
+	if !slices.Equal(old.Server.CORS.AllowedOrigins, next.Server.CORS.AllowedOrigins) {
+		changes = append(changes, fmt.Sprintf("server.cors.allowed_origins: %v -> %v",
+			old.Server.CORS.AllowedOrigins, next.Server.CORS.AllowedOrigins))
+	}
+	if old.Server.RateLimit != next.Server.RateLimit {
+		changes = append(changes, fmt.Sprintf("server.rate_limit: %+v -> %+v", old.Server.RateLimit, next.Server.RateLimit))
+	}
+	if old.Auth.Enabled != next.Auth.Enabled {
+		changes = append(changes, fmt.Sprintf("auth.enabled: %t -> %t", old.Auth.Enabled, next.Auth.Enabled))
+	}
+	if old.Auth.Header != next.Auth.Header {
+		changes = append(changes, fmt.Sprintf("auth.header: %s -> %s", old.Auth.Header, next.Auth.Header))
+	}
+	if !slices.Equal(old.Auth.APIKeys, next.Auth.APIKeys) {
+		// сами ключи в лог не пишем
+		changes = append(changes, fmt.Sprintf("auth.api_keys: %d -> %d keys", len(old.Auth.APIKeys), len(next.Auth.APIKeys)))
+	}
+	if !maps.Equal(old.Auth.TenantKeys, next.Auth.TenantKeys) {
+		changes = append(changes, fmt.Sprintf("auth.tenant_keys: %d -> %d keys", len(old.Auth.TenantKeys), len(next.Auth.TenantKeys)))
+	}
+	return changes
+}
+
+func restartRequiredChanges(old, next *Config) []string {
+	var changes []string
+	if old.Server.Port != next.Server.Port || old.Server.IPAddress != next.Server.IPAddress ||
+		old.Server.ShutdownTimeout != next.Server.ShutdownTimeout {
+		changes = append(changes, "server")
+	}
+	if old.Storage != next.Storage {
+		changes = append(changes, "storage")
+	}
+	if !reflect.DeepEqual(old.Database, next.Database) {
+		changes = append(changes, "database")
+	}
+	if old.Cache != next.Cache {
+		changes = append(changes, "cache")
+	}
+	if !reflect.DeepEqual(old.Features, next.Features) {
+		changes = append(changes, "features")
+	}
+	if old.Trash != next.Trash {
+		changes = append(changes, "trash")
+	}
+	if old.Idempotency != next.Idempotency {
+		changes = append(changes, "idempotency")
+	}
+	if !reflect.DeepEqual(old.Runtime, next.Runtime) {
+		changes = append(changes, "runtime")
+	}
+	if !reflect.DeepEqual(old.Reload, next.Reload) {
+		changes = append(changes, "reload")
+	}
+	return changes
+}
+
+// Watch - следит за изменением файла конфига (по времени модификации) и пишет в канал changed.
+// Останавливается после закрытия stop
+func (s *Store) Watch(interval time.Duration, changed chan<- struct{}, stop <-chan struct{}) {
+	if s.path == "" || interval <= 0 {
+		return
+	}
+
+	lastMod := modTime(s.path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			mod := modTime(s.path)
+			if mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+			select {
+			case changed <- struct{}{}:
+			default: // перезагрузка уже запрошена
+			}
+		}
+	}
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
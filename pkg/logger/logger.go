@@ -6,6 +6,7 @@ type MyLogger interface {
 	Info(string, string)
 	Warning(string, string)
 	Error(string, string)
+	SetLevel(string)
 }
 
 func NewMyLogger(level string) MyLogger {
@@ -16,23 +16,40 @@ var TimeFormat = time.Now().Format("02.01.2006 15:04:05")
 
 // Log - структура для логера
 type Log struct {
+	mu    sync.RWMutex
 	Level string // Level - уровень логирования. INFO, WARNING, ERROR
 }
 
 // NewLog - конструктор для стуктуры лога
 func NewLog(level string) *Log {
-	choseLevel := level
-	if level != "WARNING" && level != "ERROR" {
-		choseLevel = "INFO"
-	}
 	return &Log{
-		Level: choseLevel,
+		Level: normalizeLevel(level),
+	}
+}
+
+// SetLevel - смена уровня логирования на лету (горячая перезагрузка конфига)
+func (logs *Log) SetLevel(level string) {
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	logs.Level = normalizeLevel(level)
+}
+
+func (logs *Log) level() string {
+	logs.mu.RLock()
+	defer logs.mu.RUnlock()
+	return logs.Level
+}
+
+func normalizeLevel(level string) string {
+	if level != "WARNING" && level != "ERROR" {
+		return "INFO"
 	}
+	return level
 }
 
 // Info - метод для вывода логов с пометкой Info(обычные логи)
 func (logs *Log) Info(message string, place string) {
-	if logs.Level != "INFO" {
+	if logs.level() != "INFO" {
 		return
 	}
 	log.Println("\nLevel: Info" + "\nMessage: " + message + "\nPlace: " + place + "\n")
@@ -42,7 +59,7 @@ func (logs *Log) Info(message string, place string) {
 
 // Warning - метод для вывода логов с пометкой Warning(не крашат программу но опасны)
 func (logs *Log) Warning(message string, place string) {
-	if logs.Level == "ERROR" {
+	if logs.level() == "ERROR" {
 		return
 	}
 	log.Println("\nLevel: Warning" + "\nMessage: " + message + "\nPlace: " + place + "\n")
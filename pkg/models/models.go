@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"github.com/google/uuid"
 	"time"
 )
@@ -14,26 +15,347 @@ type HealthResponse struct {
 	Version   string    `json:"version" example:"1.0.0"`
 }
 
+// ReadinessResponse структура ответа для readiness check
+// @Description Readiness check response
+type ReadinessResponse struct {
+	Ready     bool      `json:"ready" example:"false"`
+	Status    string    `json:"status" example:"waiting for database"`
+	Timestamp time.Time `json:"timestamp" example:"2024-01-15T10:30:00Z"`
+}
+
+// Статусы подписки
+const (
+	StatusActive    = "active"
+	StatusPaused    = "paused"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired" // не хранится: вычисляется, когда end_date уже прошел
+)
+
+// Правила деления совместной подписки
+const (
+	SplitEqual      = "equal"      // поровну между владельцем и участниками
+	SplitPercentage = "percentage" // участники платят свой процент, владелец - остаток
+	SplitPayer      = "payer"      // платит только владелец
+)
+
 // Subscription - подписка пользователя
 // @Description Subscription information
 type Subscription struct {
-	UserID      uuid.UUID  `json:"user_id"`
-	ServiceName string     `json:"service_name"`
-	Price       int        `json:"price"`
-	StartDate   time.Time  `json:"start_date"`         // "07-2025"
-	EndDate     *time.Time `json:"end_date,omitempty"` // "12-2025" или null
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID              uuid.UUID      `json:"id"`        // по нему подписка восстанавливается из корзины
+	TenantID        uuid.UUID      `json:"tenant_id"` // организация подписки
+	UserID          uuid.UUID      `json:"user_id"`
+	ServiceName     string         `json:"service_name"`
+	ServiceID       *uuid.UUID     `json:"service_id,omitempty"` // запись каталога, nil - сервиса нет в каталоге
+	Category        string         `json:"category,omitempty"`
+	Tags            []string       `json:"tags,omitempty"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+	Sharing         *Sharing       `json:"sharing,omitempty"` // nil - подписка не совместная
+	Price           int            `json:"price"`             // базовая цена
+	EffectivePrice  int            `json:"effective_price"`   // не хранится: цена текущего месяца с учетом промо
+	Promo           *Promo         `json:"promo,omitempty"`
+	StartDate       time.Time      `json:"start_date"`          // "07-2025"
+	EndDate         *time.Time     `json:"end_date,omitempty"`  // "12-2025" или null
+	TrialEnd        *time.Time     `json:"trial_end,omitempty"` // последний бесплатный месяц
+	InTrial         bool           `json:"in_trial"`            // не хранится: текущий месяц входит в пробный период
+	Status          string         `json:"status" enums:"active,paused,cancelled,expired"`
+	StatusChangedAt time.Time      `json:"status_changed_at"`
+	CancelledAt     *time.Time     `json:"cancelled_at,omitempty"`
+	Pauses          []Pause        `json:"pauses,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       *time.Time     `json:"deleted_at,omitempty"` // подписка в корзине, nil - не удалена
+	Version         int            `json:"version"`              // растет при каждом изменении, входит в ETag
+}
+
+// Pause - пауза подписки: месяцы с From по To (не включая) не оплачиваются. To == nil - пауза еще идет
+// @Description Subscription pause
+type Pause struct {
+	From      time.Time  `json:"from"`
+	To        *time.Time `json:"to,omitempty"`
+	PausedAt  time.Time  `json:"paused_at"`
+	ResumedAt *time.Time `json:"resumed_at,omitempty"`
+}
+
+// Promo - промо-цена с From по To включительно: фиксированная Price или скидка Percent от базовой цены
+// @Description Promotional pricing window
+type Promo struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Price   *int      `json:"price,omitempty"`
+	Percent *int      `json:"percent,omitempty"`
+}
+
+// Sharing - совместная подписка: платит владелец (user_id подписки), расходы делятся с участниками по правилу Split
+// @Description Shared subscription: members and split rule
+type Sharing struct {
+	Split   string   `json:"split" enums:"equal,percentage,payer"`
+	Members []Member `json:"members"`
+}
+
+// Member - участник совместной подписки
+// @Description Shared subscription member
+type Member struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Percent int       `json:"percent,omitempty" example:"25"` // доля участника при split=percentage
+}
+
+// PromoRequest - промо-период в запросе: задается ровно одно из price и percent
+// @Description Promotional pricing window, e.g. first 3 months at 99
+type PromoRequest struct {
+	From    string `json:"from" example:"01-2026"`
+	To      string `json:"to" example:"03-2026"`
+	Price   *int   `json:"price,omitempty" example:"99"`
+	Percent *int   `json:"percent,omitempty" example:"50"`
 }
 
 // CreateOrUpdateRequest - запрос на создание/обновление
 // @Description Request to create or update a subscription
 type CreateOrUpdateRequest struct {
-	ServiceName string    `json:"service_name"`
-	Price       int       `json:"price"`
+	ServiceName string         `json:"service_name"`
+	Price       int            `json:"price"`
+	UserID      uuid.UUID      `json:"user_id"`
+	StartDate   string         `json:"start_date"`
+	EndDate     string         `json:"end_date,omitempty"`
+	TrialEnd    string         `json:"trial_end,omitempty" example:"09-2025"` // последний бесплатный месяц, опционально
+	Promo       *PromoRequest  `json:"promo,omitempty"`                       // опционально
+	Category    string         `json:"category,omitempty" example:"video"`    // по умолчанию - категория сервиса из каталога
+	Tags        []string       `json:"tags,omitempty" example:"family,work"`
+	Metadata    map[string]any `json:"metadata,omitempty"` // опционально, JSON-объект, заменяется целиком
+	Sharing     *Sharing       `json:"sharing,omitempty"`  // опционально, участники и правило деления
+	Version     int            `json:"-"`                  // ожидаемая версия подписки (If-Match), 0 - без проверки
+}
+
+// StatusChangeRequest - запрос на паузу, возобновление или отмену подписки
+// @Description Request to pause, resume or cancel a subscription
+type StatusChangeRequest struct {
 	UserID      uuid.UUID `json:"user_id"`
-	StartDate   string    `json:"start_date"`
-	EndDate     string    `json:"end_date,omitempty"`
+	ServiceName string    `json:"service_name"`
+	Month       string    `json:"month,omitempty" example:"10-2025"` // с какого месяца, по умолчанию текущий
+	Status      string    `json:"-"`                                 // целевой статус, задает эндпоинт
+	Version     int       `json:"-"`                                 // ожидаемая версия подписки (If-Match), 0 - без проверки
+}
+
+// ListFilter - фильтры списка подписок
+type ListFilter struct {
+	Status   string            // пусто - все статусы
+	Tags     []string          // подписка должна иметь все теги
+	Metadata map[string]string // значения ключей метаданных, metadata.<key>=<value>
+}
+
+// TrialsRequest - поиск пробных периодов, после которых в ближайшие Days дней начнется оплата
+type TrialsRequest struct {
+	UserID uuid.UUID // uuid.Nil - все пользователи
+	Days   int
+}
+
+// TrialsResponse - подписки с заканчивающимся пробным периодом
+type TrialsResponse struct {
+	Days          int            `json:"days"`
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// TrashResponse - удаленные подписки организации, ожидающие очистки
+// @Description Deleted subscriptions
+type TrashResponse struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// Операции журнала аудита
+const (
+	AuditCreate       = "create"
+	AuditUpdate       = "update"
+	AuditDelete       = "delete"        // перенос в корзину
+	AuditRestore      = "restore"       // возврат из корзины
+	AuditStatusChange = "status_change" // пауза, возобновление или отмена
+)
+
+// AuditEntry - запись журнала аудита: кто, когда и как изменил подписку. Before и After - подписка
+// до и после изменения, как ее возвращает API; у созданной нет Before, у удаленной - After
+// @Description Audit log entry
+type AuditEntry struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	UserID         uuid.UUID       `json:"user_id"`
+	ServiceName    string          `json:"service_name"`
+	Operation      string          `json:"operation" enums:"create,update,delete,restore,status_change"`
+	Actor          string          `json:"actor" example:"api-key:3f2a9c1b"`
+	RequestID      string          `json:"request_id,omitempty"`
+	Before         json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After          json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// Capture - запись об изменении подписки before -> after. Ключ подписки берется из after, у удаленной - из before
+func (e AuditEntry) Capture(before, after *Subscription) (AuditEntry, error) {
+	sub := after
+	if sub == nil {
+		sub = before
+	}
+	e.SubscriptionID, e.UserID, e.ServiceName = sub.ID, sub.UserID, sub.ServiceName
+
+	var err error
+	if e.Before, err = snapshot(before); err != nil {
+		return e, err
+	}
+	e.After, err = snapshot(after)
+	return e, err
+}
+
+// snapshot - подписка в JSON для журнала аудита, nil - нет снимка
+func snapshot(sub *Subscription) (json.RawMessage, error) {
+	if sub == nil {
+		return nil, nil
+	}
+	return json.Marshal(sub)
+}
+
+// AuditFilter - фильтры журнала аудита, нулевые значения не фильтруют
+type AuditFilter struct {
+	SubscriptionID uuid.UUID
+	UserID         uuid.UUID
+	Actor          string
+	Operation      string
+	From, To       time.Time // записи с From по To, не включая
+	Limit          int       // 0 - без ограничения
+}
+
+// AuditResponse - записи журнала аудита, сначала новые
+// @Description Audit log entries
+type AuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// IdempotencyRecord - запрос с заголовком Idempotency-Key и его ответ, который получит повтор запроса.
+// Completed == false - запрос еще выполняется, ответа нет
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string // SHA-256 метода, пути и тела запроса
+	Completed   bool
+	Status      int
+	Header      map[string]string // сохраненные заголовки ответа
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// Service - запись каталога сервисов. Aliases - другие написания имени (в нижнем регистре),
+// по ним и по Name без учета регистра находится сервис
+// @Description Service catalog entry
+type Service struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name" example:"Netflix"`
+	Aliases      []string  `json:"aliases" example:"nflx,нетфликс"`
+	Category     string    `json:"category,omitempty" example:"video"`
+	DefaultPrice *int      `json:"default_price,omitempty" example:"599"`
+	Currency     string    `json:"currency" example:"RUB"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ServiceRequest - запрос на создание/обновление записи каталога
+// @Description Request to create or update a service catalog entry
+type ServiceRequest struct {
+	Name         string   `json:"name" example:"Netflix"`
+	Aliases      []string `json:"aliases,omitempty" example:"nflx,нетфликс"`
+	Category     string   `json:"category,omitempty" example:"video"`
+	DefaultPrice *int     `json:"default_price,omitempty" example:"599"`
+	Currency     string   `json:"currency,omitempty" example:"RUB"` // по умолчанию RUB
+}
+
+// ServicesResponse - каталог сервисов
+// @Description Service catalog
+type ServicesResponse struct {
+	Services []Service `json:"services"`
+}
+
+// Organization - организация (tenant): подписки, каталог сервисов и подсчеты одной организации не видны другим
+// @Description Organization (tenant)
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name" example:"Marketing"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrganizationRequest - запрос на создание организации
+// @Description Request to create an organization
+type OrganizationRequest struct {
+	Name string `json:"name" example:"Marketing"`
+}
+
+// OrganizationsResponse - список организаций
+// @Description Organizations
+type OrganizationsResponse struct {
+	Organizations []Organization `json:"organizations"`
+}
+
+// OrganizationMember - пользователь организации. Владельцы и участники подписок становятся участниками
+// организации при записи подписки
+// @Description Organization member
+type OrganizationMember struct {
+	UserID   uuid.UUID `json:"user_id"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// OrganizationMembersResponse - участники организации
+// @Description Organization members
+type OrganizationMembersResponse struct {
+	OrganizationID uuid.UUID            `json:"organization_id"`
+	Members        []OrganizationMember `json:"members"`
+}
+
+// UserTotal - расходы одного пользователя за период с учетом долей в совместных подписках
+type UserTotal struct {
+	UserID uuid.UUID `json:"user_id"`
+	Total  int       `json:"total"`
+}
+
+// OrganizationTotal - расходы организации за период в валюте Currency: всего и по участникам
+type OrganizationTotal struct {
+	Total           int             `json:"total"`
+	Currency        string          `json:"currency"`
+	OtherCurrencies []CurrencyTotal `json:"other_currencies,omitempty"` // в Total и Users не входят
+	Users           []UserTotal     `json:"users"`
+}
+
+// OrganizationTotalResponse - ответ подсчета расходов организации
+// @Description Organization spending for a period, total and per member
+type OrganizationTotalResponse struct {
+	Success         bool            `json:"success"`
+	OrganizationID  uuid.UUID       `json:"organization_id"`
+	Total           int             `json:"total"`
+	Currency        string          `json:"currency"`
+	OtherCurrencies []CurrencyTotal `json:"other_currencies,omitempty"` // в Total и Users не входят
+	Users           []UserTotal     `json:"users"`
+	Period          PeriodInfo      `json:"period"`
+}
+
+// User - пользователь организации: профиль и настройки, по которым считаются его расходы
+// @Description User profile
+type User struct {
+	ID          uuid.UUID `json:"id"`
+	DisplayName string    `json:"display_name" example:"Иван Петров"`
+	Email       string    `json:"email,omitempty" example:"ivan@example.com"`
+	Timezone    string    `json:"timezone" example:"Europe/Moscow"`
+	Currency    string    `json:"currency" example:"RUB"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UserRequest - запрос на создание/обновление пользователя. ID при создании - id, под которым пользователь
+// уже встречается в подписках, по умолчанию новый
+// @Description Request to create or update a user
+type UserRequest struct {
+	ID          uuid.UUID `json:"id,omitempty"`
+	DisplayName string    `json:"display_name" example:"Иван Петров"`
+	Email       string    `json:"email,omitempty" example:"ivan@example.com"`
+	Timezone    string    `json:"timezone,omitempty" example:"Europe/Moscow"` // по умолчанию UTC
+	Currency    string    `json:"currency,omitempty" example:"RUB"`           // по умолчанию RUB
+}
+
+// UsersResponse - пользователи организации
+// @Description Users
+type UsersResponse struct {
+	Users []User `json:"users"`
 }
 
 // DeleteRequest - запрос на удаление
@@ -43,11 +365,23 @@ type DeleteRequest struct {
 	UserID      uuid.UUID `json:"user_id"`
 }
 
-// ErrorResponse - ошибка API
-// @Description Error response
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+// Problem - ошибка API в формате RFC 9457 (application/problem+json)
+// @Description Error response (RFC 9457 problem details). Clients should match on code (or type), not on detail
+type Problem struct {
+	Type      string       `json:"type" example:"urn:problem-type:subscription_not_found"`
+	Title     string       `json:"title" example:"Not Found"`
+	Status    int          `json:"status" example:"404"`
+	Detail    string       `json:"detail,omitempty" example:"Subscription not found"`
+	Code      string       `json:"code" example:"subscription_not_found"`
+	RequestID string       `json:"request_id,omitempty" example:"5f1c2a8f-aff1-4de2-9d28-d66a3fb3fd9e"`
+	Errors    []FieldError `json:"errors,omitempty"` // ошибки отдельных полей при validation_failed и malformed_request
+}
+
+// FieldError - ошибка одного поля или параметра запроса
+// @Description Invalid request field or parameter
+type FieldError struct {
+	Field   string `json:"field" example:"price"`
+	Message string `json:"message" example:"price must not be negative"`
 }
 
 // UserSubscriptionsResponse - подписки пользователя
@@ -63,6 +397,8 @@ type UserSubscriptionsResponse struct {
 type CalculateTotalRequest struct {
 	UserID      uuid.UUID `json:"user_id,omitempty"`      // опционально
 	ServiceName string    `json:"service_name,omitempty"` // опционально
+	Tags        []string  `json:"tags,omitempty"`         // опционально, подписка должна иметь все теги
+	Currency    string    `json:"currency,omitempty"`     // опционально, только подписки на сервисы в этой валюте
 	StartMonth  time.Time `json:"start_month"`            // "01-2024" начало периода
 	EndMonth    time.Time `json:"end_month"`              // "12-2024" конец периода
 }
@@ -70,11 +406,18 @@ type CalculateTotalRequest struct {
 // CalculateTotalResponse - ответ для подсчета суммы
 // @Description Response with total cost calculation
 type CalculateTotalResponse struct {
-	Success  bool       `json:"success"`
-	Total    int        `json:"total"`
-	Currency string     `json:"currency"`
-	Period   PeriodInfo `json:"period"`
-	Filters  FilterInfo `json:"filters"`
+	Success         bool            `json:"success"`
+	Total           int             `json:"total"`
+	Currency        string          `json:"currency"`
+	OtherCurrencies []CurrencyTotal `json:"other_currencies,omitempty"` // в Total не входят
+	Period          PeriodInfo      `json:"period"`
+	Filters         FilterInfo      `json:"filters"`
+}
+
+// CurrencyTotal - сумма подписок на сервисы в валюте Currency
+type CurrencyTotal struct {
+	Currency string `json:"currency" example:"USD"`
+	Total    int    `json:"total"`
 }
 
 type PeriodInfo struct {
@@ -83,6 +426,25 @@ type PeriodInfo struct {
 }
 
 type FilterInfo struct {
-	UserID      *string `json:"user_id,omitempty"`
-	ServiceName *string `json:"service_name,omitempty"`
+	UserID      *string  `json:"user_id,omitempty"`
+	ServiceName *string  `json:"service_name,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// CategoryTotal - расходы на одну категорию за период, Category "" - подписки без категории
+type CategoryTotal struct {
+	Category string `json:"category"`
+	Total    int    `json:"total"`
+}
+
+// CategoryTotalsResponse - ответ отчета о расходах по категориям
+// @Description Spending by category for a period
+type CategoryTotalsResponse struct {
+	Success         bool            `json:"success"`
+	Total           int             `json:"total"`
+	Currency        string          `json:"currency"`
+	OtherCurrencies []CurrencyTotal `json:"other_currencies,omitempty"` // в Total и Categories не входят
+	Categories      []CategoryTotal `json:"categories"`
+	Period          PeriodInfo      `json:"period"`
+	Filters         FilterInfo      `json:"filters"`
 }
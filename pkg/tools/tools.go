@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"agrigation_api/pkg/models"
 	"encoding/json"
 	"github.com/google/uuid"
 	"net/http"
@@ -48,14 +47,6 @@ func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-// WriteError - хелпер для ошибок
-func WriteError(w http.ResponseWriter, status int, message string) {
-	WriteJSON(w, status, models.ErrorResponse{
-		Error:   http.StatusText(status),
-		Message: message,
-	})
-}
-
 // ParseMonthYear : парсим дату "MM-YYYY" -> time.Time
 func ParseMonthYear(dateStr string) (time.Time, error) {
 	t, err := time.Parse("01-2006", dateStr)
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"agrigation_api/internal/app/server"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/config"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAuditAPI(t *testing.T) {
+	conf := config.Default()
+	conf.Auth.Enabled = true
+	conf.Auth.APIKeys = []string{"operator"}
+	conf.Auth.TenantKeys = map[string]string{"tenant-key": uuid.NewString()}
+	srv := server.NewServer(config.NewStore("", conf), NewTestLog("ERROR"))
+	srv.Activate(service.NewSubscriptionService(memory.NewRepository()))
+
+	send := func(method, path, key string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", key)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, req)
+		return rec
+	}
+	entries := func(rec *httptest.ResponseRecorder) []models.AuditEntry {
+		t.Helper()
+		var response models.AuditResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || rec.Code != http.StatusOK {
+			t.Fatal("audit:", rec.Code, rec.Body.String())
+		}
+		return response.Entries
+	}
+
+	userID := uuid.NewString()
+	subscription := func(price string) string {
+		return `{"service_name":"Netflix","price":` + price + `,"user_id":"` + userID + `","start_date":"01-2025"}`
+	}
+	rec := send("POST", "/api/v1/subscriptions/", "operator", map[string]string{"X-Actor": "ivan", "X-Request-ID": "req-1"}, subscription("500"))
+	var created models.Subscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated {
+		t.Fatal("create:", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("X-Request-ID") != "req-1" {
+		t.Error("request id must be returned", rec.Header())
+	}
+
+	// без X-Actor автор - отпечаток ключа, сам ключ в журнал не попадает
+	rec = send("PUT", "/api/v1/subscriptions/", "operator", nil, subscription("650"))
+	var updated models.Subscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil || rec.Code != http.StatusCreated {
+		t.Fatal("update:", rec.Code, rec.Body.String())
+	}
+	if !updated.UpdatedAt.After(created.UpdatedAt) {
+		t.Error("updated_at must change on update", created.UpdatedAt, updated.UpdatedAt)
+	}
+	generated := rec.Header().Get("X-Request-ID")
+	if generated == "" {
+		t.Error("request id must be generated")
+	}
+
+	history := "/api/v1/subscriptions/" + created.ID.String() + "/history"
+	list := entries(send("GET", history, "tenant-key", nil, ""))
+	if len(list) != 0 {
+		t.Error("history must be scoped to the organization", list)
+	}
+	list = entries(send("GET", history, "operator", nil, ""))
+	if len(list) != 2 {
+		t.Fatal("expected 2 entries", list)
+	}
+	update, create := list[0], list[1]
+	if create.Operation != models.AuditCreate || create.Actor != "ivan" || create.RequestID != "req-1" || create.Before != nil {
+		t.Error("create entry is wrong", create)
+	}
+	if update.Operation != models.AuditUpdate || !strings.HasPrefix(update.Actor, "api-key:") || strings.Contains(update.Actor, "operator") ||
+		update.RequestID != generated {
+		t.Error("update entry is wrong", update)
+	}
+	var before, after models.Subscription
+	if json.Unmarshal(update.Before, &before) != nil || json.Unmarshal(update.After, &after) != nil || before.Price != 500 || after.Price != 650 {
+		t.Error("update entry must keep the price before and after", string(update.Before), string(update.After))
+	}
+
+	if rec := send("POST", "/api/v1/subscriptions/pause/", "operator", nil, `{"service_name":"Netflix","user_id":"`+userID+`","month":"03-2025"}`); rec.Code != http.StatusOK {
+		t.Fatal("pause:", rec.Code, rec.Body.String())
+	}
+	if rec := send("DELETE", "/api/v1/subscriptions/", "operator", map[string]string{"X-Actor": "anna"}, `{"service_name":"Netflix","user_id":"`+userID+`"}`); rec.Code != http.StatusNoContent {
+		t.Fatal("delete:", rec.Code, rec.Body.String())
+	}
+	if rec := send("POST", "/api/v1/subscriptions/"+created.ID.String()+"/restore", "operator", nil, ""); rec.Code != http.StatusOK {
+		t.Fatal("restore:", rec.Code, rec.Body.String())
+	}
+
+	cases := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"all", "", []string{"restore", "delete", "status_change", "update", "create"}},
+		{"actor", "?actor=anna", []string{"delete"}},
+		{"operation", "?operation=status_change", []string{"status_change"}},
+		{"user", "?user_id=" + userID, []string{"restore", "delete", "status_change", "update", "create"}},
+		{"other user", "?user_id=" + uuid.NewString(), nil},
+		{"limit", "?limit=2", []string{"restore", "delete"}},
+		{"period", "?from=2000-01-01T00:00:00Z&to=2001-01-01T00:00:00Z", nil},
+	}
+	for _, c := range cases {
+		var operations []string
+		for _, entry := range entries(send("GET", "/api/v1/admin/audit"+c.query, "operator", nil, "")) {
+			operations = append(operations, entry.Operation)
+		}
+		if strings.Join(operations, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, operations)
+		}
+	}
+
+	errorCases := []struct {
+		name     string
+		path     string
+		key      string
+		expected int
+	}{
+		{"invalid subscription id", "/api/v1/subscriptions/netflix/history", "operator", http.StatusBadRequest},
+		{"unknown resource", "/api/v1/subscriptions/" + created.ID.String() + "/changes", "operator", http.StatusNotFound},
+		{"invalid limit", history + "?limit=0", "operator", http.StatusBadRequest},
+		{"audit for tenant key", "/api/v1/admin/audit", "tenant-key", http.StatusForbidden},
+		{"invalid operation", "/api/v1/admin/audit?operation=purge", "operator", http.StatusBadRequest},
+		{"invalid user id", "/api/v1/admin/audit?user_id=ivan", "operator", http.StatusBadRequest},
+		{"invalid from", "/api/v1/admin/audit?from=01-2025", "operator", http.StatusBadRequest},
+		{"user subscriptions still routed", "/api/v1/subscriptions/user/" + userID, "operator", http.StatusOK},
+		{"trials still routed", "/api/v1/subscriptions/trials/", "operator", http.StatusOK},
+	}
+	for _, c := range errorCases {
+		if rec := send("GET", c.path, c.key, nil, ""); rec.Code != c.expected {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.expected, rec.Code, rec.Body.String())
+		}
+	}
+}
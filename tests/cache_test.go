@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"agrigation_api/internal/cache"
+	"agrigation_api/internal/database/consistency"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/middleware"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLRUEvictionAndTTL(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(2)
+
+	_ = lru.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = lru.Set(ctx, "b", []byte("2"), time.Minute)
+	// обращение к "a" делает вытесняемым "b"
+	if _, ok, _ := lru.Get(ctx, "a"); !ok {
+		t.Fatal("expected hit for a")
+	}
+	_ = lru.Set(ctx, "c", []byte("3"), time.Minute)
+	if _, ok, _ := lru.Get(ctx, "b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if lru.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", lru.Len())
+	}
+
+	_ = lru.Set(ctx, "short", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := lru.Get(ctx, "short"); ok {
+		t.Error("expected expired entry to be missed")
+	}
+
+	if generation, _ := lru.Incr(ctx, "user"); generation != 1 {
+		t.Errorf("expected generation 1, got %d", generation)
+	}
+	if generation, _ := lru.Generation(ctx, "user"); generation != 1 {
+		t.Errorf("expected generation 1, got %d", generation)
+	}
+}
+
+func TestCachedSubscriptions(t *testing.T) {
+	svc := service.NewCachedSubscriptions(service.NewSubscriptionService(memory.NewRepository()),
+		cache.NewLRU(100), time.Minute, NewTestLog("ERROR"))
+
+	user, other := uuid.New(), uuid.New()
+	request := models.CalculateTotalRequest{
+		UserID:     user,
+		StartMonth: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndMonth:   time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+	create := func(userID uuid.UUID, name string, price int) {
+		t.Helper()
+		_, err := svc.CreateSubscription(context.Background(), models.CreateOrUpdateRequest{
+			UserID: userID, ServiceName: name, Price: price, StartDate: "01-2025",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	total := func(ctx context.Context, req models.CalculateTotalRequest) (int, cache.Status) {
+		t.Helper()
+		ctx, recorder := cache.WithRecorder(ctx)
+		sum, err := svc.CalculateTotal(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum, recorder.Status()
+	}
+
+	create(user, "Netflix", 400)
+	if sum, status := total(context.Background(), request); sum != 400 || status != cache.Miss {
+		t.Errorf("expected 400 MISS, got %d %s", sum, status)
+	}
+	if sum, status := total(context.Background(), request); sum != 400 || status != cache.Hit {
+		t.Errorf("expected 400 HIT, got %d %s", sum, status)
+	}
+
+	// запись другого пользователя не сбрасывает кеш user
+	create(other, "Spotify", 200)
+	if _, status := total(context.Background(), request); status != cache.Hit {
+		t.Errorf("expected HIT after other user's write, got %s", status)
+	}
+
+	// запись того же пользователя инвалидирует подсчет
+	create(user, "Spotify", 200)
+	if sum, status := total(context.Background(), request); sum != 600 || status != cache.Miss {
+		t.Errorf("expected 600 MISS, got %d %s", sum, status)
+	}
+
+	// подсчет по сервису инвалидируется записью любого пользователя этого сервиса
+	byService := request
+	byService.UserID = uuid.Nil
+	byService.ServiceName = "Spotify"
+	if sum, _ := total(context.Background(), byService); sum != 400 {
+		t.Errorf("expected 400 for Spotify, got %d", sum)
+	}
+	if err := svc.DeleteSubscription(context.Background(), other, "Spotify"); err != nil {
+		t.Fatal(err)
+	}
+	if sum, status := total(context.Background(), byService); sum != 200 || status != cache.Miss {
+		t.Errorf("expected 200 MISS after delete, got %d %s", sum, status)
+	}
+
+	if _, status := total(consistency.WithPrimary(context.Background()), request); status != cache.Bypass {
+		t.Errorf("expected BYPASS for read-your-writes, got %s", status)
+	}
+}
+
+func TestCacheStatusMiddleware(t *testing.T) {
+	handler := middleware.CacheStatusMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cached" {
+			cache.Record(r.Context(), cache.Hit)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/cached", nil))
+	if rec.Header().Get(middleware.HeaderCache) != string(cache.Hit) {
+		t.Errorf("expected X-Cache HIT, got %q", rec.Header().Get(middleware.HeaderCache))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	if value := rec.Header().Get(middleware.HeaderCache); value != "" {
+		t.Errorf("expected no X-Cache header, got %q", value)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	handlers2 "agrigation_api/internal/app/server/handlers"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCatalogHandlers(t *testing.T) {
+	testService := service.NewSubscriptionService(memory.NewRepository())
+	handlers := handlers2.NewHandler(testService, NewTestLog("ERROR"))
+
+	send := func(handler http.HandlerFunc, method, id string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, "/api/v1/services/"+id, bytes.NewReader(data))
+		req.SetPathValue("id", id)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	price := 599
+	rec := send(handlers.CreateService, "POST", "", models.ServiceRequest{
+		Name: "Netflix", Aliases: []string{"NFLX"}, Category: "video", DefaultPrice: &price,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatal("create service:", rec.Code, rec.Body.String())
+	}
+	var netflix models.Service
+	if err := json.Unmarshal(rec.Body.Bytes(), &netflix); err != nil || netflix.Currency != "RUB" {
+		t.Fatal("created service is wrong", rec.Body.String(), err)
+	}
+
+	cases := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		id       string
+		body     any
+		expected int
+	}{
+		{"alias taken", handlers.CreateService, "POST", "", models.ServiceRequest{Name: "Netflix Kids", Aliases: []string{"nflx"}}, http.StatusConflict},
+		{"without name", handlers.CreateService, "POST", "", models.ServiceRequest{Name: "  "}, http.StatusBadRequest},
+		{"invalid currency", handlers.CreateService, "POST", "", models.ServiceRequest{Name: "Okko", Currency: "rubles"}, http.StatusBadRequest},
+		{"invalid id", handlers.GetService, "GET", "netflix", nil, http.StatusBadRequest},
+		{"not found", handlers.GetService, "GET", uuid.NewString(), nil, http.StatusNotFound},
+		{"get", handlers.GetService, "GET", netflix.ID.String(), nil, http.StatusOK},
+		{"update not found", handlers.UpdateService, "PUT", uuid.NewString(), models.ServiceRequest{Name: "Okko"}, http.StatusNotFound},
+		{"list", handlers.ListServices, "GET", "", nil, http.StatusOK},
+	}
+	for _, c := range cases {
+		if rec := send(c.handler, c.method, c.id, c.body); rec.Code != c.expected {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	// имя подписки разрешается по каталогу, цена 0 - цена сервиса по умолчанию
+	userID := uuid.New()
+	sub, err := testService.CreateSubscription(context.Background(), models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: " nflx ", StartDate: "01-2025",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ServiceName != "Netflix" || sub.Price != 599 || sub.ServiceID == nil || *sub.ServiceID != netflix.ID {
+		t.Error("subscription must use the catalog entry", sub)
+	}
+	if got, err := testService.GetSubscription(context.Background(), userID, "NETFLIX"); err != nil || got == nil {
+		t.Error("subscription must be found by any spelling", got, err)
+	}
+	if _, err := testService.CreateSubscription(context.Background(), models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Okko", StartDate: "01-2025",
+	}); !errors.Is(err, service.ErrPriceRequired) {
+		t.Error("price must be required without a default price, got", err)
+	}
+
+	if rec := send(handlers.DeleteService, "DELETE", netflix.ID.String(), nil); rec.Code != http.StatusNoContent {
+		t.Error("delete service:", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	handlers2 "agrigation_api/internal/app/server/handlers"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCategoryHandlers(t *testing.T) {
+	testService := service.NewSubscriptionService(memory.NewRepository())
+	handlers := handlers2.NewHandler(testService, NewTestLog("ERROR"))
+	ctx := context.Background()
+
+	if _, err := testService.CreateService(ctx, models.ServiceRequest{Name: "Netflix", Category: "streaming"}); err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+	for _, req := range []models.CreateOrUpdateRequest{
+		{ServiceName: "netflix", Price: 600, Tags: []string{"Family"}},
+		{ServiceName: "AWS", Price: 1500, Category: "cloud", Tags: []string{"work"}},
+		{ServiceName: "Coursera", Price: 900, Category: "education", Tags: []string{"work"}},
+	} {
+		req.UserID, req.StartDate = userID, "01-2025"
+		if _, err := testService.CreateSubscription(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sub, _ := testService.GetSubscription(ctx, userID, "Netflix"); sub == nil || sub.Category != "streaming" {
+		t.Error("category must default to the catalog category", sub)
+	}
+
+	get := func(handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.SetPathValue("id", userID.String())
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := get(handlers.CategoryTotalsHandler, "/api/v1/subscriptions/total/categories/?start_month=01-2025&end_month=12-2025&user_id="+userID.String())
+	var report models.CategoryTotalsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK {
+		t.Fatal("category report:", rec.Code, rec.Body.String())
+	}
+	if report.Total != 3000 || len(report.Categories) != 3 || report.Categories[0].Category != "cloud" || report.Categories[2].Category != "streaming" {
+		t.Error("category report is wrong", rec.Body.String())
+	}
+
+	rec = get(handlers.CalculateTotalHandler, "/api/v1/subscriptions/total/?start_month=01-2025&end_month=12-2025&tag=WORK")
+	var total models.CalculateTotalResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &total); err != nil || total.Total != 2400 || len(total.Filters.Tags) != 1 {
+		t.Error("total with tag filter is wrong", rec.Body.String())
+	}
+
+	rec = get(handlers.ListUserSubscriptions, "/api/v1/subscriptions/user/"+userID.String()+"?tag=family")
+	var list struct {
+		Subscriptions []models.Subscription `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Subscriptions) != 1 || list.Subscriptions[0].ServiceName != "Netflix" {
+		t.Error("listing with tag filter is wrong", rec.Body.String())
+	}
+
+	if rec := get(handlers.ListUserSubscriptions, "/api/v1/subscriptions/user/"+userID.String()+"?tag="+strings.Repeat("a", 51)); rec.Code != http.StatusBadRequest {
+		t.Error("too long tag must be rejected", rec.Code)
+	}
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"agrigation_api/internal/database/postgres"
+	"agrigation_api/pkg/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFileAndEnv(t *testing.T) {
+	path := writeTestConfig(t, `
+server:
+  port: 8080
+  ip_address: 0.0.0.0
+  shutdown_timeout: 5s
+database:
+  host: db
+  database: subs
+logger:
+  level: WARNING
+`)
+	t.Setenv("PG_HOST", "replica")
+	t.Setenv("API_KEYS", "key1, key2")
+
+	conf, err := config.ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Server.Port != 8080 || conf.Server.ShutdownTimeout != 5*time.Second {
+		t.Error("server section is wrong", conf.Server)
+	}
+	if conf.Database.Host != "replica" || conf.Database.Database != "subs" || conf.Database.Port != 5432 {
+		t.Error("database section is wrong", conf.Database)
+	}
+	if conf.Logger.Level != "WARNING" {
+		t.Error("logger level is wrong", conf.Logger.Level)
+	}
+	if len(conf.Auth.APIKeys) != 2 || conf.Auth.APIKeys[1] != "key2" {
+		t.Error("api keys are wrong", conf.Auth.APIKeys)
+	}
+	if conf.Server.Address() != "0.0.0.0:8080" {
+		t.Error("address is wrong", conf.Server.Address())
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	path := writeTestConfig(t, `
+server:
+  port: 70000
+logger:
+  level: DEBUG
+auth:
+  enabled: true
+`)
+	_, err := config.ReadConfig(path)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, field := range []string{"server.port", "logger.level", "auth.api_keys"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("error does not mention %s: %v", field, err)
+		}
+	}
+
+	if _, err := config.ReadConfig(writeTestConfig(t, "unknown_section: 1\n")); err == nil {
+		t.Error("expected error for unknown field")
+	}
+
+	t.Setenv("SERVER_PORT", "not-a-number")
+	if _, err := config.ReadConfig(""); err == nil || !strings.Contains(err.Error(), "SERVER_PORT") {
+		t.Error("expected error for invalid env", err)
+	}
+}
+
+func TestDatabasePoolConfig(t *testing.T) {
+	path := writeTestConfig(t, `
+database:
+  host: db
+  pool:
+    max_conns: 8
+    min_conns: 2
+    max_conn_idle_time: 5m
+  sslmode: require
+  application_name: subs-test
+  statement_timeout: 3s
+`)
+	t.Setenv("PG_QUERY_TIMEOUT", "2s")
+
+	conf, err := config.ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Database.QueryTimeout != 2*time.Second {
+		t.Error("query timeout is wrong", conf.Database.QueryTimeout)
+	}
+
+	poolConfig, err := postgres.PoolConfig(conf.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if poolConfig.MaxConns != 8 || poolConfig.MinConns != 2 || poolConfig.MaxConnIdleTime != 5*time.Minute {
+		t.Error("pool settings are wrong", poolConfig.MaxConns, poolConfig.MinConns, poolConfig.MaxConnIdleTime)
+	}
+	params := poolConfig.ConnConfig.RuntimeParams
+	if params["application_name"] != "subs-test" || params["statement_timeout"] != "3000" {
+		t.Error("runtime params are wrong", params)
+	}
+	if poolConfig.ConnConfig.TLSConfig == nil {
+		t.Error("sslmode=require must enable TLS")
+	}
+
+	invalid := writeTestConfig(t, `
+database:
+  pool:
+    max_conns: 2
+    min_conns: 4
+  sslmode: strict
+  sslrootcert: /nonexistent/ca.pem
+`)
+	_, err = config.ReadConfig(invalid)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, field := range []string{"database.pool.min_conns", "database.sslmode", "database.sslrootcert"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("error does not mention %s: %v", field, err)
+		}
+	}
+}
+
+func TestStorageConfig(t *testing.T) {
+	t.Setenv("STORAGE", "memory")
+	conf, err := config.ReadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Storage.Backend != config.StorageMemory {
+		t.Error("storage backend is wrong", conf.Storage.Backend)
+	}
+
+	t.Setenv("STORAGE", "mongo")
+	if _, err := config.ReadConfig(""); err == nil || !strings.Contains(err.Error(), "storage.backend") {
+		t.Error("expected storage.backend validation error", err)
+	}
+}
+
+func TestCacheConfig(t *testing.T) {
+	t.Setenv("CACHE_BACKEND", "redis")
+	t.Setenv("REDIS_ADDRESS", "redis:6379")
+	t.Setenv("CACHE_TTL", "30s")
+	conf, err := config.ReadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Cache.Backend != config.CacheRedis || conf.Cache.Redis.Address != "redis:6379" || conf.Cache.TTL != 30*time.Second {
+		t.Error("cache config is wrong", conf.Cache)
+	}
+
+	t.Setenv("CACHE_TTL", "0s")
+	if _, err := config.ReadConfig(""); err == nil || !strings.Contains(err.Error(), "cache.ttl") {
+		t.Error("expected cache.ttl validation error", err)
+	}
+
+	t.Setenv("CACHE_BACKEND", "memcached")
+	if _, err := config.ReadConfig(""); err == nil || !strings.Contains(err.Error(), "cache.backend") {
+		t.Error("expected cache.backend validation error", err)
+	}
+}
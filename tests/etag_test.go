@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"agrigation_api/internal/app/server"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/config"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestETagAPI(t *testing.T) {
+	newServer := func(requireIfMatch bool) *server.Server {
+		conf := config.Default()
+		conf.Features.RequireIfMatch = requireIfMatch
+		srv := server.NewServer(config.NewStore("", conf), NewTestLog("ERROR"))
+		srv.Activate(service.NewSubscriptionService(memory.NewRepository()))
+		return srv
+	}
+	srv := newServer(false)
+	send := func(srv *server.Server, method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	userID := uuid.NewString()
+	subscription := func(price string) string {
+		return `{"service_name":"Netflix","price":` + price + `,"user_id":"` + userID + `","start_date":"01-2025"}`
+	}
+	key := `{"service_name":"Netflix","user_id":"` + userID + `"}`
+	get := "/api/v1/subscriptions/?user_id=" + userID + "&service_name=Netflix"
+
+	rec := send(srv, "POST", "/api/v1/subscriptions/", "", subscription("500"))
+	created := rec.Header().Get("ETag")
+	if rec.Code != http.StatusCreated || created == "" {
+		t.Fatal("create must return ETag:", rec.Code, rec.Header())
+	}
+	rec = send(srv, "GET", get, "", "")
+	if rec.Header().Get("ETag") != created {
+		t.Error("GET must return the same ETag", created, rec.Header().Get("ETag"))
+	}
+
+	// If-None-Match: пока подписка не менялась - 304 без тела
+	req := httptest.NewRequest("GET", get, nil)
+	req.Header.Set("If-None-Match", created)
+	rec = httptest.NewRecorder()
+	srv.Router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Error("expected 304 for unchanged subscription:", rec.Code, rec.Body.String())
+	}
+
+	// первый администратор обновляет подписку, второй со старым ETag получает 412
+	rec = send(srv, "PUT", "/api/v1/subscriptions/", created, subscription("650"))
+	updated := rec.Header().Get("ETag")
+	if rec.Code != http.StatusCreated || updated == "" || updated == created {
+		t.Fatal("update must return a new ETag:", rec.Code, rec.Header())
+	}
+	rec = send(srv, "PUT", "/api/v1/subscriptions/", created, subscription("700"))
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Error("stale If-Match must get 412:", rec.Code, rec.Body.String())
+	}
+	rec = send(srv, "DELETE", "/api/v1/subscriptions/", created, key)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Error("stale If-Match on delete must get 412:", rec.Code, rec.Body.String())
+	}
+	rec = send(srv, "POST", "/api/v1/subscriptions/pause/", created, key)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Error("stale If-Match on status change must get 412:", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", get, nil)
+	req.Header.Set("If-None-Match", created)
+	rec = httptest.NewRecorder()
+	srv.Router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != updated {
+		t.Error("changed subscription must be returned with the new ETag:", rec.Code, rec.Header())
+	}
+
+	rec = send(srv, "DELETE", "/api/v1/subscriptions/", `"other", `+updated, key)
+	if rec.Code != http.StatusNoContent {
+		t.Error("delete with a matching ETag in the list must succeed:", rec.Code, rec.Body.String())
+	}
+	rec = send(srv, "DELETE", "/api/v1/subscriptions/", "*", key)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Error("If-Match: * on a missing subscription must get 412:", rec.Code, rec.Body.String())
+	}
+
+	// features.require_if_match: изменение без If-Match - 428, чтение и создание - без условий
+	strict := newServer(true)
+	rec = send(strict, "POST", "/api/v1/subscriptions/", "", subscription("500"))
+	if rec.Code != http.StatusCreated {
+		t.Fatal("create must not require If-Match:", rec.Code, rec.Body.String())
+	}
+	tag := rec.Header().Get("ETag")
+	if rec = send(strict, "PUT", "/api/v1/subscriptions/", "", subscription("650")); rec.Code != http.StatusPreconditionRequired {
+		t.Error("update without If-Match must get 428:", rec.Code, rec.Body.String())
+	}
+	if rec = send(strict, "DELETE", "/api/v1/subscriptions/", "", key); rec.Code != http.StatusPreconditionRequired {
+		t.Error("delete without If-Match must get 428:", rec.Code, rec.Body.String())
+	}
+	if rec = send(strict, "PUT", "/api/v1/subscriptions/", tag, subscription("650")); rec.Code != http.StatusCreated {
+		t.Error("update with If-Match must succeed:", rec.Code, rec.Body.String())
+	}
+}
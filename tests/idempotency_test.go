@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"agrigation_api/internal/app/server"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/config"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestIdempotencyAPI(t *testing.T) {
+	srv := server.NewServer(config.NewStore("", config.Default()), NewTestLog("ERROR"))
+	srv.Activate(service.NewSubscriptionService(memory.NewRepository()))
+	send := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	userID := uuid.NewString()
+	subscription := func(price string) string {
+		return `{"service_name":"Netflix","price":` + price + `,"user_id":"` + userID + `","start_date":"01-2025"}`
+	}
+
+	first := send("POST", "/api/v1/subscriptions/", "create-1", subscription("500"))
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatal("first request must be executed:", first.Code, first.Body.String())
+	}
+	// повтор с тем же ключом получает исходный ответ, а не "already exists"
+	retry := send("POST", "/api/v1/subscriptions/", "create-1", subscription("500"))
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() ||
+		retry.Header().Get("ETag") != first.Header().Get("ETag") || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry must replay the original response:", retry.Code, retry.Header(), retry.Body.String())
+	}
+
+	rec := send("POST", "/api/v1/subscriptions/", "create-1", subscription("900"))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Error("key reused with another body must get 422:", rec.Code, rec.Body.String())
+	}
+	rec = send("POST", "/api/v1/subscriptions/", strings.Repeat("k", 201), subscription("500"))
+	if rec.Code != http.StatusBadRequest {
+		t.Error("too long key must get 400:", rec.Code)
+	}
+
+	// ошибка клиента тоже сохраняется: повтор получает тот же ответ
+	rec = send("POST", "/api/v1/subscriptions/", "create-2", subscription("500"))
+	if rec.Code != http.StatusConflict || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatal("duplicate with a new key must be executed:", rec.Code, rec.Body.String())
+	}
+	if retry := send("POST", "/api/v1/subscriptions/", "create-2", subscription("500")); retry.Code != http.StatusConflict ||
+		retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("stored error must be replayed:", retry.Code, retry.Header())
+	}
+
+	// без ключа и для GET поведение прежнее
+	if rec := send("POST", "/api/v1/subscriptions/", "", subscription("500")); rec.Code != http.StatusConflict {
+		t.Error("duplicate without a key must get 409:", rec.Code)
+	}
+	get := "/api/v1/subscriptions/?user_id=" + userID + "&service_name=Netflix"
+	if rec := send("GET", get, "create-1", ""); rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("GET must ignore Idempotency-Key:", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	handlers2 "agrigation_api/internal/app/server/handlers"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMetadataHandlers(t *testing.T) {
+	testService := service.NewSubscriptionService(memory.NewRepository())
+	handlers := handlers2.NewHandler(testService, NewTestLog("ERROR"))
+	ctx := context.Background()
+
+	userID := uuid.New()
+	for _, req := range []models.CreateOrUpdateRequest{
+		{ServiceName: "AWS", Price: 1500, Metadata: map[string]any{"cost_center": "eng", "invoice": "INV-7"}},
+		{ServiceName: "Figma", Price: 900, Metadata: map[string]any{"cost_center": "design"}},
+		{ServiceName: "Netflix", Price: 600},
+	} {
+		req.UserID, req.StartDate = userID, "01-2025"
+		if _, err := testService.CreateSubscription(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(query string) (int, []models.Subscription) {
+		req := httptest.NewRequest("GET", "/api/v1/subscriptions/user/"+userID.String()+"?"+query, nil)
+		req.SetPathValue("id", userID.String())
+		rec := httptest.NewRecorder()
+		handlers.ListUserSubscriptions(rec, req)
+		var response struct {
+			Subscriptions []models.Subscription `json:"subscriptions"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response.Subscriptions
+	}
+
+	code, subscriptions := list("metadata.cost_center=eng")
+	if code != http.StatusOK || len(subscriptions) != 1 || subscriptions[0].ServiceName != "AWS" ||
+		subscriptions[0].Metadata["invoice"] != "INV-7" {
+		t.Error("listing with metadata filter is wrong", code, subscriptions)
+	}
+	if _, subscriptions := list("metadata.cost_center=eng&metadata.invoice=INV-8"); len(subscriptions) != 0 {
+		t.Error("every metadata filter must match", subscriptions)
+	}
+	for _, query := range []string{"metadata.cost%20center=eng", "metadata.cost_center=eng&metadata.cost_center=ops"} {
+		if code, _ := list(query); code != http.StatusBadRequest {
+			t.Error("invalid metadata filter must be rejected:", query, code)
+		}
+	}
+
+	create := func(meta string) int {
+		body := `{"service_name":"Slack","price":500,"user_id":"` + userID.String() + `","start_date":"01-2025","metadata":` + meta + `}`
+		rec := httptest.NewRecorder()
+		handlers.CreateSubscription(rec, httptest.NewRequest("POST", "/api/v1/subscriptions/", bytes.NewBufferString(body)))
+		return rec.Code
+	}
+	invalid := []string{
+		`{"1st":"x"}`,
+		`{"cost.center":"x"}`,
+		`{"note":"` + strings.Repeat("a", 5000) + `"}`,
+		`["not","an","object"]`,
+	}
+	for _, meta := range invalid {
+		if code := create(meta); code != http.StatusBadRequest {
+			t.Errorf("metadata %.30s must be rejected, got %d", meta, code)
+		}
+	}
+	if code := create(`{"account_email":"team@example.com","seats":5}`); code != http.StatusCreated {
+		t.Error("valid metadata must be accepted", code)
+	}
+}
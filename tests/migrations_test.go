@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"agrigation_api/internal/database/sqlite"
+	"agrigation_api/migrations"
+	"agrigation_api/pkg/config"
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 || all[0].Version != 1 {
+		t.Fatal("first migration must have version 1")
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Version <= all[i-1].Version {
+			t.Error("migrations are not sorted", all[i-1].Version, all[i].Version)
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("CREATE INDEX ...")},
+		"0002_add_index.down.sql": {Data: []byte("DROP INDEX ...")},
+		"0001_init.up.sql":        {Data: []byte("CREATE TABLE ...")},
+		"0001_init.down.sql":      {Data: []byte("DROP TABLE ...")},
+		"README.md":               {Data: []byte("ignored")},
+	}
+	loaded, err := migrations.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || loaded[0].Name != "init" || loaded[1].Version != 2 || loaded[1].Down != "DROP INDEX ..." {
+		t.Error("unexpected migrations", loaded)
+	}
+
+	broken := []fstest.MapFS{
+		{"0001_init.up.sql": {Data: []byte("")}},                                      // нет down
+		{"init.up.sql": {Data: []byte("")}},                                           // нет версии
+		{"0001_init.up.sql": {}, "0001_init.down.sql": {}, "1_init.up.sql": {}},       // дубликат версии
+		{"0001_init.up.sql": {}, "0001_init.down.sql": {}, "0001_other.down.sql": {}}, // разные имена
+	}
+	for i, fsys := range broken {
+		if _, err := migrations.Load(fsys); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestSQLiteMigrator(t *testing.T) {
+	ctx := context.Background()
+	conf := config.Default().Storage.SQLite
+	conf.Path = filepath.Join(t.TempDir(), "migrations.db")
+	db, err := sqlite.Open(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewSQLiteMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) == 0 {
+		t.Fatal("up failed", applied, err)
+	}
+	if again, err := migrator.Up(ctx); err != nil || len(again) != 0 {
+		t.Error("second up must be a no-op", again, err)
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if !s.Applied || s.AppliedAt == nil || s.Missing {
+			t.Error("migration must be applied", s)
+		}
+	}
+
+	if _, err := migrator.To(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	var tables int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'subscriptions'").Scan(&tables); err != nil || tables != 0 {
+		t.Error("subscriptions table must be dropped", tables, err)
+	}
+}
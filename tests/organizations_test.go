@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"agrigation_api/internal/app/server"
+	"agrigation_api/internal/cache"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/internal/tenant"
+	"agrigation_api/pkg/config"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestOrganizationsAPI(t *testing.T) {
+	testService := service.NewSubscriptionService(memory.NewRepository())
+	sales, err := testService.CreateOrganization(context.Background(), models.OrganizationRequest{Name: "Sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.Default()
+	conf.Auth.Enabled = true
+	conf.Auth.APIKeys = []string{"operator"}
+	conf.Auth.TenantKeys = map[string]string{"sales-key": sales.ID.String()}
+	srv := server.NewServer(config.NewStore("", conf), NewTestLog("ERROR"))
+	srv.Activate(testService)
+
+	send := func(method, path, key, organization, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", key)
+		if organization != "" {
+			req.Header.Set("X-Tenant-ID", organization)
+		}
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	owner, member := uuid.NewString(), uuid.NewString()
+	subscription := func(price int) string {
+		return `{"service_name":"Slack","price":` + strconv.Itoa(price) + `,"user_id":"` + owner + `","start_date":"01-2025",` +
+			`"sharing":{"split":"equal","members":[{"user_id":"` + member + `"}]}}`
+	}
+	if rec := send("POST", "/api/v1/subscriptions/", "operator", "", subscription(700)); rec.Code != http.StatusCreated {
+		t.Fatal("operator create in default organization:", rec.Code, rec.Body.String())
+	}
+	if rec := send("POST", "/api/v1/subscriptions/", "operator", sales.ID.String(), subscription(300)); rec.Code != http.StatusCreated {
+		t.Fatal("operator create in selected organization:", rec.Code, rec.Body.String())
+	}
+
+	// ключ организации видит только ее данные
+	rec := send("GET", "/api/v1/subscriptions/total/?start_month=01-2025&end_month=01-2025", "sales-key", "", "")
+	var total models.CalculateTotalResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &total); err != nil || rec.Code != http.StatusOK || total.Total != 300 {
+		t.Error("tenant key must see only its organization", rec.Code, rec.Body.String())
+	}
+
+	// подписка на сервис в другой валюте в сумму организации не входит
+	if rec := send("POST", "/api/v1/services/", "sales-key", "", `{"name":"Zoom","currency":"USD"}`); rec.Code != http.StatusCreated {
+		t.Fatal("create service:", rec.Code, rec.Body.String())
+	}
+	zoom := `{"service_name":"Zoom","price":20,"user_id":"` + owner + `","start_date":"01-2025"}`
+	if rec := send("POST", "/api/v1/subscriptions/", "sales-key", "", zoom); rec.Code != http.StatusCreated {
+		t.Fatal("create subscription:", rec.Code, rec.Body.String())
+	}
+
+	rec = send("GET", "/api/v1/organizations/"+sales.ID.String()+"/total?start_month=01-2025&end_month=01-2025", "sales-key", "", "")
+	var organizationTotal models.OrganizationTotalResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &organizationTotal); err != nil || rec.Code != http.StatusOK {
+		t.Fatal("organization total:", rec.Code, rec.Body.String())
+	}
+	if organizationTotal.Total != 300 || len(organizationTotal.Users) != 2 ||
+		organizationTotal.Users[0].Total+organizationTotal.Users[1].Total != 300 {
+		t.Error("organization total must split shares between members", organizationTotal)
+	}
+	if organizationTotal.Currency != "RUB" ||
+		!slices.Equal(organizationTotal.OtherCurrencies, []models.CurrencyTotal{{Currency: "USD", Total: 20}}) {
+		t.Error("organization total must report other currencies apart", organizationTotal)
+	}
+
+	cases := []struct {
+		name         string
+		method, path string
+		key          string
+		organization string
+		body         string
+		expected     int
+	}{
+		{"invalid tenant header", "GET", "/api/v1/services/", "operator", "sales", "", http.StatusBadRequest},
+		{"tenant key selects another organization", "GET", "/api/v1/services/", "sales-key", tenant.Default.String(), "", http.StatusForbidden},
+		{"tenant key repeats its organization", "GET", "/api/v1/services/", "sales-key", sales.ID.String(), "", http.StatusOK},
+		{"tenant key lists organizations", "GET", "/api/v1/organizations/", "sales-key", "", "", http.StatusForbidden},
+		{"tenant key creates organization", "POST", "/api/v1/organizations/", "sales-key", "", `{"name":"Support"}`, http.StatusForbidden},
+		{"tenant key opens another organization", "GET", "/api/v1/organizations/" + tenant.Default.String(), "sales-key", "", "", http.StatusForbidden},
+		{"tenant key opens its organization", "GET", "/api/v1/organizations/" + sales.ID.String(), "sales-key", "", "", http.StatusOK},
+		{"unknown key", "GET", "/api/v1/organizations/", "unknown", "", "", http.StatusUnauthorized},
+		{"empty name", "POST", "/api/v1/organizations/", "operator", "", `{"name":"  "}`, http.StatusBadRequest},
+		{"name taken", "POST", "/api/v1/organizations/", "operator", "", `{"name":"Sales"}`, http.StatusConflict},
+		{"unknown organization", "GET", "/api/v1/organizations/" + uuid.NewString(), "operator", "", "", http.StatusNotFound},
+		{"unknown organization members", "GET", "/api/v1/organizations/" + uuid.NewString() + "/members", "operator", "", "", http.StatusNotFound},
+		{"write into unknown organization", "POST", "/api/v1/subscriptions/", "operator", uuid.NewString(), subscription(100), http.StatusNotFound},
+		{"delete default organization", "DELETE", "/api/v1/organizations/" + tenant.Default.String(), "operator", "", "", http.StatusConflict},
+		{"delete organization with subscriptions", "DELETE", "/api/v1/organizations/" + sales.ID.String(), "operator", "", "", http.StatusConflict},
+		{"add member", "PUT", "/api/v1/organizations/" + sales.ID.String() + "/members/" + uuid.NewString(), "sales-key", "", "", http.StatusOK},
+		{"remove unknown member", "DELETE", "/api/v1/organizations/" + sales.ID.String() + "/members/" + uuid.NewString(), "sales-key", "", "", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if rec := send(c.method, c.path, c.key, c.organization, c.body); rec.Code != c.expected {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = send("GET", "/api/v1/organizations/"+sales.ID.String()+"/members", "operator", "", "")
+	var members models.OrganizationMembersResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &members); err != nil || len(members.Members) != 3 {
+		t.Error("subscription users and added member must be listed", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCachedTenantIsolation(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewCachedSubscriptions(service.NewSubscriptionService(memory.NewRepository()),
+		cache.NewLRU(100), time.Minute, NewTestLog("ERROR"))
+	organization, err := svc.CreateOrganization(ctx, models.OrganizationRequest{Name: "Sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sales := tenant.WithID(ctx, organization.ID)
+
+	userID := uuid.New()
+	request := models.CalculateTotalRequest{
+		UserID:     userID,
+		StartMonth: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndMonth:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if _, err := svc.CreateSubscription(ctx, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Slack", Price: 700, StartDate: "01-2025"}); err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := svc.CalculateTotal(ctx, request); total != 700 {
+		t.Fatal("expected 700, got", total)
+	}
+	// тот же пользователь в другой организации не получает чужой кеш
+	if total, _ := svc.CalculateTotal(sales, request); total != 0 {
+		t.Error("cached total must not leak into another organization, got", total)
+	}
+	if _, err := svc.CreateSubscription(sales, models.CreateOrUpdateRequest{UserID: userID, ServiceName: "Slack", Price: 300, StartDate: "01-2025"}); err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := svc.CalculateTotal(sales, request); total != 300 {
+		t.Error("organization write must invalidate its cache, got", total)
+	}
+}
+
+func TestTenantKeysConfig(t *testing.T) {
+	organization := uuid.NewString()
+	t.Setenv("TENANT_API_KEYS", "sales-key="+organization+", support-key="+tenant.Default.String())
+	conf, err := config.ReadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.Auth.TenantKeys) != 2 || conf.Auth.TenantKeys["sales-key"] != organization {
+		t.Error("tenant keys are wrong", conf.Auth.TenantKeys)
+	}
+
+	t.Setenv("TENANT_API_KEYS", "sales-key")
+	if _, err := config.ReadConfig(""); err == nil || !strings.Contains(err.Error(), "TENANT_API_KEYS") {
+		t.Error("expected error for a key without organization", err)
+	}
+	t.Setenv("TENANT_API_KEYS", "sales-key=sales")
+	if _, err := config.ReadConfig(""); err == nil || !strings.Contains(err.Error(), "auth.tenant_keys") {
+		t.Error("expected error for organization that is not a UUID", err)
+	}
+}
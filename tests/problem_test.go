@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"agrigation_api/internal/app/server"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/config"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestProblemResponses(t *testing.T) {
+	srv := server.NewServer(config.NewStore("", config.Default()), NewTestLog("ERROR"))
+	srv.Activate(service.NewSubscriptionService(memory.NewRepository()))
+	send := func(method, path, body string) (*httptest.ResponseRecorder, models.Problem) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-Request-ID", "req-1")
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, req)
+		var p models.Problem
+		if rec.Code >= 400 {
+			if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("%s %s: content type %q", method, path, ct)
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, p
+	}
+
+	userID := uuid.NewString()
+	subscription := `{"service_name":"Netflix","price":500,"user_id":"` + userID + `","start_date":"01-2025"}`
+	if rec, _ := send("POST", "/api/v1/subscriptions/", subscription); rec.Code != http.StatusCreated {
+		t.Fatal(rec.Code, rec.Body.String())
+	}
+
+	cases := []struct {
+		name, method, path, body string
+		status                   int
+		code                     string
+	}{
+		{"duplicate", "POST", "/api/v1/subscriptions/", subscription, http.StatusConflict, "subscription_already_exists"},
+		{"missing subscription", "GET", "/api/v1/subscriptions/?user_id=" + userID + "&service_name=Okko", "", http.StatusNotFound, "subscription_not_found"},
+		{"delete missing", "DELETE", "/api/v1/subscriptions/", `{"service_name":"Okko","user_id":"` + userID + `"}`, http.StatusNotFound, "subscription_not_found"},
+		{"invalid json", "POST", "/api/v1/subscriptions/", `{"service_name":`, http.StatusBadRequest, "malformed_request"},
+		{"unknown service", "GET", "/api/v1/services/" + uuid.NewString(), "", http.StatusNotFound, "service_not_found"},
+		{"invalid period", "GET", "/api/v1/subscriptions/total/?start_month=05-2025&end_month=01-2025", "", http.StatusBadRequest, "invalid_period"},
+		{"unknown route", "GET", "/api/v1/nothing", "", http.StatusNotFound, "not_found"},
+		{"method not allowed", "PATCH", "/api/v1/subscriptions/", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"health method not allowed", "POST", "/health", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec, p := send(c.method, c.path, c.body)
+			if rec.Code != c.status || p.Status != c.status || p.Code != c.code || p.Type != "urn:problem-type:"+c.code {
+				t.Errorf("expected %d %s, got %d %s", c.status, c.code, rec.Code, rec.Body.String())
+			}
+			if p.RequestID != "req-1" || p.Title != http.StatusText(c.status) {
+				t.Error("problem must have title and request_id", p)
+			}
+		})
+	}
+
+	// 405 роутера сохраняет список методов
+	if rec, _ := send("PATCH", "/api/v1/subscriptions/", ""); rec.Header().Get("Allow") == "" {
+		t.Error("405 must have Allow header", rec.Header())
+	}
+
+	// все некорректные поля сразу, тип поля - без текста декодера
+	rec, p := send("POST", "/api/v1/subscriptions/", `{"service_name":"Okko","price":-1,"user_id":"`+userID+`","tags":["`+strings.Repeat("a", 51)+`"]}`)
+	fields := map[string]bool{}
+	for _, field := range p.Errors {
+		fields[field.Field] = true
+	}
+	if rec.Code != http.StatusBadRequest || p.Code != "validation_failed" || len(p.Errors) != 3 ||
+		!fields["price"] || !fields["start_date"] || !fields["tags"] {
+		t.Error("validation must report every invalid field:", rec.Code, rec.Body.String())
+	}
+	// даты проверяются до репозитория: неверный формат и конец раньше начала - ошибки полей, а не 500
+	dates := []struct{ body, field string }{
+		{`"start_date":"2025-01"`, "start_date"},
+		{`"start_date":"bad"`, "start_date"},
+		{`"start_date":"01-2025","end_date":"13-2025"`, "end_date"},
+		{`"start_date":"05-2025","end_date":"01-2025"`, "end_date"},
+	}
+	for _, c := range dates {
+		rec, p := send("POST", "/api/v1/subscriptions/", `{"service_name":"Okko","price":100,"user_id":"`+userID+`",`+c.body+`}`)
+		if rec.Code != http.StatusBadRequest || p.Code != "validation_failed" || len(p.Errors) != 1 || p.Errors[0].Field != c.field {
+			t.Errorf("%s: expected validation_failed for %s, got %d %s", c.body, c.field, rec.Code, rec.Body.String())
+		}
+	}
+	rec, p = send("POST", "/api/v1/subscriptions/", `{"service_name":"Okko","price":"free"}`)
+	if rec.Code != http.StatusBadRequest || p.Code != "malformed_request" || len(p.Errors) != 1 || p.Errors[0].Field != "price" ||
+		strings.Contains(rec.Body.String(), "models.") {
+		t.Error("wrong field type must be reported without decoder internals:", rec.Body.String())
+	}
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	handlers2 "agrigation_api/internal/app/server/handlers"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPromoHandlers(t *testing.T) {
+	testService := service.NewSubscriptionService(memory.NewRepository())
+	handlers := handlers2.NewHandler(testService, NewTestLog("ERROR"))
+
+	userID := uuid.New()
+	current := lifecycle.CurrentMonth(time.Now()).Format("01-2006")
+	create := func(name string, promo *models.PromoRequest) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(models.CreateOrUpdateRequest{
+			UserID: userID, ServiceName: name, Price: 300, StartDate: current, Promo: promo,
+		})
+		rec := httptest.NewRecorder()
+		handlers.CreateSubscription(rec, httptest.NewRequest("POST", "/api/v1/subscriptions/", bytes.NewReader(data)))
+		return rec
+	}
+
+	price, percent, tooMuch := 99, 10, 150
+	rec := create("Ivi", &models.PromoRequest{From: current, To: current, Price: &price})
+	var created models.Subscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); rec.Code != http.StatusCreated || err != nil ||
+		created.Price != 300 || created.EffectivePrice != 99 {
+		t.Fatal("expected list price 300 and effective price 99", rec.Code, rec.Body.String())
+	}
+
+	for name, promo := range map[string]*models.PromoRequest{
+		"invalid month":     {From: "13-2025", To: current, Price: &price},
+		"both":              {From: current, To: current, Price: &price, Percent: &percent},
+		"neither":           {From: current, To: current},
+		"percent too large": {From: current, To: current, Percent: &tooMuch},
+	} {
+		if rec := create("Okko", promo); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
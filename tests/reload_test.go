@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"agrigation_api/internal/middleware"
+	"agrigation_api/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestConfigReload(t *testing.T) {
+	path := writeTestConfig(t, "logger:\n  level: INFO\n")
+	conf, err := config.ReadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := config.NewStore(path, conf)
+
+	if err := os.WriteFile(path, []byte("logger:\n  level: ERROR\nserver:\n  port: 9999\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Get().Logger.Level != "ERROR" || len(result.Applied) != 1 {
+		t.Error("logger level was not reloaded", result)
+	}
+	if store.Get().Server.Port != conf.Server.Port || len(result.Ignored) != 1 {
+		t.Error("server port must not be reloaded", result)
+	}
+
+	// невалидный конфиг не применяется
+	if err := os.WriteFile(path, []byte("logger:\n  level: TRACE\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reload(); err == nil {
+		t.Error("expected reload error")
+	}
+	if store.Get().Logger.Level != "ERROR" {
+		t.Error("previous config must be kept", store.Get().Logger.Level)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	conf := config.Default()
+	conf.Server.RateLimit = config.RateLimitConfig{RequestsPerSecond: 0.001, Burst: 2}
+	store := config.NewStore("", conf)
+
+	handler := middleware.RateLimitMiddleware(store, NewTestLog("ERROR"),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/subscriptions/", nil))
+		codes = append(codes, rec.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Error("unexpected status codes", codes)
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	conf := config.Default()
+	conf.Server.CORS.AllowedOrigins = []string{"https://admin.example.com"}
+	store := config.NewStore("", conf)
+
+	handler := middleware.CORSMiddleware(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("OPTIONS", "/api/v1/subscriptions/", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://admin.example.com" {
+		t.Error("preflight failed", rec.Code, rec.Header())
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/subscriptions/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("origin must not be allowed")
+	}
+}
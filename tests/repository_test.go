@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"agrigation_api/internal/database/memory"
 	"agrigation_api/pkg/models"
 	"agrigation_api/pkg/tools"
 	"context"
@@ -9,10 +10,7 @@ import (
 )
 
 func TestRepositoryInt(t *testing.T) {
-	repo, err := NewTestRepository()
-	if err != nil {
-		t.Fatal(err)
-	}
+	repo := memory.NewRepository()
 	testRequest := models.CreateOrUpdateRequest{
 		ServiceName: "test_service",
 		Price:       300,
@@ -29,7 +27,7 @@ func TestRepositoryInt(t *testing.T) {
 	}
 
 	////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
-	sub, err := repo.CreateSubscription(context.Background(), testRequest)
+	sub, err := repo.CreateSubscription(context.Background(), testRequest, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -50,7 +48,7 @@ func TestRepositoryInt(t *testing.T) {
 		StartDate:   testRequest.StartDate,
 		EndDate:     testRequest.EndDate,
 	}
-	sub, errUp := repo.UpdateSubscription(context.Background(), testRequestUpdate)
+	sub, errUp := repo.UpdateSubscription(context.Background(), testRequestUpdate, nil)
 	if errUp != nil {
 		t.Fatal(errUp)
 	}
@@ -65,7 +63,8 @@ func TestRepositoryInt(t *testing.T) {
 	testRequestTotal := models.CalculateTotalRequest{
 		ServiceName: testRequest.ServiceName,
 		UserID:      testRequest.UserID,
-		StartMonth:  startDate,
+		StartMonth:  startDate.AddDate(0, -1, 0),
+		EndMonth:    startDate.AddDate(0, -1, 0),
 	}
 
 	price, err := repo.CalculateTotal(context.Background(), testRequestTotal)
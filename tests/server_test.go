@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"agrigation_api/internal/app/server"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerReadiness(t *testing.T) {
+	srv := server.NewServer(config.NewStore("", config.Default()), NewTestLog("ERROR"))
+	srv.Readiness.SetNotReady("waiting for database")
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code
+	}
+
+	if code := get("/health"); code != http.StatusOK {
+		t.Error("health must be available before database", code)
+	}
+	if code := get("/ready"); code != http.StatusServiceUnavailable {
+		t.Error("ready must be 503 while waiting for database", code)
+	}
+	if code := get("/api/v1/subscriptions/user/60601fee-2bf1-4721-ae6f-7636e79a0cba"); code != http.StatusServiceUnavailable {
+		t.Error("api must be 503 while waiting for database", code)
+	}
+
+	srv.Activate(service.NewSubscriptionService(memory.NewRepository()))
+
+	if code := get("/ready"); code != http.StatusOK {
+		t.Error("ready must be 200 after activation", code)
+	}
+	if code := get("/api/v1/subscriptions/user/60601fee-2bf1-4721-ae6f-7636e79a0cba"); code != http.StatusOK {
+		t.Error("api must work after activation", code)
+	}
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"agrigation_api/internal/database/memory"
 	"agrigation_api/internal/service"
 	"agrigation_api/pkg/models"
 	"agrigation_api/pkg/tools"
@@ -10,8 +11,7 @@ import (
 )
 
 func TestService(t *testing.T) {
-	testRepo, _ := NewTestRepository()
-	serv := service.NewSubscriptionService(testRepo)
+	serv := service.NewSubscriptionService(memory.NewRepository())
 
 	testRequest := models.CreateOrUpdateRequest{
 		ServiceName: "test_service",
@@ -63,7 +63,8 @@ func TestService(t *testing.T) {
 	testRequestTotal := models.CalculateTotalRequest{
 		ServiceName: testRequest.ServiceName,
 		UserID:      testRequest.UserID,
-		StartMonth:  startDate,
+		StartMonth:  startDate.AddDate(0, -1, 0),
+		EndMonth:    startDate.AddDate(0, -1, 0),
 	}
 
 	price, err := serv.CalculateTotal(context.Background(), testRequestTotal)
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	handlers2 "agrigation_api/internal/app/server/handlers"
+	"agrigation_api/internal/cache"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSharingValidation(t *testing.T) {
+	handlers := handlers2.NewHandler(service.NewSubscriptionService(memory.NewRepository()), NewTestLog("ERROR"))
+	owner, member := uuid.New().String(), uuid.New().String()
+
+	create := func(sharing string) int {
+		body := `{"service_name":"Spotify","price":1000,"user_id":"` + owner + `","start_date":"01-2025","sharing":` + sharing + `}`
+		rec := httptest.NewRecorder()
+		handlers.CreateSubscription(rec, httptest.NewRequest("POST", "/api/v1/subscriptions/", bytes.NewBufferString(body)))
+		return rec.Code
+	}
+
+	invalid := map[string]string{
+		"unknown split":        `{"split":"half","members":[{"user_id":"` + member + `"}]}`,
+		"no members":           `{"split":"equal","members":[]}`,
+		"owner as member":      `{"split":"equal","members":[{"user_id":"` + owner + `"}]}`,
+		"repeated member":      `{"split":"equal","members":[{"user_id":"` + member + `"},{"user_id":"` + member + `"}]}`,
+		"percent with equal":   `{"split":"equal","members":[{"user_id":"` + member + `","percent":50}]}`,
+		"percent out of range": `{"split":"percentage","members":[{"user_id":"` + member + `","percent":0}]}`,
+		"percents over 100":    `{"split":"percentage","members":[{"user_id":"` + member + `","percent":60},{"user_id":"` + uuid.NewString() + `","percent":50}]}`,
+	}
+	for name, sharing := range invalid {
+		if code := create(sharing); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, code)
+		}
+	}
+	if code := create(`{"split":"percentage","members":[{"user_id":"` + member + `","percent":25}]}`); code != http.StatusCreated {
+		t.Error("valid sharing must be accepted", code)
+	}
+}
+
+func TestCachedSharedTotals(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewCachedSubscriptions(service.NewSubscriptionService(memory.NewRepository()),
+		cache.NewLRU(100), time.Minute, NewTestLog("ERROR"))
+
+	owner, member := uuid.New(), uuid.New()
+	request := models.CalculateTotalRequest{
+		UserID:     member,
+		StartMonth: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndMonth:   time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+	subscription := models.CreateOrUpdateRequest{
+		UserID: owner, ServiceName: "Spotify", Price: 1000, StartDate: "01-2025",
+		Sharing: &models.Sharing{Split: models.SplitEqual, Members: []models.Member{{UserID: member}}},
+	}
+
+	if total, _ := svc.CalculateTotal(ctx, request); total != 0 {
+		t.Fatal("expected empty total, got", total)
+	}
+	if _, err := svc.CreateSubscription(ctx, subscription); err != nil {
+		t.Fatal(err)
+	}
+	// запись владельца инвалидирует кеш участника
+	if total, _ := svc.CalculateTotal(ctx, request); total != 500 {
+		t.Error("member total must include the new share, got", total)
+	}
+	if list, _ := svc.ListSubscriptions(ctx, member, models.ListFilter{}); len(list) != 1 {
+		t.Error("member must list the shared subscription", list)
+	}
+
+	subscription.Sharing = nil
+	if _, err := svc.UpdateSubscription(ctx, subscription); err != nil {
+		t.Fatal(err)
+	}
+	// участник, убранный обновлением, тоже теряет кеш
+	if total, _ := svc.CalculateTotal(ctx, request); total != 0 {
+		t.Error("removed member total must be empty, got", total)
+	}
+	if list, _ := svc.ListSubscriptions(ctx, member, models.ListFilter{}); len(list) != 0 {
+		t.Error("removed member must not list the subscription", list)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	handlers2 "agrigation_api/internal/app/server/handlers"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestStatusHandlers(t *testing.T) {
+	testService := service.NewSubscriptionService(memory.NewRepository())
+	handlers := handlers2.NewHandler(testService, NewTestLog("ERROR"))
+
+	userID := uuid.New()
+	if _, err := testService.CreateSubscription(context.Background(), models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Gym", Price: 2000, StartDate: "01-2025",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(handler http.HandlerFunc, body models.StatusChangeRequest) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("POST", "/api/v1/subscriptions/", bytes.NewReader(data)))
+		return rec
+	}
+	gym := models.StatusChangeRequest{UserID: userID, ServiceName: "Gym", Month: "03-2025"}
+
+	cases := []struct {
+		name     string
+		handler  http.HandlerFunc
+		body     models.StatusChangeRequest
+		expected int
+	}{
+		{"pause", handlers.PauseSubscription, gym, http.StatusOK},
+		{"pause twice", handlers.PauseSubscription, gym, http.StatusConflict},
+		{"resume before pause", handlers.ResumeSubscription, models.StatusChangeRequest{UserID: userID, ServiceName: "Gym", Month: "02-2025"}, http.StatusBadRequest},
+		{"invalid month", handlers.ResumeSubscription, models.StatusChangeRequest{UserID: userID, ServiceName: "Gym", Month: "2025-05"}, http.StatusBadRequest},
+		{"without service", handlers.CancelSubscription, models.StatusChangeRequest{UserID: userID}, http.StatusBadRequest},
+		{"not found", handlers.CancelSubscription, models.StatusChangeRequest{UserID: userID, ServiceName: "Pool"}, http.StatusNotFound},
+		{"resume", handlers.ResumeSubscription, models.StatusChangeRequest{UserID: userID, ServiceName: "Gym", Month: "05-2025"}, http.StatusOK},
+		{"cancel", handlers.CancelSubscription, models.StatusChangeRequest{UserID: userID, ServiceName: "Gym", Month: "09-2025"}, http.StatusOK},
+	}
+	for _, c := range cases {
+		if rec := send(c.handler, c.body); rec.Code != c.expected {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	list := func(status string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/subscriptions/user/"+userID.String()+"?status="+status, nil)
+		req.SetPathValue("id", userID.String())
+		rec := httptest.NewRecorder()
+		handlers.ListUserSubscriptions(rec, req)
+		return rec
+	}
+	var response struct {
+		Subscriptions []models.Subscription `json:"subscriptions"`
+	}
+	rec := list(models.StatusCancelled)
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || len(response.Subscriptions) != 1 {
+		t.Error("expected one cancelled subscription", rec.Body.String(), err)
+	}
+	if rec := list("deleted"); rec.Code != http.StatusBadRequest {
+		t.Error("unknown status must be rejected", rec.Code)
+	}
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"agrigation_api/internal/database/conformance"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/database/repository"
+	"agrigation_api/pkg/config"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// envTestPostgresBin - каталог с initdb и postgres. Если задан, conformance-тесты запускаются
+// и на временном PostgreSQL, который поднимается из этих бинарников и удаляется после тестов
+const envTestPostgresBin = "TEST_POSTGRES_BIN"
+
+// envTestPostgresRequired - если задан, тест падает вместо пропуска (для CI)
+const envTestPostgresRequired = "TEST_POSTGRES_REQUIRED"
+
+func TestMemoryConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repository.Repository {
+		return memory.NewRepository()
+	})
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repository.Repository {
+		conf := config.Default()
+		conf.Storage.Backend = config.StorageSQLite
+		conf.Storage.SQLite.Path = filepath.Join(t.TempDir(), "subscriptions.db")
+		return initTestRepository(t, conf)
+	})
+}
+
+func TestPostgresConformance(t *testing.T) {
+	server := startTestPostgres(t)
+
+	var databases atomic.Int64
+	conformance.Run(t, func(t *testing.T) repository.Repository {
+		// отдельная база на каждый тест, миграции применяет InitRepository
+		conf := config.Default()
+		conf.Database = server
+		conf.Database.Database = fmt.Sprintf("conformance_%d", databases.Add(1))
+		execTestPostgres(t, server, "CREATE DATABASE "+conf.Database.Database)
+		return initTestRepository(t, conf)
+	})
+}
+
+func initTestRepository(t *testing.T, conf *config.Config) repository.Repository {
+	t.Helper()
+	rep, err := repository.InitRepository(context.Background(), conf, NewTestLog("ERROR"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rep.CloseConnection)
+	return rep
+}
+
+// startTestPostgres - временный кластер PostgreSQL: initdb в t.TempDir и postgres на свободном порту.
+// Без envTestPostgresBin тест пропускается
+func startTestPostgres(t *testing.T) config.DatabaseConfig {
+	t.Helper()
+	skip := t.Skipf
+	if os.Getenv(envTestPostgresRequired) != "" {
+		skip = t.Fatalf
+	}
+	binDir := os.Getenv(envTestPostgresBin)
+	if binDir == "" {
+		skip("%s is not set", envTestPostgresBin)
+	}
+	if os.Geteuid() == 0 {
+		skip("postgres can not be started as root")
+	}
+
+	dataDir := filepath.Join(t.TempDir(), "data")
+	var output bytes.Buffer
+	initdb := exec.Command(filepath.Join(binDir, "initdb"), "-D", dataDir, "-U", "postgres", "--auth=trust", "--no-locale", "-E", "UTF8")
+	initdb.Stdout, initdb.Stderr = &output, &output
+	if err := initdb.Run(); err != nil {
+		t.Fatalf("initdb: %v\n%s", err, output.String())
+	}
+
+	port := freePort(t)
+	server := exec.Command(filepath.Join(binDir, "postgres"), "-D", dataDir, "-p", strconv.Itoa(port),
+		"-c", "listen_addresses=127.0.0.1", "-c", "unix_socket_directories=", "-c", "fsync=off")
+	server.Stdout, server.Stderr = &output, &output
+	if err := server.Start(); err != nil {
+		t.Fatal("start postgres:", err)
+	}
+	t.Cleanup(func() {
+		_ = server.Process.Signal(os.Interrupt)
+		_ = server.Wait()
+	})
+
+	conf := config.Default().Database
+	conf.Host = "127.0.0.1"
+	conf.Port = port
+	conf.Database = "postgres"
+	conf.SSLMode = "disable"
+	conf.Connect.MaxWait = 30 * time.Second
+
+	deadline := time.Now().Add(conf.Connect.MaxWait)
+	for {
+		conn, err := pgx.Connect(context.Background(), testPostgresDSN(conf))
+		if err == nil {
+			conn.Close(context.Background())
+			return conf
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("postgres is not ready: %v\n%s", err, output.String())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func execTestPostgres(t *testing.T, conf config.DatabaseConfig, sql string) {
+	t.Helper()
+	conn, err := pgx.Connect(context.Background(), testPostgresDSN(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(context.Background(), sql); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testPostgresDSN(conf config.DatabaseConfig) string {
+	return fmt.Sprintf("postgresql://%s@%s/%s?sslmode=disable",
+		conf.User, net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port)), conf.Database)
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
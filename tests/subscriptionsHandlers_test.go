@@ -2,6 +2,7 @@ package tests
 
 import (
 	handlers2 "agrigation_api/internal/app/server/handlers"
+	"agrigation_api/internal/database/memory"
 	"agrigation_api/internal/service"
 	logger2 "agrigation_api/pkg/logger"
 	"agrigation_api/pkg/models"
@@ -16,11 +17,7 @@ import (
 
 func TestSubscriptionHandlers(t *testing.T) {
 	testLoger := logger2.NewMyLogger("INFO")
-	testRepository, err := NewTestRepository()
-	testService := service.NewSubscriptionService(testRepository)
-	if err != nil {
-		t.Fatalf("Error initializing repository: %v", err)
-	}
+	testService := service.NewSubscriptionService(memory.NewRepository())
 	handlers := handlers2.NewHandler(testService, testLoger)
 
 	testRequest := &models.CreateOrUpdateRequest{
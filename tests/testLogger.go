@@ -19,6 +19,10 @@ func NewTestLog(level string) logger2.MyLogger {
 	}
 }
 
+func (logs *TestLog) SetLevel(level string) {
+	logs.Level = level
+}
+
 func (logs *TestLog) Info(message string, place string) {
 	if logs.Level != "INFO" {
 		return
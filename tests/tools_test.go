@@ -1,8 +1,10 @@
 package tests
 
 import (
+	"agrigation_api/internal/problem"
 	"agrigation_api/pkg/tools"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"testing"
@@ -49,7 +51,7 @@ func TestWriteTools(t *testing.T) {
 	w := TestResponseWriter{}
 	data := "testdata"
 	tools.WriteJSON(w, 200, data)
-	tools.WriteError(w, 500, data)
+	problem.Write(w, httptest.NewRequest("GET", "/", nil), 500, problem.Internal, data)
 }
 
 func TestParseTools(t *testing.T) {
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"agrigation_api/internal/app/server"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/config"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTrashAPI(t *testing.T) {
+	conf := config.Default()
+	conf.Auth.Enabled = true
+	conf.Auth.APIKeys = []string{"operator"}
+	conf.Auth.TenantKeys = map[string]string{"tenant-key": uuid.NewString()}
+	srv := server.NewServer(config.NewStore("", conf), NewTestLog("ERROR"))
+	srv.Activate(service.NewSubscriptionService(memory.NewRepository()))
+
+	send := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	userID := uuid.NewString()
+	subscription := `{"service_name":"Netflix","price":500,"user_id":"` + userID + `","start_date":"01-2025"}`
+	key := `{"service_name":"Netflix","user_id":"` + userID + `"}`
+	rec := send("POST", "/api/v1/subscriptions/", "operator", subscription)
+	var created models.Subscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated || created.ID == uuid.Nil {
+		t.Fatal("create:", rec.Code, rec.Body.String())
+	}
+	if rec := send("DELETE", "/api/v1/subscriptions/", "operator", key); rec.Code != http.StatusNoContent {
+		t.Fatal("delete:", rec.Code, rec.Body.String())
+	}
+
+	rec = send("GET", "/api/v1/admin/trash", "operator", "")
+	var trash models.TrashResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &trash); err != nil || rec.Code != http.StatusOK {
+		t.Fatal("trash:", rec.Code, rec.Body.String())
+	}
+	if len(trash.Subscriptions) != 1 || trash.Subscriptions[0].ID != created.ID || trash.Subscriptions[0].DeletedAt == nil {
+		t.Error("deleted subscription must be in the trash", rec.Body.String())
+	}
+
+	restore := "/api/v1/subscriptions/" + created.ID.String() + "/restore"
+	if rec := send("POST", "/api/v1/subscriptions/", "operator", subscription); rec.Code != http.StatusCreated {
+		t.Fatal("create after delete:", rec.Code, rec.Body.String())
+	}
+	if rec := send("POST", restore, "operator", ""); rec.Code != http.StatusConflict {
+		t.Error("restore over an existing subscription: expected 409, got", rec.Code, rec.Body.String())
+	}
+	if rec := send("DELETE", "/api/v1/subscriptions/", "operator", key); rec.Code != http.StatusNoContent {
+		t.Fatal("delete:", rec.Code, rec.Body.String())
+	}
+
+	rec = send("POST", restore, "operator", "")
+	var restored models.Subscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &restored); err != nil || rec.Code != http.StatusOK || restored.ID != created.ID || restored.DeletedAt != nil {
+		t.Fatal("restore:", rec.Code, rec.Body.String())
+	}
+
+	cases := []struct {
+		name         string
+		method, path string
+		key          string
+		expected     int
+	}{
+		{"restore twice", "POST", restore, "operator", http.StatusNotFound},
+		{"restore unknown", "POST", "/api/v1/subscriptions/" + uuid.NewString() + "/restore", "operator", http.StatusNotFound},
+		{"invalid id", "POST", "/api/v1/subscriptions/netflix/restore", "operator", http.StatusBadRequest},
+		{"trash for tenant key", "GET", "/api/v1/admin/trash", "tenant-key", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		if rec := send(c.method, c.path, c.key, ""); rec.Code != c.expected {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.expected, rec.Code, rec.Body.String())
+		}
+	}
+}
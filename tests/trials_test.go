@@ -0,0 +1,70 @@
+package tests
+
+import (
+	handlers2 "agrigation_api/internal/app/server/handlers"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/lifecycle"
+	"agrigation_api/internal/service"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTrialHandlers(t *testing.T) {
+	testService := service.NewSubscriptionService(memory.NewRepository())
+	handlers := handlers2.NewHandler(testService, NewTestLog("ERROR"))
+
+	userID := uuid.New()
+	current := lifecycle.CurrentMonth(time.Now())
+	create := func(req models.CreateOrUpdateRequest) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		handlers.CreateSubscription(rec, httptest.NewRequest("POST", "/api/v1/subscriptions/", bytes.NewReader(data)))
+		return rec
+	}
+
+	rec := create(models.CreateOrUpdateRequest{
+		UserID: userID, ServiceName: "Kinopoisk", Price: 300,
+		StartDate: current.AddDate(0, -1, 0).Format("01-2006"), TrialEnd: current.Format("01-2006"),
+	})
+	var created models.Subscription
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); rec.Code != http.StatusCreated || err != nil || !created.InTrial {
+		t.Fatal("subscription in trial expected", rec.Code, rec.Body.String())
+	}
+
+	for name, trialEnd := range map[string]string{"before start": "01-2020", "invalid": "2025-13"} {
+		rec := create(models.CreateOrUpdateRequest{
+			UserID: userID, ServiceName: "Okko", Price: 300, StartDate: "01-2025", TrialEnd: trialEnd,
+		})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("trial_end %s: expected 400, got %d", name, rec.Code)
+		}
+	}
+
+	trials := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handlers.EndingTrials(rec, httptest.NewRequest("GET", "/api/v1/subscriptions/trials/?"+query, nil))
+		return rec
+	}
+	var response models.TrialsResponse
+	rec = trials("days=31&user_id=" + userID.String())
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Days != 31 || len(response.Subscriptions) != 1 {
+		t.Error("expected one ending trial", rec.Code, rec.Body.String())
+	}
+	rec = trials("")
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Days != 7 {
+		t.Error("days must default to 7", rec.Body.String())
+	}
+
+	for _, query := range []string{"days=-1", "days=week", "days=1000", "user_id=bad"} {
+		if rec := trials(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"agrigation_api/internal/app/server"
+	"agrigation_api/internal/cache"
+	"agrigation_api/internal/database/memory"
+	"agrigation_api/internal/service"
+	"agrigation_api/internal/users"
+	"agrigation_api/pkg/config"
+	"agrigation_api/pkg/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUsersAPI(t *testing.T) {
+	testService := service.NewSubscriptionService(memory.NewRepository()).WithStrictUsers(true)
+	srv := server.NewServer(config.NewStore("", config.Default()), NewTestLog("ERROR"))
+	srv.Activate(testService)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.Router.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return rec
+	}
+
+	// Netflix в валюте пользователя, Gym не в каталоге - в валюте по умолчанию
+	if rec := send("POST", "/api/v1/services/", `{"name":"Netflix","currency":"EUR"}`); rec.Code != http.StatusCreated {
+		t.Fatal("create service:", rec.Code, rec.Body.String())
+	}
+	userID, member := uuid.NewString(), uuid.NewString()
+	subscription := `{"service_name":"Netflix","price":10,"user_id":"` + userID + `","start_date":"01-2025"}`
+	if rec := send("POST", "/api/v1/subscriptions/", subscription); rec.Code != http.StatusBadRequest {
+		t.Error("strict mode must reject an unknown user", rec.Code, rec.Body.String())
+	}
+
+	rec := send("POST", "/api/v1/users/", `{"id":"`+userID+`","display_name":"Ivan","email":"ivan@example.com","timezone":"Asia/Tokyo","currency":"eur"}`)
+	var user models.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil || rec.Code != http.StatusCreated || user.Currency != "EUR" {
+		t.Fatal("create user:", rec.Code, rec.Body.String())
+	}
+	if rec := send("POST", "/api/v1/subscriptions/", subscription); rec.Code != http.StatusCreated {
+		t.Error("registered user must be accepted", rec.Code, rec.Body.String())
+	}
+	gym := `{"service_name":"Gym","price":5,"user_id":"` + userID + `","start_date":"01-2025"}`
+	if rec := send("POST", "/api/v1/subscriptions/", gym); rec.Code != http.StatusCreated {
+		t.Fatal("create subscription:", rec.Code, rec.Body.String())
+	}
+	shared := `{"service_name":"Spotify","price":10,"user_id":"` + userID + `","start_date":"01-2025",` +
+		`"sharing":{"split":"equal","members":[{"user_id":"` + member + `"}]}}`
+	if rec := send("POST", "/api/v1/subscriptions/", shared); rec.Code != http.StatusBadRequest {
+		t.Error("strict mode must reject an unknown member", rec.Code, rec.Body.String())
+	}
+
+	cases := []struct {
+		name         string
+		method, path string
+		body         string
+		expected     int
+	}{
+		{"empty name", "POST", "/api/v1/users/", `{"display_name":" "}`, http.StatusBadRequest},
+		{"invalid email", "POST", "/api/v1/users/", `{"display_name":"Anna","email":"Anna <anna@example.com>"}`, http.StatusBadRequest},
+		{"invalid timezone", "POST", "/api/v1/users/", `{"display_name":"Anna","timezone":"Mars/Olympus"}`, http.StatusBadRequest},
+		{"invalid currency", "POST", "/api/v1/users/", `{"display_name":"Anna","currency":"euro"}`, http.StatusBadRequest},
+		{"taken id", "POST", "/api/v1/users/", `{"id":"` + userID + `","display_name":"Anna"}`, http.StatusConflict},
+		{"taken email", "POST", "/api/v1/users/", `{"display_name":"Anna","email":"IVAN@example.com"}`, http.StatusConflict},
+		{"invalid id", "GET", "/api/v1/users/ivan", "", http.StatusBadRequest},
+		{"unknown user", "GET", "/api/v1/users/" + uuid.NewString(), "", http.StatusNotFound},
+		{"update unknown user", "PUT", "/api/v1/users/" + uuid.NewString(), `{"display_name":"Anna"}`, http.StatusNotFound},
+		{"invalid delete mode", "DELETE", "/api/v1/users/" + userID + "?subscriptions=keep", "", http.StatusBadRequest},
+		{"delete unknown user", "DELETE", "/api/v1/users/" + uuid.NewString(), "", http.StatusNotFound},
+		{"total without period for unknown user", "GET", "/api/v1/subscriptions/total/?user_id=" + uuid.NewString(), "", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		if rec := send(c.method, c.path, c.body); rec.Code != c.expected {
+			t.Errorf("%s: expected %d, got %d: %s", c.name, c.expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	// валюта и текущий месяц подсчета - из профиля пользователя
+	rec = send("GET", "/api/v1/subscriptions/total/?user_id="+userID, "")
+	var total struct {
+		Total           int                    `json:"total"`
+		Currency        string                 `json:"currency"`
+		OtherCurrencies []models.CurrencyTotal `json:"other_currencies"`
+		Period          struct {
+			StartMonth time.Time `json:"start_month"`
+			EndMonth   time.Time `json:"end_month"`
+		} `json:"period"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &total); err != nil || rec.Code != http.StatusOK {
+		t.Fatal("total:", rec.Code, rec.Body.String())
+	}
+	month := users.CurrentMonth(&user, time.Now())
+	if total.Total != 10 || total.Currency != "EUR" || !total.Period.StartMonth.Equal(month) || !total.Period.EndMonth.Equal(month) {
+		t.Error("total must use the user's currency and current month", rec.Body.String())
+	}
+	// подписки в другой валюте в сумму не входят
+	if !slices.Equal(total.OtherCurrencies, []models.CurrencyTotal{{Currency: "RUB", Total: 5}}) {
+		t.Error("subscriptions in other currencies must be reported apart", rec.Body.String())
+	}
+	rec = send("GET", "/api/v1/subscriptions/user/"+userID, "")
+	var list struct {
+		Currency      string   `json:"currency"`
+		OtherCurrency []string `json:"other_currency_services"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || list.Currency != "EUR" || !slices.Equal(list.OtherCurrency, []string{"Gym"}) {
+		t.Error("list must name subscriptions in other currencies", rec.Code, rec.Body.String())
+	}
+
+	// по умолчанию подписки удаленного пользователя отменяются
+	if rec := send("DELETE", "/api/v1/users/"+userID, ""); rec.Code != http.StatusNoContent {
+		t.Fatal("delete user:", rec.Code, rec.Body.String())
+	}
+	sub, err := testService.GetSubscription(context.Background(), uuid.MustParse(userID), "Netflix")
+	if err != nil || sub == nil || sub.Status != models.StatusCancelled {
+		t.Error("subscriptions of a deleted user must be cancelled", sub, err)
+	}
+	if rec := send("GET", "/api/v1/users/"+userID, ""); rec.Code != http.StatusNotFound {
+		t.Error("deleted user must not be found", rec.Code)
+	}
+}
+
+func TestDeleteUserCascade(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewCachedSubscriptions(service.NewSubscriptionService(memory.NewRepository()).WithStrictUsers(true),
+		cache.NewLRU(100), time.Minute, NewTestLog("ERROR"))
+
+	user, err := svc.CreateUser(ctx, models.UserRequest{DisplayName: "Ivan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateSubscription(ctx, models.CreateOrUpdateRequest{UserID: user.ID, ServiceName: "Netflix", Price: 700, StartDate: "01-2025"}); err != nil {
+		t.Fatal(err)
+	}
+	request := models.CalculateTotalRequest{
+		StartMonth: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndMonth:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if total, _ := svc.CalculateTotal(ctx, request); total != 700 {
+		t.Fatal("expected 700, got", total)
+	}
+
+	affected, err := svc.DeleteUser(ctx, user.ID, true)
+	if err != nil || len(affected) != 1 {
+		t.Fatal("cascade delete failed", affected, err)
+	}
+	if sub, err := svc.GetSubscription(ctx, user.ID, "Netflix"); err != nil || sub != nil {
+		t.Error("cascade delete must remove subscriptions", sub, err)
+	}
+	// кеш подсчета сброшен удалением подписок
+	if total, _ := svc.CalculateTotal(ctx, request); total != 0 {
+		t.Error("cached total must be invalidated, got", total)
+	}
+	if _, err := svc.CreateSubscription(ctx, models.CreateOrUpdateRequest{UserID: user.ID, ServiceName: "Netflix", Price: 700, StartDate: "01-2025"}); !errors.Is(err, service.ErrUnknownUser) {
+		t.Error("expected ErrUnknownUser after delete, got", err)
+	}
+}